@@ -2,15 +2,20 @@
 package alien
 
 import (
+	"context"
 	"errors"
+	"log"
+	"net"
 	"net/http"
+	"net/url"
 	"path"
+	"regexp"
 	"strings"
 	"sync"
+	"sync/atomic"
 )
 
 var (
-	eof         = rune(0)
 	httpMethods = struct {
 		get, head, patch, put, delete, post string
 		connect, options, trace             string
@@ -18,12 +23,27 @@ var (
 		"GET", "HEAD", "PATCH", "PUT", "DELETE", "POST",
 		"CONNECT", "OPTIONS", "TRACE",
 	}
+	allMethods = []string{
+		httpMethods.get, httpMethods.head, httpMethods.post, httpMethods.put,
+		httpMethods.patch, httpMethods.delete, httpMethods.connect,
+		httpMethods.options, httpMethods.trace,
+	}
 	errRouteNotFound = errors.New("route not found")
+	errNotAllowed    = errors.New("method not allowed")
 	errBadPattern    = errors.New("bad pattern")
 	errUnknownMethod = errors.New("unkown http method")
-	headerName       = "_alien"
+	errBadMethod     = errors.New("bad http method")
+	errUnknownName   = errors.New("unknown route name")
+	errMissingParam  = errors.New("missing route param")
+	errTooManyParams = errors.New("too many route params")
+	routeKey         = &contextKey{"route"}
 )
 
+// contextKey is the type of keys used to store values in request context.
+type contextKey struct {
+	name string
+}
+
 type nodeType int
 
 const (
@@ -31,444 +51,1986 @@ const (
 	nodeParam
 	nodeNormal
 	nodeCatchAll
-	nodeEnd
 )
 
+// indexSize is the number of children above which a node indexes its
+// children by key.
+const indexSize = 8
+
+// node is a node of a radix tree. Chains of static characters are compressed
+// into the path of a single node, while params and catch alls get nodes of
+// their own which match a whole segment and the rest of the path respectively.
 type node struct {
-	key      rune
+	path     string
 	typ      nodeType
-	mu       sync.RWMutex
-	value    *route
+	ends     []*route
 	children []*node
+
+	// param and catchAll are the children for : and * keys, index has the
+	// static children by the first byte of their path once there are more
+	// than indexSize of them. They make findChild constant time.
+	param    *node
+	catchAll *node
+	index    map[byte]*node
+}
+
+// clone returns a copy of n which can be modified without affecting n. The
+// children are shared, they must be cloned too before being modified.
+func (n *node) clone() *node {
+	c := &node{
+		path:     n.path,
+		typ:      n.typ,
+		ends:     n.ends[:len(n.ends):len(n.ends)],
+		children: append([]*node(nil), n.children...),
+		param:    n.param,
+		catchAll: n.catchAll,
+	}
+	if n.index != nil {
+		c.index = make(map[byte]*node, len(n.index))
+		for k, v := range n.index {
+			c.index[k] = v
+		}
+	}
+	return c
 }
 
-func (n *node) branch(key rune, val *route, typ ...nodeType) *node {
-	child := &node{
-		key:   key,
-		value: val,
+// cloneChild replaces the static child c of n with a clone, and returns the
+// clone.
+func (n *node) cloneChild(c *node) *node {
+	cc := c.clone()
+	for i, v := range n.children {
+		if v == c {
+			n.children[i] = cc
+		}
+	}
+	if n.index != nil {
+		n.index[cc.path[0]] = cc
 	}
-	if len(typ) > 0 {
-		child.typ = typ[0]
+	return cc
+}
+
+// addChild adds the static child c to n.
+func (n *node) addChild(c *node) {
+	n.children = append(n.children, c)
+	if n.index != nil {
+		n.index[c.path[0]] = c
+	} else if len(n.children) > indexSize {
+		n.index = make(map[byte]*node)
+		for _, v := range n.children {
+			n.index[v.path[0]] = v
+		}
 	}
-	n.children = append(n.children, child)
-	return child
 }
 
-func (n *node) findChild(key rune) *node {
+// findChild returns the static child of n whose path starts with key.
+func (n *node) findChild(key byte) *node {
+	if n.index != nil {
+		return n.index[key]
+	}
 	for _, v := range n.children {
-		if v.key == key {
+		if v.path[0] == key {
 			return v
 		}
 	}
 	return nil
 }
 
+// addStatic returns the node for the static path below n, creating it and
+// splitting existing nodes as needed. If the path would be shadowed by a param
+// or catch all the node shadowing it is returned as conflict instead.
+func (n *node) addStatic(path string) (leaf, conflict *node) {
+	for len(path) > 0 {
+		c := n.findChild(path[0])
+		if c == nil {
+			if n.param != nil {
+				return nil, n.param
+			}
+			if n.catchAll != nil {
+				return nil, n.catchAll
+			}
+			c = &node{path: path, typ: nodeNormal}
+			n.addChild(c)
+			return c, nil
+		}
+		c = n.cloneChild(c)
+		i := 0
+		for i < len(path) && i < len(c.path) && path[i] == c.path[i] {
+			i++
+		}
+		if i < len(c.path) {
+			// c is split in place, so the parent and its index still point
+			// to it.
+			tail := &node{
+				path:     c.path[i:],
+				typ:      nodeNormal,
+				ends:     c.ends,
+				children: c.children,
+				param:    c.param,
+				catchAll: c.catchAll,
+				index:    c.index,
+			}
+			c.path = c.path[:i]
+			c.ends, c.children, c.param, c.catchAll, c.index = nil, nil, nil, nil, nil
+			c.addChild(tail)
+		}
+		n = c
+		path = path[i:]
+	}
+	return n, nil
+}
+
+// insert adds val to the tree rooted at n under pattern. Nodes below n are
+// cloned before being modified, so a clone of a tree which is being searched
+// can be modified safely.
+//
+// A *ConflictError is returned if val could never be matched because of the
+// routes already in the tree. That is the case for duplicate patterns, params
+// with a different name and the same constraints, and static segments, params
+// and catch alls sharing the same parent segment.
 func (n *node) insert(pattern string, val *route) error {
-	n.mu.Lock()
-	defer n.mu.Unlock()
 	if n.typ != nodeRoot {
 		return errors.New("inserting on a non root node")
 	}
-	var level *node
-	var child *node
-
-	for k, ch := range pattern {
-		if k == 0 {
-			if ch != '/' {
-				return errors.New("path must start with slash ")
+	if len(pattern) == 0 || pattern[0] != '/' {
+		return errors.New("path must start with slash ")
+	}
+	level := n
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case ':':
+			if c := level.shadowing(nodeParam); c != nil {
+				return newConflictError(c, val)
+			}
+			if level.param == nil {
+				level.param = &node{typ: nodeParam}
+			} else {
+				level.param = level.param.clone()
+			}
+			level = level.param
+			// the name and constraint of the param are not part of the tree.
+			if i := strings.IndexByte(pattern, '/'); i != -1 {
+				pattern = pattern[i:]
+			} else {
+				pattern = ""
+			}
+		case '*':
+			if c := level.shadowing(nodeCatchAll); c != nil {
+				return newConflictError(c, val)
+			}
+			if level.catchAll == nil {
+				level.catchAll = &node{typ: nodeCatchAll}
+			} else {
+				level.catchAll = level.catchAll.clone()
+			}
+			level = level.catchAll
+			pattern = ""
+		default:
+			i := strings.IndexAny(pattern, ":*")
+			if i == -1 {
+				i = len(pattern)
 			}
-			level = n
+			leaf, conflict := level.addStatic(pattern[:i])
+			if conflict != nil {
+				return newConflictError(conflict, val)
+			}
+			level = leaf
+			pattern = pattern[i:]
+		}
+	}
+	for _, v := range level.ends {
+		if v.version == val.version && v.shadows(val) {
+			return &ConflictError{Existing: v.path, Pattern: val.path, Segment: conflictSegment(v.path, val.path)}
 		}
+	}
+	level.ends = append(level.ends, val)
+	return nil
+}
 
-		child = level.findChild(ch)
-		switch level.typ {
-		case nodeParam:
-			if k < len(pattern) && ch != '/' {
-				continue
-			}
+// shadowing returns the child of n which would prevent a new child of type typ
+// from being matched, or be prevented from being matched by it. Params are
+// matched before catch alls, which are matched before static paths.
+func (n *node) shadowing(typ nodeType) *node {
+	switch typ {
+	case nodeParam:
+		if n.catchAll != nil {
+			return n.catchAll
 		}
-		if child != nil {
-			level = child
-			continue
+	case nodeCatchAll:
+		if n.param != nil {
+			return n.param
 		}
-		switch ch {
-		case ':':
-			level = level.branch(ch, nil, nodeParam)
-		case '*':
-			level = level.branch(ch, nil, nodeCatchAll)
-		default:
-			level = level.branch(ch, nil, nodeNormal)
+	}
+	if len(n.children) > 0 {
+		return n.children[0]
+	}
+	return nil
+}
+
+// firstRoute returns the first route found in the tree rooted at n.
+func (n *node) firstRoute() *route {
+	if len(n.ends) > 0 {
+		return n.ends[0]
+	}
+	for _, c := range n.children {
+		if rt := c.firstRoute(); rt != nil {
+			return rt
+		}
+	}
+	for _, c := range []*node{n.param, n.catchAll} {
+		if c != nil {
+			if rt := c.firstRoute(); rt != nil {
+				return rt
+			}
 		}
 	}
-	level.branch(eof, val, nodeEnd)
 	return nil
 }
 
-func (n *node) find(path string) (*route, error) {
-	n.mu.RLock()
-	defer n.mu.RUnlock()
+func (n *node) find(path, version string) (*route, error) {
 	if n.typ != nodeRoot {
 		return nil, errors.New("non node search")
 	}
-	var level *node
-	var isParam bool
-	for k, ch := range path {
-		if k == 0 {
-			level = n
-		}
-		c := level.findChild(ch)
-		if isParam {
-			if k < len(path) && ch != '/' {
-				continue
+	full := path
+	level := n
+	for len(path) > 0 {
+		if level.param != nil {
+			level = level.param
+			if i := strings.IndexByte(path, '/'); i != -1 {
+				path = path[i:]
+			} else {
+				path = ""
 			}
-			isParam = false
-		}
-		param := level.findChild(':')
-		if param != nil {
-			level = param
-			isParam = true
 			continue
 		}
-		catchAll := level.findChild('*')
-		if catchAll != nil {
-			level = catchAll
+		if level.catchAll != nil {
+			level = level.catchAll
+			path = ""
 			break
 		}
-		if c != nil {
-			level = c
-			continue
-		}
-		return nil, errRouteNotFound
-	}
-	if level != nil {
-		end := level.findChild(eof)
-		if end != nil {
-			return end.value, nil
+		c := level.findChild(path[0])
+		if c == nil {
+			return nil, errRouteNotFound
 		}
-		if slash := level.findChild('/'); slash != nil {
-			end = slash.findChild(eof)
-			if end != nil {
-				return end.value, nil
+		if !strings.HasPrefix(path, c.path) {
+			// a path ending just before the trailing slash of a route
+			// matches it.
+			if strings.HasPrefix(c.path, path) && c.path[len(path):] == "/" {
+				if end := c.findEnd(full, version); end != nil {
+					return end, nil
+				}
 			}
+			return nil, errRouteNotFound
+		}
+		level = c
+		path = path[len(c.path):]
+	}
+	if end := level.findEnd(full, version); end != nil {
+		return end, nil
+	}
+	if slash := level.findChild('/'); slash != nil && slash.path == "/" {
+		if end := slash.findEnd(full, version); end != nil {
+			return end, nil
 		}
 	}
 	return nil, errRouteNotFound
 }
 
-type route struct {
-	path       string
-	middleware []func(http.Handler) http.Handler
-	handler    func(http.ResponseWriter, *http.Request)
+// findFold is like find but compares static paths ignoring case. It returns
+// the route and path with its static parts in the case they were registered
+// with.
+func (n *node) findFold(path, version string) (*route, string) {
+	rt, b := n.fold(path, nil, version)
+	return rt, string(b)
 }
 
-func (r *route) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	var base http.Handler
-	if base == nil {
-		base = http.HandlerFunc(r.handler)
+// fold searches the children of n for path, buf holds the path matched so far
+// in canonical case.
+func (n *node) fold(path string, buf []byte, version string) (*route, []byte) {
+	if path == "" {
+		if end := n.findEnd(string(buf), version); end != nil {
+			return end, buf
+		}
+		if slash := n.findChild('/'); slash != nil && slash.path == "/" {
+			if end := slash.findEnd(string(buf), version); end != nil {
+				return end, buf
+			}
+		}
+		return nil, nil
 	}
-	for _, m := range r.middleware {
-		base = m(base)
+	for _, c := range n.children {
+		if len(path) >= len(c.path) && strings.EqualFold(path[:len(c.path)], c.path) {
+			if rt, b := c.fold(path[len(c.path):], append(buf, c.path...), version); rt != nil {
+				return rt, b
+			}
+		} else if len(path)+1 == len(c.path) && c.path[len(path)] == '/' && strings.EqualFold(path, c.path[:len(path)]) {
+			// a path ending just before the trailing slash of a route
+			// matches it.
+			b := append(buf, c.path[:len(path)]...)
+			if end := c.findEnd(string(b), version); end != nil {
+				return end, b
+			}
+		}
+	}
+	if n.param != nil {
+		i := strings.IndexByte(path, '/')
+		if i == -1 {
+			i = len(path)
+		}
+		if rt, b := n.param.fold(path[i:], append(buf, path[:i]...), version); rt != nil {
+			return rt, b
+		}
 	}
-	base.ServeHTTP(w, req)
+	if n.catchAll != nil {
+		return n.catchAll.fold("", append(buf, path...), version)
+	}
+	return nil, nil
 }
 
-// parseParams parses params found in mateched from pattern. There are two kinds
-// of params, one to capture a segment which starts with : and a nother to
-// capture everything( a.k.a catch all) whis starts with *.
-//
-// For instance
-//   pattern:="/hello/:name"
-//   matched:="/hello/world"
-// Will result into name:world. this function captures the named params and
-// theri coreesponding values, returning them in a comma separated  string of a
-// key:value nature. please see the tests for more details.
-func parseParams(matched, pattern string) (result string, err error) {
-	if strings.Contains(pattern, ":") || strings.Contains(pattern, "*") {
-		p1 := strings.Split(matched, "/")
-		p2 := strings.Split(pattern, "/")
-		s1 := len(p1)
-		s2 := len(p2)
-		if s1 < s2 {
-			err = errBadPattern
-			return
-		}
-		for k, v := range p2 {
-			if len(v) > 0 {
-				switch v[0] {
-				case ':':
-					if len(result) == 0 {
-						result = v[1:] + ":" + p1[k]
-						continue
-					}
-					result = result + "," + v[1:] + ":" + p1[k]
-				case '*':
-					name := "catch"
-					if k != s2-1 {
-						err = errBadPattern
-						return
-					}
-					if len(v) > 1 {
-						name = v[1:]
-					}
-					if len(result) == 0 {
-						result = name + ":" + strings.Join(p1[k:], "/")
-						return
-					}
-					result = result + "," + name + ":" + strings.Join(p1[k:], "/")
-					return
-				}
-			}
+// modify returns a copy of the tree rooted at n where the routes ending at the
+// node for pattern are replaced by the result of fn. It returns false if there
+// is no such node or fn doesn't change the routes. Nodes left empty are removed
+// and static nodes left with a single child are merged with it.
+func (n *node) modify(pattern string, fn func(ends []*route) ([]*route, bool)) (*node, bool) {
+	if pattern == "" {
+		ends, ok := fn(n.ends)
+		if !ok {
+			return n, false
+		}
+		c := n.clone()
+		c.ends = ends
+		return c.compact(), true
+	}
+	switch pattern[0] {
+	case ':':
+		if n.param == nil {
+			return n, false
+		}
+		rest := ""
+		if i := strings.IndexByte(pattern, '/'); i != -1 {
+			rest = pattern[i:]
+		}
+		p, ok := n.param.modify(rest, fn)
+		if !ok {
+			return n, false
+		}
+		c := n.clone()
+		c.param = p
+		return c.compact(), true
+	case '*':
+		if n.catchAll == nil {
+			return n, false
+		}
+		p, ok := n.catchAll.modify("", fn)
+		if !ok {
+			return n, false
+		}
+		c := n.clone()
+		c.catchAll = p
+		return c.compact(), true
+	}
+	child := n.findChild(pattern[0])
+	if child == nil || !strings.HasPrefix(pattern, child.path) {
+		return n, false
+	}
+	nc, ok := child.modify(pattern[len(child.path):], fn)
+	if !ok {
+		return n, false
+	}
+	c := n.clone()
+	children := c.children
+	c.children, c.index = nil, nil
+	for _, v := range children {
+		switch {
+		case v != child:
+			c.addChild(v)
+		case nc != nil:
+			c.addChild(nc)
 		}
 	}
-	return
+	return c.compact(), true
 }
 
-// Params stores route params.
-type Params map[string]string
+// compact returns nil if n is a node without routes or children, or n merged
+// with its only child if it is a static node without routes. n must not be
+// shared.
+func (n *node) compact() *node {
+	if n.typ == nodeRoot || len(n.ends) > 0 || n.param != nil || n.catchAll != nil {
+		return n
+	}
+	switch len(n.children) {
+	case 0:
+		return nil
+	case 1:
+		if n.typ != nodeNormal {
+			return n
+		}
+		c := n.children[0].clone()
+		c.path = n.path + c.path
+		return c
+	}
+	return n
+}
 
-// Load loads params found in src into p.
-func (p Params) Load(src string) {
-	s := strings.Split(src, ",")
-	var vars []string
-	for _, v := range s {
-		vars = strings.Split(v, ":")
-		if len(vars) != 2 {
+// findEnd returns the first route ending at n which accepts path and is
+// registered for version, or else the first one without a version.
+func (n *node) findEnd(path, version string) *route {
+	var unversioned *route
+	for _, v := range n.ends {
+		if v.version != version && v.version != "" || !v.match(path) {
 			continue
 		}
-		p[vars[0]] = vars[1]
+		if v.version == version {
+			return v
+		}
+		if unversioned == nil {
+			unversioned = v
+		}
 	}
+	return unversioned
 }
 
-// Get returns value associated with key.
-func (p Params) Get(key string) string {
-	return p[key]
-}
+type route struct {
+	mux         *Mux
+	path        string
+	name        string
+	constraints map[int]*regexp.Regexp
+	version     string
+	rewrite     string
+	middleware  []func(http.Handler) http.Handler
+	handler     func(http.ResponseWriter, *http.Request)
 
-// GetParams returrns route params stored in r.
-func GetParams(r *http.Request) Params {
-	c := r.Header.Get(headerName)
-	if c != "" {
-		p := make(Params)
-		p.Load(c)
-		return p
-	}
-	return nil
-}
+	// chain is handler wrapped by middleware, it is built on the first
+	// request.
+	once  sync.Once
+	chain http.Handler
 
-type router struct {
-	get, post, patch, put, head     *node
-	connect, options, trace, delete *node
+	// hits counts the requests served by the route, see EnableStats.
+	hits atomic.Int64
 }
 
-func (r *router) addRoute(method, path string, h func(http.ResponseWriter, *http.Request), wares ...func(http.Handler) http.Handler) error {
-	newRoute := &route{path: path, handler: h}
-	if len(wares) > 0 {
-		newRoute.middleware = append(newRoute.middleware, wares...)
+// match returns true if the path segments satisfy the param constraints of the
+// route.
+func (r *route) match(path string) bool {
+	if r == nil || len(r.constraints) == 0 {
+		return true
 	}
-	switch method {
-	case httpMethods.get:
-		if r.get == nil {
-			r.get = &node{typ: nodeRoot}
-		}
-		return r.get.insert(path, newRoute)
-	case httpMethods.post:
-		if r.post == nil {
-			r.post = &node{typ: nodeRoot}
-		}
-		return r.post.insert(path, newRoute)
-	case httpMethods.put:
-		if r.put == nil {
-			r.put = &node{typ: nodeRoot}
-		}
-		return r.put.insert(path, newRoute)
-	case httpMethods.patch:
-		if r.patch == nil {
-			r.patch = &node{typ: nodeRoot}
-		}
-		return r.patch.insert(path, newRoute)
-	case httpMethods.head:
-		if r.head == nil {
-			r.head = &node{typ: nodeRoot}
-		}
-		return r.head.insert(path, newRoute)
-	case httpMethods.connect:
-		if r.connect == nil {
-			r.connect = &node{typ: nodeRoot}
-		}
-		return r.connect.insert(path, newRoute)
-	case httpMethods.options:
-		if r.options == nil {
-			r.options = &node{typ: nodeRoot}
+	matched := 0
+	for k := 0; ; k++ {
+		seg, rest, more := strings.Cut(path, "/")
+		if re, ok := r.constraints[k]; ok {
+			if !re.MatchString(seg) {
+				return false
+			}
+			matched++
 		}
-		return r.options.insert(path, newRoute)
-	case httpMethods.trace:
-		if r.trace == nil {
-			r.trace = &node{typ: nodeRoot}
+		if !more {
+			break
 		}
-		return r.trace.insert(path, newRoute)
-	case httpMethods.delete:
-		if r.delete == nil {
-			r.delete = &node{typ: nodeRoot}
+		path = rest
+	}
+	return matched == len(r.constraints)
+}
+
+// shadows returns true if r matches every path matched by other, which has the
+// same shape. That is the case when other has at least the constraints of r.
+func (r *route) shadows(other *route) bool {
+	for k, re := range r.constraints {
+		o, ok := other.constraints[k]
+		if !ok || o.String() != re.String() {
+			return false
 		}
-		return r.delete.insert(path, newRoute)
 	}
-	return errUnknownMethod
+	return true
 }
 
-func (r *router) find(method, path string) (*route, error) {
-	switch method {
-	case httpMethods.get:
-		if r.get != nil {
-			return r.get.find(path)
+// ConflictError is returned when registering a route which could never be
+// matched because of a route registered before it.
+type ConflictError struct {
+	// Existing is the pattern of the registered route and Pattern the pattern
+	// of the new one.
+	Existing string
+	Pattern  string
+
+	// Segment is the segment of Pattern which conflicts with Existing.
+	Segment string
+}
+
+func (e *ConflictError) Error() string {
+	return "alien: route " + e.Pattern + " conflicts with " + e.Existing + " at segment " + e.Segment
+}
+
+// newConflictError returns the error for val conflicting with the routes of
+// the tree rooted at n.
+func newConflictError(n *node, val *route) error {
+	e := &ConflictError{Pattern: val.path}
+	if rt := n.firstRoute(); rt != nil {
+		e.Existing = rt.path
+	}
+	e.Segment = conflictSegment(e.Existing, e.Pattern)
+	return e
+}
+
+// conflictSegment returns the first segment of pattern which differs from
+// existing, or its last segment if they are the same.
+func conflictSegment(existing, pattern string) string {
+	a := strings.Split(existing, "/")
+	b := strings.Split(pattern, "/")
+	for k, v := range b {
+		if k >= len(a) || a[k] != v {
+			return v
 		}
-	case httpMethods.post:
-		if r.post != nil {
-			return r.post.find(path)
+	}
+	return b[len(b)-1]
+}
+
+// DuplicateParamError is returned when registering a pattern in which two
+// params have the same name, like /hell/:one/:one, since only one of the
+// values could be retrieved.
+type DuplicateParamError struct {
+	Pattern string
+	Name    string
+}
+
+func (e *DuplicateParamError) Error() string {
+	return "alien: param " + e.Name + " appears more than once in " + e.Pattern
+}
+
+// checkParamNames returns a *DuplicateParamError if two params of pattern
+// have the same name.
+func checkParamNames(pattern string) error {
+	names := paramNames(pattern)
+	for i, name := range names {
+		for _, n := range names[:i] {
+			if n == name {
+				return &DuplicateParamError{Pattern: pattern, Name: name}
+			}
 		}
-	case httpMethods.put:
-		if r.put != nil {
-			return r.put.find(path)
+	}
+	return nil
+}
+
+// paramNames returns the names of the params of pattern in order, catch alls
+// without a name are named catch.
+func paramNames(pattern string) []string {
+	var names []string
+	for _, v := range strings.Split(pattern, "/") {
+		if len(v) == 0 || v[0] != ':' && v[0] != '*' {
+			continue
 		}
-	case httpMethods.patch:
-		if r.patch != nil {
-			return r.patch.find(path)
+		name := "catch"
+		if len(v) > 1 {
+			name = paramName(v)
 		}
-	case httpMethods.head:
-		if r.head != nil {
-			return r.head.find(path)
+		names = append(names, name)
+	}
+	return names
+}
+
+// parseConstraints compiles the regular expressions found in named params of
+// pattern. The returned map is keyed by the index of the path segment.
+//
+// For instance
+//   pattern:="/users/:id([0-9]+)"
+// Will result into a constraint [0-9]+ on the segment at index 2.
+func parseConstraints(pattern string) (map[int]*regexp.Regexp, error) {
+	var c map[int]*regexp.Regexp
+	for k, v := range strings.Split(pattern, "/") {
+		if len(v) == 0 || v[0] != ':' {
+			if strings.ContainsAny(v, "()") {
+				return nil, errBadPattern
+			}
+			continue
 		}
-	case httpMethods.connect:
-		if r.connect != nil {
-			return r.connect.find(path)
+		i := strings.IndexByte(v, '(')
+		if i == -1 {
+			if strings.IndexByte(v, ')') != -1 {
+				return nil, errBadPattern
+			}
+			continue
 		}
-	case httpMethods.options:
-		if r.options != nil {
-			return r.options.find(path)
+		if i == 1 || v[len(v)-1] != ')' {
+			return nil, errBadPattern
 		}
-	case httpMethods.trace:
-		if r.trace != nil {
-			return r.trace.find(path)
+		re, err := regexp.Compile("^(?:" + v[i+1:len(v)-1] + ")$")
+		if err != nil {
+			return nil, err
 		}
-	case httpMethods.delete:
-		if r.delete != nil {
-			return r.delete.find(path)
+		if c == nil {
+			c = make(map[int]*regexp.Regexp)
 		}
+		c[k] = re
 	}
-	return nil, errRouteNotFound
+	return c, nil
 }
 
-// Mux is a http multiplexer that allows matching of http requests to the
-// registered http handlers.
-//
-// Mux supports named parameters in urls like
-//   /hello/:name
-// will match
-//   /hello/world
-// where by inside the request passed to the handler, the param with key name and
-// value world will be passed.
-//
-// Mux supports catch all parameters too
-//   /hello/*whatever
-// will match
-//   /hello/world
-//   /hello/world/tanzania
-//   /hello/world/afica/tanzania.png
-// where by inside the request passed to the handler, the param with key
-// whatever will be set and valuew will be
-//   world
-//   world/tanzania
-//   world/afica/tanzania.png
-//
-// If you dont specify a name in a catch all route, then the default name "catch"
-// will be ussed.
-type Mux struct {
-	prefix     string
-	middleware []func(http.Handler) http.Handler
-	notFound   http.Handler
-	*router
+// paramName returns the name of the param segment v without the leading : or *
+// and the regular expression constraint if any.
+func paramName(v string) string {
+	if i := strings.IndexByte(v, '('); i != -1 {
+		v = v[:i]
+	}
+	return v[1:]
 }
 
-// New returns a new *Mux instance with default handler for mismatched routes.
-func New() *Mux {
-	m := &Mux{}
-	m.router = &router{}
-	m.notFound = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		http.Error(w, errRouteNotFound.Error(), http.StatusNotFound)
-	})
-	return m
+func (r *route) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.compile()
+	r.chain.ServeHTTP(w, req)
 }
 
-// AddRoute registers h with pattern and method. If there is a path prefix
-// created via the Group method) it will be set.
-func (m *Mux) AddRoute(method, pattern string, h func(http.ResponseWriter, *http.Request)) error {
-	if m.prefix != "" {
-		pattern = path.Join(m.prefix, pattern)
+// serveHandler serves req with the handler of r, timing it when req is served
+// by ServerTiming.
+func (r *route) serveHandler(w http.ResponseWriter, req *http.Request) {
+	if t := timingsFrom(req.Context()); t != nil {
+		defer t.handle().Stop()
 	}
-	return m.addRoute(method, pattern, h, m.middleware...)
+	r.handler(w, req)
 }
 
-// Get registers h wih pattern and method GET.
-func (m *Mux) Get(pattern string, h func(http.ResponseWriter, *http.Request)) error {
-	return m.AddRoute(httpMethods.get, pattern, h)
+// compile wraps the handler of r with its middleware once.
+func (r *route) compile() {
+	r.once.Do(func() {
+		var base http.Handler = http.HandlerFunc(r.serveHandler)
+		for _, m := range r.middleware {
+			base = m(guard(base))
+		}
+		r.chain = base
+	})
 }
 
-// Put registers h wih pattern and method PUT.
-func (m *Mux) Put(path string, h func(http.ResponseWriter, *http.Request)) error {
-	return m.AddRoute(httpMethods.put, path, h)
+// parseParams parses params found in mateched from pattern. There are two kinds
+// of params, one to capture a segment which starts with : and a nother to
+// capture everything( a.k.a catch all) whis starts with *.
+//
+// For instance
+//   pattern:="/hello/:name"
+//   matched:="/hello/world"
+// Will result into name:world. this function captures the named params and
+// theri coreesponding values, returning them in the order they appear in
+// pattern. please see the tests for more details.
+func parseParams(matched, pattern string) (Params, error) {
+	return appendParams(nil, matched, pattern)
 }
 
-// Post registers h wih pattern and method POST.
-func (m *Mux) Post(path string, h func(http.ResponseWriter, *http.Request)) error {
-	return m.AddRoute(httpMethods.post, path, h)
+// appendParams is like parseParams but appends the params to dst. It walks
+// the segments of matched and pattern side by side, so the only allocations
+// are for growing dst.
+func appendParams(dst Params, matched, pattern string) (Params, error) {
+	if !strings.ContainsAny(pattern, ":*") {
+		return dst, nil
+	}
+	for {
+		seg, rest, more := strings.Cut(pattern, "/")
+		pattern = rest
+		if len(seg) > 0 {
+			switch seg[0] {
+			case ':':
+				val, _, _ := strings.Cut(matched, "/")
+				dst = append(dst, Param{Key: paramName(seg), Value: val})
+			case '*':
+				if more {
+					return dst, errBadPattern
+				}
+				name := "catch"
+				if len(seg) > 1 {
+					name = seg[1:]
+				}
+				return append(dst, Param{Key: name, Value: matched}), nil
+			}
+		}
+		if !more {
+			return dst, nil
+		}
+		i := strings.IndexByte(matched, '/')
+		if i == -1 {
+			return dst, errBadPattern
+		}
+		matched = matched[i+1:]
+	}
 }
 
-// Patch registers h wih pattern and method PATCH.
-func (m *Mux) Patch(path string, h func(http.ResponseWriter, *http.Request)) error {
-	return m.AddRoute(httpMethods.patch, path, h)
+// Param is a single route param.
+type Param struct {
+	Key   string
+	Value string
 }
 
-// Head registers h wih pattern and method HEAD.
-func (m *Mux) Head(path string, h func(http.ResponseWriter, *http.Request)) error {
-	return m.AddRoute(httpMethods.head, path, h)
+// Params stores route params in the order they appear in the route pattern,
+// so they can be iterated in order
+//   for _, v := range alien.GetParams(r) {
+//       fmt.Println(v.Key, v.Value)
+//   }
+type Params []Param
+
+// Get returns value associated with key.
+func (p Params) Get(key string) string {
+	v, _ := p.lookupValue(key)
+	return v
 }
 
-// Options registers h wih pattern and method OPTIONS.
-func (m *Mux) Options(path string, h func(http.ResponseWriter, *http.Request)) error {
-	return m.AddRoute(httpMethods.options, path, h)
+// Has returns true if p has a param named key, even with an empty value like
+// a catch all matching nothing.
+func (p Params) Has(key string) bool {
+	_, ok := p.lookupValue(key)
+	return ok
 }
 
-// Connect  registers h wih pattern and method CONNECT.
-func (m *Mux) Connect(path string, h func(http.ResponseWriter, *http.Request)) error {
-	return m.AddRoute(httpMethods.connect, path, h)
+// Keys returns the names of the params in the order they appear in the route
+// pattern.
+func (p Params) Keys() []string {
+	keys := make([]string, len(p))
+	for k, v := range p {
+		keys[k] = v.Key
+	}
+	return keys
 }
 
-// Trace registers h wih pattern and method TRACE.
-func (m *Mux) Trace(path string, h func(http.ResponseWriter, *http.Request)) error {
-	return m.AddRoute(httpMethods.trace, path, h)
+func (p Params) lookupValue(key string) (string, bool) {
+	for _, v := range p {
+		if v.Key == key {
+			return v.Value, true
+		}
+	}
+	return "", false
 }
 
-// Delete registers h wih pattern and method DELETE.
-func (m *Mux) Delete(path string, h func(http.ResponseWriter, *http.Request)) error {
-	return m.AddRoute(httpMethods.delete, path, h)
+// ParamsFromContext returns route params stored in ctx, ctx is the context of
+// requests served by a Mux.
+//
+// The params are recycled when the handler returns, use Copy to keep them
+// longer, for instance in a goroutine started by the handler.
+func ParamsFromContext(ctx context.Context) Params {
+	if c, ok := ctx.Value(routeKey).(*routeContext); ok && len(c.params) > 0 {
+		return c.params
+	}
+	return nil
 }
 
-// NotFoundHandler is executed when the request route is not found.
-func (m *Mux) NotFoundHandler(h http.Handler) {
-	m.notFound = h
+// WithParams returns a copy of ctx carrying params, which are returned by
+// ParamsFromContext and GetParams. Handlers served without a Mux, like by the
+// routers generated by cmd/aliengen, get their params this way.
+func WithParams(ctx context.Context, params Params) context.Context {
+	c := &routeContext{Context: ctx, params: params}
+	c.retained.Store(true)
+	return c
+}
+
+// Copy returns a copy of p which is safe to use after the handler returns.
+func (p Params) Copy() Params {
+	if p == nil {
+		return nil
+	}
+	return append(Params(nil), p...)
+}
+
+// routeContext is the context of requests served by a Mux. It holds the matched
+// route and its params, and is pooled so that routing doesn't allocate.
+type routeContext struct {
+	context.Context
+	route  *route
+	params Params
+
+	// w wraps the response writer to track whether the response was
+	// committed.
+	w responseWriter
+
+	// retained is set when the context may be used after the handler returns,
+	// so it must not be recycled.
+	retained atomic.Bool
+}
+
+var routeContextPool = sync.Pool{
+	New: func() interface{} {
+		return &routeContext{params: make(Params, 0, 8)}
+	},
+}
+
+// Value returns c itself for routeKey, other keys are looked up in the parent
+// context.
+func (c *routeContext) Value(key interface{}) interface{} {
+	if key == routeKey {
+		return c
+	}
+	return c.Context.Value(key)
+}
+
+// Done retains c before returning the done channel of the parent context.
+// Contexts derived with a cancel function watch the channel of their parent,
+// and are detached from it when cancelled, which can happen after the handler
+// returns, for instance when the body of an outgoing request is closed.
+func (c *routeContext) Done() <-chan struct{} {
+	if !c.retained.Load() {
+		c.retained.Store(true)
+	}
+	return c.Context.Done()
+}
+
+// routeOf returns the route which matched the request with context ctx.
+func routeOf(ctx context.Context) *route {
+	if c, ok := ctx.Value(routeKey).(*routeContext); ok {
+		return c.route
+	}
+	return nil
+}
+
+// retain prevents the route context of ctx from being recycled, it must be
+// called before the handler returns.
+func retain(ctx context.Context) {
+	if c, ok := ctx.Value(routeKey).(*routeContext); ok {
+		c.retained.Store(true)
+	}
+}
+
+// GetParams returrns route params stored in r. It is a shorthand for
+// ParamsFromContext(r.Context()).
+func GetParams(r *http.Request) Params {
+	return ParamsFromContext(r.Context())
+}
+
+// RoutePattern returns the pattern of the route which matched r, or an empty
+// string if r was not matched by a Mux. Middlewares can use it to label requests
+// by route instead of the raw path.
+func RoutePattern(r *http.Request) string {
+	if h := routeOf(r.Context()); h != nil {
+		return h.path
+	}
+	return ""
+}
+
+// muxOf returns the Mux which registered the route which matched r, or nil if
+// r wasn't served by a Mux.
+func muxOf(r *http.Request) *Mux {
+	if h := routeOf(r.Context()); h != nil {
+		return h.mux
+	}
+	return nil
+}
+
+// trees holds the route trees of a router. Trees stored in a router are never
+// modified, registration stores a modified copy instead, so that requests are
+// matched without locking.
+type trees struct {
+	get, post, patch, put, head     *node
+	connect, options, trace, delete *node
+	custom                          []methodTree
+	fallbacks                       []*route
+	rewrites                        *node
+
+	// groups have their own not found or method not allowed handler.
+	groups []*Mux
+
+	// static indexes the nodes of routes without params, it is built on the
+	// first lookup.
+	static *staticIndex
+}
+
+// staticIndex maps the method and path of the routes without params to the
+// node they end at, so they are found with a single map lookup.
+type staticIndex struct {
+	once  sync.Once
+	nodes map[matchKey]*node
+}
+
+// staticNode returns the node of the routes registered for method with the
+// static pattern path, or nil if there is none.
+func (t *trees) staticNode(method, path string) *node {
+	if t.static == nil {
+		return nil
+	}
+	t.static.once.Do(func() {
+		t.static.nodes = make(map[matchKey]*node)
+		for _, method := range t.methods() {
+			if root := *t.root(method); root != nil {
+				root.indexStatic(method, "", t.static.nodes)
+			}
+		}
+	})
+	return t.static.nodes[matchKey{method, path}]
+}
+
+// indexStatic adds the nodes below n reached through static paths only to
+// nodes, prefix being the path of n's parent.
+func (n *node) indexStatic(method, prefix string, nodes map[matchKey]*node) {
+	prefix += n.path
+	if len(n.ends) > 0 {
+		nodes[matchKey{method, prefix}] = n
+	}
+	for _, c := range n.children {
+		c.indexStatic(method, prefix, nodes)
+	}
+}
+
+// methodTree is the tree of a method registered with RegisterMethod.
+type methodTree struct {
+	method string
+	root   *node
+}
+
+// emptyTrees is used by routers without routes.
+var emptyTrees = &trees{}
+
+// root returns the field of t holding the tree for method, or nil if method is
+// unknown.
+func (t *trees) root(method string) **node {
+	switch method {
+	case httpMethods.get:
+		return &t.get
+	case httpMethods.post:
+		return &t.post
+	case httpMethods.put:
+		return &t.put
+	case httpMethods.patch:
+		return &t.patch
+	case httpMethods.head:
+		return &t.head
+	case httpMethods.connect:
+		return &t.connect
+	case httpMethods.options:
+		return &t.options
+	case httpMethods.trace:
+		return &t.trace
+	case httpMethods.delete:
+		return &t.delete
+	}
+	for i := range t.custom {
+		if t.custom[i].method == method {
+			return &t.custom[i].root
+		}
+	}
+	return nil
+}
+
+// methods returns the standard methods followed by the methods registered with
+// RegisterMethod.
+func (t *trees) methods() []string {
+	if len(t.custom) == 0 {
+		return allMethods
+	}
+	methods := append([]string(nil), allMethods...)
+	for _, c := range t.custom {
+		methods = append(methods, c.method)
+	}
+	return methods
+}
+
+type router struct {
+	mu     sync.Mutex // serializes registration
+	trees  atomic.Pointer[trees]
+	names  map[string]string
+	frozen atomic.Bool
+	cache  *matchCache // see MatchCache
+}
+
+// load returns the current trees of r.
+func (r *router) load() *trees {
+	if t := r.trees.Load(); t != nil {
+		return t
+	}
+	return emptyTrees
+}
+
+// update calls fn with a copy of the current trees of r, and stores it if fn
+// succeeds. fn must clone nodes before modifying them.
+func (r *router) update(fn func(t *trees) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checkFrozen()
+	t := *r.load()
+	// the roots of custom methods are modified in place.
+	t.custom = append([]methodTree(nil), t.custom...)
+	if err := fn(&t); err != nil {
+		return err
+	}
+	t.static = &staticIndex{}
+	r.trees.Store(&t)
+	return nil
+}
+
+// fallback returns the fallback route with the longest prefix of path, for GET
+// and HEAD requests which didn't match any route.
+func (r *router) fallback(method, path string) *route {
+	if method != httpMethods.get && method != httpMethods.head {
+		return nil
+	}
+	var match *route
+	for _, f := range r.load().fallbacks {
+		if f.path == "/" || path == f.path || strings.HasPrefix(path, f.path+"/") {
+			if match == nil || len(f.path) > len(match.path) {
+				match = f
+			}
+		}
+	}
+	return match
+}
+
+// addGroup registers g as having its own handlers for requests below its
+// prefix which don't match a route.
+func (r *router) addGroup(g *Mux) {
+	r.update(func(t *trees) error {
+		for _, v := range t.groups {
+			if v == g {
+				return nil
+			}
+		}
+		t.groups = append(t.groups[:len(t.groups):len(t.groups)], g)
+		return nil
+	})
+}
+
+// group returns the registered group with the longest prefix of path, or nil
+// if there is none.
+func (r *router) group(path string) *Mux {
+	var match *Mux
+	for _, g := range r.load().groups {
+		if underPrefix(path, g.prefix) {
+			if match == nil || len(g.prefix) > len(match.prefix) {
+				match = g
+			}
+		}
+	}
+	return match
+}
+
+// underPrefix returns true if path is prefix or below it. Params of prefix
+// match any segment, so /tenants/acme/users is below /tenants/:tenant.
+func underPrefix(path, prefix string) bool {
+	if prefix == "" || prefix == "/" {
+		return true
+	}
+	if !strings.ContainsAny(prefix, ":*") {
+		return path == prefix || strings.HasPrefix(path, prefix+"/")
+	}
+	for {
+		pseg, prest, pmore := strings.Cut(prefix, "/")
+		seg, rest, more := strings.Cut(path, "/")
+		switch {
+		case pseg != "" && pseg[0] == '*':
+			return true
+		case pseg != "" && pseg[0] == ':':
+			if seg == "" {
+				return false
+			}
+		case pseg != seg:
+			return false
+		}
+		if !pmore {
+			return true
+		}
+		if !more {
+			return false
+		}
+		prefix, path = prest, rest
+	}
+}
+
+func (r *router) addName(name, pattern string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.names == nil {
+		r.names = make(map[string]string)
+	}
+	r.names[name] = pattern
+}
+
+// buildURL substitutes params into pattern. params is a list of key, value
+// pairs.
+func buildURL(pattern string, params ...string) (string, error) {
+	if len(params)%2 != 0 {
+		return "", errBadPattern
+	}
+	lookup := func(key string) (string, bool) {
+		for i := 0; i < len(params); i += 2 {
+			if params[i] == key {
+				return params[i+1], true
+			}
+		}
+		return "", false
+	}
+	segments := strings.Split(pattern, "/")
+	for k, v := range segments {
+		if len(v) == 0 {
+			continue
+		}
+		switch v[0] {
+		case ':':
+			val, ok := lookup(paramName(v))
+			if !ok {
+				return "", errMissingParam
+			}
+			segments[k] = val
+		case '*':
+			name := "catch"
+			if len(v) > 1 {
+				name = v[1:]
+			}
+			val, ok := lookup(name)
+			if !ok {
+				return "", errMissingParam
+			}
+			segments[k] = strings.TrimPrefix(val, "/")
+		}
+	}
+	return strings.Join(segments, "/"), nil
+}
+
+// newRoute returns the route for h registered by m with path.
+func newRoute(path string, m *Mux, h func(http.ResponseWriter, *http.Request), wares ...func(http.Handler) http.Handler) (*route, error) {
+	constraints, err := parseConstraints(path)
+	if err != nil {
+		return nil, err
+	}
+	if max := m.top().maxParams; max > 0 && strings.Count(path, "/:")+strings.Count(path, "/*") > max {
+		return nil, errTooManyParams
+	}
+	if err := checkParamNames(path); err != nil {
+		return nil, err
+	}
+	rt := &route{mux: m, path: path, constraints: constraints, version: m.routeVersion(), handler: h}
+	if len(wares) > 0 {
+		rt.middleware = append(rt.middleware, wares...)
+	}
+	return rt, nil
+}
+
+func (r *router) addRoute(method, path, name string, m *Mux, h func(http.ResponseWriter, *http.Request), wares ...func(http.Handler) http.Handler) error {
+	newRoute, err := newRoute(path, m, h, wares...)
+	if err != nil {
+		return err
+	}
+	newRoute.name = name
+	return r.update(func(t *trees) error {
+		root := t.root(method)
+		if root == nil {
+			return errUnknownMethod
+		}
+		n := &node{typ: nodeRoot}
+		if *root != nil {
+			n = (*root).clone()
+		}
+		if err := n.insert(path, newRoute); err != nil {
+			return err
+		}
+		*root = n
+		return nil
+	})
+}
+
+// modifyRoute replaces the route registered for method with path by the result
+// of fn, or removes it if fn returns nil.
+func (r *router) modifyRoute(method, path, version string, fn func(*route) *route) error {
+	return r.update(func(t *trees) error {
+		root := t.root(method)
+		if root == nil {
+			return errUnknownMethod
+		}
+		if *root == nil {
+			return errRouteNotFound
+		}
+		n, ok := (*root).modify(path, func(ends []*route) ([]*route, bool) {
+			for i, v := range ends {
+				if v.path != path || v.version != version {
+					continue
+				}
+				result := append([]*route(nil), ends[:i]...)
+				if rt := fn(v); rt != nil {
+					result = append(result, rt)
+				}
+				return append(result, ends[i+1:]...), true
+			}
+			return nil, false
+		})
+		if !ok {
+			return errRouteNotFound
+		}
+		*root = n
+		return nil
+	})
+}
+
+// addFallback registers f as a fallback route.
+func (r *router) addFallback(f *route) {
+	r.update(func(t *trees) error {
+		t.fallbacks = append(t.fallbacks[:len(t.fallbacks):len(t.fallbacks)], f)
+		return nil
+	})
+}
+
+func (r *router) find(method, path, version string) (*route, error) {
+	t := r.load()
+	if n := t.staticNode(method, path); n != nil {
+		if end := n.findEnd(path, version); end != nil {
+			return end, nil
+		}
+	}
+	if root := t.root(method); root != nil && *root != nil {
+		return (*root).find(path, version)
+	}
+	return nil, errRouteNotFound
+}
+
+// lookup is like find, but if fold is true and there is no route for path it
+// falls back to the routes registered with CaseInsensitive, ignoring the case
+// of path. It returns the route and path in the case the route was registered
+// with.
+func (r *router) lookup(method, path, version string, fold bool) (*route, string, error) {
+	if r.cache == nil || version != "" {
+		return r.match(method, path, version, fold)
+	}
+	t := r.load()
+	if h, canonical, ok := r.cache.get(t, method, path); ok {
+		return h, canonical, nil
+	}
+	h, canonical, err := r.match(method, path, version, fold)
+	if err == nil {
+		r.cache.put(t, method, path, h, canonical)
+	}
+	return h, canonical, err
+}
+
+// match looks up the route for method and path in the trees of r, see lookup.
+func (r *router) match(method, path, version string, fold bool) (*route, string, error) {
+	h, err := r.find(method, path, version)
+	if err == nil || !fold {
+		return h, path, err
+	}
+	if root := r.load().root(method); root != nil && *root != nil {
+		if h, canonical := (*root).findFold(path, version); h != nil && h.mux.foldCase() {
+			return h, canonical, nil
+		}
+	}
+	return nil, path, err
+}
+
+// allowed returns methods which have a route matching path for version.
+func (r *router) allowed(path, version string) []string {
+	var methods []string
+	for _, method := range r.load().methods() {
+		_, err := r.find(method, path, version)
+		if err != nil && method == httpMethods.head {
+			_, err = r.find(httpMethods.get, path, version)
+		}
+		if err == nil {
+			methods = append(methods, method)
+		}
+	}
+	return methods
+}
+
+// headResponseWriter discards the response body, it is used to serve HEAD
+// requests with GET handlers.
+type headResponseWriter struct {
+	http.ResponseWriter
+}
+
+func (w headResponseWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+// Unwrap returns the underlying http.ResponseWriter, it is used by
+// http.ResponseController.
+func (w headResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Mux is a http multiplexer that allows matching of http requests to the
+// registered http handlers.
+//
+// Mux supports named parameters in urls like
+//   /hello/:name
+// will match
+//   /hello/world
+// where by inside the request passed to the handler, the param with key name and
+// value world will be passed.
+//
+// Mux supports catch all parameters too
+//   /hello/*whatever
+// will match
+//   /hello/world
+//   /hello/world/tanzania
+//   /hello/world/afica/tanzania.png
+// where by inside the request passed to the handler, the param with key
+// whatever will be set and valuew will be
+//   world
+//   world/tanzania
+//   world/afica/tanzania.png
+//
+// If you dont specify a name in a catch all route, then the default name "catch"
+// will be ussed.
+//
+// Named params can be constrained with a regular expression in parenthesis
+//   /users/:id([0-9]+)
+// will match
+//   /users/42
+// but not
+//   /users/gernest
+// The expression must match the whole segment, and can not contain a slash.
+type Mux struct {
+	prefix           string
+	host             string
+	hostParams       int
+	parent           *Mux
+	hosts            []*Mux
+	middleware       []func(http.Handler) http.Handler
+	notFound         http.Handler
+	methodNotAllowed http.Handler
+	next             http.Handler
+	autoOptions      bool
+	problemDetails   bool
+	redirectSlash    bool
+	redirectFixed    bool
+	useRawPath       bool
+	caseInsensitive  bool
+	redirectCase     bool
+	anyFold          bool
+	wrapUnmatched    bool
+	escapedValues    bool
+	validator        Validator
+	renderer         Renderer
+	errorHandler     func(http.ResponseWriter, *http.Request, error)
+	checkOrigin      func(*http.Request) bool
+	encoders         []encoder
+	meta             map[string]interface{}
+	version          string
+	hooks            hooks
+	stats            *stats
+	recorder         *recorder
+	maxParams        int
+	maxPathLength    int
+	maxSegments      int
+	basePath         string
+	logger           *log.Logger
+	certCache        string
+	maxBody          int64
+	*router
+}
+
+// statusHandler is the default handler of a Mux for requests which don't match
+// a route, it responds with status and err.
+type statusHandler struct {
+	m      *Mux
+	status int
+	err    error
+}
+
+func (h statusHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.m.httpError(w, r, h.status, h.err.Error())
+}
+
+// New returns a new *Mux instance with default handler for mismatched routes,
+// configured by opts.
+//   m := alien.New(
+//       alien.WithNotFound(notFound),
+//       alien.WithRedirectTrailingSlash(),
+//   )
+func New(opts ...Option) *Mux {
+	m := &Mux{}
+	m.router = &router{}
+	m.notFound = statusHandler{m, http.StatusNotFound, errRouteNotFound}
+	m.methodNotAllowed = statusHandler{m, http.StatusMethodNotAllowed, errNotAllowed}
+	for _, o := range opts {
+		o(m)
+	}
+	return m
+}
+
+// AddRoute registers h with pattern and method. If there is a path prefix
+// created via the Group method) it will be set.
+//
+// wares are middlewares which will wrap only this route. They are executed in
+// the order they are given, after the middlewares registered with Use. So
+//   m.Use(logger)
+//   m.AddRoute("GET", "/admin", h, requireAuth, audit)
+// will execute logger, requireAuth, audit and then h.
+func (m *Mux) AddRoute(method, pattern string, h func(http.ResponseWriter, *http.Request), wares ...func(http.Handler) http.Handler) error {
+	if m.prefix != "" {
+		pattern = path.Join(m.prefix, pattern)
+	}
+	return m.addRoute(method, pattern, "", m, h, m.chain(wares...)...)
+}
+
+// Handle registers the http.Handler h with pattern and method just like
+// AddRoute.
+func (m *Mux) Handle(method, pattern string, h http.Handler, wares ...func(http.Handler) http.Handler) error {
+	return m.AddRoute(method, pattern, h.ServeHTTP, wares...)
+}
+
+// RemoveRoute removes the route registered with method and pattern, it is safe
+// to call while m is serving requests. pattern must be the same as the one the
+// route was registered with, params included, and is relative to the prefix of
+// m like with AddRoute.
+//   m.Get("/plugins/:name", h)
+//   m.RemoveRoute("GET", "/plugins/:name")
+func (m *Mux) RemoveRoute(method, pattern string) error {
+	if m.prefix != "" {
+		pattern = path.Join(m.prefix, pattern)
+	}
+	return m.modifyRoute(method, pattern, m.routeVersion(), func(*route) *route {
+		return nil
+	})
+}
+
+// ReplaceRoute replaces the handler and middlewares of the route registered
+// with method and pattern, like RemoveRoute it is safe to call while m is
+// serving requests. Requests being served keep using the old handler.
+func (m *Mux) ReplaceRoute(method, pattern string, h func(http.ResponseWriter, *http.Request), wares ...func(http.Handler) http.Handler) error {
+	if m.prefix != "" {
+		pattern = path.Join(m.prefix, pattern)
+	}
+	rt, err := newRoute(pattern, m, h, m.chain(wares...)...)
+	if err != nil {
+		return err
+	}
+	return m.modifyRoute(method, pattern, m.routeVersion(), func(old *route) *route {
+		rt.name = old.name
+		return rt
+	})
+}
+
+// chain returns the middlewares wrapping a route registered with wares by m,
+// in the order they are applied by route.ServeHTTP.
+func (m *Mux) chain(wares ...func(http.Handler) http.Handler) []func(http.Handler) http.Handler {
+	var chain []func(http.Handler) http.Handler
+	for i := len(wares) - 1; i >= 0; i-- {
+		chain = append(chain, wares[i])
+	}
+	for g := m; g != nil; g = g.parent {
+		chain = append(chain, g.middleware...)
+	}
+	return chain
+}
+
+// AddNamedRoute registers h with pattern and method just like AddRoute, and
+// remembers the pattern under name so that it can be used to build urls with
+// the URL method.
+func (m *Mux) AddNamedRoute(name, method, pattern string, h func(http.ResponseWriter, *http.Request), wares ...func(http.Handler) http.Handler) error {
+	if m.prefix != "" {
+		pattern = path.Join(m.prefix, pattern)
+	}
+	if err := m.addRoute(method, pattern, name, m, h, m.chain(wares...)...); err != nil {
+		return err
+	}
+	m.addName(name, pattern)
+	return nil
+}
+
+// GetNamed registers h wih pattern and method GET under name.
+func (m *Mux) GetNamed(name, pattern string, h func(http.ResponseWriter, *http.Request), wares ...func(http.Handler) http.Handler) error {
+	return m.AddNamedRoute(name, httpMethods.get, pattern, h, wares...)
+}
+
+// URL builds a url path for the route registered under name. params are key,
+// value pairs for the named params of the route, catch all params without a
+// name use the key "catch".
+//
+// For instance
+//   m.GetNamed("user_show", "/users/:id", h)
+//   m.URL("user_show", "id", "42")
+// Will result into /users/42
+func (m *Mux) URL(name string, params ...string) (string, error) {
+	m.mu.Lock()
+	pattern, ok := m.names[name]
+	m.mu.Unlock()
+	if !ok {
+		return "", errUnknownName
+	}
+	u, err := buildURL(pattern, params...)
+	if err != nil {
+		return "", err
+	}
+	return m.top().basePath + u, nil
+}
+
+// Get registers h wih pattern and method GET.
+func (m *Mux) Get(pattern string, h func(http.ResponseWriter, *http.Request), wares ...func(http.Handler) http.Handler) error {
+	return m.AddRoute(httpMethods.get, pattern, h, wares...)
+}
+
+// Put registers h wih pattern and method PUT.
+func (m *Mux) Put(path string, h func(http.ResponseWriter, *http.Request), wares ...func(http.Handler) http.Handler) error {
+	return m.AddRoute(httpMethods.put, path, h, wares...)
+}
+
+// Post registers h wih pattern and method POST.
+func (m *Mux) Post(path string, h func(http.ResponseWriter, *http.Request), wares ...func(http.Handler) http.Handler) error {
+	return m.AddRoute(httpMethods.post, path, h, wares...)
+}
+
+// Patch registers h wih pattern and method PATCH.
+func (m *Mux) Patch(path string, h func(http.ResponseWriter, *http.Request), wares ...func(http.Handler) http.Handler) error {
+	return m.AddRoute(httpMethods.patch, path, h, wares...)
+}
+
+// Head registers h wih pattern and method HEAD.
+func (m *Mux) Head(path string, h func(http.ResponseWriter, *http.Request), wares ...func(http.Handler) http.Handler) error {
+	return m.AddRoute(httpMethods.head, path, h, wares...)
+}
+
+// Options registers h wih pattern and method OPTIONS.
+func (m *Mux) Options(path string, h func(http.ResponseWriter, *http.Request), wares ...func(http.Handler) http.Handler) error {
+	return m.AddRoute(httpMethods.options, path, h, wares...)
+}
+
+// Connect  registers h wih pattern and method CONNECT.
+func (m *Mux) Connect(path string, h func(http.ResponseWriter, *http.Request), wares ...func(http.Handler) http.Handler) error {
+	return m.AddRoute(httpMethods.connect, path, h, wares...)
+}
+
+// Trace registers h wih pattern and method TRACE.
+func (m *Mux) Trace(path string, h func(http.ResponseWriter, *http.Request), wares ...func(http.Handler) http.Handler) error {
+	return m.AddRoute(httpMethods.trace, path, h, wares...)
+}
+
+// Delete registers h wih pattern and method DELETE.
+func (m *Mux) Delete(path string, h func(http.ResponseWriter, *http.Request), wares ...func(http.Handler) http.Handler) error {
+	return m.AddRoute(httpMethods.delete, path, h, wares...)
+}
+
+// Any registers h with path for every supported http method, including the
+// ones registered with RegisterMethod.
+func (m *Mux) Any(path string, h func(http.ResponseWriter, *http.Request), wares ...func(http.Handler) http.Handler) error {
+	return m.Methods(m.load().methods(), path, h, wares...)
+}
+
+// RegisterMethod allows routes to be registered for the nonstandard http
+// method, which would be rejected otherwise to catch typos.
+//   m.RegisterMethod("PURGE")
+//   m.AddRoute("PURGE", "/cache/*", purge)
+// Methods are case sensitive, and hosts of m accept the methods registered
+// with it.
+func (m *Mux) RegisterMethod(method string) error {
+	if !validMethod(method) {
+		return errBadMethod
+	}
+	m.update(func(t *trees) error {
+		if t.root(method) == nil {
+			t.custom = append(t.custom, methodTree{method: method})
+		}
+		return nil
+	})
+	for _, hm := range m.hosts {
+		hm.RegisterMethod(method)
+	}
+	return nil
+}
+
+// validMethod returns true if method is a token as defined by RFC 7230.
+func validMethod(method string) bool {
+	if method == "" {
+		return false
+	}
+	for _, c := range method {
+		if c > '~' || c <= ' ' || strings.ContainsRune(`"(),/:;<=>?@[\]{}`, c) {
+			return false
+		}
+	}
+	return true
+}
+
+// Methods registers h with path for each of methods. Either all the routes are
+// registered or none of them, so if one of the methods is unknown or conflicts
+// with an existing route the ones already registered are removed.
+//   m.Methods([]string{"GET", "POST"}, "/form", h)
+func (m *Mux) Methods(methods []string, path string, h func(http.ResponseWriter, *http.Request), wares ...func(http.Handler) http.Handler) error {
+	for i, method := range methods {
+		if err := m.AddRoute(method, path, h, wares...); err != nil {
+			for _, added := range methods[:i] {
+				m.RemoveRoute(added, path)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// NotFoundHandler is executed when the request route is not found.
+//
+// Groups and hosts can have their own, which is used for the requests below
+// their prefix, the group with the longest prefix wins.
+//   api := m.Group("/api")
+//   api.NotFoundHandler(jsonNotFound) // misses below /api get json
+//   m.NotFoundHandler(htmlNotFound)   // and the rest of the site html
+func (m *Mux) NotFoundHandler(h http.Handler) {
+	m.notFound = h
+	if m.parent != nil {
+		m.addGroup(m)
+	}
+}
+
+// MethodNotAllowed sets h to be executed when the request path matches a route
+// registered with a different method. The Allow header is already set with the
+// methods registered for the path by the time h is called. Like with
+// NotFoundHandler, groups and hosts can have their own.
+func (m *Mux) MethodNotAllowed(h http.Handler) {
+	m.methodNotAllowed = h
+	if m.parent != nil {
+		m.addGroup(m)
+	}
+}
+
+// Fallback delegates the requests which don't match a route of m to next,
+// instead of responding with 404 Not Found or 405 Method Not Allowed. It eases
+// moving an application to alien one route at a time, the routes which
+// weren't moved yet are still served by the old router.
+//   m := alien.New()
+//   m.Get("/users/:id", showUser) // moved
+//   m.Fallback(oldRouter)         // everything else
+//
+// Automatic OPTIONS responses and the fallbacks of static file servers take
+// precedence. Like with NotFoundHandler, groups and hosts can have their own.
+func (m *Mux) Fallback(next http.Handler) {
+	m.next = next
+	if m.parent != nil {
+		m.addGroup(m)
+	}
+}
+
+// nextHandler returns the fallback handler of m or its closest parent which
+// has one, up to root.
+func (m *Mux) nextHandler(root *Mux) http.Handler {
+	for g := m; g != nil && g != root; g = g.parent {
+		if g.next != nil {
+			return g.next
+		}
+	}
+	return root.next
+}
+
+// missHandlers returns the not found and method not allowed handlers of m or
+// its closest parent which has them, up to root.
+func (m *Mux) missHandlers(root *Mux) (notFound, notAllowed http.Handler) {
+	for g := m; g != nil && g != root; g = g.parent {
+		if notFound == nil {
+			notFound = g.notFound
+		}
+		if notAllowed == nil {
+			notAllowed = g.methodNotAllowed
+		}
+	}
+	if notFound == nil {
+		notFound = root.notFound
+	}
+	if notAllowed == nil {
+		notAllowed = root.methodNotAllowed
+	}
+	return notFound, notAllowed
+}
+
+// AutoOptions when set to true makes OPTIONS requests to paths without a
+// registered OPTIONS route respond with 204 No Content, and the Allow header set
+// to the methods registered for the path.
+func (m *Mux) AutoOptions(ok bool) {
+	m.autoOptions = ok
+}
+
+// RedirectTrailingSlash when set to true redirects requests whose path differs
+// from the matched route only by a trailing slash to the registered form. For
+// instance a request to /users/ will be redirected to /users if the route was
+// registered as /users.
+//
+// GET and HEAD requests are redirected with 301 Moved Permanently, while the
+// rest use 308 Permanent Redirect so the method and body are preserved.
+func (m *Mux) RedirectTrailingSlash(ok bool) {
+	m.redirectSlash = ok
+}
+
+// RedirectFixedPath when set to true redirects requests whose path contains
+// redundant segments like //, ./ or ../ to the cleaned path, if it matches a
+// route. By default such requests are served as if the cleaned path was
+// requested.
+func (m *Mux) RedirectFixedPath(ok bool) {
+	m.redirectFixed = ok
+}
+
+// WrapUnmatched when set to true makes the middlewares registered with Use
+// wrap the responses to requests which don't match a route too, that is the
+// not found and method not allowed handlers and the automatic OPTIONS
+// responses. So 404s show up in access logs and get cors headers. Requests to
+// a host, or below a group with its own not found or method not allowed
+// handler, go through the middlewares of that host or group.
+//   m.Use(logger)
+//   m.WrapUnmatched(true)
+func (m *Mux) WrapUnmatched(ok bool) {
+	m.wrapUnmatched = ok
+}
+
+// CaseInsensitive when set to true makes routes registered through m and its
+// groups match requests whose path differs only by case, so /Users/42 is
+// served by /users/:id. Paths are compared ignoring case only when no route
+// matches exactly, and param values are kept as requested.
+//   legacy := m.Group("/Products")
+//   legacy.CaseInsensitive(true)
+func (m *Mux) CaseInsensitive(ok bool) {
+	m.caseInsensitive = ok
+	if ok {
+		g := m
+		for g.parent != nil {
+			g = g.parent
+		}
+		g.anyFold = true
+	}
+}
+
+// RedirectCaseInsensitive when set to true redirects requests matched by a
+// route registered with CaseInsensitive to the path in the case of the route,
+// instead of serving them. Redirects are done like with RedirectFixedPath.
+func (m *Mux) RedirectCaseInsensitive(ok bool) {
+	m.redirectCase = ok
+}
+
+// foldCase returns true if routes registered by m match ignoring case.
+func (m *Mux) foldCase() bool {
+	for g := m; g != nil; g = g.parent {
+		if g.caseInsensitive {
+			return true
+		}
+	}
+	return false
+}
+
+// redirectFold returns true if requests matched ignoring case by routes of m
+// are redirected.
+func (m *Mux) redirectFold() bool {
+	for g := m; g != nil; g = g.parent {
+		if g.redirectCase {
+			return true
+		}
+	}
+	return false
+}
+
+// SetPrefix serves m under prefix, for instance when an outer server passes it
+// the requests for a sub path without stripping it.
+//   m.SetPrefix("/app")
+//   http.Handle("/app/", m)
+// The prefix is stripped from the path of requests before routing, so routes
+// are registered without it and handlers see /users for /app/users. Paths
+// without the prefix are routed unchanged, which makes m work behind
+// http.StripPrefix as well. Redirects made by m and the urls built by URL
+// include the prefix. It applies to the whole Mux, groups and hosts included.
+func (m *Mux) SetPrefix(prefix string) {
+	prefix = path.Join("/", prefix)
+	if prefix == "/" {
+		prefix = ""
+	}
+	m.top().basePath = prefix
+}
+
+// stripBase returns r and reqPath, its path, without the prefix set with
+// SetPrefix.
+func (m *Mux) stripBase(r *http.Request, reqPath string) (*http.Request, string) {
+	rest, ok := strings.CutPrefix(reqPath, m.basePath)
+	if !ok || rest != "" && rest[0] != '/' {
+		return r, reqPath
+	}
+	if rest == "" {
+		rest = "/"
+	}
+	raw := r.URL.RawPath
+	r = withPath(r, strings.TrimPrefix(r.URL.Path, m.basePath))
+	if r.URL.Path == "" {
+		r.URL.Path = "/"
+	}
+	if raw != "" {
+		r.URL.RawPath = strings.TrimPrefix(raw, m.basePath)
+	}
+	return r, rest
+}
+
+// UseRawPath when set to true matches routes against the escaped path of
+// requests, so an encoded slash like in /files/a%2Fb.txt doesn't separate
+// segments and /files/:name matches with name a/b.txt. Static segments of
+// patterns must then be registered escaped. By default the decoded path is
+// used, where %2F is a path separator.
+func (m *Mux) UseRawPath(ok bool) {
+	m.useRawPath = ok
+}
+
+// UnescapePathValues when set to false keeps the values of params percent
+// encoded, it only applies with UseRawPath since the decoded path has no
+// escapes left. Values are decoded by default.
+func (m *Mux) UnescapePathValues(ok bool) {
+	m.escapedValues = !ok
+}
+
+// requestPath returns the path of r which routes are matched against.
+func (m *Mux) requestPath(r *http.Request) string {
+	if m.useRawPath {
+		return r.URL.EscapedPath()
+	}
+	return r.URL.Path
+}
+
+// unescapeParams decodes the values of params in place.
+func unescapeParams(params Params) {
+	for i, v := range params {
+		if strings.IndexByte(v.Value, '%') == -1 {
+			continue
+		}
+		if s, err := url.PathUnescape(v.Value); err == nil {
+			params[i].Value = s
+		}
+	}
+}
+
+// redirect redirects r to the path p, keeping the query string.
+func redirect(w http.ResponseWriter, r *http.Request, p string) {
+	code := http.StatusMovedPermanently
+	if r.Method != httpMethods.get && r.Method != httpMethods.head {
+		code = http.StatusPermanentRedirect
+	}
+	if r.URL.RawQuery != "" {
+		p += "?" + r.URL.RawQuery
+	}
+	http.Redirect(w, r, p, code)
 }
 
 // ServeHTTP implements http.Handler interface. It muliplexes http requests
 // against registered handlers.
+//
+// Requests whose path contains a NUL byte are rejected with 400 Bad Request,
+// and those exceeding the limits set with WithMaxPathLength and
+// WithMaxSegments with 414 URI Too Long, before any routing.
+//
+// HEAD requests to paths without a registered HEAD route are served by the GET
+// handler of the path if there is any, with the response body discarded.
 func (m *Mux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	p := path.Clean(r.URL.Path)
-	h, err := m.find(r.Method, p)
+	if len(m.hooks.request) > 0 || len(m.hooks.response) > 0 || m.stats != nil || m.recorder != nil {
+		m.serveHooked(w, r)
+		return
+	}
+	m.serve(w, r)
+}
+
+// serve routes r to its handler, and returns the route which served it or nil
+// if none did.
+func (m *Mux) serve(w http.ResponseWriter, r *http.Request) *route {
+	reqPath := m.requestPath(r)
+	if m.basePath != "" {
+		r, reqPath = m.stripBase(r, reqPath)
+	}
+	if m.maxPathLength > 0 && len(reqPath) > m.maxPathLength ||
+		m.maxSegments > 0 && strings.Count(reqPath, "/") > m.maxSegments {
+		m.httpError(w, r, http.StatusRequestURITooLong, http.StatusText(http.StatusRequestURITooLong))
+		return nil
+	}
+	if strings.IndexByte(reqPath, 0) != -1 {
+		m.httpError(w, r, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return nil
+	}
+	p := path.Clean(reqPath)
+	rt, owner := m.router, m
+	hm := m.matchHost(r.Host)
+	if hm != nil {
+		rt, owner = hm.router, hm
+	}
+	if to, ok := rt.rewrite(p); ok {
+		reqPath, p = to, path.Clean(to)
+		r = withPath(r, to)
+	}
+	version := requestVersion(r)
+	h, canonical, err := rt.lookup(r.Method, p, version, m.anyFold)
+	if err != nil && r.Method == httpMethods.head {
+		if h, canonical, err = rt.lookup(httpMethods.get, p, version, m.anyFold); err == nil {
+			w = headResponseWriter{w}
+		}
+	}
 	if err != nil {
-		m.notFound.ServeHTTP(w, r)
+		m.serveUnmatched(w, r, rt, owner, p, version)
+		return nil
+	}
+	if canonical != p {
+		if h.mux.redirectFold() {
+			if p != "/" && strings.HasSuffix(reqPath, "/") {
+				canonical += "/"
+			}
+			redirect(w, r, m.basePath+canonical)
+			return nil
+		}
+		p = canonical
+	}
+	if m.redirectSlash && !strings.Contains(h.path, "*") {
+		hasSlash := p != "/" && strings.HasSuffix(reqPath, "/")
+		wantSlash := h.path != "/" && strings.HasSuffix(h.path, "/")
+		if hasSlash != wantSlash {
+			if wantSlash {
+				p += "/"
+			}
+			redirect(w, r, m.basePath+p)
+			return nil
+		}
+	}
+	if m.redirectFixed {
+		// the case of the request is kept, see CaseInsensitive.
+		fixed := path.Clean(reqPath)
+		if fixed != "/" && strings.HasSuffix(reqPath, "/") {
+			fixed += "/"
+		}
+		if fixed != reqPath {
+			redirect(w, r, m.basePath+fixed)
+			return nil
+		}
+	}
+	c := routeContextPool.Get().(*routeContext)
+	c.Context = r.Context()
+	c.route = h
+	c.params = c.params[:0]
+	if hm != nil && hm.hostParams > 0 {
+		host := r.Host
+		if name, _, err := net.SplitHostPort(host); err == nil {
+			host = name
+		}
+		matchHostParams(strings.ToLower(host), hm.host, &c.params)
+	}
+	c.params, _ = appendParams(c.params, p, h.path)
+	if m.useRawPath && !m.escapedValues {
+		unescapeParams(c.params)
+	}
+	if t := timingsFrom(r.Context()); t != nil {
+		t.routed()
+	}
+	c.w = responseWriter{ResponseWriter: w}
+	h.ServeHTTP(&c.w, r.WithContext(c))
+	if !c.retained.Load() {
+		c.Context = nil
+		c.route = nil
+		c.w = responseWriter{}
+		routeContextPool.Put(c)
+	}
+	return h
+}
+
+// serveUnmatched responds to r whose path p didn't match any route of rt.
+// owner is the Mux of rt.
+func (m *Mux) serveUnmatched(w http.ResponseWriter, r *http.Request, rt *router, owner *Mux, p, version string) {
+	if g := rt.group(p); g != nil {
+		owner = g
+	}
+	var h http.Handler
+	next := owner.nextHandler(m)
+	if allow := rt.allowed(p, version); len(allow) > 0 {
+		if m.autoOptions && r.Method == httpMethods.options {
+			w.Header().Set("Allow", strings.Join(append(allow, httpMethods.options), ", "))
+			h = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			})
+		} else if next != nil {
+			h = next
+		} else {
+			w.Header().Set("Allow", strings.Join(allow, ", "))
+			_, h = owner.missHandlers(m)
+		}
+	} else if f := rt.fallback(r.Method, p); f != nil {
+		// fallback routes have middlewares of their own.
+		f.ServeHTTP(w, r)
 		return
+	} else if next != nil {
+		h = next
+	} else {
+		h, _ = owner.missHandlers(m)
 	}
-	params, _ := parseParams(p, h.path) // check if there is any url params
-	if params != "" {
-		r.Header.Set(headerName, params)
+	if m.wrapUnmatched {
+		for _, mw := range owner.chain() {
+			h = mw(guard(h))
+		}
 	}
 	h.ServeHTTP(w, r)
 }
@@ -481,12 +2043,35 @@ func (m *Mux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 //   home.Get("/alone",myHandler)
 // will match
 //   /home/alone
+//
+// Middlewares registered with Use on the returned Mux will only wrap routes
+// registered through it.
+//   api:=m.Group("/api")
+//   api.Use(requireAuth)
+//   api.Get("/users",myHandler) // wrapped by requireAuth
+//   m.Get("/public",myHandler) // not wrapped
+//
+// Groups can be nested, the prefix and middlewares of m are inherited by the
+// returned Mux, and the middlewares of m are executed first.
+//   api:=m.Group("/api")
+//   v1:=api.Group("/v1")
+//   v1.Get("/users",myHandler)
+// will match
+//   /api/v1/users
+//
+// pattern can have params, they are part of the routes of the group so their
+// middlewares and handlers read them with GetParams like any other param.
+//   tenant:=m.Group("/tenants/:tenant")
+//   tenant.Use(loadTenant) // GetParams(r).Get("tenant") is set
+//   tenant.Get("/users/:id",myHandler)
+// will match
+//   /tenants/acme/users/42
 func (m *Mux) Group(pattern string) *Mux {
 	return &Mux{
-		prefix: pattern,
+		prefix: path.Join(m.prefix, pattern),
+		parent: m,
 		router: m.router,
 	}
-
 }
 
 // Use assigns midlewares to the current *Mux. All routes registered by the *Mux
@@ -496,3 +2081,97 @@ func (m *Mux) Use(middleware ...func(http.Handler) http.Handler) {
 		m.middleware = append(m.middleware, middleware...)
 	}
 }
+
+// Host returns a Mux whose routes only match requests with the Host header set
+// to pattern. The pattern can start with a wildcard to match any subdomain, for
+// instance
+//   api:=m.Host("api.example.com")
+//   api.Get("/users",myHandler)
+//   m.Host("*.example.com").Get("/",myHandler)
+// Labels of the pattern can be named params, which match any label and are
+// returned by GetParams before the params of the path
+//   m.Host(":tenant.example.com").Get("/dash/:page", dash)
+// gives tenant=acme and page=usage for acme.example.com/dash/usage.
+//
+// Requests with a host matching one of the registered patterns are matched only
+// against the routes of that host, exact patterns taking precedence over
+// patterns with params, those with fewer params first, and then over
+// wildcards. Requests to any other host are matched against the routes of m.
+func (m *Mux) Host(pattern string) *Mux {
+	m.checkFrozen()
+	labels := strings.Split(pattern, ".")
+	params := 0
+	for i, v := range labels {
+		if strings.HasPrefix(v, ":") {
+			params++
+			continue
+		}
+		labels[i] = strings.ToLower(v)
+	}
+	hm := &Mux{
+		prefix:     m.prefix,
+		host:       strings.Join(labels, "."),
+		hostParams: params,
+		parent:     m,
+		router:     &router{},
+	}
+	if c := m.top().cache; c != nil {
+		hm.cache = newMatchCache(c.size)
+	}
+	for _, c := range m.load().custom {
+		hm.RegisterMethod(c.method)
+	}
+	m.hosts = append(m.hosts, hm)
+	return hm
+}
+
+// matchHost returns the host Mux matching host, or nil if there is none.
+func (m *Mux) matchHost(host string) *Mux {
+	if len(m.hosts) == 0 {
+		return nil
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(host)
+	var params, wildcard *Mux
+	for _, hm := range m.hosts {
+		if hm.host == host {
+			return hm
+		}
+		if hm.hostParams > 0 && (params == nil || hm.hostParams < params.hostParams) && matchHostParams(host, hm.host, nil) {
+			params = hm
+		}
+		if wildcard == nil && strings.HasPrefix(hm.host, "*.") && strings.HasSuffix(host, hm.host[1:]) {
+			wildcard = hm
+		}
+	}
+	if params != nil {
+		return params
+	}
+	return wildcard
+}
+
+// matchHostParams reports whether host matches pattern, whose labels starting
+// with a colon match any label. If dst isn't nil the values of those labels
+// are appended to it.
+func matchHostParams(host, pattern string, dst *Params) bool {
+	for {
+		p, pattern2, pmore := strings.Cut(pattern, ".")
+		h, host2, hmore := strings.Cut(host, ".")
+		if pmore != hmore || h == "" {
+			return false
+		}
+		if len(p) > 1 && p[0] == ':' {
+			if dst != nil {
+				*dst = append(*dst, Param{Key: p[1:], Value: h})
+			}
+		} else if p != h {
+			return false
+		}
+		if !pmore {
+			return true
+		}
+		pattern, host = pattern2, host2
+	}
+}
@@ -6,19 +6,27 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"path"
+	"reflect"
+	"strings"
 	"testing"
 )
 
 func TestParseParams(t *testing.T) {
 	sample := []struct {
-		match, pattern, result string
+		match, pattern string
+		result         Params
 	}{
-		{"/hello/world", "/hello/:name", "name:world"},
-		{"/let/the/bullet/fly", "/let/the/:which/:what", "which:bullet,what:fly"},
-		{"/hello/to/hell.jpg", "/hello/*else", "else:to/hell.jpg"},
-		{"/hello/to/hell.jpg", "/hello/to/*else", "else:hell.jpg"},
-		{"/hello/to/hell.jpg", "/hello/:name/*else", "name:to,else:hell.jpg"},
-		{"/everything/goes/here", "/*", "catch:everything/goes/here"},
+		{"/hello/world", "/hello/:name", Params{{"name", "world"}}},
+		{"/let/the/bullet/fly", "/let/the/:which/:what", Params{{"which", "bullet"}, {"what", "fly"}}},
+		{"/hello/to/hell.jpg", "/hello/*else", Params{{"else", "to/hell.jpg"}}},
+		{"/hello/to/hell.jpg", "/hello/to/*else", Params{{"else", "hell.jpg"}}},
+		{"/hello/to/hell.jpg", "/hello/:name/*else", Params{{"name", "to"}, {"else", "hell.jpg"}}},
+		{"/a/b/c/d/e", "/a/:x/:y/*", Params{{"x", "b"}, {"y", "c"}, {"catch", "d/e"}}},
+		{"/a/b/c/d/e", "/a/:x([a-z])/:y/*rest", Params{{"x", "b"}, {"y", "c"}, {"rest", "d/e"}}},
+		{"/everything/goes/here", "/*", Params{{"catch", "everything/goes/here"}}},
+		{"/hello/world", "/hello/world", nil},
 	}
 
 	for _, v := range sample {
@@ -26,12 +34,35 @@ func TestParseParams(t *testing.T) {
 		if err != nil {
 			t.Error(err)
 		}
-		if n != v.result {
-			t.Errorf("expected %s got %s", v.result, n)
+		if !reflect.DeepEqual(n, v.result) {
+			t.Errorf("expected %v got %v", v.result, n)
 		}
 	}
 }
 
+func TestParams(t *testing.T) {
+	p, err := parseParams("/repos/gernest/alien/contents/", "/repos/:owner/:repo/contents/*path")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys := p.Keys(); !reflect.DeepEqual(keys, []string{"owner", "repo", "path"}) {
+		t.Errorf("expected the keys in pattern order got %v", keys)
+	}
+	if !p.Has("path") || p.Get("path") != "" {
+		t.Errorf("expected an empty path param got %v", p)
+	}
+	if p.Has("id") {
+		t.Error("expected no id param")
+	}
+	var values []string
+	for _, v := range p {
+		values = append(values, v.Value)
+	}
+	if !reflect.DeepEqual(values, []string{"gernest", "alien", ""}) {
+		t.Errorf("unexpected values %v", values)
+	}
+}
+
 func TestRouter(t *testing.T) {
 	h := func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(r.URL.Path))
@@ -75,6 +106,7 @@ func TestNode(t *testing.T) {
 		{"/hello/:namea/people/:name", "/hello/:namea/people/:name"},
 		{"/home/*", "/home/alone"},
 		{"/very/:name/*", "/very/complex/complicate/too/much"},
+		{"/deep/:a/:b/:c/*rest", "/deep/1/2/3/4/5"},
 		{"/this/:is/:war", "/this/:is/:war"},
 		{"/practical/", "/practical/"},
 		{"/practical/joke/", "/practical/joke/"},
@@ -88,7 +120,7 @@ func TestNode(t *testing.T) {
 		}
 	}
 	for _, v := range sample {
-		h, err := n.find(v.match)
+		h, err := n.find(v.match, "")
 		if err != nil {
 			t.Fatal(err, v.match)
 		}
@@ -99,15 +131,66 @@ func TestNode(t *testing.T) {
 
 }
 
+func TestNode_compression(t *testing.T) {
+	n := &node{typ: nodeRoot}
+	for _, v := range []string{"/user/repos", "/user/keys", "/users/:user", "/user"} {
+		if err := n.insert(v, &route{path: v}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(n.children) != 1 || n.children[0].path != "/user" {
+		t.Fatalf("expected a single /user child got %d", len(n.children))
+	}
+	user := n.children[0]
+	var paths []string
+	for _, c := range user.children {
+		paths = append(paths, c.path)
+	}
+	expect := []string{"/", "s/"}
+	if !reflect.DeepEqual(paths, expect) {
+		t.Errorf("expected %v got %v", expect, paths)
+	}
+
+	sample := []struct {
+		path, route string
+	}{
+		{"/user", "/user"},
+		{"/user/repos", "/user/repos"},
+		{"/user/keys", "/user/keys"},
+		{"/users/gernest", "/users/:user"},
+		{"/user/rep", ""},
+		{"/users", ""},
+		{"/user/reposx", ""},
+	}
+	for _, v := range sample {
+		h, err := n.find(v.path, "")
+		if v.route == "" {
+			if err == nil {
+				t.Errorf("%s: expected no match got %s", v.path, h.path)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", v.path, err)
+			continue
+		}
+		if h.path != v.route {
+			t.Errorf("%s: expected %s got %s", v.path, v.route, h.path)
+		}
+	}
+}
+
 func TestRouter_mismatch(t *testing.T) {
 	h := func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(r.URL.Path))
 	}
 	sample := []struct {
 		method, path, phony string
+		code                int
 	}{
-		{"GET", "/hello", "/"},
-		{"POST", "/", "/hello"},
+		{"GET", "/hello", "/", http.StatusMethodNotAllowed},
+		{"POST", "/", "/hello", http.StatusMethodNotAllowed},
+		{"GET", "/world", "/nowhere", http.StatusNotFound},
 	}
 	m := New()
 	for _, v := range sample {
@@ -131,13 +214,46 @@ func TestRouter_mismatch(t *testing.T) {
 		if err != nil {
 			t.Fatal(err)
 		}
-		if resp.StatusCode != http.StatusNotFound {
-			t.Errorf("expected %d got %d %s", http.StatusNotFound, resp.StatusCode, req.URL.Path)
+		if resp.StatusCode != v.code {
+			t.Errorf("expected %d got %d %s", v.code, resp.StatusCode, req.URL.Path)
 		}
 		resp.Body.Close()
 	}
 }
 
+func TestMux_MethodNotAllowed(t *testing.T) {
+	h := func(_ http.ResponseWriter, _ *http.Request) {}
+	m := New()
+	m.Get("/hello/:name", h)
+	m.Put("/hello/:name", h)
+	m.Post("/world", h)
+
+	req, _ := http.NewRequest("DELETE", "/hello/world", nil)
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, req)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected %d got %d", http.StatusMethodNotAllowed, w.Code)
+	}
+	allow := "GET, HEAD, PUT"
+	if a := w.Header().Get("Allow"); a != allow {
+		t.Errorf("expected %s got %s", allow, a)
+	}
+
+	m.MethodNotAllowed(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+		w.Write([]byte(w.Header().Get("Allow")))
+	}))
+	req, _ = http.NewRequest("GET", "/world", nil)
+	w = httptest.NewRecorder()
+	m.ServeHTTP(w, req)
+	if w.Code != http.StatusTeapot {
+		t.Errorf("expected %d got %d", http.StatusTeapot, w.Code)
+	}
+	if w.Body.String() != "POST" {
+		t.Errorf("expected POST got %s", w.Body)
+	}
+}
+
 func TestRouter_params(t *testing.T) {
 	h := func(w http.ResponseWriter, r *http.Request) {
 		p := GetParams(r)
@@ -146,13 +262,18 @@ func TestRouter_params(t *testing.T) {
 	sample := []struct {
 		path, match, params string
 	}{
-		{"/hello/:name", "/hello/world", "map[name:world]"},
-		{"/home/*", "/home/alone", "map[catch:alone]"},
-		//	{"/very/:name/*", "/very/complex/complicate/too/much", "map[name:complex catch:complicate/too/much]"},
+		{"/hello/:name", "/hello/world", "[{name world}]"},
+		{"/home/*", "/home/alone", "[{catch alone}]"},
+		{"/very/:name/*", "/very/complex/complicate/too/much", "[{name complex} {catch complicate/too/much}]"},
+		{"/repos/:owner/:repo/contents/*path", "/repos/gernest/alien/contents/docs/README.md", "[{owner gernest} {repo alien} {path docs/README.md}]"},
+		{"/files/:bucket/:key([0-9]+)/*", "/files/media/2024/a/b.png", "[{bucket media} {key 2024} {catch a/b.png}]"},
+		{"/files/:bucket", "/files/media", "[{bucket media}]"},
 	}
 	m := New()
 	for _, v := range sample {
-		m.Get(v.path, h)
+		if err := m.Get(v.path, h); err != nil {
+			t.Fatal(v.path, err)
+		}
 	}
 
 	ts := httptest.NewServer(m)
@@ -242,3 +363,1276 @@ func TestAlienMiddlewares(t *testing.T) {
 		t.Errorf(" expected alien got %s ", w.Body)
 	}
 }
+
+func TestMux_URL(t *testing.T) {
+	h := func(_ http.ResponseWriter, _ *http.Request) {}
+	m := New()
+	m.GetNamed("user_show", "/users/:id", h)
+	m.AddNamedRoute("files", "POST", "/files/:owner/*path", h)
+	m.GetNamed("contents", "/repos/:owner/:repo/contents/*", h)
+	g := m.Group("/api")
+	g.GetNamed("api_user", "/users/:id", h)
+
+	sample := []struct {
+		name   string
+		params []string
+		url    string
+	}{
+		{"user_show", []string{"id", "42"}, "/users/42"},
+		{"files", []string{"owner", "gernest", "path", "hello/world.jpg"}, "/files/gernest/hello/world.jpg"},
+		{"api_user", []string{"id", "7"}, "/api/users/7"},
+		{"contents", []string{"owner", "gernest", "repo", "alien", "catch", "docs/README.md"}, "/repos/gernest/alien/contents/docs/README.md"},
+	}
+	for _, v := range sample {
+		u, err := m.URL(v.name, v.params...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u != v.url {
+			t.Errorf("expected %s got %s", v.url, u)
+		}
+	}
+	if _, err := m.URL("user_show"); err != errMissingParam {
+		t.Errorf("expected %v got %v", errMissingParam, err)
+	}
+	if _, err := m.URL("user_show", "id"); err != errBadPattern {
+		t.Errorf("expected %v got %v", errBadPattern, err)
+	}
+	if _, err := m.URL("nothing"); err != errUnknownName {
+		t.Errorf("expected %v got %v", errUnknownName, err)
+	}
+}
+
+func TestMux_AutoOptions(t *testing.T) {
+	h := func(_ http.ResponseWriter, _ *http.Request) {}
+	m := New()
+	m.Get("/hello", h)
+	m.Post("/hello", h)
+
+	req, _ := http.NewRequest("OPTIONS", "/hello", nil)
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, req)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected %d got %d", http.StatusMethodNotAllowed, w.Code)
+	}
+
+	m.AutoOptions(true)
+	w = httptest.NewRecorder()
+	m.ServeHTTP(w, req)
+	if w.Code != http.StatusNoContent {
+		t.Errorf("expected %d got %d", http.StatusNoContent, w.Code)
+	}
+	allow := "GET, HEAD, POST, OPTIONS"
+	if a := w.Header().Get("Allow"); a != allow {
+		t.Errorf("expected %s got %s", allow, a)
+	}
+
+	req, _ = http.NewRequest("OPTIONS", "/nowhere", nil)
+	w = httptest.NewRecorder()
+	m.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected %d got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestMux_HeadFallback(t *testing.T) {
+	m := New()
+	m.Get("/hello", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("X-Alien", "hello")
+		w.Write([]byte("hello"))
+	})
+	m.Head("/world", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	m.Get("/world", func(w http.ResponseWriter, _ *http.Request) {})
+
+	req, _ := http.NewRequest("HEAD", "/hello", nil)
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected %d got %d", http.StatusOK, w.Code)
+	}
+	if w.Header().Get("X-Alien") != "hello" {
+		t.Errorf("expected hello got %s", w.Header().Get("X-Alien"))
+	}
+	if w.Body.Len() != 0 {
+		t.Errorf("expected empty body got %s", w.Body)
+	}
+
+	req, _ = http.NewRequest("HEAD", "/world", nil)
+	w = httptest.NewRecorder()
+	m.ServeHTTP(w, req)
+	if w.Code != http.StatusTeapot {
+		t.Errorf("expected %d got %d", http.StatusTeapot, w.Code)
+	}
+}
+
+func TestMux_constraints(t *testing.T) {
+	h := func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, GetParams(r))
+	}
+	m := New()
+	m.Get("/users/:id([0-9]+)", h)
+	m.Get("/users/:name([a-z]+)/profile", h)
+	m.Get("/files/:name(.+\\.jpg)", h)
+	m.Get("/files/:name(.+\\.png)", h)
+
+	sample := []struct {
+		path, params string
+		code         int
+	}{
+		{"/users/42", "[{id 42}]", http.StatusOK},
+		{"/users/gernest", "", http.StatusNotFound},
+		{"/users/gernest/profile", "[{name gernest}]", http.StatusOK},
+		{"/users/42/profile", "", http.StatusNotFound},
+		{"/files/hello.jpg", "[{name hello.jpg}]", http.StatusOK},
+		{"/files/hello.png", "[{name hello.png}]", http.StatusOK},
+		{"/files/hello.gif", "", http.StatusNotFound},
+	}
+	for _, v := range sample {
+		req, _ := http.NewRequest("GET", v.path, nil)
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, req)
+		if w.Code != v.code {
+			t.Errorf("%s: expected %d got %d", v.path, v.code, w.Code)
+		}
+		if v.code == http.StatusOK && w.Body.String() != v.params {
+			t.Errorf("%s: expected %s got %s", v.path, v.params, w.Body)
+		}
+	}
+
+	bad := []string{
+		"/users/:id([0-9]+",
+		"/users/:id(*)",
+		"/users/:(foo)",
+		"/users(foo)",
+	}
+	for _, v := range bad {
+		if err := m.Get(v, h); err == nil {
+			t.Errorf("%s: expected error", v)
+		}
+	}
+}
+
+func TestMux_routeMiddlewares(t *testing.T) {
+	mark := func(s string) func(http.Handler) http.Handler {
+		return func(in http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Add("X-Mark", s)
+				in.ServeHTTP(w, r)
+			})
+		}
+	}
+	h := func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte(strings.Join(w.Header()["X-Mark"], "") + "h"))
+	}
+	m := New()
+	m.Use(mark("use"))
+	m.Get("/admin", h, mark("auth"), mark("audit"))
+	m.Get("/public", h)
+
+	sample := []struct {
+		path, body string
+	}{
+		{"/admin", "useauthaudith"},
+		{"/public", "useh"},
+	}
+	for _, v := range sample {
+		req, _ := http.NewRequest("GET", v.path, nil)
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, req)
+		if w.Body.String() != v.body {
+			t.Errorf("%s: expected %s got %s", v.path, v.body, w.Body)
+		}
+	}
+}
+
+func TestMux_GroupUse(t *testing.T) {
+	h := func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte(strings.Join(w.Header()["X-Mark"], "") + "h"))
+	}
+	auth := func(in http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("X-Mark", "auth")
+			in.ServeHTTP(w, r)
+		})
+	}
+	m := New()
+	api := m.Group("/api")
+	api.Use(auth)
+	api.Get("/users", h)
+	m.Get("/public", h)
+
+	sample := []struct {
+		path, body string
+	}{
+		{"/api/users", "authh"},
+		{"/public", "h"},
+	}
+	for _, v := range sample {
+		req, _ := http.NewRequest("GET", v.path, nil)
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, req)
+		if w.Body.String() != v.body {
+			t.Errorf("%s: expected %s got %s", v.path, v.body, w.Body)
+		}
+	}
+}
+
+func TestMux_nestedGroups(t *testing.T) {
+	mark := func(s string) func(http.Handler) http.Handler {
+		return func(in http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Add("X-Mark", s)
+				in.ServeHTTP(w, r)
+			})
+		}
+	}
+	h := func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte(strings.Join(w.Header()["X-Mark"], "") + "h"))
+	}
+	m := New()
+	m.Use(mark("root"))
+	api := m.Group("/api")
+	api.Use(mark("api"))
+	v1 := api.Group("/v1")
+	v1.Use(mark("v1"))
+	v1.Get("/users", h, mark("route"))
+	api.Get("/status", h)
+	m.Get("/", h)
+
+	sample := []struct {
+		path, body string
+	}{
+		{"/api/v1/users", "rootapiv1routeh"},
+		{"/api/status", "rootapih"},
+		{"/", "rooth"},
+	}
+	for _, v := range sample {
+		req, _ := http.NewRequest("GET", v.path, nil)
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, req)
+		if w.Body.String() != v.body {
+			t.Errorf("%s: expected %s got %s", v.path, v.body, w.Body)
+		}
+	}
+}
+
+func TestMux_paramGroup(t *testing.T) {
+	h := func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.Header.Get("X-Tenant"), GetParams(r))
+	}
+	m := New()
+	tenant := m.Group("/tenants/:tenant([a-z]+)")
+	tenant.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.Header.Set("X-Tenant", GetParams(r).Get("tenant"))
+			next.ServeHTTP(w, r)
+		})
+	})
+	tenant.NotFoundHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "tenant route not found", http.StatusNotFound)
+	}))
+	if err := tenant.GetNamed("tenant_user", "/users/:id", h); err != nil {
+		t.Fatal(err)
+	}
+	projects := tenant.Group("/projects/:project")
+	projects.Get("/files/*", h)
+
+	sample := []struct {
+		path, body string
+	}{
+		{"/tenants/acme/users/42", "acme[{tenant acme} {id 42}]"},
+		{"/tenants/acme/projects/alien/files/a/b.go", "acme[{tenant acme} {project alien} {catch a/b.go}]"},
+		{"/tenants/acme/nothing", "tenant route not found\n"},
+		{"/tenants/acme", "tenant route not found\n"},
+		{"/tenants", errRouteNotFound.Error() + "\n"},
+	}
+	for _, v := range sample {
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, httptest.NewRequest("GET", v.path, nil))
+		if w.Body.String() != v.body {
+			t.Errorf("%s: expected %q got %q", v.path, v.body, w.Body)
+		}
+	}
+	u, err := m.URL("tenant_user", "tenant", "acme", "id", "42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u != "/tenants/acme/users/42" {
+		t.Errorf("expected /tenants/acme/users/42 got %s", u)
+	}
+}
+
+func TestUnderPrefix(t *testing.T) {
+	sample := []struct {
+		path, prefix string
+		ok           bool
+	}{
+		{"/api/users", "/api", true},
+		{"/apis", "/api", false},
+		{"/anything", "", true},
+		{"/tenants/acme", "/tenants/:tenant", true},
+		{"/tenants/acme/users", "/tenants/:tenant", true},
+		{"/tenants", "/tenants/:tenant", false},
+		{"/tenants/", "/tenants/:tenant", false},
+		{"/tenant/acme", "/tenants/:tenant", false},
+		{"/files/a/b", "/files/*", true},
+	}
+	for _, v := range sample {
+		if ok := underPrefix(v.path, v.prefix); ok != v.ok {
+			t.Errorf("%s under %s: expected %v got %v", v.path, v.prefix, v.ok, ok)
+		}
+	}
+}
+
+func TestMux_Host(t *testing.T) {
+	say := func(s string) func(http.ResponseWriter, *http.Request) {
+		return func(w http.ResponseWriter, _ *http.Request) {
+			w.Write([]byte(s))
+		}
+	}
+	m := New()
+	m.Get("/", say("default"))
+	m.Host("api.example.com").Get("/", say("api"))
+	m.Host("*.example.com").Get("/", say("wildcard"))
+
+	sample := []struct {
+		host, body string
+		code       int
+	}{
+		{"api.example.com", "api", http.StatusOK},
+		{"API.example.com:8080", "api", http.StatusOK},
+		{"www.example.com", "wildcard", http.StatusOK},
+		{"example.com", "default", http.StatusOK},
+		{"localhost", "default", http.StatusOK},
+	}
+	for _, v := range sample {
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.Host = v.host
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, req)
+		if w.Code != v.code {
+			t.Errorf("%s: expected %d got %d", v.host, v.code, w.Code)
+		}
+		if w.Body.String() != v.body {
+			t.Errorf("%s: expected %s got %s", v.host, v.body, w.Body)
+		}
+	}
+
+	m.Get("/only", say("only"))
+	req, _ := http.NewRequest("GET", "/only", nil)
+	req.Host = "api.example.com"
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected %d got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestMux_hostParams(t *testing.T) {
+	h := func(w http.ResponseWriter, r *http.Request) {
+		for _, p := range GetParams(r) {
+			fmt.Fprintf(w, "%s=%s;", p.Key, p.Value)
+		}
+	}
+	m := New()
+	m.Get("/", h)
+	m.Host(":tenant.example.com").Get("/dash/:page", h)
+	m.Host(":Env.:tenant.example.com").Get("/dash/:page", h)
+	m.Host("admin.example.com").Get("/dash/:page", h)
+	m.Host("*.example.com").Get("/", h)
+
+	sample := []struct {
+		host, path, body string
+		code             int
+	}{
+		{"acme.example.com", "/dash/usage", "tenant=acme;page=usage;", http.StatusOK},
+		{"ACME.Example.com:8443", "/dash/usage", "tenant=acme;page=usage;", http.StatusOK},
+		{"staging.acme.example.com", "/dash/usage", "Env=staging;tenant=acme;page=usage;", http.StatusOK},
+		{"admin.example.com", "/dash/usage", "page=usage;", http.StatusOK},
+		{"a.b.c.example.com", "/", "", http.StatusOK},
+		{"example.com", "/", "", http.StatusOK},
+		{"acme.example.org", "/dash/usage", "", http.StatusNotFound},
+	}
+	for _, v := range sample {
+		req := httptest.NewRequest("GET", v.path, nil)
+		req.Host = v.host
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, req)
+		if w.Code != v.code {
+			t.Errorf("%s%s: expected %d got %d", v.host, v.path, v.code, w.Code)
+		}
+		if v.code == http.StatusOK && w.Body.String() != v.body {
+			t.Errorf("%s%s: expected %s got %s", v.host, v.path, v.body, w.Body)
+		}
+	}
+}
+
+func TestMux_RedirectTrailingSlash(t *testing.T) {
+	h := func(_ http.ResponseWriter, _ *http.Request) {}
+	m := New()
+	m.Get("/users", h)
+	m.Get("/posts/", h)
+	m.Post("/users/:id/", h)
+	m.Get("/files/*", h)
+
+	sample := []struct {
+		method, path, location string
+		code                   int
+	}{
+		{"GET", "/users/", "/users", http.StatusMovedPermanently},
+		{"GET", "/users/?page=1", "/users?page=1", http.StatusMovedPermanently},
+		{"GET", "/posts", "/posts/", http.StatusMovedPermanently},
+		{"POST", "/users/42", "/users/42/", http.StatusPermanentRedirect},
+		{"GET", "/users", "", http.StatusOK},
+		{"GET", "/posts/", "", http.StatusOK},
+		{"GET", "/files/hello/", "", http.StatusOK},
+	}
+	m.RedirectTrailingSlash(true)
+	for _, v := range sample {
+		req, _ := http.NewRequest(v.method, v.path, nil)
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, req)
+		if w.Code != v.code {
+			t.Errorf("%s: expected %d got %d", v.path, v.code, w.Code)
+		}
+		if l := w.Header().Get("Location"); l != v.location {
+			t.Errorf("%s: expected %s got %s", v.path, v.location, l)
+		}
+	}
+}
+
+func TestMux_RedirectFixedPath(t *testing.T) {
+	h := func(_ http.ResponseWriter, _ *http.Request) {}
+	m := New()
+	m.Get("/users/:id", h)
+	m.Get("/posts/", h)
+
+	sample := []struct {
+		path, location string
+		code           int
+	}{
+		{"//users/42", "/users/42", http.StatusMovedPermanently},
+		{"/users/./42", "/users/42", http.StatusMovedPermanently},
+		{"/posts/../users/42", "/users/42", http.StatusMovedPermanently},
+		{"/posts//", "/posts/", http.StatusMovedPermanently},
+		{"/users/42", "", http.StatusOK},
+		{"/nowhere/../", "", http.StatusNotFound},
+	}
+	req, _ := http.NewRequest("GET", "/", nil)
+	for _, v := range sample {
+		if v.location == "" {
+			continue
+		}
+		req.URL.Path = v.path
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: expected %d got %d", v.path, http.StatusOK, w.Code)
+		}
+	}
+	m.RedirectFixedPath(true)
+	for _, v := range sample {
+		req.URL.Path = v.path
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, req)
+		if w.Code != v.code {
+			t.Errorf("%s: expected %d got %d", v.path, v.code, w.Code)
+		}
+		if l := w.Header().Get("Location"); l != v.location {
+			t.Errorf("%s: expected %s got %s", v.path, v.location, l)
+		}
+	}
+}
+
+func TestMux_UseRawPath(t *testing.T) {
+	h := func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, GetParams(r))
+	}
+	m := New()
+	m.Get("/files/:name", h)
+	m.Get("/files/:dir/:name", h)
+	m.Get("/hello%20world/:name", h)
+
+	sample := []struct {
+		url, params string
+		code        int
+	}{
+		{"/files/a%2Fb.txt", "[{name a/b.txt}]", http.StatusOK},
+		{"/files/docs/a%2Fb.txt", "[{dir docs} {name a/b.txt}]", http.StatusOK},
+		{"/files/a%20b.txt", "[{name a b.txt}]", http.StatusOK},
+		{"/hello%20world/x", "[{name x}]", http.StatusOK},
+	}
+	serve := func(u string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", u, nil)
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, req)
+		return w
+	}
+	if w := serve("/files/a%2Fb.txt"); w.Body.String() != "[{dir a} {name b.txt}]" {
+		t.Errorf("expected the decoded path to be used got %s", w.Body)
+	}
+	m.UseRawPath(true)
+	for _, v := range sample {
+		w := serve(v.url)
+		if w.Code != v.code {
+			t.Errorf("%s: expected %d got %d", v.url, v.code, w.Code)
+		}
+		if w.Body.String() != v.params {
+			t.Errorf("%s: expected %s got %s", v.url, v.params, w.Body)
+		}
+	}
+	m.UnescapePathValues(false)
+	if w := serve("/files/a%2Fb.txt"); w.Body.String() != "[{name a%2Fb.txt}]" {
+		t.Errorf("expected escaped values got %s", w.Body)
+	}
+}
+
+func TestMux_SetPrefix(t *testing.T) {
+	m := New(WithPrefix("/app/"), WithRedirectTrailingSlash())
+	h := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.Path))
+	}
+	m.Get("/", h)
+	m.GetNamed("user", "/users/:id", h)
+	m.Get("/dirs/", h)
+	m.Redirect("GET", "/old", "/users/1", http.StatusFound)
+	mux := http.NewServeMux()
+	mux.Handle("/app/", m)
+	mux.Handle("/stripped/", http.StripPrefix("/stripped", m))
+	sample := []struct {
+		path     string
+		direct   bool
+		code     int
+		result   string
+		location string
+	}{
+		{"/app", true, http.StatusOK, "/", ""},
+		{"/app/", false, http.StatusOK, "/", ""},
+		{"/app/users/42", false, http.StatusOK, "/users/42", ""},
+		{"/app/dirs", false, http.StatusMovedPermanently, "", "/app/dirs/"},
+		{"/app/old?x=1", false, http.StatusFound, "", "/app/users/1?x=1"},
+		{"/stripped/users/42", false, http.StatusOK, "/users/42", ""},
+		{"/application/users/42", true, http.StatusNotFound, "", ""},
+	}
+	for _, v := range sample {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", v.path, nil)
+		if v.direct {
+			// paths the ServeMux would redirect or not pass to m.
+			m.ServeHTTP(w, req)
+		} else {
+			mux.ServeHTTP(w, req)
+		}
+		if w.Code != v.code {
+			t.Errorf("%s: expected %d got %d", v.path, v.code, w.Code)
+		}
+		if v.result != "" && w.Body.String() != v.result {
+			t.Errorf("%s: expected %s got %s", v.path, v.result, w.Body)
+		}
+		if l := w.Header().Get("Location"); l != v.location {
+			t.Errorf("%s: expected location %s got %s", v.path, v.location, l)
+		}
+	}
+	u, err := m.URL("user", "id", "42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u != "/app/users/42" {
+		t.Errorf("expected /app/users/42 got %s", u)
+	}
+}
+
+func TestMux_CaseInsensitive(t *testing.T) {
+	h := func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, RoutePattern(r), GetParams(r))
+	}
+	m := New()
+	m.Get("/strict", h)
+	legacy := m.Group("/Products")
+	legacy.CaseInsensitive(true)
+	for _, p := range []string{"/item/:id([0-9]+)/Details", "/list/", "/files/*"} {
+		if err := legacy.Get(p, h); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sample := []struct {
+		path, body string
+		code       int
+	}{
+		{"/Products/item/42/Details", "/Products/item/:id([0-9]+)/Details[{id 42}]", http.StatusOK},
+		{"/products/Item/42/details", "/Products/item/:id([0-9]+)/Details[{id 42}]", http.StatusOK},
+		{"/PRODUCTS/ITEM/42/DETAILS/", "/Products/item/:id([0-9]+)/Details[{id 42}]", http.StatusOK},
+		{"/products/item/abc/details", "", http.StatusNotFound},
+		{"/PRODUCTS/LIST", "/Products/list[]", http.StatusOK},
+		{"/products/FILES/A/b.txt", "/Products/files/*[{catch A/b.txt}]", http.StatusOK},
+		{"/Strict", "", http.StatusNotFound},
+	}
+	for _, v := range sample {
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, httptest.NewRequest("GET", v.path, nil))
+		if w.Code != v.code {
+			t.Errorf("%s: expected %d got %d", v.path, v.code, w.Code)
+		}
+		if v.body != "" && w.Body.String() != v.body {
+			t.Errorf("%s: expected %s got %s", v.path, v.body, w.Body)
+		}
+	}
+
+	info, params, err := m.Match("GET", "/products/item/7/details")
+	if err != nil || info.Pattern != "/Products/item/:id([0-9]+)/Details" || params.Get("id") != "7" {
+		t.Errorf("expected the route to match got %v %v %v", info.Pattern, params, err)
+	}
+
+	legacy.RedirectCaseInsensitive(true)
+	redirects := []struct {
+		path, location string
+	}{
+		{"/products/item/42/details?x=1", "/Products/item/42/Details?x=1"},
+		{"/PRODUCTS/ITEM/42/DETAILS/", "/Products/item/42/Details/"},
+		{"/products/files/A", "/Products/files/A"},
+	}
+	for _, v := range redirects {
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, httptest.NewRequest("GET", v.path, nil))
+		if w.Code != http.StatusMovedPermanently {
+			t.Errorf("%s: expected %d got %d", v.path, http.StatusMovedPermanently, w.Code)
+		}
+		if l := w.Header().Get("Location"); l != v.location {
+			t.Errorf("%s: expected %s got %s", v.path, v.location, l)
+		}
+	}
+}
+
+func TestMux_NotFoundHandler(t *testing.T) {
+	m := New()
+	m.Get("/hello", func(_ http.ResponseWriter, _ *http.Request) {})
+	m.NotFoundHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":"not found"}`))
+	}))
+	req, _ := http.NewRequest("GET", "/world", nil)
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected %d got %d", http.StatusNotFound, w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected application/json got %s", ct)
+	}
+	if w.Body.String() != `{"error":"not found"}` {
+		t.Errorf("unexpected body %s", w.Body)
+	}
+}
+
+func TestMux_Fallback(t *testing.T) {
+	old := http.NewServeMux()
+	old.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("old " + r.Method + " " + r.URL.Path))
+	})
+	m := New(WithAutoOptions())
+	m.Fallback(old)
+	m.Get("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("new"))
+	})
+	api := m.Group("/api")
+	api.Fallback(http.NotFoundHandler())
+	api.Get("/status", func(w http.ResponseWriter, r *http.Request) {})
+	sample := []struct {
+		method, path string
+		code         int
+		result       string
+	}{
+		{"GET", "/users/1", http.StatusOK, "new"},
+		{"GET", "/orders/1", http.StatusOK, "old GET /orders/1"},
+		{"POST", "/users/1", http.StatusOK, "old POST /users/1"},
+		{"OPTIONS", "/users/1", http.StatusNoContent, ""},
+		{"GET", "/api/users", http.StatusNotFound, "404 page not found\n"},
+	}
+	for _, v := range sample {
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, httptest.NewRequest(v.method, v.path, nil))
+		if w.Code != v.code {
+			t.Errorf("%s %s: expected %d got %d", v.method, v.path, v.code, w.Code)
+		}
+		if w.Body.String() != v.result {
+			t.Errorf("%s %s: expected %q got %q", v.method, v.path, v.result, w.Body)
+		}
+		if v.code == http.StatusOK && w.Header().Get("Allow") != "" {
+			t.Errorf("%s %s: expected no Allow header for the fallback", v.method, v.path)
+		}
+	}
+}
+
+func TestMux_groupNotFoundHandler(t *testing.T) {
+	h := func(_ http.ResponseWriter, _ *http.Request) {}
+	reply := func(s string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTeapot)
+			w.Write([]byte(s))
+		})
+	}
+	m := New()
+	m.NotFoundHandler(reply("site"))
+	api := m.Group("/api")
+	api.NotFoundHandler(reply("api"))
+	v2 := api.Group("/v2")
+	v2.MethodNotAllowed(reply("v2 not allowed"))
+	v2.Get("/users", h)
+	m.Get("/apis", h)
+	docs := m.Host("docs.example.com")
+	docs.NotFoundHandler(reply("docs"))
+	docs.Get("/", h)
+
+	sample := []struct {
+		method, host, path, body string
+	}{
+		{"GET", "", "/nowhere", "site"},
+		{"GET", "", "/apiary", "site"},
+		{"GET", "", "/api", "api"},
+		{"GET", "", "/api/users", "api"},
+		{"GET", "", "/api/v2/nowhere", "api"},
+		{"POST", "", "/api/v2/users", "v2 not allowed"},
+		{"POST", "", "/apis", errNotAllowed.Error()},
+		{"GET", "docs.example.com", "/nowhere", "docs"},
+	}
+	for _, v := range sample {
+		req := httptest.NewRequest(v.method, v.path, nil)
+		if v.host != "" {
+			req.Host = v.host
+		}
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, req)
+		if body := strings.TrimSpace(w.Body.String()); body != v.body {
+			t.Errorf("%s %s%s: expected %q got %q", v.method, v.host, v.path, v.body, body)
+		}
+	}
+}
+
+func TestMux_WrapUnmatched(t *testing.T) {
+	mark := func(s string) func(http.Handler) http.Handler {
+		return func(h http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Add("X-Mark", s)
+				h.ServeHTTP(w, r)
+			})
+		}
+	}
+	h := func(_ http.ResponseWriter, _ *http.Request) {}
+	m := New()
+	m.Use(mark("log"))
+	m.AutoOptions(true)
+	m.Get("/users", h)
+	api := m.Group("/api")
+	api.Use(mark("api"))
+	api.NotFoundHandler(http.NotFoundHandler())
+
+	sample := []struct {
+		method, path, marks string
+		code                int
+	}{
+		{"GET", "/nowhere", "log", http.StatusNotFound},
+		{"POST", "/users", "log", http.StatusMethodNotAllowed},
+		{"OPTIONS", "/users", "log", http.StatusNoContent},
+		{"GET", "/api/nowhere", "log,api", http.StatusNotFound},
+		{"GET", "/users", "log", http.StatusOK},
+	}
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+	if w := serve("GET", "/nowhere"); len(w.Header()["X-Mark"]) != 0 {
+		t.Errorf("expected unmatched requests not to be wrapped got %v", w.Header()["X-Mark"])
+	}
+	m.WrapUnmatched(true)
+	for _, v := range sample {
+		w := serve(v.method, v.path)
+		if w.Code != v.code {
+			t.Errorf("%s %s: expected %d got %d", v.method, v.path, v.code, w.Code)
+		}
+		if marks := strings.Join(w.Header()["X-Mark"], ","); marks != v.marks {
+			t.Errorf("%s %s: expected %s got %s", v.method, v.path, v.marks, marks)
+		}
+	}
+}
+
+func TestRoutePattern(t *testing.T) {
+	var pattern string
+	m := New()
+	m.Get("/users/:id", func(_ http.ResponseWriter, r *http.Request) {
+		pattern = RoutePattern(r)
+	})
+	req, _ := http.NewRequest("GET", "/users/42", nil)
+	m.ServeHTTP(httptest.NewRecorder(), req)
+	if pattern != "/users/:id" {
+		t.Errorf("expected /users/:id got %s", pattern)
+	}
+	if p := RoutePattern(req); p != "" {
+		t.Errorf("expected empty pattern got %s", p)
+	}
+}
+
+func TestParamsFromContext(t *testing.T) {
+	var got Params
+	m := New()
+	m.Get("/users/:id/posts/:post", func(_ http.ResponseWriter, r *http.Request) {
+		got = ParamsFromContext(r.Context())
+	})
+	req, _ := http.NewRequest("GET", "/users/42/posts/7", nil)
+	req.Header.Set("_alien", "id:1")
+	m.ServeHTTP(httptest.NewRecorder(), req)
+	expect := Params{{"id", "42"}, {"post", "7"}}
+	if !reflect.DeepEqual(got, expect) {
+		t.Errorf("expected %v got %v", expect, got)
+	}
+	if p := GetParams(req); p != nil {
+		t.Errorf("expected no params got %v", p)
+	}
+	ctx := WithParams(req.Context(), expect)
+	if p := ParamsFromContext(ctx); !reflect.DeepEqual(p, expect) {
+		t.Errorf("expected %v got %v", expect, p)
+	}
+	if p := RoutePattern(req.WithContext(ctx)); p != "" {
+		t.Errorf("expected no route got %s", p)
+	}
+}
+
+func TestMux_registerWhileServing(t *testing.T) {
+	m := New()
+	m.Get("/static", func(_ http.ResponseWriter, _ *http.Request) {})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			m.Get(fmt.Sprintf("/users/%d/:name", i), func(_ http.ResponseWriter, _ *http.Request) {})
+			m.Post(fmt.Sprintf("/static/%d", i), func(_ http.ResponseWriter, _ *http.Request) {})
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		req, _ := http.NewRequest("GET", "/static", nil)
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected %d got %d", http.StatusOK, w.Code)
+		}
+	}
+	<-done
+	req, _ := http.NewRequest("GET", "/users/99/gernest", nil)
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected %d got %d", http.StatusOK, w.Code)
+	}
+}
+
+func TestMux_RemoveRoute(t *testing.T) {
+	h := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(RoutePattern(r)))
+	}
+	m := New()
+	m.Get("/plugins", h)
+	m.Get("/plugins/:name", h)
+	m.Get("/plugins/:name/settings", h)
+	m.Get("/files/*", h)
+	api := m.Group("/api")
+	api.Get("/users/:id([0-9]+)", h)
+	api.Get("/users/:name", h)
+
+	if err := m.RemoveRoute("GET", "/plugins/:name"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.RemoveRoute("GET", "/files/*"); err != nil {
+		t.Fatal(err)
+	}
+	if err := api.RemoveRoute("GET", "/users/:id([0-9]+)"); err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []struct {
+		method, pattern string
+		err             error
+	}{
+		{"GET", "/plugins/:name", errRouteNotFound},
+		{"GET", "/nowhere", errRouteNotFound},
+		{"POST", "/plugins", errRouteNotFound},
+		{"CRAP", "/plugins", errUnknownMethod},
+	} {
+		if err := m.RemoveRoute(v.method, v.pattern); err != v.err {
+			t.Errorf("%s %s: expected %v got %v", v.method, v.pattern, v.err, err)
+		}
+	}
+
+	sample := []struct {
+		path, pattern string
+		code          int
+	}{
+		{"/plugins", "/plugins", http.StatusOK},
+		{"/plugins/auth", "", http.StatusNotFound},
+		{"/plugins/auth/settings", "/plugins/:name/settings", http.StatusOK},
+		{"/files/a.txt", "", http.StatusNotFound},
+		{"/api/users/42", "/api/users/:name", http.StatusOK},
+	}
+	for _, v := range sample {
+		req, _ := http.NewRequest("GET", v.path, nil)
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, req)
+		if w.Code != v.code {
+			t.Errorf("%s: expected %d got %d", v.path, v.code, w.Code)
+		}
+		if v.code == http.StatusOK && w.Body.String() != v.pattern {
+			t.Errorf("%s: expected %s got %s", v.path, v.pattern, w.Body)
+		}
+	}
+}
+
+func TestMux_ReplaceRoute(t *testing.T) {
+	m := New()
+	m.Get("/version", func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte("v1"))
+	})
+	err := m.ReplaceRoute("GET", "/version", func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte("v2"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest("GET", "/version", nil)
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, req)
+	if w.Body.String() != "v2" {
+		t.Errorf("expected v2 got %s", w.Body)
+	}
+	if err := m.ReplaceRoute("GET", "/missing", nil); err != errRouteNotFound {
+		t.Errorf("expected %v got %v", errRouteNotFound, err)
+	}
+}
+
+func TestNode_modify(t *testing.T) {
+	n := &node{typ: nodeRoot}
+	for _, v := range []string{"/user/repos", "/user/keys", "/user"} {
+		n.insert(v, &route{path: v})
+	}
+	remove := func(path string) func([]*route) ([]*route, bool) {
+		return func(ends []*route) ([]*route, bool) {
+			return nil, len(ends) > 0 && ends[0].path == path
+		}
+	}
+	r, ok := n.modify("/user/keys", remove("/user/keys"))
+	if !ok {
+		t.Fatal("expected /user/keys to be removed")
+	}
+	r, _ = r.modify("/user", remove("/user"))
+	// the remaining route is compressed back into a single node.
+	if len(r.children) != 1 || r.children[0].path != "/user/repos" {
+		t.Errorf("expected a single /user/repos node got %d", len(r.children))
+	}
+	if _, err := n.find("/user/keys", ""); err != nil {
+		t.Errorf("expected the original tree to be unchanged got %v", err)
+	}
+}
+
+func TestMux_conflicts(t *testing.T) {
+	h := func(_ http.ResponseWriter, _ *http.Request) {}
+	sample := []struct {
+		existing, pattern, segment string
+	}{
+		{"/users/:id", "/users/:name", ":name"},
+		{"/users/:id", "/users/:id", ":id"},
+		{"/users/:id", "/users/new", "new"},
+		{"/users/new", "/users/:id", ":id"},
+		{"/files/*", "/files/*path", "*path"},
+		{"/files/:name", "/files/*", "*"},
+		{"/files/*", "/files/:name", ":name"},
+		{"/files/*", "/files/index.html", "index.html"},
+		{"/users/:id([0-9]+)", "/users/:name([0-9]+)", ":name([0-9]+)"},
+	}
+	for _, v := range sample {
+		m := New()
+		if err := m.Get(v.existing, h); err != nil {
+			t.Fatal(err)
+		}
+		err := m.Get(v.pattern, h)
+		e, ok := err.(*ConflictError)
+		if !ok {
+			t.Errorf("%s: expected *ConflictError got %v", v.pattern, err)
+			continue
+		}
+		if e.Existing != v.existing || e.Pattern != v.pattern || e.Segment != v.segment {
+			t.Errorf("%s: unexpected error %v", v.pattern, e)
+		}
+	}
+
+	ok := [][]string{
+		{"/users/:id([0-9]+)", "/users/:name"},
+		{"/users/:id", "/users/:id/posts", "/users"},
+		{"/files/:name(.+\\.jpg)", "/files/:name(.+\\.png)"},
+	}
+	for _, patterns := range ok {
+		m := New()
+		for _, p := range patterns {
+			if err := m.Get(p, h); err != nil {
+				t.Errorf("%s: %v", p, err)
+			}
+		}
+	}
+
+	for _, routes := range [][]testRoute{githubAPI, gplusAPI, parseAPI, staticRoutes} {
+		m := New()
+		for _, v := range routes {
+			if err := m.AddRoute(v.method, v.path, h); err != nil {
+				t.Errorf("%s %s: %v", v.method, v.path, err)
+			}
+		}
+	}
+}
+
+func TestMux_duplicateParams(t *testing.T) {
+	h := func(_ http.ResponseWriter, _ *http.Request) {}
+	sample := []struct {
+		prefix, pattern, name string
+	}{
+		{"", "/hell/:one/:one", "one"},
+		{"", "/hell/:one([0-9]+)/x/:one", "one"},
+		{"", "/files/:catch/*", "catch"},
+		{"", "/files/:path/*path", "path"},
+		{"/tenants/:id", "/users/:id", "id"},
+	}
+	for _, v := range sample {
+		m := New()
+		err := m.Group(v.prefix).Get(v.pattern, h)
+		e, ok := err.(*DuplicateParamError)
+		if !ok {
+			t.Errorf("%s%s: expected *DuplicateParamError got %v", v.prefix, v.pattern, err)
+			continue
+		}
+		if e.Name != v.name || e.Pattern != path.Join("/", v.prefix, v.pattern) {
+			t.Errorf("%s%s: unexpected error %v", v.prefix, v.pattern, e)
+		}
+	}
+	m := New()
+	if err := m.Get("/hell/:one/:two/*rest", h); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMux_Any(t *testing.T) {
+	m := New()
+	h := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Method))
+	}
+	if err := m.Any("/webhook", h); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Methods([]string{"GET", "POST"}, "/form", h); err != nil {
+		t.Fatal(err)
+	}
+	for _, method := range allMethods {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, "/webhook", nil)
+		m.ServeHTTP(w, req)
+		if method != "HEAD" && w.Body.String() != method {
+			t.Errorf("expected %s got %s", method, w.Body.String())
+		}
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest(method, "/form", nil)
+		m.ServeHTTP(w, req)
+		allowed := method == "GET" || method == "POST" || method == "HEAD"
+		if allowed != (w.Code == http.StatusOK) {
+			t.Errorf("%s /form: unexpected status %d", method, w.Code)
+		}
+	}
+
+	// a failing method leaves no route behind
+	if err := m.Methods([]string{"PUT", "BREW"}, "/form", h); err != errUnknownMethod {
+		t.Errorf("expected %v got %v", errUnknownMethod, err)
+	}
+	if err := m.Methods([]string{"PATCH", "POST"}, "/form", h); err == nil {
+		t.Error("expected a conflict")
+	}
+	if allow := m.allowed("/form", ""); len(allow) != 3 {
+		t.Errorf("expected GET, HEAD and POST to be allowed got %v", allow)
+	}
+}
+
+func TestMux_RegisterMethod(t *testing.T) {
+	m := New()
+	h := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Method + " " + r.URL.Path))
+	}
+	if err := m.AddRoute("PURGE", "/cache/*", h); err != errUnknownMethod {
+		t.Errorf("expected %v got %v", errUnknownMethod, err)
+	}
+	for _, method := range []string{"", "PUR GE", "PURGE/1"} {
+		if err := m.RegisterMethod(method); err != errBadMethod {
+			t.Errorf("%q: expected %v got %v", method, errBadMethod, err)
+		}
+	}
+	api := m.Host("api.example.com")
+	if err := m.RegisterMethod("PURGE"); err != nil {
+		t.Fatal(err)
+	}
+	m.RegisterMethod("PURGE")
+	m.RegisterMethod("MKCOL")
+	if err := m.AddRoute("PURGE", "/cache/*", h); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.AddRoute("MKCOL", "/dav/*", h); err != nil {
+		t.Fatal(err)
+	}
+	if err := api.AddRoute("PURGE", "/", h); err != nil {
+		t.Errorf("expected hosts to accept PURGE got %v", err)
+	}
+	if err := m.Host("www.example.com").AddRoute("MKCOL", "/", h); err != nil {
+		t.Errorf("expected new hosts to accept MKCOL got %v", err)
+	}
+	sample := []struct {
+		method, path, body string
+		status             int
+	}{
+		{"PURGE", "/cache/users", "PURGE /cache/users", http.StatusOK},
+		{"MKCOL", "/dav/docs", "MKCOL /dav/docs", http.StatusOK},
+		{"GET", "/dav/docs", "", http.StatusMethodNotAllowed},
+	}
+	for _, v := range sample {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(v.method, v.path, nil)
+		m.ServeHTTP(w, req)
+		if w.Code != v.status {
+			t.Errorf("%s %s: expected %d got %d", v.method, v.path, v.status, w.Code)
+		}
+		if v.status == http.StatusOK && w.Body.String() != v.body {
+			t.Errorf("%s %s: expected %s got %s", v.method, v.path, v.body, w.Body.String())
+		}
+		if v.status == http.StatusMethodNotAllowed && w.Header().Get("Allow") != "MKCOL" {
+			t.Errorf("expected Allow: MKCOL got %s", w.Header().Get("Allow"))
+		}
+	}
+	if len(m.Routes()) != 4 {
+		t.Errorf("expected 4 routes got %v", m.Routes())
+	}
+	if err := m.Any("/any", h); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := m.Match("PURGE", "/any"); err != nil {
+		t.Errorf("expected Any to register PURGE got %v", err)
+	}
+}
+
+func FuzzParseParams(f *testing.F) {
+	f.Add("/hello/world", "/hello/:name")
+	f.Add("/a/b/c/d/e", "/a/:x/:y/*")
+	f.Add("//", "/:a/:b")
+	f.Add("/x\x00y/z", "/:a/*rest")
+	f.Add("", "/*")
+	f.Fuzz(func(t *testing.T, matched, pattern string) {
+		params, err := parseParams(matched, pattern)
+		if err != nil || strings.Contains(pattern, "*") {
+			return
+		}
+		n := 0
+		for _, seg := range strings.Split(pattern, "/") {
+			if strings.HasPrefix(seg, ":") {
+				n++
+			}
+		}
+		if len(params) != n {
+			t.Errorf("%q %q: expected %d params got %v", matched, pattern, n, params)
+		}
+	})
+}
+
+func FuzzMux_ServeHTTP(f *testing.F) {
+	h := func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, GetParams(r))
+	}
+	m := New(WithMaxPathLength(1024), WithMaxSegments(32), WithCaseInsensitive(), WithRedirectFixedPath())
+	m.Get("/", h)
+	m.Get("/users/:id([0-9]+)", h)
+	m.Get("/users/:id([0-9]+)/posts/*", h)
+	m.Get("/static/*path", h)
+	m.Group("/tenants/:tenant").Get("/users/:id", h)
+	m.Post("/users", h)
+	for _, p := range []string{"/", "/users/42", "/USERS/42/posts/a/b", "/a//b/../c", "/x\x00", "/%2F", strings.Repeat("/a", 40)} {
+		f.Add(p)
+	}
+	f.Fuzz(func(t *testing.T, p string) {
+		for _, raw := range []bool{false, true} {
+			m.UseRawPath(raw)
+			req := &http.Request{Method: "GET", URL: &url.URL{Path: p}, Header: http.Header{}}
+			w := httptest.NewRecorder()
+			m.ServeHTTP(w, req)
+			if w.Code >= http.StatusInternalServerError {
+				t.Errorf("%q: unexpected status %d", p, w.Code)
+			}
+		}
+	})
+}
+
+func TestMux_hostilePaths(t *testing.T) {
+	h := func(_ http.ResponseWriter, _ *http.Request) {}
+	m := New(WithMaxPathLength(64), WithMaxSegments(8))
+	m.Get("/files/*", h)
+	sample := []struct {
+		path string
+		code int
+	}{
+		{"/files/a/b", http.StatusOK},
+		{"/files/" + strings.Repeat("a", 64), http.StatusRequestURITooLong},
+		{"/files" + strings.Repeat("/a", 8), http.StatusRequestURITooLong},
+		{"/files" + strings.Repeat("/", 9), http.StatusRequestURITooLong},
+		{"/files/a\x00b", http.StatusBadRequest},
+	}
+	for _, v := range sample {
+		req := &http.Request{Method: "GET", URL: &url.URL{Path: v.path}, Header: http.Header{}}
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, req)
+		if w.Code != v.code {
+			t.Errorf("%q: expected %d got %d", v.path, v.code, w.Code)
+		}
+	}
+}
+
+func TestTrees_staticNode(t *testing.T) {
+	for _, routes := range [][]testRoute{staticRoutes, githubAPI} {
+		m := loadAlien(routes)
+		for _, v := range routes {
+			walked, err := (*m.load().root(v.method)).find(v.path, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rt, _ := m.find(v.method, v.path, ""); rt != walked {
+				t.Errorf("%s %s: expected the route of the tree %s got %v", v.method, v.path, walked.path, rt)
+			}
+			indexed := m.load().staticNode(v.method, v.path) != nil
+			if static := !strings.ContainsAny(v.path, ":*"); indexed != static {
+				t.Errorf("%s %s: expected indexed %v got %v", v.method, v.path, static, indexed)
+			}
+		}
+	}
+
+	m := New()
+	m.Get("/status", listUsers)
+	m.Version("2").Get("/status", alienHandle)
+	m.Get("/docs/", listUsers)
+	sample := []struct {
+		path, version, handler string
+	}{
+		{"/status", "", "listUsers"},
+		{"/status", "2", "alienHandle"},
+		{"/status", "3", "listUsers"},
+		{"/docs", "", "listUsers"},
+	}
+	for _, v := range sample {
+		rt, err := m.find("GET", v.path, v.version)
+		if err != nil || !strings.HasSuffix(funcName(rt.handler), v.handler) {
+			t.Errorf("%s %s: expected %s got %v", v.path, v.version, v.handler, err)
+		}
+	}
+	m.Get("/late", listUsers)
+	if m.load().staticNode("GET", "/late") == nil {
+		t.Error("expected the index to be rebuilt after registration")
+	}
+}
@@ -0,0 +1,304 @@
+// Package alientest tests alien routers without a listener. Requests built with
+// a fluent API are served by the Mux directly, and the responses checked with
+// assertions which report failures to the test.
+//
+//   c := alientest.New(m)
+//   c.Get("/users/1").
+//   	WithHeader("Accept", "application/json").
+//   	Expect(t).
+//   	Status(http.StatusOK).
+//   	JSONPath("$.id", 1).
+//   	JSONPath("$.roles[0]", "admin")
+package alientest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// Client sends requests to a handler, usually an *alien.Mux.
+type Client struct {
+	h http.Handler
+}
+
+// New returns a client sending requests to h.
+func New(h http.Handler) *Client {
+	return &Client{h: h}
+}
+
+// Request returns a request with method for target, the path and query of the
+// request.
+func (c *Client) Request(method, target string) *Request {
+	return &Request{c: c, method: method, target: target, header: make(http.Header)}
+}
+
+// Get returns a GET request for target.
+func (c *Client) Get(target string) *Request {
+	return c.Request(http.MethodGet, target)
+}
+
+// Head returns a HEAD request for target.
+func (c *Client) Head(target string) *Request {
+	return c.Request(http.MethodHead, target)
+}
+
+// Post returns a POST request for target.
+func (c *Client) Post(target string) *Request {
+	return c.Request(http.MethodPost, target)
+}
+
+// Put returns a PUT request for target.
+func (c *Client) Put(target string) *Request {
+	return c.Request(http.MethodPut, target)
+}
+
+// Patch returns a PATCH request for target.
+func (c *Client) Patch(target string) *Request {
+	return c.Request(http.MethodPatch, target)
+}
+
+// Delete returns a DELETE request for target.
+func (c *Client) Delete(target string) *Request {
+	return c.Request(http.MethodDelete, target)
+}
+
+// Options returns an OPTIONS request for target.
+func (c *Client) Options(target string) *Request {
+	return c.Request(http.MethodOptions, target)
+}
+
+// Request is a request being built, it is sent by Expect.
+type Request struct {
+	c      *Client
+	method string
+	target string
+	header http.Header
+	host   string
+	body   []byte
+	err    error
+}
+
+// WithHeader adds the header key with value.
+func (r *Request) WithHeader(key, value string) *Request {
+	r.header.Add(key, value)
+	return r
+}
+
+// WithHost sets the Host header.
+func (r *Request) WithHost(host string) *Request {
+	r.host = host
+	return r
+}
+
+// WithCookie adds a cookie.
+func (r *Request) WithCookie(c *http.Cookie) *Request {
+	r.header.Add("Cookie", c.String())
+	return r
+}
+
+// WithBody sets the body, and its Content-Type to contentType.
+func (r *Request) WithBody(contentType string, body []byte) *Request {
+	r.header.Set("Content-Type", contentType)
+	r.body = body
+	return r
+}
+
+// WithJSON sets the body to v encoded as JSON.
+func (r *Request) WithJSON(v interface{}) *Request {
+	b, err := json.Marshal(v)
+	if err != nil {
+		r.err = err
+	}
+	return r.WithBody("application/json", b)
+}
+
+// WithForm sets the body to the urlencoded form values.
+func (r *Request) WithForm(values url.Values) *Request {
+	return r.WithBody("application/x-www-form-urlencoded", []byte(values.Encode()))
+}
+
+// Expect sends the request and returns its response, failures of the
+// assertions made on it are reported to t.
+func (r *Request) Expect(t testing.TB) *Response {
+	t.Helper()
+	if r.err != nil {
+		t.Fatalf("alientest: %s %s: %v", r.method, r.target, r.err)
+	}
+	var body io.Reader
+	if r.body != nil {
+		body = bytes.NewReader(r.body)
+	}
+	req := httptest.NewRequest(r.method, r.target, body)
+	for k, v := range r.header {
+		req.Header[k] = v
+	}
+	if r.host != "" {
+		req.Host = r.host
+	}
+	w := httptest.NewRecorder()
+	r.c.h.ServeHTTP(w, req)
+	return &Response{t: t, name: r.method + " " + r.target, Recorder: w}
+}
+
+// Response is the response to a request, its methods assert what it must be.
+type Response struct {
+	t    testing.TB
+	name string
+
+	// Recorder holds the response for checks the assertions don't cover.
+	Recorder *httptest.ResponseRecorder
+
+	decoded bool
+	json    interface{}
+}
+
+// Status asserts that the status code is code.
+func (r *Response) Status(code int) *Response {
+	r.t.Helper()
+	if r.Recorder.Code != code {
+		r.t.Errorf("%s: expected status %d got %d", r.name, code, r.Recorder.Code)
+	}
+	return r
+}
+
+// Header asserts that the header key has value.
+func (r *Response) Header(key, value string) *Response {
+	r.t.Helper()
+	if v := r.Recorder.Header().Get(key); v != value {
+		r.t.Errorf("%s: expected header %s %q got %q", r.name, key, value, v)
+	}
+	return r
+}
+
+// Body asserts that the body is body.
+func (r *Response) Body(body string) *Response {
+	r.t.Helper()
+	if b := r.Recorder.Body.String(); b != body {
+		r.t.Errorf("%s: expected body %q got %q", r.name, body, b)
+	}
+	return r
+}
+
+// BodyContains asserts that the body contains s.
+func (r *Response) BodyContains(s string) *Response {
+	r.t.Helper()
+	if b := r.Recorder.Body.String(); !strings.Contains(b, s) {
+		r.t.Errorf("%s: expected body containing %q got %q", r.name, s, b)
+	}
+	return r
+}
+
+// JSON asserts that the body is JSON equal to v encoded as JSON.
+func (r *Response) JSON(v interface{}) *Response {
+	r.t.Helper()
+	got, ok := r.decode()
+	if !ok {
+		return r
+	}
+	if expect := normalize(r, v); !reflect.DeepEqual(got, expect) {
+		r.t.Errorf("%s: expected json %v got %v", r.name, expect, got)
+	}
+	return r
+}
+
+// JSONPath asserts that the value at path in the JSON body is v. Paths start
+// with $ for the whole body, followed by .name for object members and [i] for
+// array elements, for instance $.users[0].name.
+func (r *Response) JSONPath(path string, v interface{}) *Response {
+	r.t.Helper()
+	got, ok := r.decode()
+	if !ok {
+		return r
+	}
+	got, err := lookup(got, path)
+	if err != "" {
+		r.t.Errorf("%s: json path %s: %s", r.name, path, err)
+		return r
+	}
+	if expect := normalize(r, v); !reflect.DeepEqual(got, expect) {
+		r.t.Errorf("%s: expected %v at %s got %v", r.name, expect, path, got)
+	}
+	return r
+}
+
+// decode decodes the body as JSON once.
+func (r *Response) decode() (interface{}, bool) {
+	r.t.Helper()
+	if !r.decoded {
+		if err := json.Unmarshal(r.Recorder.Body.Bytes(), &r.json); err != nil {
+			r.t.Errorf("%s: body is not json: %v", r.name, err)
+			return nil, false
+		}
+		r.decoded = true
+	}
+	return r.json, true
+}
+
+// normalize returns v as it would be decoded from JSON, so 1 and 1.0 or a
+// struct and a map compare equal.
+func normalize(r *Response, v interface{}) interface{} {
+	r.t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		r.t.Errorf("%s: can't encode %v: %v", r.name, v, err)
+		return nil
+	}
+	var n interface{}
+	json.Unmarshal(b, &n)
+	return n
+}
+
+// lookup returns the value at path in v, or why there is none.
+func lookup(v interface{}, path string) (interface{}, string) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, "must start with $"
+	}
+	path = path[1:]
+	for path != "" {
+		switch path[0] {
+		case '.':
+			end := strings.IndexAny(path[1:], ".[") + 1
+			if end == 0 {
+				end = len(path)
+			}
+			name := path[1:end]
+			obj, ok := v.(map[string]interface{})
+			if !ok {
+				return nil, "not an object at " + name
+			}
+			if v, ok = obj[name]; !ok {
+				return nil, "no member " + name
+			}
+			path = path[end:]
+		case '[':
+			end := strings.IndexByte(path, ']')
+			if end == -1 {
+				return nil, "unterminated index"
+			}
+			i, err := strconv.Atoi(path[1:end])
+			if err != nil {
+				return nil, "bad index " + path[1:end]
+			}
+			arr, ok := v.([]interface{})
+			if !ok {
+				return nil, "not an array at index " + path[1:end]
+			}
+			if i < 0 || i >= len(arr) {
+				return nil, "index " + path[1:end] + " out of range"
+			}
+			v = arr[i]
+			path = path[end+1:]
+		default:
+			return nil, "unexpected " + path
+		}
+	}
+	return v, ""
+}
@@ -0,0 +1,119 @@
+package alientest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gernest/alien"
+)
+
+// recordingT records the failures reported to it instead of failing.
+type recordingT struct {
+	testing.TB
+	errors []string
+}
+
+func (t *recordingT) Helper() {}
+
+func (t *recordingT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func newMux() *alien.Mux {
+	m := alien.New()
+	m.Get("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Id", alien.GetParams(r).Get("id"))
+		alien.JSON(w, http.StatusOK, map[string]interface{}{
+			"id":    1,
+			"name":  "gernest",
+			"roles": []string{"admin", "dev"},
+			"team":  map[string]interface{}{"name": "alien", "size": 2.5},
+			"host":  r.Host,
+			"token": r.Header.Get("Authorization"),
+		})
+	})
+	m.Post("/echo", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", r.Header.Get("Content-Type"))
+		w.WriteHeader(http.StatusCreated)
+		io.Copy(w, r.Body)
+	})
+	return m
+}
+
+func TestClient(t *testing.T) {
+	c := New(newMux())
+	c.Get("/users/1").
+		WithHeader("Authorization", "Bearer t").
+		WithHost("api.example.com").
+		Expect(t).
+		Status(http.StatusOK).
+		Header("X-Id", "1").
+		BodyContains(`"name":"gernest"`).
+		JSONPath("$.id", 1).
+		JSONPath("$.roles[1]", "dev").
+		JSONPath("$.team.size", 2.5).
+		JSONPath("$.host", "api.example.com").
+		JSONPath("$.token", "Bearer t")
+	c.Post("/echo").
+		WithJSON(map[string]int{"n": 1}).
+		Expect(t).
+		Status(http.StatusCreated).
+		Header("Content-Type", "application/json").
+		JSON(struct {
+			N float64 `json:"n"`
+		}{1})
+	c.Post("/echo").
+		WithForm(url.Values{"a": {"b"}}).
+		Expect(t).
+		Body("a=b")
+	c.Request("DELETE", "/users/1").Expect(t).Status(http.StatusMethodNotAllowed)
+}
+
+func TestResponse_failures(t *testing.T) {
+	c := New(newMux())
+	sample := []struct {
+		check func(r *Response)
+		err   string
+	}{
+		{func(r *Response) { r.Status(http.StatusCreated) }, "expected status 201 got 200"},
+		{func(r *Response) { r.Header("X-Id", "2") }, `expected header X-Id "2" got "1"`},
+		{func(r *Response) { r.Body("{}") }, `expected body "{}"`},
+		{func(r *Response) { r.BodyContains("nope") }, `expected body containing "nope"`},
+		{func(r *Response) { r.JSON(map[string]int{"id": 1}) }, "expected json"},
+		{func(r *Response) { r.JSONPath("$.id", 2) }, "expected 2 at $.id got 1"},
+		{func(r *Response) { r.JSONPath("$.missing", 2) }, "no member missing"},
+		{func(r *Response) { r.JSONPath("$.roles[5]", 2) }, "index 5 out of range"},
+		{func(r *Response) { r.JSONPath("$.name[0]", 2) }, "not an array"},
+		{func(r *Response) { r.JSONPath("id", 2) }, "must start with $"},
+	}
+	for _, v := range sample {
+		rt := &recordingT{TB: t}
+		v.check(c.Get("/users/1").Expect(rt))
+		if len(rt.errors) != 1 || !strings.Contains(rt.errors[0], v.err) {
+			t.Errorf("expected an error containing %q got %q", v.err, rt.errors)
+		}
+	}
+
+	rt := &recordingT{TB: t}
+	c.Post("/echo").WithBody("text/plain", []byte("hi")).Expect(rt).JSONPath("$", "hi")
+	if len(rt.errors) != 1 || !strings.Contains(rt.errors[0], "body is not json") {
+		t.Errorf("expected a json error got %q", rt.errors)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	var v interface{}
+	json.Unmarshal([]byte(`{"a":[{"b":{"c":[1,2]}}]}`), &v)
+	got, err := lookup(v, "$.a[0].b.c[1]")
+	if err != "" || got != 2.0 {
+		t.Errorf("expected 2 got %v %s", got, err)
+	}
+	if got, _ := lookup(v, "$"); got == nil {
+		t.Error("expected the whole document for $")
+	}
+}
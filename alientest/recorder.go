@@ -0,0 +1,48 @@
+package alientest
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+)
+
+// Recorder is an httptest.ResponseRecorder which records pushes and can be
+// hijacked, to test that middlewares wrapping the writer pass them on.
+//   w := alientest.NewRecorder()
+//   m.ServeHTTP(w, req)
+//   if !w.Flushed || len(w.Pushed) == 0 || !w.Hijacked {
+//       t.Error("expected the middleware to pass the writer on")
+//   }
+type Recorder struct {
+	*httptest.ResponseRecorder
+
+	// Pushed are the targets pushed to the client.
+	Pushed []string
+
+	// Hijacked is set once the connection is hijacked.
+	Hijacked bool
+}
+
+// NewRecorder returns an initialized Recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{ResponseRecorder: httptest.NewRecorder()}
+}
+
+// Push records target.
+func (w *Recorder) Push(target string, _ *http.PushOptions) error {
+	w.Pushed = append(w.Pushed, target)
+	return nil
+}
+
+// Hijack returns a connection whose peer is closed, reads from it fail with
+// io.EOF.
+func (w *Recorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if w.Hijacked {
+		return nil, nil, http.ErrHijacked
+	}
+	w.Hijacked = true
+	conn, peer := net.Pipe()
+	peer.Close()
+	return conn, bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn)), nil
+}
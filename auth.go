@@ -0,0 +1,39 @@
+package alien
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+)
+
+var userKey = &contextKey{"user"}
+
+// BasicAuth returns a middleware which requires requests to be authenticated
+// with HTTP basic authentication. validate is called with the credentials sent
+// by the client, requests without credentials or for which validate returns
+// false are rejected with 401 Unauthorized and a WWW-Authenticate challenge for
+// realm.
+//
+// The name of the authenticated user is available to handlers via
+// BasicAuthUser.
+func BasicAuth(realm string, validate func(user, pass string) bool) func(http.Handler) http.Handler {
+	challenge := "Basic realm=" + strconv.Quote(realm) + `, charset="UTF-8"`
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, pass, ok := r.BasicAuth()
+			if !ok || !validate(user, pass) {
+				w.Header().Set("WWW-Authenticate", challenge)
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+			h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userKey, user)))
+		})
+	}
+}
+
+// BasicAuthUser returns the name of the user authenticated by the BasicAuth
+// middleware, or an empty string if there is none.
+func BasicAuthUser(r *http.Request) string {
+	user, _ := r.Context().Value(userKey).(string)
+	return user
+}
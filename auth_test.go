@@ -0,0 +1,48 @@
+package alien
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBasicAuth(t *testing.T) {
+	validate := func(user, pass string) bool {
+		return user == "gernest" && pass == "alien"
+	}
+	m := New()
+	m.Use(BasicAuth("space", validate))
+	m.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(BasicAuthUser(r)))
+	})
+
+	sample := []struct {
+		user, pass string
+		code       int
+	}{
+		{"gernest", "alien", http.StatusOK},
+		{"gernest", "human", http.StatusUnauthorized},
+		{"", "", http.StatusUnauthorized},
+	}
+	for _, v := range sample {
+		req, _ := http.NewRequest("GET", "/", nil)
+		if v.user != "" {
+			req.SetBasicAuth(v.user, v.pass)
+		}
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, req)
+		if w.Code != v.code {
+			t.Errorf("%s: expected %d got %d", v.user, v.code, w.Code)
+		}
+		if v.code == http.StatusOK {
+			if w.Body.String() != v.user {
+				t.Errorf("expected %s got %s", v.user, w.Body)
+			}
+			continue
+		}
+		challenge := `Basic realm="space", charset="UTF-8"`
+		if c := w.Header().Get("WWW-Authenticate"); c != challenge {
+			t.Errorf("expected %s got %s", challenge, c)
+		}
+	}
+}
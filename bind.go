@@ -0,0 +1,281 @@
+package alien
+
+import (
+	"encoding"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+var (
+	errBindTarget   = errors.New("bind target must be a non nil pointer to a struct")
+	errBindType     = errors.New("unsupported field type")
+	fileType        = reflect.TypeOf((*multipart.FileHeader)(nil))
+	filesType       = reflect.TypeOf([]*multipart.FileHeader(nil))
+	durationType    = reflect.TypeOf(time.Duration(0))
+	timeType        = reflect.TypeOf(time.Time{})
+	textUnmarshaler = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// BindError is returned by the Bind functions when a value can't be decoded
+// into the field it is bound to.
+type BindError struct {
+	Field string
+	Value string
+	Err   error
+}
+
+func (e *BindError) Error() string {
+	return "alien: can't bind " + strconv.Quote(e.Value) + " to " + e.Field + ": " + e.Err.Error()
+}
+
+func (e *BindError) Unwrap() error {
+	return e.Err
+}
+
+// Status returns 400 Bad Request, the status for responses to requests which
+// can't be decoded.
+func (e *BindError) Status() int {
+	return http.StatusBadRequest
+}
+
+// BindQuery populates the struct pointed to by dst from the query values of r.
+// Fields are bound to the value named by their query tag, fields without the
+// tag or tagged with "-" are ignored.
+//   type search struct {
+//       Query string     `query:"q"`
+//       Page  int        `query:"page" default:"1"`
+//       Tags  []string   `query:"tag"`
+//       Since *time.Time `query:"since" layout:"2006-01-02"`
+//   }
+//   var s search
+//   err := alien.BindQuery(r, &s)
+//
+// Supported field types are strings, booleans, numbers, time.Duration,
+// time.Time, types implementing encoding.TextUnmarshaler and pointers and
+// slices of those. Times are parsed with the layout tag, or time.RFC3339 if it
+// is missing. The default tag is used when the value is absent, and fields of
+// embedded structs are bound as if they were fields of dst.
+//
+// dst is then validated, see SetValidator. Errors are a *BindError or a
+// *ValidationError.
+func BindQuery(r *http.Request, dst interface{}) error {
+	if err := bindValues(dst, r.URL.Query(), nil, "query", true); err != nil {
+		return err
+	}
+	return validate(r, dst)
+}
+
+// defaultMaxMemory is the memory used by BindForm to store multipart forms.
+const defaultMaxMemory = 32 << 20
+
+// BindForm populates the struct pointed to by dst from the form of r, fields
+// are bound to the value named by their form tag. It is like BindQuery except
+// the values come from the urlencoded or multipart body of r as well as its
+// query, body values take precedence. Uploaded files are bound to fields of
+// type *multipart.FileHeader or []*multipart.FileHeader.
+//   type upload struct {
+//       Title  string                  `form:"title"`
+//       Avatar *multipart.FileHeader   `form:"avatar"`
+//       Photos []*multipart.FileHeader `form:"photos"`
+//   }
+//
+// Up to 32MB of multipart forms are stored in memory, the rest of the files
+// are stored on disk. Use BindMultipart to change that limit.
+func BindForm(r *http.Request, dst interface{}) error {
+	return BindMultipart(r, dst, defaultMaxMemory)
+}
+
+// BindMultipart is like BindForm but stores up to maxMemory bytes of multipart
+// forms in memory.
+func BindMultipart(r *http.Request, dst interface{}, maxMemory int64) error {
+	var files map[string][]*multipart.FileHeader
+	values := make(url.Values)
+	err := r.ParseMultipartForm(maxMemory)
+	switch err {
+	case nil:
+		files = r.MultipartForm.File
+		for k, v := range r.MultipartForm.Value {
+			values[k] = append(values[k], v...)
+		}
+	case http.ErrNotMultipart:
+		for k, v := range r.PostForm {
+			values[k] = append(values[k], v...)
+		}
+	default:
+		return err
+	}
+	// r.Form has query values before multipart values, so it can't be used
+	// for the body values to take precedence.
+	for k, v := range r.URL.Query() {
+		values[k] = append(values[k], v...)
+	}
+	if err := bindValues(dst, values, files, "form", true); err != nil {
+		return err
+	}
+	return validate(r, dst)
+}
+
+// bindValues sets the fields of dst tagged with tag from values and files.
+func bindValues(dst interface{}, values url.Values, files map[string][]*multipart.FileHeader, tag string, defaults bool) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return errBindTarget
+	}
+	return bindStruct(v.Elem(), values, files, tag, defaults)
+}
+
+// bindStruct binds values to the fields of v with tag, fields without a value
+// are set to their default if defaults is true.
+func bindStruct(v reflect.Value, values url.Values, files map[string][]*multipart.FileHeader, tag string, defaults bool) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := f.Tag.Get(tag)
+		if name == "" && f.Anonymous && f.Type.Kind() == reflect.Struct {
+			if err := bindStruct(v.Field(i), values, files, tag, defaults); err != nil {
+				return err
+			}
+			continue
+		}
+		if name == "" || name == "-" || f.PkgPath != "" {
+			continue
+		}
+		switch f.Type {
+		case fileType:
+			if fh := files[name]; len(fh) > 0 {
+				v.Field(i).Set(reflect.ValueOf(fh[0]))
+			}
+			continue
+		case filesType:
+			if fh := files[name]; len(fh) > 0 {
+				v.Field(i).Set(reflect.ValueOf(fh))
+			}
+			continue
+		}
+		vals, ok := values[name]
+		if !ok || len(vals) == 0 {
+			def, ok := f.Tag.Lookup("default")
+			if !ok || !defaults {
+				continue
+			}
+			vals = []string{def}
+		}
+		if err := bindField(v.Field(i), vals, f.Tag.Get("layout")); err != nil {
+			return &BindError{Field: f.Name, Value: vals[0], Err: err}
+		}
+	}
+	return nil
+}
+
+// bindDefaults sets the fields of v which have one of tags to their default.
+func bindDefaults(v reflect.Value, tags ...string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tagged := false
+		for _, tag := range tags {
+			if name := f.Tag.Get(tag); name != "" && name != "-" {
+				tagged = true
+			}
+		}
+		if !tagged && f.Anonymous && f.Type.Kind() == reflect.Struct {
+			if err := bindDefaults(v.Field(i), tags...); err != nil {
+				return err
+			}
+			continue
+		}
+		def, ok := f.Tag.Lookup("default")
+		if !ok || !tagged || f.PkgPath != "" {
+			continue
+		}
+		if err := bindField(v.Field(i), []string{def}, f.Tag.Get("layout")); err != nil {
+			return &BindError{Field: f.Name, Value: def, Err: err}
+		}
+	}
+	return nil
+}
+
+// bindField sets v from vals, slices get all of vals while other types get the
+// first one.
+func bindField(v reflect.Value, vals []string, layout string) error {
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+		s := reflect.MakeSlice(v.Type(), len(vals), len(vals))
+		for i, val := range vals {
+			if err := bindValue(s.Index(i), val, layout); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+		return nil
+	}
+	return bindValue(v, vals[0], layout)
+}
+
+func bindValue(v reflect.Value, val, layout string) error {
+	if v.Kind() == reflect.Ptr {
+		p := reflect.New(v.Type().Elem())
+		if err := bindValue(p.Elem(), val, layout); err != nil {
+			return err
+		}
+		v.Set(p)
+		return nil
+	}
+	if v.CanAddr() && v.Addr().Type().Implements(textUnmarshaler) && v.Type() != timeType {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(val))
+	}
+	switch v.Type() {
+	case timeType:
+		if layout == "" {
+			layout = time.RFC3339
+		}
+		t, err := time.Parse(layout, val)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	case durationType:
+		d, err := time.ParseDuration(val)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(val)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(val, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(val, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(val, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		return errBindType
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package alien
+
+import (
+	"bytes"
+	"errors"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+type pagination struct {
+	Page    int `query:"page" default:"1"`
+	PerPage int `query:"per_page" default:"20"`
+}
+
+type search struct {
+	pagination
+	Query    string        `query:"q"`
+	Tags     []string      `query:"tag"`
+	IDs      []int64       `query:"id"`
+	Since    *time.Time    `query:"since" layout:"2006-01-02"`
+	Until    time.Time     `query:"until"`
+	Timeout  time.Duration `query:"timeout"`
+	Exact    bool          `query:"exact"`
+	Score    *float64      `query:"score"`
+	IP       net.IP        `query:"ip"`
+	Ignored  string        `query:"-"`
+	Untagged string
+}
+
+func TestBindQuery(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/search?q=alien&tag=go&tag=web&id=1&id=2&since=2017-01-02"+
+		"&until=2017-01-02T15:04:05Z&timeout=2s&exact=true&per_page=50&ip=127.0.0.1&Ignored=x&Untagged=x", nil)
+	var s search
+	if err := BindQuery(req, &s); err != nil {
+		t.Fatal(err)
+	}
+	since := time.Date(2017, 1, 2, 0, 0, 0, 0, time.UTC)
+	expect := search{
+		pagination: pagination{Page: 1, PerPage: 50},
+		Query:      "alien",
+		Tags:       []string{"go", "web"},
+		IDs:        []int64{1, 2},
+		Since:      &since,
+		Until:      time.Date(2017, 1, 2, 15, 4, 5, 0, time.UTC),
+		Timeout:    2 * time.Second,
+		Exact:      true,
+		IP:         net.ParseIP("127.0.0.1"),
+	}
+	if !reflect.DeepEqual(s, expect) {
+		t.Errorf("expected %+v got %+v", expect, s)
+	}
+
+	sample := []struct {
+		query, field string
+		err          error
+	}{
+		{"page=one", "Page", strconv.ErrSyntax},
+		{"id=1&id=x", "IDs", strconv.ErrSyntax},
+		{"exact=maybe", "Exact", strconv.ErrSyntax},
+	}
+	for _, v := range sample {
+		req, _ := http.NewRequest("GET", "/search?"+v.query, nil)
+		err := BindQuery(req, &search{})
+		var be *BindError
+		if !errors.As(err, &be) {
+			t.Fatalf("%s: expected *BindError got %v", v.query, err)
+		}
+		if be.Field != v.field {
+			t.Errorf("%s: expected %s got %s", v.query, v.field, be.Field)
+		}
+		if !errors.Is(err, v.err) {
+			t.Errorf("%s: expected %v got %v", v.query, v.err, err)
+		}
+	}
+
+	if err := BindQuery(req, search{}); err != errBindTarget {
+		t.Errorf("expected %v got %v", errBindTarget, err)
+	}
+}
+
+type upload struct {
+	Title  string                  `form:"title"`
+	Tags   []string                `form:"tag"`
+	Draft  bool                    `form:"draft" default:"true"`
+	Avatar *multipart.FileHeader   `form:"avatar"`
+	Photos []*multipart.FileHeader `form:"photos"`
+}
+
+func TestBindForm(t *testing.T) {
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	mw.WriteField("title", "alien")
+	mw.WriteField("draft", "false")
+	for _, name := range []string{"avatar", "photos", "photos"} {
+		fw, _ := mw.CreateFormFile(name, name+".jpg")
+		fw.Write([]byte("jpg"))
+	}
+	mw.Close()
+	req, _ := http.NewRequest("POST", "/upload?tag=go&title=ignored", body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	var u upload
+	if err := BindMultipart(req, &u, 1<<10); err != nil {
+		t.Fatal(err)
+	}
+	if u.Title != "alien" || u.Draft || !reflect.DeepEqual(u.Tags, []string{"go"}) {
+		t.Errorf("unexpected values %+v", u)
+	}
+	if u.Avatar == nil || u.Avatar.Filename != "avatar.jpg" {
+		t.Errorf("expected avatar.jpg got %v", u.Avatar)
+	}
+	if len(u.Photos) != 2 {
+		t.Errorf("expected 2 got %d", len(u.Photos))
+	}
+
+	req, _ = http.NewRequest("POST", "/upload", strings.NewReader("title=alien&tag=go&tag=web"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	u = upload{}
+	if err := BindForm(req, &u); err != nil {
+		t.Fatal(err)
+	}
+	expect := upload{Title: "alien", Tags: []string{"go", "web"}, Draft: true}
+	if !reflect.DeepEqual(u, expect) {
+		t.Errorf("expected %+v got %+v", expect, u)
+	}
+}
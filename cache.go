@@ -0,0 +1,97 @@
+package alien
+
+import (
+	"container/list"
+	"sync"
+)
+
+// matchKey is the key of a cached match.
+type matchKey struct {
+	method, path string
+}
+
+// matchEntry is a cached match, valid as long as the router still has the
+// trees it was found in.
+type matchEntry struct {
+	key       matchKey
+	trees     *trees
+	route     *route
+	canonical string
+}
+
+// matchCache keeps the routes matched for the most recently requested paths.
+type matchCache struct {
+	size int
+
+	mu    sync.Mutex
+	items map[matchKey]*list.Element
+	lru   list.List
+}
+
+func newMatchCache(size int) *matchCache {
+	return &matchCache{size: size, items: make(map[matchKey]*list.Element, size)}
+}
+
+// get returns the route matched for method and path in t.
+func (c *matchCache) get(t *trees, method, path string) (*route, string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.items[matchKey{method, path}]
+	if !ok {
+		return nil, "", false
+	}
+	v := e.Value.(*matchEntry)
+	if v.trees != t {
+		c.lru.Remove(e)
+		delete(c.items, v.key)
+		return nil, "", false
+	}
+	c.lru.MoveToFront(e)
+	return v.route, v.canonical, true
+}
+
+// put caches the route matched for method and path in t, evicting the least
+// recently used match if the cache is full.
+func (c *matchCache) put(t *trees, method, path string, rt *route, canonical string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := matchKey{method, path}
+	if e, ok := c.items[key]; ok {
+		c.lru.MoveToFront(e)
+		e.Value = &matchEntry{key: key, trees: t, route: rt, canonical: canonical}
+		return
+	}
+	if c.lru.Len() >= c.size {
+		last := c.lru.Back()
+		c.lru.Remove(last)
+		delete(c.items, last.Value.(*matchEntry).key)
+	}
+	c.items[key] = c.lru.PushFront(&matchEntry{key: key, trees: t, route: rt, canonical: canonical})
+}
+
+// MatchCache keeps the routes matched for the size most recently requested
+// paths, so hot paths of large route tables are served without walking the
+// route tree. Registering routes invalidates the cache. Only requests without
+// an API version are cached, and a size of zero or less removes the cache.
+//   m.MatchCache(1024)
+//
+// Lookups take a lock, so the cache only pays off for tables large enough for
+// a walk of the tree to cost more, measure with the routes of the application.
+func (m *Mux) MatchCache(size int) {
+	m = m.top()
+	for _, r := range m.routers() {
+		r.cache = nil
+		if size > 0 {
+			r.cache = newMatchCache(size)
+		}
+	}
+}
+
+// routers returns the router of m followed by those of its hosts.
+func (m *Mux) routers() []*router {
+	r := []*router{m.router}
+	for _, h := range m.hosts {
+		r = append(r, h.routers()...)
+	}
+	return r
+}
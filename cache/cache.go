@@ -0,0 +1,283 @@
+// Package cache provides an in memory response cache middleware for alien.
+//
+//   c := cache.New(cache.Options{TTL: time.Minute, MaxEntries: 1000})
+//   m := alien.New()
+//   m.Get("/users", listUsers, c.Middleware)
+//   m.Post("/users", func(w http.ResponseWriter, r *http.Request) {
+//   	createUser(w, r)
+//   	c.Invalidate("/users*")
+//   })
+//
+// Only successful responses to GET requests are cached, responses setting
+// cookies or with Cache-Control private or no-store are never cached. HEAD
+// requests are answered from the cached GET responses. Responses are cached
+// per host, and requests with an Authorization or Cookie header bypass the
+// cache unless Options.Authenticated is set.
+package cache
+
+import (
+	"bufio"
+	"bytes"
+	"container/list"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gernest/alien"
+)
+
+// DefaultMaxBodySize is the default size limit of cached response bodies.
+const DefaultMaxBodySize = 1 << 20
+
+// Options configures the cache.
+type Options struct {
+	// TTL is how long responses are cached. Defaults to one minute.
+	TTL time.Duration
+
+	// MaxEntries is the maximum number of cached responses, the least recently
+	// used response is evicted when it is exceeded. Zero means no limit.
+	MaxEntries int
+
+	// MaxBodySize is the maximum size of cached response bodies. Defaults to
+	// DefaultMaxBodySize.
+	MaxBodySize int
+
+	// Vary is a list of request headers whose values are part of the cache
+	// key, for instance Accept or Accept-Encoding.
+	Vary []string
+
+	// Authenticated caches the responses to requests with an Authorization or
+	// Cookie header, which are served to every client. Only set it if those
+	// responses don't depend on the credentials, or if the headers carrying
+	// them are listed in Vary.
+	Authenticated bool
+
+	// Skipper returns true for requests which bypass the middleware, see
+	// alien.SkipPaths.
+	Skipper func(r *http.Request) bool
+}
+
+// Cache is an in memory response cache, it is safe for concurrent use.
+type Cache struct {
+	opts    Options
+	mu      sync.Mutex
+	lru     *list.List
+	entries map[string]*list.Element
+}
+
+type entry struct {
+	key     string
+	path    string
+	code    int
+	header  http.Header
+	body    []byte
+	expires time.Time
+}
+
+// New returns an empty cache configured by o.
+func New(o Options) *Cache {
+	if o.TTL <= 0 {
+		o.TTL = time.Minute
+	}
+	if o.MaxBodySize <= 0 {
+		o.MaxBodySize = DefaultMaxBodySize
+	}
+	return &Cache{
+		opts:    o,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+func (c *Cache) key(r *http.Request) string {
+	var b strings.Builder
+	b.WriteString(r.Host)
+	b.WriteString(r.URL.RequestURI())
+	for _, v := range c.opts.Vary {
+		b.WriteByte('\n')
+		b.WriteString(r.Header.Get(v))
+	}
+	return b.String()
+}
+
+// Middleware serves cached responses, and caches the responses of h.
+func (c *Cache) Middleware(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead || c.opts.Skipper != nil && c.opts.Skipper(r) ||
+			!c.opts.Authenticated && (r.Header.Get("Authorization") != "" || r.Header.Get("Cookie") != "") {
+			h.ServeHTTP(w, r)
+			return
+		}
+		key := c.key(r)
+		if e := c.get(key); e != nil {
+			dst := w.Header()
+			for k, v := range e.header {
+				dst[k] = v
+			}
+			dst.Set("X-Cache", "HIT")
+			w.WriteHeader(e.code)
+			if r.Method == http.MethodGet {
+				w.Write(e.body)
+			}
+			return
+		}
+		if r.Method == http.MethodHead {
+			h.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("X-Cache", "MISS")
+		cw := &cacheWriter{ResponseWriter: alien.WrapWriter(w), max: c.opts.MaxBodySize}
+		h.ServeHTTP(cw, r)
+		if cw.cacheable() {
+			header := w.Header().Clone()
+			header.Del("X-Cache")
+			c.add(&entry{
+				key:     key,
+				path:    r.URL.Path,
+				code:    cw.code,
+				header:  header,
+				body:    cw.buf.Bytes(),
+				expires: time.Now().Add(c.opts.TTL),
+			})
+		}
+	})
+}
+
+func (c *Cache) get(key string) *entry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	e := el.Value.(*entry)
+	if time.Now().After(e.expires) {
+		c.remove(el)
+		return nil
+	}
+	c.lru.MoveToFront(el)
+	return e
+}
+
+func (c *Cache) add(e *entry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[e.key]; ok {
+		c.remove(el)
+	}
+	c.entries[e.key] = c.lru.PushFront(e)
+	if c.opts.MaxEntries > 0 && c.lru.Len() > c.opts.MaxEntries {
+		c.remove(c.lru.Back())
+	}
+}
+
+func (c *Cache) remove(el *list.Element) {
+	c.lru.Remove(el)
+	delete(c.entries, el.Value.(*entry).key)
+}
+
+// Invalidate removes the cached responses for paths matching pattern on every
+// host, and returns the number of removed responses. A pattern ending with * matches
+// every path starting with the rest of the pattern, any other pattern must
+// match the path exactly.
+//   c.Invalidate("/users/42") // only /users/42
+//   c.Invalidate("/users/*") // /users/42, /users/42/posts ...
+func (c *Cache) Invalidate(pattern string) int {
+	prefix := strings.HasSuffix(pattern, "*")
+	pattern = strings.TrimSuffix(pattern, "*")
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := 0
+	for el := c.lru.Front(); el != nil; {
+		next := el.Next()
+		p := el.Value.(*entry).path
+		if p == pattern || (prefix && strings.HasPrefix(p, pattern)) {
+			c.remove(el)
+			n++
+		}
+		el = next
+	}
+	return n
+}
+
+// Purge removes all cached responses.
+func (c *Cache) Purge() {
+	c.mu.Lock()
+	c.lru.Init()
+	c.entries = make(map[string]*list.Element)
+	c.mu.Unlock()
+}
+
+// Len returns the number of cached responses.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// cacheWriter passes the response through while keeping a copy of the body,
+// flushes and pushes are passed to the writer it wraps.
+type cacheWriter struct {
+	alien.ResponseWriter
+	buf      bytes.Buffer
+	max      int
+	code     int
+	tooLarge bool
+	hijacked bool
+}
+
+func (w *cacheWriter) WriteHeader(code int) {
+	if w.code == 0 && code >= 200 {
+		w.code = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *cacheWriter) Write(b []byte) (int, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	if !w.tooLarge {
+		if w.buf.Len()+n > w.max {
+			w.tooLarge = true
+			w.buf = bytes.Buffer{}
+		} else {
+			w.buf.Write(b[:n])
+		}
+	}
+	return n, err
+}
+
+// ReadFrom copies the body from r through Write, so that it is kept as well.
+func (w *cacheWriter) ReadFrom(r io.Reader) (int64, error) {
+	return io.Copy(struct{ io.Writer }{w}, r)
+}
+
+// Hijack passes the connection on, responses of hijacked connections are not
+// cached.
+func (w *cacheWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	c, rw, err := w.ResponseWriter.Hijack()
+	if err == nil {
+		w.hijacked = true
+	}
+	return c, rw, err
+}
+
+func (w *cacheWriter) cacheable() bool {
+	if w.tooLarge || w.hijacked || (w.code != http.StatusOK && w.code != 0) {
+		return false
+	}
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	h := w.Header()
+	if h.Get("Set-Cookie") != "" || alien.HasTrailers(h) {
+		return false
+	}
+	cc := strings.ToLower(h.Get("Cache-Control"))
+	return !strings.Contains(cc, "no-store") && !strings.Contains(cc, "private")
+}
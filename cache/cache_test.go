@@ -0,0 +1,221 @@
+package cache
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gernest/alien"
+	"github.com/gernest/alien/alientest"
+)
+
+func TestCache(t *testing.T) {
+	hits := 0
+	c := New(Options{TTL: time.Hour, Vary: []string{"Accept"}})
+	m := alien.New()
+	h := func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.Header().Set("Content-Type", "text/plain")
+		fmt.Fprintf(w, "%s %d", r.URL.Path, hits)
+	}
+	m.Get("/users/:id", h, c.Middleware)
+	m.Get("/posts", h, c.Middleware)
+	m.Get("/private", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "private")
+		h(w, r)
+	}, c.Middleware)
+
+	get := func(path, accept string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", path, nil)
+		req.Header.Set("Accept", accept)
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, req)
+		return w
+	}
+	sample := []struct {
+		path, accept, body, cache string
+	}{
+		{"/users/1", "", "/users/1 1", "MISS"},
+		{"/users/1", "", "/users/1 1", "HIT"},
+		{"/users/1", "application/json", "/users/1 2", "MISS"},
+		{"/users/2", "", "/users/2 3", "MISS"},
+		{"/posts", "", "/posts 4", "MISS"},
+		{"/private", "", "/private 5", "MISS"},
+		{"/private", "", "/private 6", "MISS"},
+	}
+	for _, v := range sample {
+		w := get(v.path, v.accept)
+		if w.Body.String() != v.body {
+			t.Errorf("%s: expected %s got %s", v.path, v.body, w.Body)
+		}
+		if x := w.Header().Get("X-Cache"); x != v.cache {
+			t.Errorf("%s: expected %s got %s", v.path, v.cache, x)
+		}
+		if ct := w.Header().Get("Content-Type"); ct != "text/plain" {
+			t.Errorf("%s: expected text/plain got %s", v.path, ct)
+		}
+	}
+	if c.Len() != 4 {
+		t.Errorf("expected 4 entries got %d", c.Len())
+	}
+	if n := c.Invalidate("/users/*"); n != 3 {
+		t.Errorf("expected 3 invalidated entries got %d", n)
+	}
+	if w := get("/users/1", ""); w.Header().Get("X-Cache") != "MISS" {
+		t.Error("expected invalidated entry to be missed")
+	}
+	if w := get("/posts", ""); w.Header().Get("X-Cache") != "HIT" {
+		t.Error("expected /posts to be cached")
+	}
+	c.Purge()
+	if c.Len() != 0 {
+		t.Errorf("expected empty cache got %d", c.Len())
+	}
+}
+
+func TestCache_shared(t *testing.T) {
+	hits := 0
+	h := func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		fmt.Fprintf(w, "%s %s %d", r.Host, r.Header.Get("Authorization"), hits)
+	}
+	c := New(Options{})
+	m := alien.New()
+	m.Host(":tenant.example.com").Get("/dash", h, c.Middleware)
+	auth := New(Options{Authenticated: true, Vary: []string{"Authorization"}})
+	m.Get("/me", h, auth.Middleware)
+
+	sample := []struct {
+		host, path, header, value, body, cache string
+	}{
+		{"acme.example.com", "/dash", "", "", "acme.example.com  1", "MISS"},
+		{"other.example.com", "/dash", "", "", "other.example.com  2", "MISS"},
+		{"acme.example.com", "/dash", "", "", "acme.example.com  1", "HIT"},
+		{"acme.example.com", "/dash", "Authorization", "Bearer alice", "acme.example.com Bearer alice 3", ""},
+		{"acme.example.com", "/dash", "Authorization", "Bearer bob", "acme.example.com Bearer bob 4", ""},
+		{"acme.example.com", "/dash", "Cookie", "session=alice", "acme.example.com  5", ""},
+		{"example.com", "/me", "Authorization", "Bearer alice", "example.com Bearer alice 6", "MISS"},
+		{"example.com", "/me", "Authorization", "Bearer bob", "example.com Bearer bob 7", "MISS"},
+		{"example.com", "/me", "Authorization", "Bearer alice", "example.com Bearer alice 6", "HIT"},
+	}
+	for _, v := range sample {
+		req, _ := http.NewRequest("GET", "http://"+v.host+v.path, nil)
+		if v.header != "" {
+			req.Header.Set(v.header, v.value)
+		}
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, req)
+		if w.Body.String() != v.body {
+			t.Errorf("%s%s %s: expected %s got %s", v.host, v.path, v.value, v.body, w.Body)
+		}
+		if x := w.Header().Get("X-Cache"); x != v.cache {
+			t.Errorf("%s%s %s: expected %q got %q", v.host, v.path, v.value, v.cache, x)
+		}
+	}
+}
+
+func TestCache_eviction(t *testing.T) {
+	c := New(Options{MaxEntries: 2})
+	m := alien.New()
+	m.Get("/:id", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.Path))
+	}, c.Middleware)
+	for _, p := range []string{"/1", "/2", "/1", "/3"} {
+		req, _ := http.NewRequest("GET", p, nil)
+		m.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	if c.Len() != 2 {
+		t.Fatalf("expected 2 entries got %d", c.Len())
+	}
+	if c.Invalidate("/2") != 0 {
+		t.Error("expected /2 to be evicted")
+	}
+	if c.Invalidate("/1") != 1 {
+		t.Error("expected /1 to be cached")
+	}
+
+	c = New(Options{TTL: time.Nanosecond})
+	m = alien.New()
+	m.Get("/", func(w http.ResponseWriter, _ *http.Request) {}, c.Middleware)
+	req, _ := http.NewRequest("GET", "/", nil)
+	m.ServeHTTP(httptest.NewRecorder(), req)
+	time.Sleep(time.Millisecond)
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, req)
+	if w.Header().Get("X-Cache") != "MISS" {
+		t.Error("expected expired entry to be missed")
+	}
+}
+
+func TestCache_hijack(t *testing.T) {
+	c := New(Options{})
+	m := alien.New()
+	m.Get("/ws", func(w http.ResponseWriter, r *http.Request) {
+		if _, _, err := http.NewResponseController(w).Hijack(); err != nil {
+			t.Error(err)
+		}
+	}, c.Middleware)
+	req, _ := http.NewRequest("GET", "/ws", nil)
+	m.ServeHTTP(alientest.NewRecorder(), req)
+	if c.Len() != 0 {
+		t.Errorf("expected hijacked responses not to be cached got %d entries", c.Len())
+	}
+}
+
+func TestCache_trailers(t *testing.T) {
+	c := New(Options{})
+	m := alien.New()
+	m.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Trailer", "X-Checksum")
+		w.Write([]byte("hello"))
+		w.Header().Set("X-Checksum", "abc")
+	}, c.Middleware)
+	req, _ := http.NewRequest("GET", "/", nil)
+	m.ServeHTTP(httptest.NewRecorder(), req)
+	if c.Len() != 0 {
+		t.Errorf("expected responses with trailers not to be cached got %d entries", c.Len())
+	}
+}
+
+func TestCache_writer(t *testing.T) {
+	c := New(Options{})
+	m := alien.New()
+	m.Use(c.Middleware)
+	m.Get("/flush", func(w http.ResponseWriter, r *http.Request) {
+		if err := w.(http.Pusher).Push("/app.js", nil); err != nil {
+			t.Error(err)
+		}
+		w.Write([]byte("hello"))
+		w.(http.Flusher).Flush()
+	})
+	m.Get("/hijack", func(w http.ResponseWriter, r *http.Request) {
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		conn.Close()
+	})
+
+	req, _ := http.NewRequest("GET", "/flush", nil)
+	w := alientest.NewRecorder()
+	m.ServeHTTP(w, req)
+	if !w.Flushed {
+		t.Error("expected the response to be flushed")
+	}
+	if len(w.Pushed) != 1 || w.Pushed[0] != "/app.js" {
+		t.Errorf("expected /app.js to be pushed got %v", w.Pushed)
+	}
+
+	req, _ = http.NewRequest("GET", "/hijack", nil)
+	w = alientest.NewRecorder()
+	m.ServeHTTP(w, req)
+	if !w.Hijacked {
+		t.Error("expected the connection to be hijacked")
+	}
+	if c.Len() != 1 {
+		t.Errorf("expected only the flushed response to be cached got %d entries", c.Len())
+	}
+}
@@ -0,0 +1,75 @@
+package alien
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMux_MatchCache(t *testing.T) {
+	m := New(WithMatchCache(2))
+	write := func(s string) func(http.ResponseWriter, *http.Request) {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(s + GetParams(r).Get("id")))
+		}
+	}
+	m.Get("/users/:id", write("show"))
+	api := m.Host("api.example.com")
+	api.Get("/users/:id", write("api"))
+	get := func(host, path string) string {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Host = host
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, req)
+		return w.Body.String()
+	}
+	sample := []struct {
+		host, path, body string
+	}{
+		{"example.com", "/users/new", "shownew"},
+		{"example.com", "/users/new", "shownew"},
+		{"example.com", "/users/1", "show1"},
+		{"api.example.com", "/users/new", "apinew"},
+	}
+	for _, v := range sample {
+		if b := get(v.host, v.path); b != v.body {
+			t.Errorf("%s%s: expected %s got %s", v.host, v.path, v.body, b)
+		}
+	}
+	if n := m.cache.lru.Len(); n != 2 {
+		t.Errorf("expected 2 cached matches got %d", n)
+	}
+
+	// changing the routes drops the cached matches.
+	m.ReplaceRoute("GET", "/users/:id", write("edit"))
+	if b := get("example.com", "/users/new"); b != "editnew" {
+		t.Errorf("expected the replaced route got %s", b)
+	}
+
+	m.MatchCache(0)
+	if m.cache != nil || api.cache != nil {
+		t.Error("expected the cache to be removed")
+	}
+}
+
+func TestMatchCache_evict(t *testing.T) {
+	c := newMatchCache(2)
+	tr := &trees{}
+	a, b, d := &route{path: "/a"}, &route{path: "/b"}, &route{path: "/d"}
+	c.put(tr, "GET", "/a", a, "/a")
+	c.put(tr, "GET", "/b", b, "/b")
+	c.get(tr, "GET", "/a")
+	c.put(tr, "GET", "/d", d, "/d")
+	if _, _, ok := c.get(tr, "GET", "/b"); ok {
+		t.Error("expected the least recently used match to be evicted")
+	}
+	if rt, _, ok := c.get(tr, "GET", "/a"); !ok || rt != a {
+		t.Error("expected /a to be kept")
+	}
+	if _, _, ok := c.get(&trees{}, "GET", "/d"); ok {
+		t.Error("expected a miss for other trees")
+	}
+	if _, _, ok := c.get(tr, "GET", "/d"); ok {
+		t.Error("expected stale matches to be dropped")
+	}
+}
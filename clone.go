@@ -0,0 +1,189 @@
+package alien
+
+import "net/http"
+
+// Clone returns a copy of m with its settings, middlewares, groups, hosts and
+// routes, which can be changed without affecting m. It is meant to build
+// variants of a base router, for instance one per test or per tenant.
+//   base := alien.New()
+//   base.Get("/users/:id", getUser)
+//   admin := base.Clone()
+//   admin.Get("/admin", dashboard) // not served by base
+//
+// The clone is never frozen, even if m is. It shares the counters of
+// EnableStats and the recorder of EnableRecorder with m, and the handlers of
+// their endpoints report those of m.
+func (m *Mux) Clone() *Mux {
+	c := &cloner{
+		muxes:   make(map[*Mux]*Mux),
+		routers: make(map[*router]*router),
+		routes:  make(map[*route]*route),
+	}
+	return c.mux(m.top())
+}
+
+// cloner copies a Mux with its groups and hosts, so that the routes of the
+// copy refer to the copied groups.
+type cloner struct {
+	muxes   map[*Mux]*Mux
+	routers map[*router]*router
+	routes  map[*route]*route
+}
+
+// mux returns the copy of m.
+func (c *cloner) mux(m *Mux) *Mux {
+	if m == nil {
+		return nil
+	}
+	if v, ok := c.muxes[m]; ok {
+		return v
+	}
+	v := &Mux{}
+	c.muxes[m] = v
+	*v = *m
+	v.parent = c.mux(m.parent)
+	v.hosts = nil
+	for _, h := range m.hosts {
+		v.hosts = append(v.hosts, c.mux(h))
+	}
+	v.middleware = m.middleware[:len(m.middleware):len(m.middleware)]
+	v.encoders = m.encoders[:len(m.encoders):len(m.encoders)]
+	v.hooks = hooks{
+		start:    m.hooks.start[:len(m.hooks.start):len(m.hooks.start)],
+		shutdown: m.hooks.shutdown[:len(m.hooks.shutdown):len(m.hooks.shutdown)],
+		request:  m.hooks.request[:len(m.hooks.request):len(m.hooks.request)],
+		response: m.hooks.response[:len(m.hooks.response):len(m.hooks.response)],
+	}
+	if m.meta != nil {
+		v.meta = make(map[string]interface{}, len(m.meta))
+		for k, val := range m.meta {
+			v.meta[k] = val
+		}
+	}
+	v.notFound = c.handler(m.notFound)
+	v.methodNotAllowed = c.handler(m.methodNotAllowed)
+	v.router = c.router(m.router)
+	return v
+}
+
+// handler returns h bound to the copy of its Mux if it is a default handler.
+func (c *cloner) handler(h http.Handler) http.Handler {
+	if s, ok := h.(statusHandler); ok {
+		s.m = c.mux(s.m)
+		return s
+	}
+	return h
+}
+
+// router returns the copy of r, with copies of its trees.
+func (c *cloner) router(r *router) *router {
+	if v, ok := c.routers[r]; ok {
+		return v
+	}
+	v := &router{}
+	c.routers[r] = v
+	if r.cache != nil {
+		v.cache = newMatchCache(r.cache.size)
+	}
+	r.mu.Lock()
+	if r.names != nil {
+		v.names = make(map[string]string, len(r.names))
+		for k, p := range r.names {
+			v.names[k] = p
+		}
+	}
+	r.mu.Unlock()
+	t := *r.load()
+	for _, p := range []**node{
+		&t.get, &t.post, &t.patch, &t.put, &t.head,
+		&t.connect, &t.options, &t.trace, &t.delete, &t.rewrites,
+	} {
+		*p = c.node(*p)
+	}
+	t.custom = append([]methodTree(nil), t.custom...)
+	for i := range t.custom {
+		t.custom[i].root = c.node(t.custom[i].root)
+	}
+	fallbacks := t.fallbacks
+	t.fallbacks = nil
+	for _, f := range fallbacks {
+		t.fallbacks = append(t.fallbacks, c.route(f))
+	}
+	groups := t.groups
+	t.groups = nil
+	for _, g := range groups {
+		t.groups = append(t.groups, c.mux(g))
+	}
+	t.static = &staticIndex{}
+	v.trees.Store(&t)
+	return v
+}
+
+// node returns a copy of the tree n.
+func (c *cloner) node(n *node) *node {
+	if n == nil {
+		return nil
+	}
+	v := &node{
+		path:     n.path,
+		typ:      n.typ,
+		param:    c.node(n.param),
+		catchAll: c.node(n.catchAll),
+	}
+	for _, e := range n.ends {
+		v.ends = append(v.ends, c.route(e))
+	}
+	for _, ch := range n.children {
+		v.addChild(c.node(ch))
+	}
+	return v
+}
+
+// route returns a copy of r registered by the copy of its Mux.
+func (c *cloner) route(r *route) *route {
+	if v, ok := c.routes[r]; ok {
+		return v
+	}
+	v := &route{
+		mux:         c.mux(r.mux),
+		path:        r.path,
+		name:        r.name,
+		constraints: r.constraints,
+		version:     r.version,
+		rewrite:     r.rewrite,
+		middleware:  r.middleware,
+		handler:     r.handler,
+	}
+	c.routes[r] = v
+	return v
+}
+
+// Freeze makes the registration of routes and hosts on m, its groups and hosts
+// panic, and wraps the handlers of the registered routes with their
+// middlewares, so the first requests don't pay for it. Nothing else is
+// frozen, settings like the not found handler can still be changed.
+//   m.Freeze()
+//   m.Get("/late", h) // panics
+//
+// Use Clone to get a copy of a frozen Mux which can be changed.
+func (m *Mux) Freeze() {
+	m = m.top()
+	for _, r := range m.routers() {
+		r.mu.Lock()
+		r.frozen.Store(true)
+		r.mu.Unlock()
+		for _, f := range r.load().fallbacks {
+			f.compile()
+		}
+	}
+	m.walkRoutes(func(_ string, rt *route) {
+		rt.compile()
+	})
+}
+
+// checkFrozen panics if r is frozen.
+func (r *router) checkFrozen() {
+	if r.frozen.Load() {
+		panic("alien: registration on a frozen Mux")
+	}
+}
@@ -0,0 +1,109 @@
+package alien
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMux_Clone(t *testing.T) {
+	base := New()
+	base.Use(func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Base", "1")
+			h.ServeHTTP(w, r)
+		})
+	})
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.Path))
+	}
+	base.GetNamed("user", "/users/:id", ok)
+	api := base.Group("/api")
+	api.NotFoundHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	api.Get("/status", ok)
+	base.Host("api.example.com").Get("/", ok)
+
+	c := base.Clone()
+	c.Get("/admin", ok)
+	c.Host("admin.example.com").Get("/", ok)
+	c.ProblemDetails(true)
+
+	sample := []struct {
+		mux                *Mux
+		host, path, accept string
+		code               int
+		base               string
+	}{
+		{base, "example.com", "/users/1", "", http.StatusOK, "1"},
+		{c, "example.com", "/users/1", "", http.StatusOK, "1"},
+		{c, "example.com", "/api/status", "", http.StatusOK, "1"},
+		{c, "example.com", "/api/missing", "", http.StatusTeapot, ""},
+		{c, "api.example.com", "/", "", http.StatusOK, "1"},
+		{c, "admin.example.com", "/", "", http.StatusOK, "1"},
+		{c, "example.com", "/admin", "", http.StatusOK, "1"},
+		{base, "example.com", "/admin", "", http.StatusNotFound, ""},
+		{base, "admin.example.com", "/", "", http.StatusNotFound, ""},
+		{c, "example.com", "/missing", "application/json", http.StatusNotFound, ""},
+		{base, "example.com", "/missing", "application/json", http.StatusNotFound, ""},
+	}
+	for _, v := range sample {
+		req := httptest.NewRequest("GET", v.path, nil)
+		req.Host = v.host
+		req.Header.Set("Accept", v.accept)
+		w := httptest.NewRecorder()
+		v.mux.ServeHTTP(w, req)
+		if w.Code != v.code || w.Header().Get("X-Base") != v.base {
+			t.Errorf("%s%s: expected %d %q got %d %q", v.host, v.path, v.code, v.base, w.Code, w.Header().Get("X-Base"))
+		}
+		if v.accept != "" {
+			problem := strings.HasPrefix(w.Header().Get("Content-Type"), "application/problem+json")
+			if problem != (v.mux == c) {
+				t.Errorf("%s: expected problem details only from the clone got %s", v.path, w.Header().Get("Content-Type"))
+			}
+		}
+	}
+	if u, err := c.URL("user", "id", "1"); err != nil || u != "/users/1" {
+		t.Errorf("expected the named route of base got %s %v", u, err)
+	}
+}
+
+func TestMux_Freeze(t *testing.T) {
+	m := New()
+	m.Get("/users/:id", func(w http.ResponseWriter, r *http.Request) {})
+	api := m.Host("api.example.com")
+	m.Freeze()
+
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest("GET", "/users/1", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200 got %d", w.Code)
+	}
+	sample := []struct {
+		name string
+		fn   func()
+	}{
+		{"route", func() { m.Get("/late", func(w http.ResponseWriter, r *http.Request) {}) }},
+		{"group route", func() { m.Group("/v1").Post("/late", func(w http.ResponseWriter, r *http.Request) {}) }},
+		{"host route", func() { api.Get("/late", func(w http.ResponseWriter, r *http.Request) {}) }},
+		{"host", func() { m.Host("late.example.com") }},
+		{"static", func() { m.Static("/assets", ".") }},
+	}
+	for _, v := range sample {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: expected a panic on a frozen mux", v.name)
+				}
+			}()
+			v.fn()
+		}()
+	}
+
+	c := m.Clone()
+	if err := c.Get("/late", func(w http.ResponseWriter, r *http.Request) {}); err != nil {
+		t.Errorf("expected the clone to accept routes got %v", err)
+	}
+}
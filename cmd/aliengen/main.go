@@ -0,0 +1,393 @@
+// Command aliengen generates a router for the routes of a manifest of the
+// config package. The router matches requests with switch statements compiled
+// from the patterns instead of walking a route tree, for embedded and latency
+// critical services whose routes are known at build time.
+//
+//   aliengen -in routes.json -out routes_gen.go -pkg api
+//
+// or from a go:generate directive
+//   //go:generate aliengen -in routes.json -out routes_gen.go -pkg api
+//
+// The generated file declares the Handlers and Middleware structs, with a
+// field for every handler and middleware named in the manifest, and NewRouter
+// which returns the router serving them
+//   rt := api.NewRouter(api.Handlers{ListUsers: listUsers}, api.Middleware{Auth: auth})
+//   http.ListenAndServe(":8090", rt)
+//
+// Handlers read params with alien.GetParams like with a Mux. The manifest is
+// registered with a Mux first, so patterns which conflict are reported like at
+// runtime. Patterns with regular expression constraints are not supported.
+//
+// Requests are matched on their exact path, without the cleaning, redirects
+// and fallbacks of a Mux. HEAD requests are served by the GET route of a
+// pattern without a HEAD route, misses get 404 Not Found and requests for
+// patterns registered with other methods 405 Method Not Allowed.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"go/format"
+	"go/token"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/gernest/alien"
+	"github.com/gernest/alien/config"
+)
+
+func main() {
+	in := flag.String("in", "routes.json", "manifest to generate the router from")
+	out := flag.String("out", "routes_gen.go", "file to write the router to")
+	pkg := flag.String("pkg", "main", "package of the generated file")
+	flag.Parse()
+	b, err := os.ReadFile(*in)
+	if err != nil {
+		fail(err)
+	}
+	mf, err := (&config.Loader{}).Parse(b)
+	if err != nil {
+		fail(err)
+	}
+	src, err := generate(mf, *pkg, filepath.Base(*in))
+	if err != nil {
+		fail(err)
+	}
+	if err := os.WriteFile(*out, src, 0644); err != nil {
+		fail(err)
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "aliengen:", err)
+	os.Exit(1)
+}
+
+// pattern is a pattern of the manifest with the routes registered for it.
+type pattern struct {
+	Path     string
+	Static   bool
+	Segments []segment
+	Rest     bool // the pattern ends with a catch all
+	Routes   []route
+	Allow    string
+}
+
+// segment is a segment of a pattern, either Static or a Param.
+type segment struct {
+	Static string
+	Param  string
+	Rest   bool
+}
+
+// route is a route of the manifest, Index is its handler in the router.
+type route struct {
+	Methods    []string
+	Index      int
+	Handler    string
+	Middleware []string
+}
+
+// source is the data of the generated file.
+type source struct {
+	Package     string
+	Manifest    string
+	Handlers    []string
+	Middleware  []string
+	Routes      []route
+	Static      []pattern
+	Params      []pattern
+	MaxSegments int
+}
+
+// generate returns the source of the router serving the routes of mf, in
+// package pkg. name is the manifest file name, for comments.
+func generate(mf *config.Manifest, pkg, name string) ([]byte, error) {
+	if err := validate(mf); err != nil {
+		return nil, err
+	}
+	s := source{Package: pkg, Manifest: name}
+	handlers := make(map[string]bool)
+	middleware := make(map[string]bool)
+	patterns := make(map[string]*pattern)
+	var order []string
+	for _, v := range mf.Routes {
+		if v.Disabled {
+			continue
+		}
+		if strings.ContainsRune(v.Path, '(') {
+			return nil, errors.New("constraints are not supported: " + v.Path)
+		}
+		rt := route{
+			Methods: []string{strings.ToUpper(v.Method)},
+			Index:   len(s.Routes),
+			Handler: field(v.Handler),
+		}
+		if rt.Handler == "" {
+			return nil, errors.New("handler name is not an identifier: " + v.Handler)
+		}
+		if !handlers[rt.Handler] {
+			handlers[rt.Handler] = true
+			s.Handlers = append(s.Handlers, rt.Handler)
+		}
+		for _, mw := range v.Middleware {
+			f := field(mw)
+			if f == "" {
+				return nil, errors.New("middleware name is not an identifier: " + mw)
+			}
+			if !middleware[f] {
+				middleware[f] = true
+				s.Middleware = append(s.Middleware, f)
+			}
+			rt.Middleware = append(rt.Middleware, f)
+		}
+		s.Routes = append(s.Routes, rt)
+		p, ok := patterns[v.Path]
+		if !ok {
+			p = newPattern(v.Path)
+			patterns[v.Path] = p
+			order = append(order, v.Path)
+		}
+		p.Routes = append(p.Routes, rt)
+	}
+	for _, k := range order {
+		p := patterns[k]
+		var allow []string
+		get, head := -1, false
+		for i, rt := range p.Routes {
+			allow = append(allow, rt.Methods[0])
+			switch rt.Methods[0] {
+			case http.MethodGet:
+				get = i
+			case http.MethodHead:
+				head = true
+			}
+		}
+		if get != -1 && !head {
+			p.Routes[get].Methods = append(p.Routes[get].Methods, http.MethodHead)
+		}
+		p.Allow = strings.Join(allow, ", ")
+		if p.Static {
+			s.Static = append(s.Static, *p)
+			continue
+		}
+		s.Params = append(s.Params, *p)
+		if len(p.Segments) > s.MaxSegments {
+			s.MaxSegments = len(p.Segments)
+		}
+	}
+	// patterns with more static segments are more specific.
+	sort.SliceStable(s.Params, func(i, j int) bool {
+		return staticCount(s.Params[i]) > staticCount(s.Params[j])
+	})
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, s); err != nil {
+		return nil, err
+	}
+	return format.Source(b.Bytes())
+}
+
+// validate registers the routes of mf with a Mux, to report conflicts.
+func validate(mf *config.Manifest) error {
+	l := &config.Loader{
+		Handlers:   make(map[string]func(http.ResponseWriter, *http.Request)),
+		Middleware: make(map[string]func(http.Handler) http.Handler),
+	}
+	for _, v := range mf.Routes {
+		l.Handlers[v.Handler] = func(http.ResponseWriter, *http.Request) {}
+		for _, mw := range v.Middleware {
+			l.Middleware[mw] = func(h http.Handler) http.Handler { return h }
+		}
+	}
+	return l.Apply(alien.New(), mf)
+}
+
+// newPattern returns the pattern for path.
+func newPattern(path string) *pattern {
+	p := &pattern{Path: path, Static: !strings.ContainsAny(path, ":*")}
+	if p.Static {
+		return p
+	}
+	for _, v := range strings.Split(path[1:], "/") {
+		switch {
+		case strings.HasPrefix(v, ":"):
+			p.Segments = append(p.Segments, segment{Param: v[1:]})
+		case strings.HasPrefix(v, "*"):
+			name := v[1:]
+			if name == "" {
+				name = "catch"
+			}
+			p.Segments = append(p.Segments, segment{Param: name, Rest: true})
+			p.Rest = true
+		default:
+			p.Segments = append(p.Segments, segment{Static: v})
+		}
+	}
+	return p
+}
+
+// staticCount returns the number of static segments of p.
+func staticCount(p pattern) int {
+	n := 0
+	for _, v := range p.Segments {
+		if v.Param == "" {
+			n++
+		}
+	}
+	return n
+}
+
+// field returns the exported field name for the handler or middleware name,
+// or an empty string if name is not an identifier.
+func field(name string) string {
+	if name == "" || !token.IsIdentifier(name) {
+		return ""
+	}
+	r := []rune(name)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
+
+var tmpl = template.Must(template.New("router").Funcs(template.FuncMap{
+	"quote": strconv.Quote,
+}).Parse(`// Code generated by aliengen from {{.Manifest}}. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"net/http"
+{{- if .Params}}
+	"strings"
+
+	"github.com/gernest/alien"
+{{- end}}
+)
+
+// Handlers are the handlers of the routes of {{.Manifest}}.
+type Handlers struct {
+{{- range .Handlers}}
+	{{.}} func(http.ResponseWriter, *http.Request)
+{{- end}}
+}
+
+// Middleware are the middlewares of the routes of {{.Manifest}}.
+type Middleware struct {
+{{- range .Middleware}}
+	{{.}} func(http.Handler) http.Handler
+{{- end}}
+}
+
+// Router serves the routes of {{.Manifest}}.
+type Router struct {
+	routes [{{len .Routes}}]http.Handler
+
+	// NotFound and MethodNotAllowed serve the requests which don't match a
+	// route, the Allow header is set for MethodNotAllowed.
+	NotFound         http.Handler
+	MethodNotAllowed http.Handler
+}
+
+// NewRouter returns a Router serving the routes of {{.Manifest}} with h and mw.
+// It panics if one of them is nil.
+func NewRouter(h Handlers, mw Middleware) *Router {
+	rt := &Router{
+		NotFound: http.NotFoundHandler(),
+		MethodNotAllowed: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}),
+	}
+{{- range .Routes}}
+	rt.routes[{{.Index}}] = chain({{quote .Handler}}, h.{{.Handler}}{{range .Middleware}}, mw.{{.}}{{end}})
+{{- end}}
+	return rt
+}
+
+// chain wraps h with wares, the first one being the outermost.
+func chain(name string, h func(http.ResponseWriter, *http.Request), wares ...func(http.Handler) http.Handler) http.Handler {
+	if h == nil {
+		panic("{{.Package}}: no handler " + name)
+	}
+	var handler http.Handler = http.HandlerFunc(h)
+	for i := len(wares) - 1; i >= 0; i-- {
+		if wares[i] == nil {
+			panic("{{.Package}}: nil middleware for handler " + name)
+		}
+		handler = wares[i](handler)
+	}
+	return handler
+}
+
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p := r.URL.Path
+{{- if .Static}}
+	switch p {
+{{- range .Static}}
+	case {{quote .Path}}:
+		switch r.Method {
+{{- range .Routes}}
+		case {{range $i, $m := .Methods}}{{if $i}}, {{end}}{{quote $m}}{{end}}:
+			rt.routes[{{.Index}}].ServeHTTP(w, r)
+{{- end}}
+		default:
+			rt.notAllowed(w, r, {{quote .Allow}})
+		}
+		return
+{{- end}}
+	}
+{{- end}}
+{{- range .Params}}
+	// {{.Path}}
+	if s, ok := split(p, {{len .Segments}}, {{.Rest}}); ok{{range $i, $s := .Segments}}{{if $s.Param}}{{if not $s.Rest}} && s[{{$i}}] != ""{{end}}{{else}} && s[{{$i}}] == {{quote $s.Static}}{{end}}{{end}} {
+		params := alien.Params{ {{- range $i, $s := .Segments}}{{if $s.Param}}{Key: {{quote $s.Param}}, Value: s[{{$i}}]}, {{end}}{{end -}} }
+		r = r.WithContext(alien.WithParams(r.Context(), params))
+		switch r.Method {
+{{- range .Routes}}
+		case {{range $i, $m := .Methods}}{{if $i}}, {{end}}{{quote $m}}{{end}}:
+			rt.routes[{{.Index}}].ServeHTTP(w, r)
+{{- end}}
+		default:
+			rt.notAllowed(w, r, {{quote .Allow}})
+		}
+		return
+	}
+{{- end}}
+	rt.NotFound.ServeHTTP(w, r)
+}
+
+// notAllowed serves r with MethodNotAllowed.
+func (rt *Router) notAllowed(w http.ResponseWriter, r *http.Request, allow string) {
+	w.Header().Set("Allow", allow)
+	rt.MethodNotAllowed.ServeHTTP(w, r)
+}
+{{- if .Params}}
+
+// split returns the n segments of p, the last one holding the rest of p if
+// rest is true.
+func split(p string, n int, rest bool) (s [{{.MaxSegments}}]string, ok bool) {
+	if p == "" || p[0] != '/' {
+		return s, false
+	}
+	p = p[1:]
+	for i := 0; i < n-1; i++ {
+		j := strings.IndexByte(p, '/')
+		if j == -1 {
+			return s, false
+		}
+		s[i], p = p[:j], p[j+1:]
+	}
+	if !rest && strings.IndexByte(p, '/') != -1 {
+		return s, false
+	}
+	s[n-1] = p
+	return s, true
+}
+{{- end}}
+`))
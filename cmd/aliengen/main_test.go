@@ -0,0 +1,84 @@
+package main
+
+import (
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/gernest/alien/config"
+)
+
+func TestGenerate(t *testing.T) {
+	mf := &config.Manifest{Routes: []config.Route{
+		{Method: "GET", Path: "/users", Handler: "listUsers"},
+		{Method: "post", Path: "/users", Handler: "createUser", Middleware: []string{"auth", "audit"}},
+		{Method: "GET", Path: "/users/:id", Handler: "showUser"},
+		{Method: "GET", Path: "/users/:id/posts/:post", Handler: "showPost"},
+		{Method: "GET", Path: "/files/*", Handler: "files"},
+		{Method: "GET", Path: "/old", Handler: "old", Disabled: true},
+	}}
+	src, err := generate(mf, "api", "routes.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "routes_gen.go", src, 0); err != nil {
+		t.Fatalf("generated invalid go: %v\n%s", err, src)
+	}
+	code := string(src)
+	for _, v := range []string{
+		"// Code generated by aliengen from routes.json. DO NOT EDIT.",
+		"package api",
+		"ListUsers  func(http.ResponseWriter, *http.Request)",
+		"Audit func(http.Handler) http.Handler",
+		`rt.routes[1] = chain("CreateUser", h.CreateUser, mw.Auth, mw.Audit)`,
+		`case "/users":`,
+		`case "GET", "HEAD":`,
+		`rt.notAllowed(w, r, "GET, POST")`,
+		`split(p, 4, false); ok && s[0] == "users" && s[1] != "" && s[2] == "posts" && s[3] != ""`,
+		`alien.Params{{Key: "id", Value: s[1]}, {Key: "post", Value: s[3]}}`,
+		`split(p, 2, true); ok && s[0] == "files"`,
+		`{Key: "catch", Value: s[1]}`,
+		"s [4]string",
+	} {
+		if !strings.Contains(code, v) {
+			t.Errorf("expected the generated code to contain %s", v)
+		}
+	}
+	if strings.Contains(code, "Old") {
+		t.Error("expected disabled routes to be left out")
+	}
+	// the most specific pattern is matched first.
+	if strings.Index(code, "// /users/:id/posts/:post") > strings.Index(code, "// /users/:id\n") {
+		t.Error("expected /users/:id/posts/:post to be matched before /users/:id")
+	}
+
+	src, err = generate(&config.Manifest{Routes: []config.Route{{Method: "GET", Path: "/", Handler: "home"}}}, "api", "routes.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(src), "strings") {
+		t.Error("expected no strings import without param routes")
+	}
+}
+
+func TestGenerate_errors(t *testing.T) {
+	sample := []struct {
+		routes []config.Route
+		err    string
+	}{
+		{[]config.Route{{Method: "GET", Path: "/users/:id([0-9]+)", Handler: "show"}}, "constraints are not supported"},
+		{[]config.Route{{Method: "GET", Path: "/", Handler: "show-user"}}, "handler name is not an identifier"},
+		{[]config.Route{{Method: "GET", Path: "/", Handler: "show", Middleware: []string{"a.b"}}}, "middleware name is not an identifier"},
+		{[]config.Route{
+			{Method: "GET", Path: "/users/:id", Handler: "show"},
+			{Method: "GET", Path: "/users/new", Handler: "new"},
+		}, "conflicts"},
+	}
+	for _, v := range sample {
+		_, err := generate(&config.Manifest{Routes: v.routes}, "api", "routes.json")
+		if err == nil || !strings.Contains(err.Error(), v.err) {
+			t.Errorf("expected an error containing %q got %v", v.err, err)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+// Package compress provides a gzip response compression middleware for alien.
+//
+//   m := alien.New()
+//   m.Use(compress.Gzip)
+//
+// Responses are compressed only when the client accepts gzip, the handler did
+// not set a Content-Encoding and the content type is not already compressed,
+// like images, videos or archives.
+package compress
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gernest/alien"
+)
+
+// compressed are content type prefixes of responses which are already
+// compressed.
+var compressed = []string{
+	"image/", "video/", "audio/", "font/woff",
+	"application/zip", "application/gzip", "application/x-gzip",
+	"application/x-bzip2", "application/x-7z-compressed",
+	"application/x-rar-compressed", "application/x-xz",
+	"application/zstd", "application/octet-stream",
+}
+
+// Gzip compresses responses with the default compression level.
+func Gzip(h http.Handler) http.Handler {
+	return GzipLevel(gzip.DefaultCompression)(h)
+}
+
+// GzipLevel returns a middleware compressing responses with level, which is any
+// of the compression levels defined in compress/gzip. It panics if level is
+// invalid.
+func GzipLevel(level int) func(http.Handler) http.Handler {
+	if _, err := gzip.NewWriterLevel(io.Discard, level); err != nil {
+		panic(err)
+	}
+	pool := &sync.Pool{
+		New: func() interface{} {
+			gz, _ := gzip.NewWriterLevel(io.Discard, level)
+			return gz
+		},
+	}
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+			if !acceptsGzip(r.Header.Get("Accept-Encoding")) {
+				h.ServeHTTP(w, r)
+				return
+			}
+			gw := &gzipWriter{ResponseWriter: alien.WrapWriter(w), pool: pool}
+			defer gw.close()
+			h.ServeHTTP(gw, r)
+		})
+	}
+}
+
+// acceptsGzip returns true if the Accept-Encoding header value allows gzip.
+func acceptsGzip(accept string) bool {
+	ok := false
+	for _, v := range strings.Split(accept, ",") {
+		coding, q := v, 1.0
+		if i := strings.IndexByte(v, ';'); i != -1 {
+			coding = v[:i]
+			param := strings.TrimSpace(v[i+1:])
+			if strings.HasPrefix(param, "q=") {
+				if f, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = f
+				}
+			}
+		}
+		switch strings.ToLower(strings.TrimSpace(coding)) {
+		case "gzip":
+			return q > 0
+		case "*":
+			ok = q > 0
+		}
+	}
+	return ok
+}
+
+// gzipWriter compresses the response, pushes and deadlines are passed to the
+// writer it wraps.
+type gzipWriter struct {
+	alien.ResponseWriter
+	pool     *sync.Pool
+	gz       *gzip.Writer
+	code     int
+	decided  bool
+	hijacked bool
+}
+
+func (w *gzipWriter) WriteHeader(code int) {
+	if w.decided || w.code != 0 {
+		return
+	}
+	if code < 200 {
+		// informational responses are passed as is.
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	w.code = code
+}
+
+func (w *gzipWriter) Write(b []byte) (int, error) {
+	if !w.decided {
+		if w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", http.DetectContentType(b))
+		}
+		w.decide()
+	}
+	if w.gz != nil {
+		return w.gz.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// decide decides whether to compress the response and writes the header.
+func (w *gzipWriter) decide() {
+	w.decided = true
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	header := w.Header()
+	if w.shouldCompress() {
+		header.Del("Content-Length")
+		header.Set("Content-Encoding", "gzip")
+		w.gz = w.pool.Get().(*gzip.Writer)
+		w.gz.Reset(w.ResponseWriter)
+	}
+	w.ResponseWriter.WriteHeader(w.code)
+}
+
+func (w *gzipWriter) shouldCompress() bool {
+	switch w.code {
+	case http.StatusNoContent, http.StatusNotModified:
+		return false
+	}
+	header := w.Header()
+	if header.Get("Content-Encoding") != "" {
+		return false
+	}
+	ct := strings.ToLower(header.Get("Content-Type"))
+	for _, v := range compressed {
+		if strings.HasPrefix(ct, v) {
+			return false
+		}
+	}
+	return true
+}
+
+func (w *gzipWriter) Flush() {
+	if !w.decided {
+		w.decide()
+	}
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// ReadFrom copies r through Write, so that the body is compressed.
+func (w *gzipWriter) ReadFrom(r io.Reader) (int64, error) {
+	return io.Copy(struct{ io.Writer }{w}, r)
+}
+
+func (w *gzipWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if w.decided {
+		return nil, nil, errors.New("compress: hijacking not supported")
+	}
+	conn, rw, err := w.ResponseWriter.Hijack()
+	if err == nil {
+		w.hijacked = true
+	}
+	return conn, rw, err
+}
+
+// Status returns the status code of the response, which is held until the
+// first write.
+func (w *gzipWriter) Status() int {
+	if w.code != 0 {
+		return w.code
+	}
+	return w.ResponseWriter.Status()
+}
+
+func (w *gzipWriter) close() {
+	if w.hijacked {
+		return
+	}
+	if !w.decided {
+		if w.code == 0 {
+			// nothing was written, let net/http write the default response.
+			return
+		}
+		w.decided = true
+		w.ResponseWriter.WriteHeader(w.code)
+		return
+	}
+	if w.gz != nil {
+		w.gz.Close()
+		w.gz.Reset(io.Discard)
+		w.pool.Put(w.gz)
+		w.gz = nil
+	}
+}
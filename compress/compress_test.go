@@ -0,0 +1,155 @@
+package compress
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gernest/alien"
+	"github.com/gernest/alien/alientest"
+)
+
+func TestAcceptsGzip(t *testing.T) {
+	sample := []struct {
+		accept string
+		ok     bool
+	}{
+		{"gzip", true},
+		{"deflate, gzip;q=0.8", true},
+		{"gzip;q=0", false},
+		{"*", true},
+		{"*;q=0", false},
+		{"deflate, br", false},
+		{"", false},
+	}
+	for _, v := range sample {
+		if ok := acceptsGzip(v.accept); ok != v.ok {
+			t.Errorf("%s: expected %v got %v", v.accept, v.ok, ok)
+		}
+	}
+}
+
+func TestGzip(t *testing.T) {
+	body := strings.Repeat("hello alien ", 100)
+	m := alien.New()
+	m.Use(Gzip)
+	m.Get("/text", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Length", "1200")
+		io.WriteString(w, body)
+	})
+	m.Get("/image", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		io.WriteString(w, body)
+	})
+	m.Get("/empty", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	m.Get("/stream", func(w http.ResponseWriter, _ *http.Request) {
+		io.WriteString(w, body)
+		w.(http.Flusher).Flush()
+		io.WriteString(w, body)
+	})
+
+	for _, p := range []string{"/text", "/stream"} {
+		req, _ := http.NewRequest("GET", p, nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, req)
+		if e := w.Header().Get("Content-Encoding"); e != "gzip" {
+			t.Fatalf("%s: expected gzip got %s", p, e)
+		}
+		if v := w.Header().Get("Vary"); v != "Accept-Encoding" {
+			t.Errorf("%s: expected Accept-Encoding got %s", p, v)
+		}
+		if l := w.Header().Get("Content-Length"); l != "" {
+			t.Errorf("%s: expected no Content-Length got %s", p, l)
+		}
+		gz, err := gzip.NewReader(w.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := io.ReadAll(gz)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expect := body
+		if p == "/stream" {
+			expect += body
+			if !w.Flushed {
+				t.Error("expected the response to be flushed")
+			}
+		}
+		if string(b) != expect {
+			t.Errorf("%s: unexpected body %s", p, b)
+		}
+	}
+
+	sample := []struct {
+		path, accept string
+		code         int
+	}{
+		{"/text", "", http.StatusOK},
+		{"/image", "gzip", http.StatusOK},
+		{"/empty", "gzip", http.StatusNoContent},
+	}
+	for _, v := range sample {
+		req, _ := http.NewRequest("GET", v.path, nil)
+		req.Header.Set("Accept-Encoding", v.accept)
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, req)
+		if w.Code != v.code {
+			t.Errorf("%s: expected %d got %d", v.path, v.code, w.Code)
+		}
+		if e := w.Header().Get("Content-Encoding"); e != "" {
+			t.Errorf("%s: expected no encoding got %s", v.path, e)
+		}
+		if v.code == http.StatusOK && w.Body.String() != body {
+			t.Errorf("%s: unexpected body", v.path)
+		}
+	}
+}
+
+func TestGzip_writer(t *testing.T) {
+	m := alien.New()
+	m.Use(Gzip)
+	m.Get("/flush", func(w http.ResponseWriter, r *http.Request) {
+		if err := w.(http.Pusher).Push("/app.js", nil); err != nil {
+			t.Error(err)
+		}
+		w.Write([]byte("hello"))
+		w.(http.Flusher).Flush()
+	})
+	m.Get("/hijack", func(w http.ResponseWriter, r *http.Request) {
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		conn.Close()
+	})
+
+	req, _ := http.NewRequest("GET", "/flush", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := alientest.NewRecorder()
+	m.ServeHTTP(w, req)
+	if !w.Flushed {
+		t.Error("expected the response to be flushed")
+	}
+	if len(w.Pushed) != 1 || w.Pushed[0] != "/app.js" {
+		t.Errorf("expected /app.js to be pushed got %v", w.Pushed)
+	}
+	if e := w.Header().Get("Content-Encoding"); e != "gzip" {
+		t.Errorf("expected gzip encoding got %q", e)
+	}
+
+	req, _ = http.NewRequest("GET", "/hijack", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w = alientest.NewRecorder()
+	m.ServeHTTP(w, req)
+	if !w.Hijacked {
+		t.Error("expected the connection to be hijacked")
+	}
+}
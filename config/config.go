@@ -0,0 +1,161 @@
+// Package config builds alien routes from a manifest, so deployments can
+// toggle endpoints without recompiling.
+//
+// The manifest lists routes with the names of their handler and middlewares
+//   {
+//     "routes": [
+//       {"method": "GET", "path": "/users", "handler": "listUsers"},
+//       {"method": "POST", "path": "/users", "handler": "createUser", "middleware": ["auth"]},
+//       {"method": "DELETE", "path": "/users/:id", "handler": "deleteUser", "disabled": true}
+//     ]
+//   }
+// and the names are resolved with the handlers and middlewares of a Loader
+//   l := &config.Loader{
+//   	Handlers: map[string]func(http.ResponseWriter, *http.Request){
+//   		"listUsers":  listUsers,
+//   		"createUser": createUser,
+//   		"deleteUser": deleteUser,
+//   	},
+//   	Middleware: map[string]func(http.Handler) http.Handler{"auth": auth},
+//   }
+//   err := l.LoadFile(m, "routes.json")
+//
+// Manifests are JSON by default, set Loader.Unmarshal to use another format
+// like YAML
+//   l.Unmarshal = yaml.Unmarshal
+//
+// Watch serves the routes of a manifest and reloads them when it changes,
+// without restarting the server
+//   w, err := l.Watch(m, "routes.json")
+//   go w.Run(ctx)
+//   http.ListenAndServe(":8090", w)
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gernest/alien"
+)
+
+// Manifest lists the routes to register.
+type Manifest struct {
+	Routes []Route `json:"routes" yaml:"routes"`
+}
+
+// Route describes a route of the manifest.
+type Route struct {
+	Method string `json:"method" yaml:"method"`
+	Path   string `json:"path" yaml:"path"`
+
+	// Handler and Middleware are the names of the handler and middlewares of
+	// the route in the Loader, middlewares are executed in order.
+	Handler    string   `json:"handler" yaml:"handler"`
+	Middleware []string `json:"middleware,omitempty" yaml:"middleware,omitempty"`
+
+	// Name registers the route as a named route, see alien.Mux.AddNamedRoute.
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+
+	// Meta is attached to the route with alien.Mux.Meta.
+	Meta map[string]interface{} `json:"meta,omitempty" yaml:"meta,omitempty"`
+
+	// Disabled routes are not registered.
+	Disabled bool `json:"disabled,omitempty" yaml:"disabled,omitempty"`
+}
+
+// Loader registers the routes of manifests.
+type Loader struct {
+	// Handlers and Middleware map the names used in manifests to their
+	// functions.
+	Handlers   map[string]func(http.ResponseWriter, *http.Request)
+	Middleware map[string]func(http.Handler) http.Handler
+
+	// Unmarshal decodes manifests, defaults to json.Unmarshal.
+	Unmarshal func(data []byte, v interface{}) error
+}
+
+// Parse decodes the manifest b.
+func (l *Loader) Parse(b []byte) (*Manifest, error) {
+	unmarshal := l.Unmarshal
+	if unmarshal == nil {
+		unmarshal = json.Unmarshal
+	}
+	var mf Manifest
+	if err := unmarshal(b, &mf); err != nil {
+		return nil, err
+	}
+	return &mf, nil
+}
+
+// Apply registers the enabled routes of mf with m. The names of all the
+// handlers and middlewares are checked before any route is registered, so an
+// unknown name leaves m untouched.
+func (l *Loader) Apply(m *alien.Mux, mf *Manifest) error {
+	for _, rt := range mf.Routes {
+		if rt.Disabled {
+			continue
+		}
+		if _, err := l.resolve(rt); err != nil {
+			return err
+		}
+	}
+	for _, rt := range mf.Routes {
+		if rt.Disabled {
+			continue
+		}
+		if err := l.register(m, rt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadFile reads the manifest in the file name and registers its routes with
+// m.
+func (l *Loader) LoadFile(m *alien.Mux, name string) error {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	mf, err := l.Parse(b)
+	if err != nil {
+		return err
+	}
+	return l.Apply(m, mf)
+}
+
+// resolve returns the middlewares of rt, or an error if one of its names is
+// unknown.
+func (l *Loader) resolve(rt Route) ([]func(http.Handler) http.Handler, error) {
+	if _, ok := l.Handlers[rt.Handler]; !ok {
+		return nil, errors.New("config: unknown handler " + rt.Handler + " for " + rt.Method + " " + rt.Path)
+	}
+	var wares []func(http.Handler) http.Handler
+	for _, name := range rt.Middleware {
+		mw, ok := l.Middleware[name]
+		if !ok {
+			return nil, errors.New("config: unknown middleware " + name + " for " + rt.Method + " " + rt.Path)
+		}
+		wares = append(wares, mw)
+	}
+	return wares, nil
+}
+
+// register adds rt to m.
+func (l *Loader) register(m *alien.Mux, rt Route) error {
+	wares, err := l.resolve(rt)
+	if err != nil {
+		return err
+	}
+	for k, v := range rt.Meta {
+		m = m.Meta(k, v)
+	}
+	method := strings.ToUpper(rt.Method)
+	if rt.Name != "" {
+		return m.AddNamedRoute(rt.Name, method, rt.Path, l.Handlers[rt.Handler], wares...)
+	}
+	return m.AddRoute(method, rt.Path, l.Handlers[rt.Handler], wares...)
+}
@@ -0,0 +1,130 @@
+package config
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gernest/alien"
+)
+
+const manifest = `{
+  "routes": [
+    {"method": "GET", "path": "/users", "handler": "list", "meta": {"doc": "List users"}},
+    {"method": "post", "path": "/users", "handler": "create", "middleware": ["auth", "audit"]},
+    {"method": "GET", "path": "/users/:id", "handler": "show", "name": "user_show"},
+    {"method": "DELETE", "path": "/users/:id", "handler": "delete", "disabled": true}
+  ]
+}`
+
+func testLoader() *Loader {
+	write := func(s string) func(http.ResponseWriter, *http.Request) {
+		return func(w http.ResponseWriter, r *http.Request) {
+			doc, _ := alien.RouteMeta(r, "doc").(string)
+			w.Write([]byte(strings.Join(w.Header()["X-Mark"], "") + s + doc))
+		}
+	}
+	mark := func(s string) func(http.Handler) http.Handler {
+		return func(h http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Add("X-Mark", s)
+				h.ServeHTTP(w, r)
+			})
+		}
+	}
+	return &Loader{
+		Handlers: map[string]func(http.ResponseWriter, *http.Request){
+			"list":   write("list"),
+			"create": write("create"),
+			"show":   write("show"),
+		},
+		Middleware: map[string]func(http.Handler) http.Handler{
+			"auth":  mark("auth,"),
+			"audit": mark("audit,"),
+		},
+	}
+}
+
+func TestLoader_LoadFile(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "routes.json")
+	if err := os.WriteFile(name, []byte(manifest), 0600); err != nil {
+		t.Fatal(err)
+	}
+	m := alien.New()
+	if err := testLoader().LoadFile(m, name); err != nil {
+		t.Fatal(err)
+	}
+	sample := []struct {
+		method, path, body string
+		status             int
+	}{
+		{"GET", "/users", "listList users", http.StatusOK},
+		{"POST", "/users", "auth,audit,create", http.StatusOK},
+		{"GET", "/users/42", "show", http.StatusOK},
+		{"DELETE", "/users/42", "", http.StatusMethodNotAllowed},
+	}
+	for _, v := range sample {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(v.method, v.path, nil)
+		m.ServeHTTP(w, req)
+		if w.Code != v.status {
+			t.Errorf("%s %s: expected %d got %d", v.method, v.path, v.status, w.Code)
+		}
+		if v.status == http.StatusOK && w.Body.String() != v.body {
+			t.Errorf("%s %s: expected %s got %s", v.method, v.path, v.body, w.Body.String())
+		}
+	}
+	if u, _ := m.URL("user_show", "id", "42"); u != "/users/42" {
+		t.Errorf("expected /users/42 got %s", u)
+	}
+}
+
+func TestLoader_Apply(t *testing.T) {
+	sample := []struct {
+		route Route
+		err   string
+	}{
+		{Route{Method: "GET", Path: "/", Handler: "missing"}, "unknown handler missing"},
+		{Route{Method: "GET", Path: "/", Handler: "list", Middleware: []string{"missing"}}, "unknown middleware missing"},
+		{Route{Method: "GET", Path: "/", Handler: "missing", Disabled: true}, ""},
+	}
+	for _, v := range sample {
+		m := alien.New()
+		mf := &Manifest{Routes: []Route{{Method: "GET", Path: "/users", Handler: "list"}, v.route}}
+		err := testLoader().Apply(m, mf)
+		if v.err == "" {
+			if err != nil {
+				t.Errorf("expected no error got %v", err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), v.err) {
+			t.Errorf("expected %s got %v", v.err, err)
+		}
+		if len(m.Routes()) != 0 {
+			t.Errorf("expected no routes to be registered got %v", m.Routes())
+		}
+	}
+}
+
+func TestLoader_Unmarshal(t *testing.T) {
+	l := testLoader()
+	l.Unmarshal = func(b []byte, v interface{}) error {
+		mf := v.(*Manifest)
+		for _, line := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+			f := strings.Fields(line)
+			mf.Routes = append(mf.Routes, Route{Method: f[0], Path: f[1], Handler: f[2]})
+		}
+		return nil
+	}
+	mf, err := l.Parse([]byte("GET /users list\nPOST /users create\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mf.Routes) != 2 || mf.Routes[1].Handler != "create" {
+		t.Errorf("expected 2 routes got %v", mf.Routes)
+	}
+}
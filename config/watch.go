@@ -0,0 +1,145 @@
+package config
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gernest/alien"
+)
+
+// DefaultInterval is how often a Watcher checks its manifest by default.
+const DefaultInterval = time.Second
+
+// WatchOption configures a Watcher.
+type WatchOption func(*Watcher)
+
+// WatchInterval sets how often the manifest is checked for changes.
+func WatchInterval(d time.Duration) WatchOption {
+	return func(w *Watcher) {
+		w.interval = d
+	}
+}
+
+// OnReloadError sets fn to be called with the errors of reloads, which keep
+// the routes being served. They are logged by default.
+func OnReloadError(fn func(err error)) WatchOption {
+	return func(w *Watcher) {
+		w.onError = fn
+	}
+}
+
+// Watcher serves the routes of a manifest file and reloads them when the file
+// changes. Every reload registers the routes of the manifest with a clone of
+// the base Mux, and the clone replaces the Mux being served only once all the
+// routes are registered, so a bad manifest never takes routes down.
+//   w, err := l.Watch(base, "routes.json", config.OnReloadError(report))
+//   if err != nil {
+//   	return err
+//   }
+//   go w.Run(ctx)
+//   http.ListenAndServe(":8090", w)
+//
+// Changes are detected by polling the modification time and size of the file,
+// every DefaultInterval unless set with WatchInterval. Replace the manifest by
+// renaming a new file over it, a file being written in place can be read half
+// written.
+type Watcher struct {
+	l        *Loader
+	base     *alien.Mux
+	name     string
+	interval time.Duration
+	onError  func(err error)
+
+	live atomic.Pointer[alien.Mux]
+
+	mu      sync.Mutex // serializes reloads
+	modTime time.Time
+	size    int64
+}
+
+// Watch loads the manifest in the file name on top of the routes of base, and
+// returns a Watcher serving them. base itself is never changed, routes added
+// to it later are picked by the next reload.
+func (l *Loader) Watch(base *alien.Mux, name string, opts ...WatchOption) (*Watcher, error) {
+	w := &Watcher{l: l, base: base, name: name, interval: DefaultInterval}
+	for _, o := range opts {
+		o(w)
+	}
+	if err := w.Reload(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// ServeHTTP serves r with the Mux of the last successful load.
+func (w *Watcher) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	w.live.Load().ServeHTTP(rw, r)
+}
+
+// Mux returns the Mux of the last successful load.
+func (w *Watcher) Mux() *alien.Mux {
+	return w.live.Load()
+}
+
+// Reload loads the manifest and swaps the Mux being served for one with its
+// routes. On error the Mux being served is kept.
+func (w *Watcher) Reload() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	info, err := os.Stat(w.name)
+	if err != nil {
+		return err
+	}
+	w.modTime, w.size = info.ModTime(), info.Size()
+	m := w.base.Clone()
+	if err := w.l.LoadFile(m, w.name); err != nil {
+		return err
+	}
+	w.live.Store(m)
+	return nil
+}
+
+// Run reloads the manifest whenever it changes, until ctx is done.
+func (w *Watcher) Run(ctx context.Context) {
+	t := time.NewTicker(w.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if w.changed() {
+				if err := w.Reload(); err != nil {
+					w.report(err)
+				}
+			}
+		}
+	}
+}
+
+// changed returns true if the manifest changed since the last load, failed
+// loads are not retried until it changes again.
+func (w *Watcher) changed() bool {
+	info, err := os.Stat(w.name)
+	if err != nil {
+		w.report(err)
+		return false
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return !info.ModTime().Equal(w.modTime) || info.Size() != w.size
+}
+
+// report passes err to the error hook.
+func (w *Watcher) report(err error) {
+	if w.onError != nil {
+		w.onError(err)
+		return
+	}
+	log.Printf("config: reloading %s: %v", w.name, err)
+}
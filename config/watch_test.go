@@ -0,0 +1,88 @@
+package config
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gernest/alien"
+)
+
+func TestLoader_Watch(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "routes.json")
+	// manifests are replaced with a rename, so that the watcher never reads a
+	// partially written file.
+	write := func(s string, mod time.Time) {
+		tmp := name + ".tmp"
+		if err := os.WriteFile(tmp, []byte(s), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(tmp, mod, mod); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(tmp, name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	status := func(h http.Handler, path string) int {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w.Code
+	}
+	now := time.Now()
+	write(`{"routes": [{"method": "GET", "path": "/users", "handler": "list"}]}`, now)
+
+	base := alien.New()
+	base.Get("/health", func(w http.ResponseWriter, r *http.Request) {})
+	errs := make(chan error, 1)
+	w, err := testLoader().Watch(base, name,
+		WatchInterval(5*time.Millisecond),
+		OnReloadError(func(err error) { errs <- err }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status(w, "/users") != http.StatusOK || status(w, "/health") != http.StatusOK {
+		t.Fatal("expected the manifest and base routes to be served")
+	}
+	if status(base, "/users") != http.StatusNotFound {
+		t.Error("expected base to be left untouched")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.Run(ctx)
+
+	write(`{"routes": [{"method": "GET", "path": "/users/:id", "handler": "show"}]}`, now.Add(time.Second))
+	deadline := time.Now().Add(2 * time.Second)
+	for status(w, "/users/1") != http.StatusOK {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the manifest to be reloaded")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if status(w, "/users") != http.StatusNotFound || status(w, "/health") != http.StatusOK {
+		t.Error("expected the routes of the new manifest and base only")
+	}
+
+	write(`{"routes": [{"method": "GET", "path": "/admin", "handler": "missing"}]}`, now.Add(2*time.Second))
+	select {
+	case err := <-errs:
+		if !strings.Contains(err.Error(), "unknown handler missing") {
+			t.Errorf("expected an unknown handler error got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected a reload error")
+	}
+	if status(w, "/users/1") != http.StatusOK || status(w.Mux(), "/admin") != http.StatusNotFound {
+		t.Error("expected the last good routes to be kept")
+	}
+
+	if _, err := testLoader().Watch(base, filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("expected an error for a missing manifest")
+	}
+}
@@ -0,0 +1,98 @@
+package alien
+
+import (
+	"net/http"
+	"sync"
+)
+
+// Context bundles what handlers registered with ContextHandler need to serve a
+// request.
+type Context struct {
+	Writer  http.ResponseWriter
+	Request *http.Request
+	Params  Params
+
+	values map[string]interface{}
+}
+
+var contextPool = sync.Pool{
+	New: func() interface{} {
+		return &Context{}
+	},
+}
+
+// ContextHandler adapts h to a handler which can be registered like any other.
+//   m.Get("/users/:id", alien.ContextHandler(func(c *alien.Context) {
+//       c.JSON(http.StatusOK, users[c.Param("id")])
+//   }))
+//
+// Contexts are pooled, so c must not be used after h returns.
+func ContextHandler(h func(c *Context)) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c := contextPool.Get().(*Context)
+		c.Writer = w
+		c.Request = r
+		c.Params = GetParams(r)
+		h(c)
+		c.Writer = nil
+		c.Request = nil
+		c.Params = nil
+		for k := range c.values {
+			delete(c.values, k)
+		}
+		contextPool.Put(c)
+	}
+}
+
+// Param returns the value of the route param key.
+func (c *Context) Param(key string) string {
+	return c.Params.Get(key)
+}
+
+// Query returns the first value of the query param key.
+func (c *Context) Query(key string) string {
+	return c.Request.URL.Query().Get(key)
+}
+
+// Set stores value under key for the rest of the request.
+func (c *Context) Set(key string, value interface{}) {
+	if c.values == nil {
+		c.values = make(map[string]interface{})
+	}
+	c.values[key] = value
+}
+
+// Get returns the value stored under key with Set, or nil if there is none.
+func (c *Context) Get(key string) interface{} {
+	return c.values[key]
+}
+
+// Bind populates dst from the form and query of the request, see BindForm.
+func (c *Context) Bind(dst interface{}) error {
+	return BindForm(c.Request, dst)
+}
+
+// JSON writes v encoded as JSON with status code, see JSON.
+func (c *Context) JSON(code int, v interface{}) error {
+	return JSON(c.Writer, code, v)
+}
+
+// XML writes v encoded as XML with status code, see XML.
+func (c *Context) XML(code int, v interface{}) error {
+	return XML(c.Writer, code, v)
+}
+
+// Text writes s with status code, see Text.
+func (c *Context) Text(code int, s string) error {
+	return Text(c.Writer, code, s)
+}
+
+// HTML renders the template name with data, see HTML.
+func (c *Context) HTML(code int, name string, data interface{}) error {
+	return HTML(c.Writer, c.Request, code, name, data)
+}
+
+// Redirect redirects the request to url with status code.
+func (c *Context) Redirect(code int, url string) {
+	http.Redirect(c.Writer, c.Request, url, code)
+}
@@ -0,0 +1,49 @@
+package alien
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestContextHandler(t *testing.T) {
+	m := New()
+	m.Post("/users/:id", ContextHandler(func(c *Context) {
+		if c.Get("user") != nil {
+			t.Error("expected values of previous requests to be cleared")
+		}
+		c.Set("user", c.Param("id"))
+		var s signup
+		if err := c.Bind(&s); err != nil {
+			c.Text(http.StatusBadRequest, err.Error())
+			return
+		}
+		c.JSON(http.StatusOK, map[string]interface{}{
+			"id": c.Get("user"), "name": s.Name, "age": s.Age, "q": c.Query("q"),
+		})
+	}))
+	m.Get("/old", ContextHandler(func(c *Context) {
+		c.Redirect(http.StatusMovedPermanently, "/new")
+	}))
+	sample := []struct {
+		method, path, body, result string
+		status                     int
+	}{
+		{"POST", "/users/42?q=go", "name=alien&age=20", "{\"age\":20,\"id\":\"42\",\"name\":\"alien\",\"q\":\"go\"}\n", http.StatusOK},
+		{"POST", "/users/42", "name=alien&age=2", "alien: validation failed: too young", http.StatusBadRequest},
+		{"GET", "/old", "", "", http.StatusMovedPermanently},
+	}
+	for _, v := range sample {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(v.method, v.path, strings.NewReader(v.body))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		m.ServeHTTP(w, req)
+		if w.Code != v.status {
+			t.Errorf("%s: expected %d got %d", v.path, v.status, w.Code)
+		}
+		if v.result != "" && w.Body.String() != v.result {
+			t.Errorf("%s: expected %q got %q", v.path, v.result, w.Body.String())
+		}
+	}
+}
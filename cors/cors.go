@@ -0,0 +1,240 @@
+// Package cors provides a Cross-Origin Resource Sharing middleware for alien.
+//
+// The middleware answers preflight requests itself. Preflight requests to paths
+// without a registered OPTIONS route don't match a route, so they only reach
+// middlewares registered with Use when the Mux wraps unmatched requests
+//   m := alien.New()
+//   m.Use(cors.New(cors.Options{
+//   	AllowedOrigins: []string{"https://example.com"},
+//   }))
+//   m.WrapUnmatched(true)
+//   m.Get("/users", users)
+// Wrapping the whole Mux works as well
+//   http.ListenAndServe(":8090", cors.New(o)(m))
+package cors
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var (
+	defaultMethods = []string{"GET", "HEAD", "POST"}
+	defaultHeaders = []string{"Accept", "Content-Type", "X-Requested-With"}
+)
+
+// Options configures the cors middleware.
+type Options struct {
+	// AllowedOrigins is a list of origins allowed to make cross origin
+	// requests. An origin can contain one wildcard like https://*.example.com,
+	// and a single "*" allows any origin. Defaults to "*".
+	AllowedOrigins []string
+
+	// AllowedMethods is a list of methods clients are allowed to use. Defaults
+	// to GET, HEAD and POST.
+	AllowedMethods []string
+
+	// AllowedHeaders is a list of non simple headers clients are allowed to
+	// send. A single "*" allows any header. Defaults to Accept, Content-Type and
+	// X-Requested-With.
+	AllowedHeaders []string
+
+	// ExposedHeaders is a list of response headers clients are allowed to read.
+	ExposedHeaders []string
+
+	// AllowCredentials allows requests with cookies, authorization headers or
+	// client certificates. It requires AllowedOrigins to list the origins, New
+	// panics if any origin is allowed.
+	AllowCredentials bool
+
+	// MaxAge is the number of seconds clients can cache preflight responses.
+	MaxAge int
+
+	// OptionsPassthrough passes preflight requests to the next handler after
+	// setting the cors headers, for instance to let alien answer with the Allow
+	// header when AutoOptions is enabled.
+	OptionsPassthrough bool
+
+	// Skipper returns true for requests which bypass the middleware, see
+	// alien.SkipPaths.
+	Skipper func(r *http.Request) bool
+}
+
+type cors struct {
+	Options
+	anyOrigin bool
+	anyHeader bool
+	methods   string
+	headers   string
+	exposed   string
+}
+
+// New returns a middleware which handles cors requests as configured by o. It
+// panics if o allows credentials from any origin, which would let any site
+// make authenticated requests on behalf of its visitors.
+func New(o Options) func(http.Handler) http.Handler {
+	c := &cors{Options: o}
+	if len(c.AllowedOrigins) == 0 {
+		c.AllowedOrigins = []string{"*"}
+	}
+	for _, v := range c.AllowedOrigins {
+		if v == "*" {
+			c.anyOrigin = true
+		}
+	}
+	if c.anyOrigin && c.AllowCredentials {
+		panic("cors: credentials can't be allowed from any origin")
+	}
+	if len(c.AllowedMethods) == 0 {
+		c.AllowedMethods = defaultMethods
+	}
+	if len(c.AllowedHeaders) == 0 {
+		c.AllowedHeaders = defaultHeaders
+	}
+	headers := make([]string, len(c.AllowedHeaders))
+	for k, v := range c.AllowedHeaders {
+		if v == "*" {
+			c.anyHeader = true
+		}
+		headers[k] = http.CanonicalHeaderKey(v)
+	}
+	c.AllowedHeaders = headers
+	c.methods = strings.ToUpper(strings.Join(c.AllowedMethods, ", "))
+	c.headers = strings.Join(c.AllowedHeaders, ", ")
+	c.exposed = strings.Join(c.ExposedHeaders, ", ")
+	return c.handler
+}
+
+func (c *cors) handler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if c.Skipper != nil && c.Skipper(r) {
+			h.ServeHTTP(w, r)
+			return
+		}
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			c.preflight(w, r)
+			if c.OptionsPassthrough {
+				h.ServeHTTP(w, r)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		c.actual(w, r)
+		h.ServeHTTP(w, r)
+	})
+}
+
+func (c *cors) preflight(w http.ResponseWriter, r *http.Request) {
+	header := w.Header()
+	header.Add("Vary", "Origin")
+	header.Add("Vary", "Access-Control-Request-Method")
+	header.Add("Vary", "Access-Control-Request-Headers")
+	origin := r.Header.Get("Origin")
+	if !c.allowOrigin(origin) {
+		return
+	}
+	if !c.allowMethod(r.Header.Get("Access-Control-Request-Method")) {
+		return
+	}
+	requested := r.Header.Get("Access-Control-Request-Headers")
+	if !c.allowHeaders(requested) {
+		return
+	}
+	c.setOrigin(header, origin)
+	header.Set("Access-Control-Allow-Methods", c.methods)
+	if requested != "" {
+		if c.anyHeader {
+			header.Set("Access-Control-Allow-Headers", requested)
+		} else {
+			header.Set("Access-Control-Allow-Headers", c.headers)
+		}
+	}
+	if c.MaxAge > 0 {
+		header.Set("Access-Control-Max-Age", strconv.Itoa(c.MaxAge))
+	}
+}
+
+func (c *cors) actual(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	header := w.Header()
+	header.Add("Vary", "Origin")
+	if origin == "" || !c.allowOrigin(origin) || !c.allowMethod(r.Method) {
+		return
+	}
+	c.setOrigin(header, origin)
+	if c.exposed != "" {
+		header.Set("Access-Control-Expose-Headers", c.exposed)
+	}
+}
+
+func (c *cors) setOrigin(header http.Header, origin string) {
+	if c.anyOrigin {
+		header.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		header.Set("Access-Control-Allow-Origin", origin)
+	}
+	if c.AllowCredentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+func (c *cors) allowOrigin(origin string) bool {
+	if origin == "" {
+		return false
+	}
+	if c.anyOrigin {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	for _, v := range c.AllowedOrigins {
+		v = strings.ToLower(v)
+		if i := strings.IndexByte(v, '*'); i != -1 {
+			if len(origin) > len(v)-1 && strings.HasPrefix(origin, v[:i]) && strings.HasSuffix(origin, v[i+1:]) {
+				return true
+			}
+			continue
+		}
+		if v == origin {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *cors) allowMethod(method string) bool {
+	method = strings.ToUpper(method)
+	if method == http.MethodOptions {
+		return true
+	}
+	for _, v := range c.AllowedMethods {
+		if strings.ToUpper(v) == method {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *cors) allowHeaders(requested string) bool {
+	if c.anyHeader || requested == "" {
+		return true
+	}
+	for _, v := range strings.Split(requested, ",") {
+		v = http.CanonicalHeaderKey(strings.TrimSpace(v))
+		if v == "" {
+			continue
+		}
+		ok := false
+		for _, a := range c.AllowedHeaders {
+			if a == v {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
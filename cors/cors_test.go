@@ -0,0 +1,149 @@
+package cors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gernest/alien"
+)
+
+func testMux(o Options) http.Handler {
+	m := alien.New()
+	m.AutoOptions(true)
+	m.Get("/users", func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte("users"))
+	})
+	m.Put("/users", func(_ http.ResponseWriter, _ *http.Request) {})
+	return New(o)(m)
+}
+
+func TestPreflight(t *testing.T) {
+	h := testMux(Options{
+		AllowedOrigins: []string{"https://*.example.com"},
+		AllowedMethods: []string{"GET", "PUT"},
+		AllowedHeaders: []string{"content-type", "x-token"},
+		MaxAge:         600,
+	})
+	sample := []struct {
+		origin, method, headers string
+		allowed                 bool
+	}{
+		{"https://www.example.com", "PUT", "X-Token, Content-Type", true},
+		{"https://www.example.com", "DELETE", "", false},
+		{"https://www.example.com", "PUT", "X-Secret", false},
+		{"https://example.org", "GET", "", false},
+	}
+	for _, v := range sample {
+		req, _ := http.NewRequest("OPTIONS", "/users", nil)
+		req.Header.Set("Origin", v.origin)
+		req.Header.Set("Access-Control-Request-Method", v.method)
+		if v.headers != "" {
+			req.Header.Set("Access-Control-Request-Headers", v.headers)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != http.StatusNoContent {
+			t.Errorf("expected %d got %d", http.StatusNoContent, w.Code)
+		}
+		origin := w.Header().Get("Access-Control-Allow-Origin")
+		if v.allowed {
+			if origin != v.origin {
+				t.Errorf("expected %s got %s", v.origin, origin)
+			}
+			if m := w.Header().Get("Access-Control-Allow-Methods"); m != "GET, PUT" {
+				t.Errorf("expected GET, PUT got %s", m)
+			}
+			if a := w.Header().Get("Access-Control-Max-Age"); a != "600" {
+				t.Errorf("expected 600 got %s", a)
+			}
+		} else if origin != "" {
+			t.Errorf("%v: expected no allowed origin got %s", v, origin)
+		}
+	}
+}
+
+func TestPassthrough(t *testing.T) {
+	h := testMux(Options{OptionsPassthrough: true})
+	req, _ := http.NewRequest("OPTIONS", "/users", nil)
+	req.Header.Set("Origin", "https://example.com")
+	req.Header.Set("Access-Control-Request-Method", "GET")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusNoContent {
+		t.Errorf("expected %d got %d", http.StatusNoContent, w.Code)
+	}
+	if a := w.Header().Get("Allow"); a != "GET, HEAD, PUT, OPTIONS" {
+		t.Errorf("expected the router to set Allow got %s", a)
+	}
+	if o := w.Header().Get("Access-Control-Allow-Origin"); o != "*" {
+		t.Errorf("expected * got %s", o)
+	}
+}
+
+func TestActual(t *testing.T) {
+	h := testMux(Options{
+		AllowedOrigins:   []string{"https://example.com"},
+		ExposedHeaders:   []string{"X-Total"},
+		AllowCredentials: true,
+	})
+	req, _ := http.NewRequest("GET", "/users", nil)
+	req.Header.Set("Origin", "https://example.com")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Body.String() != "users" {
+		t.Errorf("expected users got %s", w.Body)
+	}
+	header := w.Header()
+	if o := header.Get("Access-Control-Allow-Origin"); o != "https://example.com" {
+		t.Errorf("expected https://example.com got %s", o)
+	}
+	if c := header.Get("Access-Control-Allow-Credentials"); c != "true" {
+		t.Errorf("expected true got %s", c)
+	}
+	if e := header.Get("Access-Control-Expose-Headers"); e != "X-Total" {
+		t.Errorf("expected X-Total got %s", e)
+	}
+
+	req.Header.Set("Origin", "https://evil.com")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if o := w.Header().Get("Access-Control-Allow-Origin"); o != "" {
+		t.Errorf("expected no allowed origin got %s", o)
+	}
+}
+
+func TestNew_wrapUnmatched(t *testing.T) {
+	m := alien.New()
+	m.Use(New(Options{AllowedOrigins: []string{"https://example.com"}, AllowedMethods: []string{"PUT"}}))
+	m.WrapUnmatched(true)
+	m.Put("/users", func(_ http.ResponseWriter, _ *http.Request) {})
+	req, _ := http.NewRequest("OPTIONS", "/users", nil)
+	req.Header.Set("Origin", "https://example.com")
+	req.Header.Set("Access-Control-Request-Method", "PUT")
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, req)
+	if w.Code != http.StatusNoContent {
+		t.Errorf("expected %d got %d", http.StatusNoContent, w.Code)
+	}
+	if o := w.Header().Get("Access-Control-Allow-Origin"); o != "https://example.com" {
+		t.Errorf("expected https://example.com got %s", o)
+	}
+}
+
+func TestNew_panics(t *testing.T) {
+	sample := []Options{
+		{AllowCredentials: true},
+		{AllowCredentials: true, AllowedOrigins: []string{"https://example.com", "*"}},
+	}
+	for _, v := range sample {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%v: expected panic", v.AllowedOrigins)
+				}
+			}()
+			New(v)
+		}()
+	}
+}
@@ -0,0 +1,111 @@
+package alien
+
+import (
+	"errors"
+	"net/http"
+)
+
+// ErrorHandler sets h to handle the errors returned by the handlers registered
+// with AddRouteE and friends through m, so status codes, logging and the format
+// of error responses are decided in one place.
+//   m.ErrorHandler(func(w http.ResponseWriter, r *http.Request, err error) {
+//       log.Println(r.URL.Path, err)
+//       alien.JSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+//   })
+//
+// Groups and hosts use the error handler of their parent unless they have their
+// own. Without one, a *Problem is written with WriteProblem, errors with a
+// Status() int method like *BindError respond with that status and their
+// message, and the rest with 500 Internal Server Error.
+func (m *Mux) ErrorHandler(h func(w http.ResponseWriter, r *http.Request, err error)) {
+	m.errorHandler = h
+}
+
+// handleError responds to r with err using the error handler of m.
+func (m *Mux) handleError(w http.ResponseWriter, r *http.Request, err error) {
+	for g := m; g != nil; g = g.parent {
+		if g.errorHandler != nil {
+			g.errorHandler(w, r, err)
+			return
+		}
+	}
+	var p *Problem
+	if errors.As(err, &p) {
+		WriteProblem(w, p)
+		return
+	}
+	var status interface{ Status() int }
+	if errors.As(err, &status) && status.Status() < http.StatusInternalServerError {
+		m.httpError(w, r, status.Status(), err.Error())
+		return
+	}
+	m.logf("alien: %s %s: %v", r.Method, r.URL.Path, err)
+	m.httpError(w, r, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+}
+
+// AddRouteE registers h with pattern and method just like AddRoute, errors
+// returned by h are passed to the error handler of m.
+//   m.GetE("/users/:id", func(w http.ResponseWriter, r *http.Request) error {
+//       id, err := alien.GetParams(r).Int("id")
+//       if err != nil {
+//           return err
+//       }
+//       return alien.JSON(w, http.StatusOK, users[id])
+//   })
+func (m *Mux) AddRouteE(method, pattern string, h func(http.ResponseWriter, *http.Request) error, wares ...func(http.Handler) http.Handler) error {
+	return m.AddRoute(method, pattern, m.handlerE(h), wares...)
+}
+
+// handlerE adapts h to a plain handler.
+func (m *Mux) handlerE(h func(http.ResponseWriter, *http.Request) error) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := h(w, r); err != nil {
+			m.handleError(w, r, err)
+		}
+	}
+}
+
+// GetE registers h with path and method GET, see AddRouteE.
+func (m *Mux) GetE(path string, h func(http.ResponseWriter, *http.Request) error, wares ...func(http.Handler) http.Handler) error {
+	return m.AddRouteE(httpMethods.get, path, h, wares...)
+}
+
+// PutE registers h with path and method PUT, see AddRouteE.
+func (m *Mux) PutE(path string, h func(http.ResponseWriter, *http.Request) error, wares ...func(http.Handler) http.Handler) error {
+	return m.AddRouteE(httpMethods.put, path, h, wares...)
+}
+
+// PostE registers h with path and method POST, see AddRouteE.
+func (m *Mux) PostE(path string, h func(http.ResponseWriter, *http.Request) error, wares ...func(http.Handler) http.Handler) error {
+	return m.AddRouteE(httpMethods.post, path, h, wares...)
+}
+
+// PatchE registers h with path and method PATCH, see AddRouteE.
+func (m *Mux) PatchE(path string, h func(http.ResponseWriter, *http.Request) error, wares ...func(http.Handler) http.Handler) error {
+	return m.AddRouteE(httpMethods.patch, path, h, wares...)
+}
+
+// HeadE registers h with path and method HEAD, see AddRouteE.
+func (m *Mux) HeadE(path string, h func(http.ResponseWriter, *http.Request) error, wares ...func(http.Handler) http.Handler) error {
+	return m.AddRouteE(httpMethods.head, path, h, wares...)
+}
+
+// OptionsE registers h with path and method OPTIONS, see AddRouteE.
+func (m *Mux) OptionsE(path string, h func(http.ResponseWriter, *http.Request) error, wares ...func(http.Handler) http.Handler) error {
+	return m.AddRouteE(httpMethods.options, path, h, wares...)
+}
+
+// ConnectE registers h with path and method CONNECT, see AddRouteE.
+func (m *Mux) ConnectE(path string, h func(http.ResponseWriter, *http.Request) error, wares ...func(http.Handler) http.Handler) error {
+	return m.AddRouteE(httpMethods.connect, path, h, wares...)
+}
+
+// TraceE registers h with path and method TRACE, see AddRouteE.
+func (m *Mux) TraceE(path string, h func(http.ResponseWriter, *http.Request) error, wares ...func(http.Handler) http.Handler) error {
+	return m.AddRouteE(httpMethods.trace, path, h, wares...)
+}
+
+// DeleteE registers h with path and method DELETE, see AddRouteE.
+func (m *Mux) DeleteE(path string, h func(http.ResponseWriter, *http.Request) error, wares ...func(http.Handler) http.Handler) error {
+	return m.AddRouteE(httpMethods.delete, path, h, wares...)
+}
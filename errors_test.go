@@ -0,0 +1,50 @@
+package alien
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMux_ErrorHandler(t *testing.T) {
+	m := New()
+	m.GetE("/users/:id", func(w http.ResponseWriter, r *http.Request) error {
+		id, err := GetParams(r).Int("id")
+		if err != nil {
+			return err
+		}
+		if id == 0 {
+			return errors.New("database is down")
+		}
+		w.Write([]byte("ok"))
+		return nil
+	})
+	api := m.Group("/api")
+	api.ErrorHandler(func(w http.ResponseWriter, r *http.Request, err error) {
+		JSON(w, http.StatusTeapot, map[string]string{"error": err.Error()})
+	})
+	api.PostE("/fail", func(w http.ResponseWriter, r *http.Request) error {
+		return errors.New("boom")
+	})
+	sample := []struct {
+		method, path, body string
+		status             int
+	}{
+		{"GET", "/users/42", "ok", http.StatusOK},
+		{"GET", "/users/x", "alien: bad route param id=\"x\": invalid syntax\n", http.StatusBadRequest},
+		{"GET", "/users/0", "Internal Server Error\n", http.StatusInternalServerError},
+		{"POST", "/api/fail", "{\"error\":\"boom\"}\n", http.StatusTeapot},
+	}
+	for _, v := range sample {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(v.method, v.path, nil)
+		m.ServeHTTP(w, req)
+		if w.Code != v.status {
+			t.Errorf("%s: expected %d got %d", v.path, v.status, w.Code)
+		}
+		if w.Body.String() != v.body {
+			t.Errorf("%s: expected %q got %q", v.path, v.body, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,235 @@
+// Package etag provides a middleware which adds ETags to responses and answers
+// conditional GET requests with 304 Not Modified.
+//
+//   m := alien.New()
+//   m.Use(etag.New(etag.Options{}))
+//
+// Successful responses to GET and HEAD requests are buffered, and the ETag is
+// computed from the body. Responses larger than Options.MaxSize are streamed
+// without an ETag.
+package etag
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gernest/alien"
+)
+
+// DefaultMaxSize is the default size limit of buffered responses.
+const DefaultMaxSize = 1 << 20
+
+// Options configures the etag middleware.
+type Options struct {
+	// Weak makes the middleware produce weak ETags.
+	Weak bool
+
+	// MaxSize is the maximum size of response bodies which are buffered to
+	// compute an ETag. Defaults to DefaultMaxSize.
+	MaxSize int
+
+	// Skipper returns true for requests which bypass the middleware, see
+	// alien.SkipPaths.
+	Skipper func(r *http.Request) bool
+}
+
+// New returns a middleware which adds ETags to responses.
+func New(o Options) func(http.Handler) http.Handler {
+	if o.MaxSize <= 0 {
+		o.MaxSize = DefaultMaxSize
+	}
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet && r.Method != http.MethodHead || o.Skipper != nil && o.Skipper(r) {
+				h.ServeHTTP(w, r)
+				return
+			}
+			ew := &etagWriter{ResponseWriter: alien.WrapWriter(w), max: o.MaxSize}
+			h.ServeHTTP(ew, r)
+			ew.finish(r, o.Weak)
+		})
+	}
+}
+
+// Generate returns the ETag for body.
+func Generate(body []byte, weak bool) string {
+	sum := sha256.Sum256(body)
+	tag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	if weak {
+		return "W/" + tag
+	}
+	return tag
+}
+
+// Match returns true if the If-None-Match header of r matches etag. Weak
+// comparison is used, as required for GET and HEAD requests.
+func Match(r *http.Request, etag string) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" || etag == "" {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, v := range strings.Split(header, ",") {
+		v = strings.TrimSpace(v)
+		if v == "*" || strings.TrimPrefix(v, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// LastModified sets the Last-Modified header to t, and answers with 304 Not
+// Modified if the If-Modified-Since header of r is not older than t. It returns
+// true if the 304 response was written, in which case the handler should
+// return without writing anything else.
+//
+//   if etag.LastModified(w, r, post.UpdatedAt) {
+//   	return
+//   }
+func LastModified(w http.ResponseWriter, r *http.Request, t time.Time) bool {
+	if t.IsZero() {
+		return false
+	}
+	t = t.UTC().Truncate(time.Second)
+	w.Header().Set("Last-Modified", t.Format(http.TimeFormat))
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	// If-None-Match takes precedence over If-Modified-Since.
+	if r.Header.Get("If-None-Match") != "" {
+		return false
+	}
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil || t.After(since) {
+		return false
+	}
+	notModified(w)
+	return true
+}
+
+func notModified(w http.ResponseWriter) {
+	h := w.Header()
+	h.Del("Content-Type")
+	h.Del("Content-Length")
+	w.WriteHeader(http.StatusNotModified)
+}
+
+// etagWriter buffers the response until it exceeds max bytes, after which it
+// is streamed. Pushes and deadlines are passed to the writer it wraps.
+type etagWriter struct {
+	alien.ResponseWriter
+	buf       bytes.Buffer
+	max       int
+	code      int
+	streaming bool
+	hijacked  bool
+}
+
+func (w *etagWriter) WriteHeader(code int) {
+	if w.streaming || w.code != 0 {
+		return
+	}
+	if code < 200 {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	w.code = code
+	if code != http.StatusOK {
+		w.stream()
+	}
+}
+
+func (w *etagWriter) Write(b []byte) (int, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	if !w.streaming && w.buf.Len()+len(b) > w.max {
+		w.stream()
+	}
+	if w.streaming {
+		return w.ResponseWriter.Write(b)
+	}
+	return w.buf.Write(b)
+}
+
+// stream writes the header and what was buffered so far, then passes the rest
+// of the response through.
+func (w *etagWriter) stream() {
+	if w.streaming {
+		return
+	}
+	w.streaming = true
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	restore := alien.HoldTrailers(w.Header())
+	w.ResponseWriter.WriteHeader(w.code)
+	restore()
+	if w.buf.Len() > 0 {
+		w.ResponseWriter.Write(w.buf.Bytes())
+		w.buf.Reset()
+	}
+}
+
+// ReadFrom copies r through Write, so that the body is hashed.
+func (w *etagWriter) ReadFrom(r io.Reader) (int64, error) {
+	return io.Copy(struct{ io.Writer }{w}, r)
+}
+
+func (w *etagWriter) Flush() {
+	w.stream()
+	w.ResponseWriter.Flush()
+}
+
+func (w *etagWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if w.streaming {
+		return nil, nil, errors.New("etag: hijacking not supported")
+	}
+	conn, rw, err := w.ResponseWriter.Hijack()
+	if err == nil {
+		w.hijacked = true
+	}
+	return conn, rw, err
+}
+
+// Status returns the status code of the response, which is held while the
+// body is buffered.
+func (w *etagWriter) Status() int {
+	if w.code != 0 {
+		return w.code
+	}
+	return w.ResponseWriter.Status()
+}
+
+func (w *etagWriter) finish(r *http.Request, weak bool) {
+	if w.streaming || w.hijacked {
+		return
+	}
+	if w.code == 0 {
+		// nothing was written, let net/http write the default response.
+		return
+	}
+	h := w.Header()
+	tag := h.Get("ETag")
+	if tag == "" {
+		tag = Generate(w.buf.Bytes(), weak)
+		h.Set("ETag", tag)
+	}
+	// 304 responses have no body to send trailers after.
+	restore := alien.HoldTrailers(h)
+	if Match(r, tag) {
+		notModified(w.ResponseWriter)
+		return
+	}
+	w.ResponseWriter.WriteHeader(w.code)
+	restore()
+	w.ResponseWriter.Write(w.buf.Bytes())
+}
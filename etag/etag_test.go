@@ -0,0 +1,175 @@
+package etag
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gernest/alien"
+	"github.com/gernest/alien/alientest"
+)
+
+func TestNew(t *testing.T) {
+	m := alien.New()
+	m.Use(New(Options{MaxSize: 10}))
+	m.Get("/small", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("hello"))
+	})
+	m.Get("/large", func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte(strings.Repeat("hello", 10)))
+	})
+	m.Get("/missing", func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "missing", http.StatusNotFound)
+	})
+
+	tag := Generate([]byte("hello"), false)
+	req, _ := http.NewRequest("GET", "/small", nil)
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != "hello" {
+		t.Errorf("unexpected response %d %s", w.Code, w.Body)
+	}
+	if e := w.Header().Get("ETag"); e != tag {
+		t.Errorf("expected %s got %s", tag, e)
+	}
+
+	for _, v := range []string{tag, "W/" + tag, `"other", ` + tag, "*"} {
+		req.Header.Set("If-None-Match", v)
+		w = httptest.NewRecorder()
+		m.ServeHTTP(w, req)
+		if w.Code != http.StatusNotModified {
+			t.Errorf("%s: expected %d got %d", v, http.StatusNotModified, w.Code)
+		}
+		if w.Body.Len() != 0 || w.Header().Get("Content-Type") != "" {
+			t.Errorf("%s: expected empty response", v)
+		}
+	}
+
+	for _, p := range []string{"/large", "/missing"} {
+		req, _ = http.NewRequest("GET", p, nil)
+		req.Header.Set("If-None-Match", "*")
+		w = httptest.NewRecorder()
+		m.ServeHTTP(w, req)
+		if w.Header().Get("ETag") != "" {
+			t.Errorf("%s: expected no ETag", p)
+		}
+		if w.Code == http.StatusNotModified {
+			t.Errorf("%s: expected a full response", p)
+		}
+	}
+}
+
+func TestWeak(t *testing.T) {
+	m := alien.New()
+	m.Use(New(Options{Weak: true}))
+	m.Get("/", func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte("hello"))
+	})
+	req, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, req)
+	if e := w.Header().Get("ETag"); e != Generate([]byte("hello"), true) || !strings.HasPrefix(e, "W/") {
+		t.Errorf("unexpected etag %s", e)
+	}
+}
+
+func TestLastModified(t *testing.T) {
+	modified := time.Date(2016, 1, 1, 12, 0, 0, 0, time.UTC)
+	m := alien.New()
+	m.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		if LastModified(w, r, modified) {
+			return
+		}
+		w.Write([]byte("hello"))
+	})
+	sample := []struct {
+		since time.Time
+		code  int
+	}{
+		{modified, http.StatusNotModified},
+		{modified.Add(time.Hour), http.StatusNotModified},
+		{modified.Add(-time.Hour), http.StatusOK},
+		{time.Time{}, http.StatusOK},
+	}
+	for _, v := range sample {
+		req, _ := http.NewRequest("GET", "/", nil)
+		if !v.since.IsZero() {
+			req.Header.Set("If-Modified-Since", v.since.Format(http.TimeFormat))
+		}
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, req)
+		if w.Code != v.code {
+			t.Errorf("%v: expected %d got %d", v.since, v.code, w.Code)
+		}
+		if l := w.Header().Get("Last-Modified"); l != modified.Format(http.TimeFormat) {
+			t.Errorf("unexpected Last-Modified %s", l)
+		}
+	}
+}
+
+func TestTrailers(t *testing.T) {
+	m := alien.New()
+	m.Use(New(Options{MaxSize: 10}))
+	m.Get("/", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Trailer", "X-Checksum")
+		w.Write([]byte("hello"))
+		w.Header().Set("X-Checksum", "abc")
+	})
+	req, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, req)
+	res := w.Result()
+	if res.Header.Get("X-Checksum") != "" || res.Trailer.Get("X-Checksum") != "abc" {
+		t.Errorf("expected the checksum in the trailer got header %v trailer %v", res.Header, res.Trailer)
+	}
+
+	req.Header.Set("If-None-Match", res.Header.Get("ETag"))
+	w = httptest.NewRecorder()
+	m.ServeHTTP(w, req)
+	if w.Code != http.StatusNotModified || w.Result().Header.Get("X-Checksum") != "" {
+		t.Errorf("expected a 304 without the checksum got %d %v", w.Code, w.Result().Header)
+	}
+}
+
+func TestNew_writer(t *testing.T) {
+	m := alien.New()
+	m.Use(New(Options{}))
+	m.Get("/flush", func(w http.ResponseWriter, r *http.Request) {
+		if err := w.(http.Pusher).Push("/app.js", nil); err != nil {
+			t.Error(err)
+		}
+		w.Write([]byte("hello"))
+		w.(http.Flusher).Flush()
+	})
+	m.Get("/hijack", func(w http.ResponseWriter, r *http.Request) {
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		conn.Close()
+	})
+
+	req, _ := http.NewRequest("GET", "/flush", nil)
+	w := alientest.NewRecorder()
+	m.ServeHTTP(w, req)
+	if !w.Flushed {
+		t.Error("expected the response to be flushed")
+	}
+	if len(w.Pushed) != 1 || w.Pushed[0] != "/app.js" {
+		t.Errorf("expected /app.js to be pushed got %v", w.Pushed)
+	}
+	if e := w.Header().Get("ETag"); e != "" {
+		t.Errorf("expected no ETag for a flushed response got %s", e)
+	}
+
+	req, _ = http.NewRequest("GET", "/hijack", nil)
+	w = alientest.NewRecorder()
+	m.ServeHTTP(w, req)
+	if !w.Hijacked {
+		t.Error("expected the connection to be hijacked")
+	}
+}
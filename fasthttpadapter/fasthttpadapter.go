@@ -0,0 +1,65 @@
+// Package fasthttpadapter serves an alien Mux with fasthttp, for servers which
+// need its connection handling. It is a module of its own, so that alien
+// doesn't depend on fasthttp.
+//
+//   m := alien.New()
+//   m.Use(fasthttpadapter.Params)
+//   m.Get("/users/:id", user)
+//   log.Fatal(fasthttp.ListenAndServe(":8090", fasthttpadapter.New(m)))
+//
+// Requests are converted with fasthttpadaptor, which shares the body of the
+// RequestCtx instead of copying it, so routing, middlewares and
+// alien.GetParams work unchanged. The user values of the RequestCtx are read
+// by handlers with the Value method of the request context, and Params copies
+// the route params back to the user values, for the fasthttp handlers wrapping
+// the Mux.
+package fasthttpadapter
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gernest/alien"
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttpadaptor"
+)
+
+type contextKey struct {
+	name string
+}
+
+var requestCtxKey = &contextKey{"request ctx"}
+
+// New returns a fasthttp.RequestHandler serving requests with h, usually an
+// *alien.Mux. Flushes and hijacks are supported as fasthttpadaptor supports
+// them.
+func New(h http.Handler) fasthttp.RequestHandler {
+	return fasthttpadaptor.NewFastHTTPHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the adaptor serves requests with the RequestCtx as their context.
+		if ctx, ok := r.Context().(*fasthttp.RequestCtx); ok {
+			r = r.WithContext(context.WithValue(ctx, requestCtxKey, ctx))
+		}
+		h.ServeHTTP(w, r)
+	}))
+}
+
+// RequestCtx returns the RequestCtx r was converted from, or nil if r isn't
+// served by a handler returned by New.
+func RequestCtx(r *http.Request) *fasthttp.RequestCtx {
+	ctx, _ := r.Context().Value(requestCtxKey).(*fasthttp.RequestCtx)
+	return ctx
+}
+
+// Params is a middleware which sets the route params of requests as user
+// values of their RequestCtx, so they are read with ctx.UserValue once the Mux
+// is done with the request.
+func Params(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ctx := RequestCtx(r); ctx != nil {
+			for _, p := range alien.GetParams(r) {
+				ctx.SetUserValue(p.Key, p.Value)
+			}
+		}
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,72 @@
+package fasthttpadapter
+
+import (
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/gernest/alien"
+	"github.com/valyala/fasthttp"
+)
+
+func serve(h fasthttp.RequestHandler, method, uri, body string) *fasthttp.RequestCtx {
+	var req fasthttp.Request
+	req.Header.SetMethod(method)
+	req.SetRequestURI(uri)
+	req.Header.Set("X-Request-Id", "42")
+	req.SetBodyString(body)
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Init(&req, nil, nil)
+	ctx.SetUserValue("tenant", "acme")
+	h(ctx)
+	return ctx
+}
+
+func TestNew(t *testing.T) {
+	m := alien.New()
+	m.Use(Params)
+	m.Get("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		if RequestCtx(r) == nil {
+			t.Error("expected the RequestCtx")
+		}
+		w.Header().Set("X-Request-Id", r.Header.Get("X-Request-Id"))
+		w.Header().Set("X-Tenant", r.Context().Value("tenant").(string))
+		w.Write([]byte(alien.GetParams(r).Get("id")))
+	})
+	m.Post("/echo", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		io.Copy(w, r.Body)
+	})
+	h := New(m)
+
+	sample := []struct {
+		method, uri, body string
+		code              int
+		result            string
+	}{
+		{"GET", "/users/7?active=true", "", http.StatusOK, "7"},
+		{"POST", "/echo", "hello", http.StatusCreated, "hello"},
+		{"GET", "/missing", "", http.StatusNotFound, ""},
+	}
+	for _, v := range sample {
+		ctx := serve(h, v.method, v.uri, v.body)
+		if code := ctx.Response.StatusCode(); code != v.code {
+			t.Errorf("%s %s: expected %d got %d", v.method, v.uri, v.code, code)
+		}
+		if v.result != "" && string(ctx.Response.Body()) != v.result {
+			t.Errorf("%s %s: expected %s got %s", v.method, v.uri, v.result, ctx.Response.Body())
+		}
+	}
+
+	ctx := serve(h, "GET", "/users/7", "")
+	if id, _ := ctx.UserValue("id").(string); id != "7" {
+		t.Errorf("expected the id param to be set got %q", id)
+	}
+	header := &ctx.Response.Header
+	if v := string(header.Peek("X-Request-Id")); v != "42" {
+		t.Errorf("expected the request header to be copied got %q", v)
+	}
+	if v := string(header.Peek("X-Tenant")); v != "acme" {
+		t.Errorf("expected the user value to be read got %q", v)
+	}
+}
@@ -0,0 +1,258 @@
+package alien
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+var (
+	errBadForwarded = errors.New("alien: malformed Forwarded header")
+	forwardedKey    = &contextKey{"forwarded"}
+)
+
+// Forwarded is an element of the Forwarded header defined by RFC 7239, it
+// describes a request as received by one of the proxies it went through.
+type Forwarded struct {
+	// For is the node which made the request to the proxy.
+	For string
+
+	// By is the interface of the proxy where the request came in.
+	By string
+
+	// Proto is the scheme used to make the request, http or https.
+	Proto string
+
+	// Host is the Host header of the request.
+	Host string
+}
+
+// ParseForwarded parses the value of a Forwarded header into its elements,
+// the values of multiple headers can be joined with a comma.
+//   ParseForwarded(`for=192.0.2.60;proto=http;by=203.0.113.43, for="[2001:db8::1]:4711"`)
+// returns
+//   []Forwarded{
+//       {For: "192.0.2.60", By: "203.0.113.43", Proto: "http"},
+//       {For: "[2001:db8::1]:4711"},
+//   }
+//
+// Values are unquoted but nodes keep their port and brackets, unknown
+// parameters are ignored.
+func ParseForwarded(s string) ([]Forwarded, error) {
+	var elems []Forwarded
+	var cur Forwarded
+	pairs := 0
+	for {
+		s = strings.TrimLeft(s, " \t")
+		if s == "" {
+			break
+		}
+		switch s[0] {
+		case ',':
+			if pairs > 0 {
+				elems = append(elems, cur)
+			}
+			cur, pairs = Forwarded{}, 0
+			s = s[1:]
+			continue
+		case ';':
+			s = s[1:]
+			continue
+		}
+		eq := strings.IndexByte(s, '=')
+		if eq <= 0 || !isToken(s[:eq]) {
+			return nil, errBadForwarded
+		}
+		name := strings.ToLower(s[:eq])
+		value, rest, ok := forwardedValue(s[eq+1:])
+		if !ok {
+			return nil, errBadForwarded
+		}
+		s = rest
+		pairs++
+		switch name {
+		case "for":
+			cur.For = value
+		case "by":
+			cur.By = value
+		case "proto":
+			cur.Proto = strings.ToLower(value)
+		case "host":
+			cur.Host = value
+		}
+	}
+	if pairs > 0 {
+		elems = append(elems, cur)
+	}
+	return elems, nil
+}
+
+// forwardedValue reads the token or quoted string at the start of s, and
+// returns it along with the rest of s.
+func forwardedValue(s string) (value, rest string, ok bool) {
+	if s == "" {
+		return "", "", false
+	}
+	if s[0] != '"' {
+		end := strings.IndexAny(s, ",; \t")
+		if end < 0 {
+			end = len(s)
+		}
+		return s[:end], s[end:], end > 0
+	}
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+			if i == len(s) {
+				return "", "", false
+			}
+			b.WriteByte(s[i])
+		case '"':
+			return b.String(), s[i+1:], true
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return "", "", false
+}
+
+// isToken reports whether s is a token as defined by RFC 7230.
+func isToken(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c <= ' ' || c >= 0x7f || strings.IndexByte(`"(),/:;<=>?@[\]{}`, c) >= 0 {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// nodeHost returns the address of a Forwarded node, without its port and
+// brackets.
+func nodeHost(node string) string {
+	if host, _, err := net.SplitHostPort(node); err == nil {
+		return host
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(node, "["), "]")
+}
+
+// ForwardedHeaders returns a middleware which resolves how the client made the
+// request when it comes through proxies, the result is returned by
+// GetForwarded and ClientIP. trusted lists the proxies like for RealIP.
+//   m.Use(alien.ForwardedHeaders([]string{"10.0.0.0/8"}))
+//   m.Get("/", func(w http.ResponseWriter, r *http.Request) {
+//       f := alien.GetForwarded(r)
+//       fmt.Fprintln(w, f.For, f.Proto, f.Host)
+//   })
+//
+// The Forwarded header is preferred, the X-Forwarded-For, X-Forwarded-Proto and
+// X-Forwarded-Host headers are used when it is missing or malformed. The
+// headers are only used when the peer is trusted.
+func ForwardedHeaders(trusted []string) func(http.Handler) http.Handler {
+	prefixes := parseTrusted(trusted)
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			f := resolveForwarded(r, prefixes)
+			ctx := context.WithValue(r.Context(), forwardedKey, f)
+			ctx = context.WithValue(ctx, realIPKey, f.For)
+			h.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// GetForwarded returns how the client made r, as resolved by
+// ForwardedHeaders. Without it, For is the host of r.RemoteAddr, Proto is
+// the scheme of r and Host is r.Host.
+func GetForwarded(r *http.Request) Forwarded {
+	if f, ok := r.Context().Value(forwardedKey).(Forwarded); ok {
+		return f
+	}
+	return direct(r)
+}
+
+// direct describes r as received from the peer.
+func direct(r *http.Request) Forwarded {
+	f := Forwarded{For: remoteHost(r), Proto: "http", Host: r.Host}
+	if r.TLS != nil {
+		f.Proto = "https"
+	}
+	return f
+}
+
+// resolveForwarded describes how the client made r, trusting the headers set
+// by the proxies in prefixes.
+func resolveForwarded(r *http.Request, prefixes []netip.Prefix) Forwarded {
+	f := direct(r)
+	if !isTrusted(f.For, prefixes) {
+		return f
+	}
+	if values := r.Header.Values("Forwarded"); len(values) > 0 {
+		if elems, err := ParseForwarded(strings.Join(values, ",")); err == nil && len(elems) > 0 {
+			hops := make([]string, len(elems))
+			for i, e := range elems {
+				hops[i] = nodeHost(e.For)
+			}
+			if i := clientHop(hops, prefixes); i >= 0 {
+				f.For = hops[i]
+				if elems[i].Proto != "" {
+					f.Proto = elems[i].Proto
+				}
+				if elems[i].Host != "" {
+					f.Host = elems[i].Host
+				}
+			}
+			return f
+		}
+	}
+	if values := r.Header.Values("X-Forwarded-For"); len(values) > 0 {
+		hops := strings.Split(strings.Join(values, ","), ",")
+		for i := range hops {
+			hops[i] = strings.TrimSpace(hops[i])
+		}
+		if i := clientHop(hops, prefixes); i >= 0 {
+			f.For = hops[i]
+		}
+	} else if ip := strings.TrimSpace(r.Header.Get("X-Real-Ip")); ip != "" {
+		if _, err := netip.ParseAddr(ip); err == nil {
+			f.For = ip
+		}
+	}
+	if proto := firstValue(r.Header.Get("X-Forwarded-Proto")); proto != "" {
+		f.Proto = strings.ToLower(proto)
+	}
+	if host := firstValue(r.Header.Get("X-Forwarded-Host")); host != "" {
+		f.Host = host
+	}
+	return f
+}
+
+// clientHop returns the index of the client in hops, the addresses a request
+// went through. They are read from right to left and the first one which isn't
+// trusted is the client, so addresses the client added are ignored. It returns
+// -1 if the last hop isn't an address.
+func clientHop(hops []string, prefixes []netip.Prefix) int {
+	client := -1
+	for i := len(hops) - 1; i >= 0; i-- {
+		if _, err := netip.ParseAddr(hops[i]); err != nil {
+			break
+		}
+		client = i
+		if !isTrusted(hops[i], prefixes) {
+			break
+		}
+	}
+	return client
+}
+
+// firstValue returns the first of the comma separated values of a header.
+func firstValue(s string) string {
+	if i := strings.IndexByte(s, ','); i >= 0 {
+		s = s[:i]
+	}
+	return strings.TrimSpace(s)
+}
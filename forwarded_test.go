@@ -0,0 +1,99 @@
+package alien
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestParseForwarded(t *testing.T) {
+	sample := []struct {
+		header string
+		elems  []Forwarded
+		err    error
+	}{
+		{"", nil, nil},
+		{"for=192.0.2.60;proto=HTTP;by=203.0.113.43", []Forwarded{{For: "192.0.2.60", By: "203.0.113.43", Proto: "http"}}, nil},
+		{`For="[2001:db8:cafe::17]:4711"`, []Forwarded{{For: "[2001:db8:cafe::17]:4711"}}, nil},
+		{"for=192.0.2.43, for=198.51.100.17;host=example.com", []Forwarded{{For: "192.0.2.43"}, {For: "198.51.100.17", Host: "example.com"}}, nil},
+		{`for="_gazonk";secret="a,b;\"c\""`, []Forwarded{{For: "_gazonk"}}, nil},
+		{" , for=unknown ;; ,", []Forwarded{{For: "unknown"}}, nil},
+		{"for", nil, errBadForwarded},
+		{"for=", nil, errBadForwarded},
+		{`for="192.0.2.43`, nil, errBadForwarded},
+		{"f:or=192.0.2.43", nil, errBadForwarded},
+	}
+	for _, v := range sample {
+		elems, err := ParseForwarded(v.header)
+		if err != v.err {
+			t.Errorf("%s: expected %v got %v", v.header, v.err, err)
+			continue
+		}
+		if !reflect.DeepEqual(elems, v.elems) {
+			t.Errorf("%s: expected %+v got %+v", v.header, v.elems, elems)
+		}
+	}
+}
+
+func TestForwardedHeaders(t *testing.T) {
+	sample := []struct {
+		remote string
+		tls    bool
+		header http.Header
+		result Forwarded
+	}{
+		{"203.0.113.9:1234", false, nil, Forwarded{For: "203.0.113.9", Proto: "http", Host: "example.com"}},
+		{"203.0.113.9:1234", true, http.Header{"Forwarded": {"for=198.51.100.1"}}, Forwarded{For: "203.0.113.9", Proto: "https", Host: "example.com"}},
+		{"10.0.0.1:1234", false, http.Header{
+			"Forwarded": {`for=6.6.6.6;proto=http, for="198.51.100.1:5555";proto=https;host=api.example.com`, "for=10.0.0.2"},
+		}, Forwarded{For: "198.51.100.1", Proto: "https", Host: "api.example.com"}},
+		// Forwarded is preferred over the legacy headers.
+		{"10.0.0.1:1234", false, http.Header{
+			"Forwarded":         {`for="[2001:db8::1]"`},
+			"X-Forwarded-For":   {"198.51.100.1"},
+			"X-Forwarded-Proto": {"https"},
+		}, Forwarded{For: "2001:db8::1", Proto: "http", Host: "example.com"}},
+		{"10.0.0.1:1234", false, http.Header{"Forwarded": {"for=unknown"}}, Forwarded{For: "10.0.0.1", Proto: "http", Host: "example.com"}},
+		// a malformed Forwarded header falls back to the legacy headers.
+		{"10.0.0.1:1234", false, http.Header{
+			"Forwarded":         {"for"},
+			"X-Forwarded-For":   {"198.51.100.1, 10.0.0.2"},
+			"X-Forwarded-Proto": {"HTTPS, http"},
+			"X-Forwarded-Host":  {"api.example.com"},
+		}, Forwarded{For: "198.51.100.1", Proto: "https", Host: "api.example.com"}},
+	}
+	var got Forwarded
+	var ip string
+	m := New()
+	m.Use(ForwardedHeaders([]string{"10.0.0.0/8"}))
+	m.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		got = GetForwarded(r)
+		ip = ClientIP(r)
+	})
+	for _, v := range sample {
+		req := httptest.NewRequest("GET", "http://example.com/", nil)
+		req.RemoteAddr = v.remote
+		if v.tls {
+			req.TLS = &tls.ConnectionState{}
+		}
+		for k, vals := range v.header {
+			req.Header[k] = vals
+		}
+		m.ServeHTTP(httptest.NewRecorder(), req)
+		if got != v.result {
+			t.Errorf("%s %v: expected %+v got %+v", v.remote, v.header, v.result, got)
+		}
+		if ip != v.result.For {
+			t.Errorf("%s %v: expected client %s got %s", v.remote, v.header, v.result.For, ip)
+		}
+	}
+
+	req := httptest.NewRequest("GET", "https://example.com/", nil)
+	req.RemoteAddr = "203.0.113.9:1234"
+	expect := Forwarded{For: "203.0.113.9", Proto: "https", Host: "example.com"}
+	if f := GetForwarded(req); f != expect {
+		t.Errorf("expected %+v without the middleware got %+v", expect, f)
+	}
+}
@@ -326,3 +326,47 @@ func BenchmarkAlien_GithubParam(b *testing.B) {
 func BenchmarkAlien_GithubAll(b *testing.B) {
 	benchRoutes(b, githubAlien, githubAPI)
 }
+
+// githubRouting has the GitHub API routes with a handler which doesn't
+// allocate, so that only the allocations of routing are measured.
+func githubRouting() *Mux {
+	m := New()
+	for _, v := range githubAPI {
+		m.AddRoute(v.method, v.path, alienHandle)
+	}
+	return m
+}
+
+func BenchmarkAlien_GithubRouting(b *testing.B) {
+	benchRoutes(b, githubRouting(), githubAPI)
+}
+
+// BenchmarkAlien_GithubRoutingCached is BenchmarkAlien_GithubRouting with the
+// matches of all the routes cached.
+func BenchmarkAlien_GithubRoutingCached(b *testing.B) {
+	m := githubRouting()
+	m.MatchCache(len(githubAPI))
+	benchRoutes(b, m, githubAPI)
+}
+
+func BenchmarkAlien_GithubParamCached(b *testing.B) {
+	m := githubRouting()
+	m.MatchCache(len(githubAPI))
+	req, _ := http.NewRequest("GET", "/repos/julienschmidt/httprouter/stargazers", nil)
+	benchRequest(b, m, req)
+}
+
+func TestGithubRouting_allocs(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping allocation test in short mode")
+	}
+	m := githubRouting()
+	w := new(mockResponseWriter)
+	for _, v := range githubAPI {
+		r, _ := http.NewRequest(v.method, v.path, nil)
+		// the request copy made to set the context is the only allocation.
+		if n := testing.AllocsPerRun(100, func() { m.ServeHTTP(w, r) }); n > 1 {
+			t.Errorf("%s %s: expected at most 1 allocation got %v", v.method, v.path, n)
+		}
+	}
+}
@@ -0,0 +1,10 @@
+module github.com/gernest/alien
+
+go 1.24.0
+
+require golang.org/x/crypto v0.43.0
+
+require (
+	golang.org/x/net v0.45.0 // indirect
+	golang.org/x/text v0.30.0 // indirect
+)
@@ -0,0 +1,165 @@
+// Package health serves liveness and readiness endpoints for alien, which
+// report the status of named checks as JSON.
+//
+//   h := health.New(
+//   	health.Check{Name: "db", Func: db.PingContext},
+//   	health.Check{Name: "disk", Func: checkDisk, Timeout: time.Second, Liveness: true},
+//   )
+//   h.Serve(m, "/healthz")
+//
+// GET /healthz runs every check and responds with 200 OK if they all pass, or
+// 503 Service Unavailable otherwise
+//   {
+//     "status": "fail",
+//     "checks": {
+//       "db": {"status": "fail", "error": "connection refused", "duration": "1.2ms"},
+//       "disk": {"status": "ok", "duration": "15µs"}
+//     }
+//   }
+// GET /healthz/live only runs the checks marked as Liveness, so orchestrators
+// don't restart a process whose dependencies are down.
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/gernest/alien"
+)
+
+// DefaultTimeout is the timeout of checks which don't have their own.
+const DefaultTimeout = 5 * time.Second
+
+var errTimeout = errors.New("check timed out")
+
+// Check is a named probe of a dependency, like pinging a database.
+type Check struct {
+	Name string
+
+	// Func returns an error when the dependency is unhealthy. It should
+	// return when ctx is done, the check fails then anyway.
+	Func func(ctx context.Context) error
+
+	// Timeout after which the check fails, defaults to DefaultTimeout.
+	Timeout time.Duration
+
+	// Liveness makes the check part of the liveness endpoint, checks are only
+	// part of the readiness endpoint otherwise.
+	Liveness bool
+}
+
+// Result is the outcome of a check.
+type Result struct {
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// Report is the response of the health endpoints.
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks,omitempty"`
+}
+
+// Checker runs checks.
+type Checker struct {
+	checks []Check
+}
+
+// New returns a Checker running checks.
+func New(checks ...Check) *Checker {
+	return &Checker{checks: checks}
+}
+
+// Serve registers the readiness endpoint of c with pattern on m, and the
+// liveness endpoint below it at pattern/live.
+func (c *Checker) Serve(m *alien.Mux, pattern string) error {
+	if err := m.Get(pattern, c.Readiness().ServeHTTP); err != nil {
+		return err
+	}
+	return m.Get(path.Join(pattern, "live"), c.Liveness().ServeHTTP)
+}
+
+// Readiness returns a handler running every check.
+func (c *Checker) Readiness() http.Handler {
+	return c.handler(false)
+}
+
+// Liveness returns a handler running the checks marked as Liveness.
+func (c *Checker) Liveness() http.Handler {
+	return c.handler(true)
+}
+
+func (c *Checker) handler(live bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := c.Run(r.Context(), live)
+		code := http.StatusOK
+		if report.Status != "ok" {
+			code = http.StatusServiceUnavailable
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		alien.JSON(w, code, report)
+	})
+}
+
+// Run runs the checks concurrently and reports their results, only the checks
+// marked as Liveness are run if live is true.
+func (c *Checker) Run(ctx context.Context, live bool) Report {
+	report := Report{Status: "ok"}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range c.checks {
+		if live && !check.Liveness {
+			continue
+		}
+		if report.Checks == nil {
+			report.Checks = make(map[string]Result)
+		}
+		wg.Add(1)
+		go func(check Check) {
+			defer wg.Done()
+			start := time.Now()
+			err := run(ctx, check)
+			res := Result{Status: "ok", Duration: time.Since(start).String()}
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				res.Status, res.Error = "fail", err.Error()
+				report.Status = "fail"
+			}
+			report.Checks[check.Name] = res
+		}(check)
+	}
+	wg.Wait()
+	return report
+}
+
+// run calls check.Func with its timeout, and fails when the timeout expires
+// even if the check doesn't return.
+func run(ctx context.Context, check Check) error {
+	timeout := check.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				done <- errors.New("check panicked")
+			}
+		}()
+		done <- check.Func(ctx)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return errTimeout
+	}
+}
@@ -0,0 +1,76 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gernest/alien"
+)
+
+func TestChecker_Serve(t *testing.T) {
+	ok := func(context.Context) error { return nil }
+	down := func(context.Context) error { return errors.New("connection refused") }
+	hang := func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	}
+	m := alien.New()
+	err := New(
+		Check{Name: "db", Func: down},
+		Check{Name: "disk", Func: ok, Liveness: true},
+		Check{Name: "upstream", Func: hang, Timeout: 10 * time.Millisecond},
+	).Serve(m, "/healthz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := New(Check{Name: "db", Func: ok}).Serve(m.Group("/v2"), "/healthz"); err != nil {
+		t.Fatal(err)
+	}
+	sample := []struct {
+		path   string
+		code   int
+		status string
+		checks map[string]Result
+	}{
+		{"/healthz", http.StatusServiceUnavailable, "fail", map[string]Result{
+			"db":       {Status: "fail", Error: "connection refused"},
+			"disk":     {Status: "ok"},
+			"upstream": {Status: "fail", Error: errTimeout.Error()},
+		}},
+		{"/healthz/live", http.StatusOK, "ok", map[string]Result{
+			"disk": {Status: "ok"},
+		}},
+		{"/v2/healthz", http.StatusOK, "ok", map[string]Result{
+			"db": {Status: "ok"},
+		}},
+		{"/v2/healthz/live", http.StatusOK, "ok", nil},
+	}
+	for _, v := range sample {
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, httptest.NewRequest("GET", v.path, nil))
+		if w.Code != v.code {
+			t.Errorf("%s: expected %d got %d", v.path, v.code, w.Code)
+		}
+		var report Report
+		if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+			t.Fatalf("%s: %v", v.path, err)
+		}
+		if report.Status != v.status {
+			t.Errorf("%s: expected status %s got %s", v.path, v.status, report.Status)
+		}
+		if len(report.Checks) != len(v.checks) {
+			t.Errorf("%s: expected %d checks got %v", v.path, len(v.checks), report.Checks)
+		}
+		for name, expect := range v.checks {
+			got := report.Checks[name]
+			if got.Status != expect.Status || got.Error != expect.Error || got.Duration == "" {
+				t.Errorf("%s %s: expected %+v got %+v", v.path, name, expect, got)
+			}
+		}
+	}
+}
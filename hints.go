@@ -0,0 +1,59 @@
+package alien
+
+import (
+	"net/http"
+	"path"
+	"strings"
+)
+
+// preloadTypes maps file extensions to the destination of preload links.
+var preloadTypes = map[string]string{
+	".css":   "style",
+	".js":    "script",
+	".mjs":   "script",
+	".woff":  "font",
+	".woff2": "font",
+	".ttf":   "font",
+	".otf":   "font",
+	".png":   "image",
+	".jpg":   "image",
+	".jpeg":  "image",
+	".gif":   "image",
+	".svg":   "image",
+	".webp":  "image",
+	".avif":  "image",
+}
+
+// EarlyHints sends a 103 Early Hints response with links as Link headers, so
+// the client can preload assets while the final response is being prepared.
+//   alien.EarlyHints(w, "/assets/app.css", "</fonts/inter.woff2>; rel=preload; as=font; crossorigin")
+//   rows, err := slowQuery(r.Context())
+//
+// Links are Link header values, or paths which are preloaded with the
+// destination guessed from their extension, like style for .css files. The
+// links are kept in the header of the final response, as RFC 8297 recommends.
+//
+// Nothing is sent once the response is committed. Middlewares which buffer
+// the response, like Timeout, drop the hints.
+func EarlyHints(w http.ResponseWriter, links ...string) {
+	if len(links) == 0 || Committed(w) {
+		return
+	}
+	h := w.Header()
+	for _, v := range links {
+		if !strings.HasPrefix(v, "<") {
+			link := "<" + v + ">; rel=preload"
+			switch as := preloadTypes[strings.ToLower(path.Ext(v))]; as {
+			case "":
+			case "font":
+				// fonts are always fetched in cors mode.
+				link += "; as=font; crossorigin"
+			default:
+				link += "; as=" + as
+			}
+			v = link
+		}
+		h.Add("Link", v)
+	}
+	w.WriteHeader(http.StatusEarlyHints)
+}
@@ -0,0 +1,72 @@
+package alien
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// hintsRecorder records the informational responses written before the final
+// response.
+type hintsRecorder struct {
+	*httptest.ResponseRecorder
+	hints []http.Header
+}
+
+func (w *hintsRecorder) WriteHeader(code int) {
+	if code == http.StatusEarlyHints {
+		w.hints = append(w.hints, w.Header().Clone())
+		return
+	}
+	w.ResponseRecorder.WriteHeader(code)
+}
+
+func TestEarlyHints(t *testing.T) {
+	m := New()
+	m.Use(func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			EarlyHints(w, "/app.css")
+			h.ServeHTTP(w, r)
+		})
+	})
+	m.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		EarlyHints(w, "/app.js", "/inter.woff2", "/data", "<https://cdn.example.com>; rel=preconnect")
+		w.Write([]byte("hello"))
+		EarlyHints(w, "/late.css")
+	})
+	w := &hintsRecorder{ResponseRecorder: httptest.NewRecorder()}
+	m.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "hello" {
+		t.Fatalf("expected the final response after the hints got %d %s", w.Code, w.Body)
+	}
+	links := []string{
+		"</app.css>; rel=preload; as=style",
+		"</app.js>; rel=preload; as=script",
+		"</inter.woff2>; rel=preload; as=font; crossorigin",
+		"</data>; rel=preload",
+		"<https://cdn.example.com>; rel=preconnect",
+	}
+	if len(w.hints) != 2 {
+		t.Fatalf("expected 2 early hints got %d", len(w.hints))
+	}
+	if got := w.hints[1].Values("Link"); !reflect.DeepEqual(got, links) {
+		t.Errorf("expected %v got %v", links, got)
+	}
+	if got := w.Header().Values("Link"); !reflect.DeepEqual(got, links) {
+		t.Errorf("expected the links to be kept in the final response got %v", got)
+	}
+
+	// buffered responses drop the hints.
+	m = New()
+	m.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		EarlyHints(w, "/app.css")
+		w.Write([]byte("hello"))
+	}, Timeout(time.Second))
+	w = &hintsRecorder{ResponseRecorder: httptest.NewRecorder()}
+	m.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusOK || len(w.hints) != 0 {
+		t.Errorf("expected the hints to be dropped got %d %d", w.Code, len(w.hints))
+	}
+}
@@ -0,0 +1,126 @@
+package alien
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// hooks are the lifecycle hooks of a Mux.
+type hooks struct {
+	start    []func() error
+	shutdown []func(ctx context.Context) error
+	request  []func(r *http.Request)
+	response []func(status int, dur time.Duration, r *http.Request)
+}
+
+// top returns the Mux at the root of the groups and hosts of m.
+func (m *Mux) top() *Mux {
+	for m.parent != nil {
+		m = m.parent
+	}
+	return m
+}
+
+// OnStart registers fn to be called by Run, RunTLS and RunAutoTLS once the
+// listener is open and before requests are served. Hooks are called in the
+// order they were registered, the first error stops the server from starting
+// and is returned by Run.
+//   m.OnStart(func() error {
+//       return cache.Warmup()
+//   })
+func (m *Mux) OnStart(fn func() error) {
+	t := m.top()
+	t.hooks.start = append(t.hooks.start, fn)
+}
+
+// OnShutdown registers fn to be called by Run, RunTLS and RunAutoTLS once the
+// server is shut down, with a context which expires with the drain timeout.
+// Hooks are called in the reverse order they were registered, their errors are
+// returned by Run.
+//   m.OnShutdown(func(ctx context.Context) error {
+//       return db.Close()
+//   })
+func (m *Mux) OnShutdown(fn func(ctx context.Context) error) {
+	t := m.top()
+	t.hooks.shutdown = append(t.hooks.shutdown, fn)
+}
+
+// OnRequest registers fn to be called with every request served by m, before
+// it is routed.
+func (m *Mux) OnRequest(fn func(r *http.Request)) {
+	t := m.top()
+	t.hooks.request = append(t.hooks.request, fn)
+}
+
+// OnResponse registers fn to be called after every request served by m with
+// the status of the response and the time it took to serve it, matched or
+// not. It is a lightweight alternative to a logging or metrics middleware.
+//   m.OnResponse(func(status int, dur time.Duration, r *http.Request) {
+//       log.Println(r.Method, r.URL.Path, status, dur)
+//   })
+// r is the request as received, the matched route is only known by
+// middlewares and handlers.
+func (m *Mux) OnResponse(fn func(status int, dur time.Duration, r *http.Request)) {
+	t := m.top()
+	t.hooks.response = append(t.hooks.response, fn)
+}
+
+// serveHooked serves r calling the request and response hooks of m, and
+// counting or recording it when stats or the recorder are enabled.
+func (m *Mux) serveHooked(w http.ResponseWriter, r *http.Request) {
+	for _, fn := range m.hooks.request {
+		fn(r)
+	}
+	if len(m.hooks.response) == 0 && m.stats == nil && m.recorder == nil {
+		m.serve(w, r)
+		return
+	}
+	if s := m.stats; s != nil {
+		s.requests.Add(1)
+		s.inFlight.Add(1)
+		defer s.inFlight.Add(-1)
+	}
+	start := time.Now()
+	rw := WrapWriter(w)
+	rt := m.serve(rw, r)
+	dur := time.Since(start)
+	status := rw.Status()
+	if status == 0 {
+		status = http.StatusOK
+	}
+	if m.stats != nil {
+		m.stats.record(status)
+		if rt != nil {
+			rt.hits.Add(1)
+		}
+	}
+	if m.recorder != nil {
+		m.recorder.record(r, rt, status, dur)
+	}
+	for _, fn := range m.hooks.response {
+		fn(status, dur, r)
+	}
+}
+
+// start calls the start hooks of m.
+func (m *Mux) start() error {
+	for _, fn := range m.hooks.start {
+		if err := fn(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// shutdown calls the shutdown hooks of m in reverse order.
+func (m *Mux) shutdown(ctx context.Context) error {
+	var errs []error
+	for i := len(m.hooks.shutdown) - 1; i >= 0; i-- {
+		if err := m.hooks.shutdown[i](ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
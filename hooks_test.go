@@ -0,0 +1,101 @@
+package alien
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMux_OnRequest(t *testing.T) {
+	m := New()
+	var seen []string
+	m.OnRequest(func(r *http.Request) {
+		seen = append(seen, "request "+r.URL.Path)
+	})
+	m.Group("/api").OnResponse(func(status int, dur time.Duration, r *http.Request) {
+		if dur <= 0 {
+			t.Errorf("expected a duration got %v", dur)
+		}
+		seen = append(seen, "response "+r.URL.Path+" "+http.StatusText(status))
+	})
+	m.Get("/ok", func(w http.ResponseWriter, r *http.Request) {
+		seen = append(seen, "handler")
+	})
+	m.Get("/created", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})
+	for _, p := range []string{"/ok", "/created", "/missing"} {
+		m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", p, nil))
+	}
+	expect := []string{
+		"request /ok", "handler", "response /ok OK",
+		"request /created", "response /created Created",
+		"request /missing", "response /missing Not Found",
+	}
+	if !reflect.DeepEqual(seen, expect) {
+		t.Errorf("expected %v got %v", expect, seen)
+	}
+}
+
+func TestMux_OnStart(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var calls []string
+	m := New()
+	m.OnStart(func() error {
+		calls = append(calls, "start 1")
+		return nil
+	})
+	m.OnStart(func() error {
+		calls = append(calls, "start 2")
+		return nil
+	})
+	m.OnShutdown(func(ctx context.Context) error {
+		calls = append(calls, "shutdown 1")
+		return nil
+	})
+	errClose := errors.New("close failed")
+	m.OnShutdown(func(ctx context.Context) error {
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("expected the drain deadline")
+		}
+		calls = append(calls, "shutdown 2")
+		return errClose
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = m.Run("", Listener(ln), ShutdownContext(ctx))
+	if !errors.Is(err, errClose) {
+		t.Errorf("expected %v got %v", errClose, err)
+	}
+	expect := []string{"start 1", "start 2", "shutdown 2", "shutdown 1"}
+	if !reflect.DeepEqual(calls, expect) {
+		t.Errorf("expected %v got %v", expect, calls)
+	}
+
+	// a failing start hook stops Run before serving.
+	ln, err = net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	errWarmup := errors.New("warmup failed")
+	m = New()
+	m.OnStart(func() error { return errWarmup })
+	m.OnShutdown(func(ctx context.Context) error {
+		t.Error("expected shutdown hooks not to be called")
+		return nil
+	})
+	if err := m.Run("", Listener(ln)); err != errWarmup {
+		t.Errorf("expected %v got %v", errWarmup, err)
+	}
+	if _, err := ln.Accept(); err == nil {
+		t.Error("expected the listener to be closed")
+	}
+}
@@ -0,0 +1,325 @@
+// Package jwtauth provides a JSON Web Token authentication middleware for alien.
+//
+//   m := alien.New()
+//   m.Use(jwtauth.New(jwtauth.Options{Key: []byte("secret")}))
+//   m.Get("/me", func(w http.ResponseWriter, r *http.Request) {
+//   	claims := jwtauth.ClaimsFrom(r)
+//   	w.Write([]byte(claims.Subject()))
+//   })
+//
+// Tokens signed with HMAC (HS256, HS384, HS512), RSA (RS256, RS384, RS512,
+// PS256, PS384, PS512) and ECDSA (ES256, ES384, ES512) are supported. The
+// algorithm of a token must match the type of the verification key, so a token
+// can't trick the middleware into using a public key as an HMAC secret.
+package jwtauth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	_ "crypto/sha256" // registers SHA256 and SHA224
+	_ "crypto/sha512" // registers SHA384 and SHA512
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var (
+	errNoToken       = errors.New("jwtauth: no token")
+	errMalformed     = errors.New("jwtauth: malformed token")
+	errAlgorithm     = errors.New("jwtauth: unsupported algorithm")
+	errKey           = errors.New("jwtauth: invalid key for algorithm")
+	errSignature     = errors.New("jwtauth: invalid signature")
+	errExpired       = errors.New("jwtauth: token is expired")
+	errNotValidYet   = errors.New("jwtauth: token is not valid yet")
+	errBadLookup     = errors.New("jwtauth: bad token lookup")
+	claimsKey        = &contextKey{"claims"}
+	defaultLookup    = "header:Authorization"
+	hashes           = map[string]crypto.Hash{"256": crypto.SHA256, "384": crypto.SHA384, "512": crypto.SHA512}
+	encoding         = base64.RawURLEncoding
+	invalidChallenge = `Bearer error="invalid_token"`
+)
+
+type contextKey struct {
+	name string
+}
+
+// Claims are the claims of a verified token.
+type Claims map[string]interface{}
+
+// Subject returns the sub claim.
+func (c Claims) Subject() string {
+	s, _ := c["sub"].(string)
+	return s
+}
+
+// Issuer returns the iss claim.
+func (c Claims) Issuer() string {
+	s, _ := c["iss"].(string)
+	return s
+}
+
+// Time returns the value of a numeric date claim like exp, nbf or iat, and false
+// if the claim is missing or not a number.
+func (c Claims) Time(key string) (time.Time, bool) {
+	v, ok := c[key].(float64)
+	if !ok {
+		return time.Time{}, false
+	}
+	sec := int64(v)
+	return time.Unix(sec, int64((v-float64(sec))*1e9)), true
+}
+
+// Options configures the jwt middleware.
+type Options struct {
+	// Key verifies token signatures. It is a []byte secret for HMAC tokens, a
+	// *rsa.PublicKey for RSA tokens or an *ecdsa.PublicKey for ECDSA tokens.
+	Key interface{}
+
+	// KeyFunc returns the verification key for a token given its decoded
+	// header, for instance to pick a key by kid. It takes precedence over Key.
+	KeyFunc func(header map[string]interface{}) (interface{}, error)
+
+	// Lookup is a comma separated list of places to look for the token, tried
+	// in order. Each place is one of header:<name>, cookie:<name> or
+	// query:<name>. Tokens in the Authorization header must have the Bearer
+	// scheme. Defaults to header:Authorization.
+	Lookup string
+
+	// Leeway is the allowed clock skew when checking exp and nbf.
+	Leeway time.Duration
+
+	// Validate is called with the claims of tokens which passed signature and
+	// time checks, returning an error rejects the request.
+	Validate func(Claims) error
+
+	// ErrorHandler writes the response for rejected requests. Defaults to 401
+	// Unauthorized with a Bearer challenge.
+	ErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
+
+	// Skipper returns true for requests which bypass the middleware, see
+	// alien.SkipPaths.
+	Skipper func(r *http.Request) bool
+}
+
+// New returns a middleware which rejects requests without a valid token and
+// stores the claims of valid tokens in the request context. It panics if
+// o.Lookup is invalid.
+func New(o Options) func(http.Handler) http.Handler {
+	lookup, err := parseLookup(o.Lookup)
+	if err != nil {
+		panic(err)
+	}
+	if o.ErrorHandler == nil {
+		o.ErrorHandler = unauthorized
+	}
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if o.Skipper != nil && o.Skipper(r) {
+				h.ServeHTTP(w, r)
+				return
+			}
+			token := ""
+			for _, fn := range lookup {
+				if token = fn(r); token != "" {
+					break
+				}
+			}
+			if token == "" {
+				o.ErrorHandler(w, r, errNoToken)
+				return
+			}
+			claims, err := Parse(token, o)
+			if err != nil {
+				o.ErrorHandler(w, r, err)
+				return
+			}
+			h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), claimsKey, claims)))
+		})
+	}
+}
+
+func unauthorized(w http.ResponseWriter, r *http.Request, err error) {
+	challenge := "Bearer"
+	if err != errNoToken {
+		challenge = invalidChallenge
+	}
+	w.Header().Set("WWW-Authenticate", challenge)
+	http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+}
+
+// ClaimsFrom returns the claims stored in r by the middleware, or nil if there
+// are none.
+func ClaimsFrom(r *http.Request) Claims {
+	return FromContext(r.Context())
+}
+
+// FromContext returns the claims stored in ctx by the middleware, or nil if
+// there are none.
+func FromContext(ctx context.Context) Claims {
+	c, _ := ctx.Value(claimsKey).(Claims)
+	return c
+}
+
+func parseLookup(src string) ([]func(*http.Request) string, error) {
+	if src == "" {
+		src = defaultLookup
+	}
+	var fns []func(*http.Request) string
+	for _, v := range strings.Split(src, ",") {
+		parts := strings.SplitN(strings.TrimSpace(v), ":", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			return nil, errBadLookup
+		}
+		name := strings.TrimSpace(parts[1])
+		switch parts[0] {
+		case "header":
+			fns = append(fns, func(r *http.Request) string {
+				v := r.Header.Get(name)
+				if strings.EqualFold(name, "Authorization") {
+					if len(v) > 7 && strings.EqualFold(v[:7], "Bearer ") {
+						return strings.TrimSpace(v[7:])
+					}
+					return ""
+				}
+				return v
+			})
+		case "cookie":
+			fns = append(fns, func(r *http.Request) string {
+				if c, err := r.Cookie(name); err == nil {
+					return c.Value
+				}
+				return ""
+			})
+		case "query":
+			fns = append(fns, func(r *http.Request) string {
+				return r.URL.Query().Get(name)
+			})
+		default:
+			return nil, errBadLookup
+		}
+	}
+	return fns, nil
+}
+
+// Parse verifies token as configured by o and returns its claims.
+func Parse(token string, o Options) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errMalformed
+	}
+	var header map[string]interface{}
+	if err := decode(parts[0], &header); err != nil {
+		return nil, err
+	}
+	alg, _ := header["alg"].(string)
+	key := o.Key
+	if o.KeyFunc != nil {
+		k, err := o.KeyFunc(header)
+		if err != nil {
+			return nil, err
+		}
+		key = k
+	}
+	sig, err := encoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errMalformed
+	}
+	if err := verify(alg, key, parts[0]+"."+parts[1], sig); err != nil {
+		return nil, err
+	}
+	var claims Claims
+	if err := decode(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if exp, ok := claims.Time("exp"); ok && now.After(exp.Add(o.Leeway)) {
+		return nil, errExpired
+	}
+	if nbf, ok := claims.Time("nbf"); ok && now.Add(o.Leeway).Before(nbf) {
+		return nil, errNotValidYet
+	}
+	if o.Validate != nil {
+		if err := o.Validate(claims); err != nil {
+			return nil, err
+		}
+	}
+	return claims, nil
+}
+
+func decode(src string, v interface{}) error {
+	b, err := encoding.DecodeString(src)
+	if err != nil {
+		return errMalformed
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return errMalformed
+	}
+	return nil
+}
+
+func verify(alg string, key interface{}, signed string, sig []byte) error {
+	if len(alg) != 5 {
+		return errAlgorithm
+	}
+	hash, ok := hashes[alg[2:]]
+	if !ok {
+		return errAlgorithm
+	}
+	switch alg[:2] {
+	case "HS":
+		secret, ok := key.([]byte)
+		if !ok {
+			return errKey
+		}
+		mac := hmac.New(hash.New, secret)
+		mac.Write([]byte(signed))
+		if !hmac.Equal(sig, mac.Sum(nil)) {
+			return errSignature
+		}
+		return nil
+	case "RS", "PS":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errKey
+		}
+		h := hash.New()
+		h.Write([]byte(signed))
+		if alg[0] == 'R' {
+			err := rsa.VerifyPKCS1v15(pub, hash, h.Sum(nil), sig)
+			if err != nil {
+				return errSignature
+			}
+			return nil
+		}
+		opts := &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: hash}
+		if err := rsa.VerifyPSS(pub, hash, h.Sum(nil), sig, opts); err != nil {
+			return errSignature
+		}
+		return nil
+	case "ES":
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return errKey
+		}
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if len(sig) != 2*size {
+			return errSignature
+		}
+		h := hash.New()
+		h.Write([]byte(signed))
+		r := new(big.Int).SetBytes(sig[:size])
+		s := new(big.Int).SetBytes(sig[size:])
+		if !ecdsa.Verify(pub, h.Sum(nil), r, s) {
+			return errSignature
+		}
+		return nil
+	}
+	return errAlgorithm
+}
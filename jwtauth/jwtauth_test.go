@@ -0,0 +1,169 @@
+package jwtauth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gernest/alien"
+)
+
+func sign(t *testing.T, alg string, key interface{}, claims Claims) string {
+	h, _ := json.Marshal(map[string]string{"alg": alg, "typ": "JWT"})
+	c, _ := json.Marshal(claims)
+	signed := encoding.EncodeToString(h) + "." + encoding.EncodeToString(c)
+	hash := hashes[alg[2:]]
+	d := hash.New()
+	d.Write([]byte(signed))
+	var sig []byte
+	var err error
+	switch alg[:2] {
+	case "HS":
+		mac := hmac.New(hash.New, key.([]byte))
+		mac.Write([]byte(signed))
+		sig = mac.Sum(nil)
+	case "RS":
+		sig, err = rsa.SignPKCS1v15(rand.Reader, key.(*rsa.PrivateKey), hash, d.Sum(nil))
+	case "PS":
+		sig, err = rsa.SignPSS(rand.Reader, key.(*rsa.PrivateKey), hash, d.Sum(nil), &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+	case "ES":
+		priv := key.(*ecdsa.PrivateKey)
+		r, s, e := ecdsa.Sign(rand.Reader, priv, d.Sum(nil))
+		size := (priv.Curve.Params().BitSize + 7) / 8
+		sig = make([]byte, 2*size)
+		r.FillBytes(sig[:size])
+		s.FillBytes(sig[size:])
+		err = e
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + encoding.EncodeToString(sig)
+}
+
+func TestParse(t *testing.T) {
+	secret := []byte("secret")
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := Claims{"sub": "gernest", "exp": float64(time.Now().Add(time.Hour).Unix())}
+	sample := []struct {
+		alg             string
+		signKey, verify interface{}
+		err             error
+	}{
+		{"HS256", secret, secret, nil},
+		{"HS512", secret, []byte("other"), errSignature},
+		{"RS256", rsaKey, &rsaKey.PublicKey, nil},
+		{"PS384", rsaKey, &rsaKey.PublicKey, nil},
+		{"ES256", ecKey, &ecKey.PublicKey, nil},
+		{"HS256", secret, &rsaKey.PublicKey, errKey},
+		{"RS256", rsaKey, secret, errKey},
+	}
+	for _, v := range sample {
+		token := sign(t, v.alg, v.signKey, claims)
+		c, err := Parse(token, Options{Key: v.verify})
+		if err != v.err {
+			t.Errorf("%s: expected %v got %v", v.alg, v.err, err)
+			continue
+		}
+		if err == nil && c.Subject() != "gernest" {
+			t.Errorf("%s: expected gernest got %s", v.alg, c.Subject())
+		}
+	}
+
+	expired := sign(t, "HS256", secret, Claims{"exp": float64(time.Now().Add(-time.Minute).Unix())})
+	if _, err := Parse(expired, Options{Key: secret}); err != errExpired {
+		t.Errorf("expected %v got %v", errExpired, err)
+	}
+	if _, err := Parse(expired, Options{Key: secret, Leeway: time.Hour}); err != nil {
+		t.Error(err)
+	}
+	early := sign(t, "HS256", secret, Claims{"nbf": float64(time.Now().Add(time.Hour).Unix())})
+	if _, err := Parse(early, Options{Key: secret}); err != errNotValidYet {
+		t.Errorf("expected %v got %v", errNotValidYet, err)
+	}
+	none := encoding.EncodeToString([]byte(`{"alg":"none"}`)) + "." + encoding.EncodeToString([]byte(`{}`)) + "."
+	if _, err := Parse(none, Options{Key: secret}); err != errAlgorithm {
+		t.Errorf("expected %v got %v", errAlgorithm, err)
+	}
+	if _, err := Parse("hello", Options{Key: secret}); err != errMalformed {
+		t.Errorf("expected %v got %v", errMalformed, err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	secret := []byte("secret")
+	errAdmin := errors.New("not admin")
+	m := alien.New()
+	m.Use(New(Options{
+		Key:    secret,
+		Lookup: "header:Authorization,cookie:jwt,query:token",
+		Validate: func(c Claims) error {
+			if c["admin"] != true {
+				return errAdmin
+			}
+			return nil
+		},
+	}))
+	m.Get("/me", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(ClaimsFrom(r).Subject()))
+	})
+	admin := sign(t, "HS256", secret, Claims{"sub": "gernest", "admin": true})
+	user := sign(t, "HS256", secret, Claims{"sub": "gernest"})
+
+	sample := []struct {
+		setup func(*http.Request)
+		code  int
+	}{
+		{func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+admin) }, http.StatusOK},
+		{func(r *http.Request) { r.AddCookie(&http.Cookie{Name: "jwt", Value: admin}) }, http.StatusOK},
+		{func(r *http.Request) { r.URL.RawQuery = "token=" + admin }, http.StatusOK},
+		{func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+user) }, http.StatusUnauthorized},
+		{func(r *http.Request) { r.Header.Set("Authorization", "Basic "+admin) }, http.StatusUnauthorized},
+		{func(r *http.Request) {}, http.StatusUnauthorized},
+	}
+	for k, v := range sample {
+		req, _ := http.NewRequest("GET", "/me", nil)
+		v.setup(req)
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, req)
+		if w.Code != v.code {
+			t.Errorf("%d: expected %d got %d", k, v.code, w.Code)
+		}
+		if v.code == http.StatusOK && w.Body.String() != "gernest" {
+			t.Errorf("%d: expected gernest got %s", k, w.Body)
+		}
+		if v.code == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("%d: expected a challenge", k)
+		}
+	}
+}
+
+func TestNew_skipper(t *testing.T) {
+	m := alien.New()
+	m.Use(New(Options{Key: []byte("secret"), Skipper: alien.SkipPaths("/healthz")}))
+	h := func(_ http.ResponseWriter, _ *http.Request) {}
+	m.Get("/healthz", h)
+	m.Get("/me", h)
+	for path, code := range map[string]int{"/healthz": http.StatusOK, "/me": http.StatusUnauthorized} {
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != code {
+			t.Errorf("%s: expected %d got %d", path, code, w.Code)
+		}
+	}
+}
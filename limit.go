@@ -0,0 +1,178 @@
+package alien
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+var errBadSize = errors.New("bad size")
+
+// sizeUnits are the multipliers of the size suffixes accepted by BodyLimit.
+var sizeUnits = []struct {
+	suffix string
+	n      int64
+}{
+	{"KB", 1 << 10},
+	{"MB", 1 << 20},
+	{"GB", 1 << 30},
+	{"K", 1 << 10},
+	{"M", 1 << 20},
+	{"G", 1 << 30},
+	{"B", 1},
+}
+
+// parseSize parses human readable sizes like 512, 10KB or 2MB into bytes.
+// Units are powers of 1024.
+func parseSize(s string) (int64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	n := int64(1)
+	for _, u := range sizeUnits {
+		if strings.HasSuffix(s, u.suffix) {
+			s = strings.TrimSpace(strings.TrimSuffix(s, u.suffix))
+			n = u.n
+			break
+		}
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || v < 0 || v > math.MaxInt64/n {
+		return 0, errBadSize
+	}
+	return v * n, nil
+}
+
+// BodyLimit returns a middleware which limits the size of request bodies to
+// limit. The limit is a number of bytes with an optional unit, for instance
+// 512, 10KB or 2MB. It panics if limit is invalid.
+//
+// Requests whose Content-Length exceeds the limit are rejected with 413 Request
+// Entity Too Large without calling the handler, otherwise reading past the
+// limit from the body returns an *http.MaxBytesError and the connection is
+// closed after the response.
+//
+// BodyLimit can be used on the whole Mux, on a group or on a single route
+//   m.Use(alien.BodyLimit("2MB"))
+//   m.Post("/upload", upload, alien.BodyLimit("20MB"))
+func BodyLimit(limit string) func(http.Handler) http.Handler {
+	n, err := parseSize(limit)
+	if err != nil {
+		panic("alien: bad body limit " + limit)
+	}
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > n {
+				http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+				return
+			}
+			if r.Body != nil {
+				// net/http closes the connection once the limit is exceeded
+				// only if it is given its own writer.
+				r.Body = http.MaxBytesReader(innermostWriter(w), r.Body, n)
+			}
+			h.ServeHTTP(w, r)
+		})
+	}
+}
+
+// innermostWriter returns the writer of net/http wrapped by w.
+func innermostWriter(w http.ResponseWriter) http.ResponseWriter {
+	for {
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return w
+		}
+		w = u.Unwrap()
+	}
+}
+
+// inFlight bounds the requests served at once, see MaxInFlight.
+type inFlight struct {
+	slots      chan struct{}
+	queue      int64
+	timeout    time.Duration
+	retryAfter string
+	waiting    atomic.Int64
+}
+
+func newInFlight(n, queue int, timeout time.Duration) *inFlight {
+	if n < 1 || queue < 0 || timeout < 0 {
+		panic("alien: bad max in flight")
+	}
+	retry := time.Second
+	if timeout > retry {
+		retry = timeout
+	}
+	return &inFlight{
+		slots:      make(chan struct{}, n),
+		queue:      int64(queue),
+		timeout:    timeout,
+		retryAfter: strconv.Itoa(int(math.Ceil(retry.Seconds()))),
+	}
+}
+
+// acquire takes a slot, waiting in the queue if there is room. It returns
+// false if the request is shed or the client went away.
+func (l *inFlight) acquire(r *http.Request) bool {
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	default:
+	}
+	if l.waiting.Add(1) > l.queue {
+		l.waiting.Add(-1)
+		return false
+	}
+	defer l.waiting.Add(-1)
+	var expired <-chan time.Time
+	if l.timeout > 0 {
+		t := time.NewTimer(l.timeout)
+		defer t.Stop()
+		expired = t.C
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	case <-expired:
+		return false
+	case <-r.Context().Done():
+		return false
+	}
+}
+
+func (l *inFlight) middleware(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !l.acquire(r) {
+			if r.Context().Err() != nil {
+				// the client is gone.
+				return
+			}
+			w.Header().Set("Retry-After", l.retryAfter)
+			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+			return
+		}
+		defer func() { <-l.slots }()
+		h.ServeHTTP(w, r)
+	})
+}
+
+// MaxInFlight returns a middleware which serves at most n requests at once,
+// to protect the services behind the handlers during spikes. Requests over
+// the limit wait in a queue of up to queue requests for at most timeout, a
+// timeout of zero waits until a request is done or the client goes away.
+// Requests which don't fit in the queue, or wait for too long, are shed with
+// 503 Service Unavailable and a Retry-After header of timeout, or a second
+// if it is shorter. It panics if n is less than one or queue or timeout are
+// negative.
+//
+// The limit is shared by the routes the middleware is used on, a group or a
+// Mux are bounded as a whole
+//   api := m.Group("/api")
+//   api.Use(alien.MaxInFlight(100, 500, 2*time.Second))
+//   m.Post("/reports", report, alien.MaxInFlight(4, 0, 0))
+func MaxInFlight(n, queue int, timeout time.Duration) func(http.Handler) http.Handler {
+	return newInFlight(n, queue, timeout).middleware
+}
@@ -0,0 +1,207 @@
+package alien
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSize(t *testing.T) {
+	sample := []struct {
+		size string
+		n    int64
+	}{
+		{"512", 512},
+		{"512B", 512},
+		{"10KB", 10 << 10},
+		{"2MB", 2 << 20},
+		{"2 mb", 2 << 20},
+		{"1G", 1 << 30},
+	}
+	for _, v := range sample {
+		n, err := parseSize(v.size)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != v.n {
+			t.Errorf("%s: expected %d got %d", v.size, v.n, n)
+		}
+	}
+	for _, v := range []string{"", "MB", "-1KB", "2TB", "9223372036854775807KB", "8589934592G"} {
+		if _, err := parseSize(v); err == nil {
+			t.Errorf("%s: expected error", v)
+		}
+	}
+}
+
+func TestBodyLimit(t *testing.T) {
+	m := New()
+	m.Post("/upload", func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		w.Write([]byte("ok"))
+	}, BodyLimit("10B"))
+
+	sample := []struct {
+		body    string
+		chunked bool
+		code    int
+	}{
+		{"hello", false, http.StatusOK},
+		{"hello world", false, http.StatusRequestEntityTooLarge},
+		{"hello world", true, http.StatusRequestEntityTooLarge},
+	}
+	for _, v := range sample {
+		req, _ := http.NewRequest("POST", "/upload", strings.NewReader(v.body))
+		if v.chunked {
+			req.ContentLength = -1
+		}
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, req)
+		if w.Code != v.code {
+			t.Errorf("%s: expected %d got %d", v.body, v.code, w.Code)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic")
+		}
+	}()
+	BodyLimit("lots")
+}
+
+func TestBodyLimit_chunked(t *testing.T) {
+	m := New()
+	m.Post("/upload", func(w http.ResponseWriter, r *http.Request) {
+		_, err := io.ReadAll(r.Body)
+		var tooLarge *http.MaxBytesError
+		if !errors.As(err, &tooLarge) {
+			t.Errorf("expected %T got %v", tooLarge, err)
+		}
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+	}, BodyLimit("10B"))
+	ts := httptest.NewServer(m)
+	defer ts.Close()
+
+	// the body is sent chunked, it is only rejected once read past the limit.
+	body := io.MultiReader(strings.NewReader(strings.Repeat("hello world", 10)))
+	res, err := http.Post(ts.URL+"/upload", "text/plain", body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected %d got %d", http.StatusRequestEntityTooLarge, res.StatusCode)
+	}
+	if !res.Close {
+		t.Error("expected the connection to be closed")
+	}
+}
+
+func TestMaxInFlight(t *testing.T) {
+	l := newInFlight(1, 1, time.Minute)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	m := New()
+	g := m.Group("/api")
+	g.Use(l.middleware)
+	g.Get("/slow", func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+	})
+	g.Get("/fast", func(w http.ResponseWriter, r *http.Request) {})
+	serve := func(path string) chan *httptest.ResponseRecorder {
+		done := make(chan *httptest.ResponseRecorder, 1)
+		go func() {
+			w := httptest.NewRecorder()
+			m.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+			done <- w
+		}()
+		return done
+	}
+	waiting := func(n int64) {
+		for l.waiting.Load() != n {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	first := serve("/api/slow")
+	<-started
+	second := serve("/api/slow")
+	waiting(1)
+
+	// the queue is full, the limit is shared by the routes of the group.
+	w := <-serve("/api/fast")
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") != "60" {
+		t.Errorf("expected the request to be shed got %d %q", w.Code, w.Header().Get("Retry-After"))
+	}
+
+	release <- struct{}{}
+	<-started
+	if w := <-first; w.Code != http.StatusOK {
+		t.Errorf("expected %d got %d", http.StatusOK, w.Code)
+	}
+	release <- struct{}{}
+	if w := <-second; w.Code != http.StatusOK {
+		t.Errorf("expected the queued request to be served got %d", w.Code)
+	}
+}
+
+func TestMaxInFlight_timeout(t *testing.T) {
+	l := newInFlight(1, 2, 10*time.Millisecond)
+	release := make(chan struct{})
+	h := l.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	go h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	for len(l.slots) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	defer close(release)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") != "1" {
+		t.Errorf("expected the request to time out got %d %q", w.Code, w.Header().Get("Retry-After"))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil).WithContext(ctx))
+	if w.Body.Len() != 0 || w.Header().Get("Retry-After") != "" {
+		t.Error("expected nothing to be written for a client which went away")
+	}
+	if l.waiting.Load() != 0 {
+		t.Errorf("expected the queue to be empty got %d", l.waiting.Load())
+	}
+}
+
+func TestMaxInFlight_panics(t *testing.T) {
+	sample := []struct {
+		n, queue int
+		timeout  time.Duration
+	}{
+		{0, 0, 0},
+		{1, -1, 0},
+		{1, 0, -time.Second},
+	}
+	for _, v := range sample {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected a panic for %v", v)
+				}
+			}()
+			MaxInFlight(v.n, v.queue, v.timeout)
+		}()
+	}
+}
@@ -0,0 +1,123 @@
+// Package logger provides an access log middleware for alien.
+//
+//   m := alien.New()
+//   m.Use(logger.New(os.Stdout, logger.CommonLog))
+//
+// Every request served by the routes of m will be logged in the Apache common
+// log format.
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gernest/alien"
+)
+
+// Entry holds the details of a served request.
+type Entry struct {
+	Time       time.Time     `json:"time"`
+	RemoteAddr string        `json:"remote_addr"`
+	User       string        `json:"user,omitempty"`
+	Method     string        `json:"method"`
+	Path       string        `json:"path"`
+	Proto      string        `json:"proto"`
+	Pattern    string        `json:"pattern,omitempty"`
+	Status     int           `json:"status"`
+	Size       int64         `json:"size"`
+	Latency    time.Duration `json:"latency"`
+	Referer    string        `json:"referer,omitempty"`
+	UserAgent  string        `json:"user_agent,omitempty"`
+}
+
+// Formatter writes e to w.
+type Formatter func(w io.Writer, e *Entry) error
+
+// CommonLog writes e in the Apache common log format.
+func CommonLog(w io.Writer, e *Entry) error {
+	size := "-"
+	if e.Size > 0 {
+		size = fmt.Sprint(e.Size)
+	}
+	_, err := fmt.Fprintf(w, "%s - %s [%s] \"%s %s %s\" %d %s\n",
+		e.RemoteAddr, dash(e.User), e.Time.Format("02/Jan/2006:15:04:05 -0700"),
+		e.Method, e.Path, e.Proto, e.Status, size,
+	)
+	return err
+}
+
+// JSON writes e as a single line json object. The latency is in nanoseconds.
+func JSON(w io.Writer, e *Entry) error {
+	return json.NewEncoder(w).Encode(e)
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// New returns a middleware which logs every request to out using format.
+func New(out io.Writer, format Formatter) func(http.Handler) http.Handler {
+	l := &logger{out: out, format: format}
+	return l.handler
+}
+
+type logger struct {
+	mu     sync.Mutex
+	buf    bytes.Buffer
+	out    io.Writer
+	format Formatter
+}
+
+func (l *logger) handler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rw := alien.WrapWriter(w)
+		h.ServeHTTP(rw, r)
+		e := &Entry{
+			Time:       start,
+			RemoteAddr: r.RemoteAddr,
+			Method:     r.Method,
+			Path:       r.URL.RequestURI(),
+			Proto:      r.Proto,
+			Pattern:    alien.RoutePattern(r),
+			Status:     rw.Status(),
+			Size:       rw.Written(),
+			Latency:    time.Since(start),
+			Referer:    r.Referer(),
+			UserAgent:  r.UserAgent(),
+		}
+		if host, _, err := net.SplitHostPort(e.RemoteAddr); err == nil {
+			e.RemoteAddr = host
+		}
+		if e.Status == 0 {
+			e.Status = http.StatusOK
+		}
+		if u := r.URL.User; u != nil {
+			e.User = u.Username()
+		} else if user, _, ok := r.BasicAuth(); ok {
+			e.User = user
+		}
+		l.write(e)
+	})
+}
+
+// write formats e into a buffer first, so that entries from concurrent requests
+// don't interleave.
+func (l *logger) write(e *Entry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.buf.Reset()
+	if err := l.format(&l.buf, e); err != nil {
+		return
+	}
+	l.out.Write(l.buf.Bytes())
+}
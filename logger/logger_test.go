@@ -0,0 +1,58 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gernest/alien"
+)
+
+func TestCommonLog(t *testing.T) {
+	buf := &bytes.Buffer{}
+	m := alien.New()
+	m.Use(New(buf, CommonLog))
+	m.Get("/users/:id", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("hello"))
+	})
+	req, _ := http.NewRequest("GET", "/users/42?page=1", nil)
+	req.RemoteAddr = "127.0.0.1:1234"
+	req.SetBasicAuth("gernest", "secret")
+	m.ServeHTTP(httptest.NewRecorder(), req)
+
+	line := buf.String()
+	if !strings.HasPrefix(line, "127.0.0.1 - gernest [") {
+		t.Errorf("unexpected prefix %s", line)
+	}
+	suffix := "] \"GET /users/42?page=1 HTTP/1.1\" 201 5\n"
+	if !strings.HasSuffix(line, suffix) {
+		t.Errorf("expected suffix %s got %s", suffix, line)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	buf := &bytes.Buffer{}
+	m := alien.New()
+	m.Use(New(buf, JSON))
+	m.Get("/users/:id", func(_ http.ResponseWriter, _ *http.Request) {})
+	req, _ := http.NewRequest("GET", "/users/42", nil)
+	m.ServeHTTP(httptest.NewRecorder(), req)
+
+	e := &Entry{}
+	if err := json.Unmarshal(buf.Bytes(), e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Status != http.StatusOK {
+		t.Errorf("expected %d got %d", http.StatusOK, e.Status)
+	}
+	if e.Pattern != "/users/:id" {
+		t.Errorf("expected /users/:id got %s", e.Pattern)
+	}
+	if e.Path != "/users/42" {
+		t.Errorf("expected /users/42 got %s", e.Path)
+	}
+}
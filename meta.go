@@ -0,0 +1,43 @@
+package alien
+
+import "net/http"
+
+// Meta returns a Mux which attaches the metadata key with value to the routes
+// registered through it, on top of the metadata of m. Calls can be chained and
+// the latest value of a key wins.
+//   m.Meta("auth", "admin").Meta("doc", "List users").Get("/users", listUsers)
+//
+// Middlewares retrieve the metadata of the matched route with RouteMeta, so
+// authorization, documentation and metrics labels can be driven from the
+// route registration.
+//   func requireRole(h http.Handler) http.Handler {
+//       return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+//           if role, ok := alien.RouteMeta(r, "auth").(string); ok && !hasRole(r, role) {
+//               http.Error(w, "forbidden", http.StatusForbidden)
+//               return
+//           }
+//           h.ServeHTTP(w, r)
+//       })
+//   }
+//
+// The returned Mux shares the prefix, middlewares and settings of m, groups
+// created from it inherit its metadata.
+func (m *Mux) Meta(key string, value interface{}) *Mux {
+	return &Mux{
+		prefix: m.prefix,
+		parent: m,
+		router: m.router,
+		meta:   map[string]interface{}{key: value},
+	}
+}
+
+// RouteMeta returns the metadata key of the route which matched r, or nil if
+// the route has no such metadata or r was not matched by a Mux.
+func RouteMeta(r *http.Request, key string) interface{} {
+	for m := muxOf(r); m != nil; m = m.parent {
+		if v, ok := m.meta[key]; ok {
+			return v
+		}
+	}
+	return nil
+}
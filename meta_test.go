@@ -0,0 +1,46 @@
+package alien
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestMux_Meta(t *testing.T) {
+	m := New()
+	h := func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%v %v", RouteMeta(r, "auth"), RouteMeta(r, "doc"))
+	}
+	admin := m.Meta("auth", "admin").Group("/admin")
+	m.Get("/", h)
+	admin.Meta("doc", "List users").Get("/users", h)
+	admin.Meta("auth", "root").Get("/root", h)
+	sample := []struct {
+		path, body string
+	}{
+		{"/", "<nil> <nil>"},
+		{"/admin/users", "admin List users"},
+		{"/admin/root", "root <nil>"},
+	}
+	for _, v := range sample {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", v.path, nil)
+		m.ServeHTTP(w, req)
+		if w.Body.String() != v.body {
+			t.Errorf("%s: expected %s got %s", v.path, v.body, w.Body.String())
+		}
+	}
+	info, _, err := m.Match("GET", "/admin/users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta := map[string]interface{}{"auth": "admin", "doc": "List users"}
+	if !reflect.DeepEqual(info.Meta, meta) {
+		t.Errorf("expected %v got %v", meta, info.Meta)
+	}
+	if len(m.Routes()) != 3 {
+		t.Errorf("expected 3 routes got %d", len(m.Routes()))
+	}
+}
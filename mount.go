@@ -0,0 +1,34 @@
+package alien
+
+import (
+	"net/http"
+	"path"
+	"strings"
+)
+
+// Mount delegates requests of any method to h for prefix and every path below
+// it. The prefix is stripped from the path of the requests passed to h, so
+// other routers and third party handlers can be mounted without knowing where
+// they live.
+//   m.Mount("/metrics", promhttp.Handler())
+//   m.Mount("/legacy", legacyMux, requireAuth)
+// A request to /legacy/users reaches legacyMux with the path /users, after
+// going through the middlewares of m and requireAuth. The request to the
+// prefix itself has the path /.
+//
+// Handlers which expect the full path, like net/http/pprof, should be
+// registered with Handle instead.
+func (m *Mux) Mount(prefix string, h http.Handler, wares ...func(http.Handler) http.Handler) error {
+	strip := func(w http.ResponseWriter, r *http.Request) {
+		p := "/" + GetParams(r).Get("catch")
+		if p != "/" && strings.HasSuffix(r.URL.Path, "/") {
+			p += "/"
+		}
+		h.ServeHTTP(w, withPath(r, p))
+	}
+	prefix = path.Join("/", prefix)
+	if err := m.Any(prefix, strip, wares...); err != nil {
+		return err
+	}
+	return m.Any(path.Join(prefix, "*"), strip, wares...)
+}
@@ -0,0 +1,47 @@
+package alien
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMux_Mount(t *testing.T) {
+	sub := http.NewServeMux()
+	sub.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(w.Header().Get("X-Mark") + r.Method + " " + r.URL.Path))
+	})
+	m := New()
+	api := m.Group("/api")
+	if err := api.Mount("/legacy", sub, func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Mark", "mw ")
+			h.ServeHTTP(w, r)
+		})
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Handle("GET", "/full/*", sub); err != nil {
+		t.Fatal(err)
+	}
+	sample := []struct {
+		method, path, body string
+	}{
+		{"GET", "/api/legacy", "mw GET /"},
+		{"GET", "/api/legacy/", "mw GET /"},
+		{"POST", "/api/legacy/users", "mw POST /users"},
+		{"DELETE", "/api/legacy/users/42/", "mw DELETE /users/42/"},
+		{"GET", "/full/path", "GET /full/path"},
+	}
+	for _, v := range sample {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(v.method, v.path, nil)
+		m.ServeHTTP(w, req)
+		if w.Body.String() != v.body {
+			t.Errorf("%s %s: expected %s got %s", v.method, v.path, v.body, w.Body.String())
+		}
+	}
+	if err := m.Mount("/api/legacy", sub); err == nil {
+		t.Error("expected an error mounting twice")
+	}
+}
@@ -0,0 +1,169 @@
+package alien
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var errNotAcceptable = errors.New("alien: not acceptable")
+
+// Negotiate returns the offer which best matches the Accept header of r, or an
+// empty string if none of the offers is acceptable. Media ranges are weighted
+// by their q value, and the most specific range matching an offer decides its
+// weight, so with
+//   Accept: text/*;q=0.5, text/html, */*;q=0.1
+// text/html is preferred to text/plain, which is preferred to image/png. Offers
+// of equal weight are picked in the order they are given, and the first offer
+// is returned if r has no Accept header.
+//   switch alien.Negotiate(r, "application/json", "text/html") {
+//   case "text/html":
+//       alien.HTML(w, r, http.StatusOK, "users/index", users)
+//   default:
+//       alien.JSON(w, http.StatusOK, users)
+//   }
+func Negotiate(r *http.Request, offers ...string) string {
+	accept := r.Header.Values("Accept")
+	if len(accept) == 0 {
+		if len(offers) == 0 {
+			return ""
+		}
+		return offers[0]
+	}
+	ranges := parseAccept(strings.Join(accept, ","))
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		if q := acceptQ(ranges, offer); q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// mediaRange is a media range of an Accept header.
+type mediaRange struct {
+	typ, subtype string
+	q            float64
+}
+
+func parseAccept(accept string) []mediaRange {
+	var ranges []mediaRange
+	for _, v := range strings.Split(accept, ",") {
+		params := strings.Split(v, ";")
+		typ, subtype, ok := strings.Cut(strings.TrimSpace(params[0]), "/")
+		if !ok {
+			continue
+		}
+		mr := mediaRange{typ: strings.ToLower(typ), subtype: strings.ToLower(subtype), q: 1}
+		for _, p := range params[1:] {
+			k, val, _ := strings.Cut(strings.TrimSpace(p), "=")
+			if strings.EqualFold(k, "q") {
+				if q, err := strconv.ParseFloat(val, 64); err == nil && q >= 0 && q <= 1 {
+					mr.q = q
+				}
+			}
+		}
+		ranges = append(ranges, mr)
+	}
+	return ranges
+}
+
+// acceptQ returns the weight of offer, the q value of the most specific range
+// matching it.
+func acceptQ(ranges []mediaRange, offer string) float64 {
+	typ, subtype, _ := strings.Cut(strings.ToLower(offer), "/")
+	if i := strings.IndexByte(subtype, ';'); i != -1 {
+		subtype = strings.TrimSpace(subtype[:i])
+	}
+	q, specificity := 0.0, -1
+	for _, mr := range ranges {
+		s := -1
+		switch {
+		case mr.typ == typ && mr.subtype == subtype:
+			s = 2
+		case mr.typ == typ && mr.subtype == "*":
+			s = 1
+		case mr.typ == "*" && mr.subtype == "*":
+			s = 0
+		}
+		if s > specificity {
+			q, specificity = mr.q, s
+		}
+	}
+	return q
+}
+
+// Encoder writes v with status code in the format of the content type it is
+// registered for with SetEncoder.
+type Encoder func(w http.ResponseWriter, r *http.Request, code int, v interface{}) error
+
+// Templater is implemented by values which can be rendered as HTML by Render,
+// Template returns the name of their template.
+type Templater interface {
+	Template() string
+}
+
+type encoder struct {
+	contentType string
+	encode      Encoder
+}
+
+// defaultEncoders are used by Render after the encoders set with SetEncoder.
+var defaultEncoders = []encoder{
+	{"application/json", func(w http.ResponseWriter, _ *http.Request, code int, v interface{}) error {
+		return JSON(w, code, v)
+	}},
+	{"application/xml", func(w http.ResponseWriter, _ *http.Request, code int, v interface{}) error {
+		return XML(w, code, v)
+	}},
+	{"text/html", func(w http.ResponseWriter, r *http.Request, code int, v interface{}) error {
+		return HTML(w, r, code, v.(Templater).Template(), v)
+	}},
+}
+
+// SetEncoder sets the encoder used by Render for contentType for requests
+// served by routes of m, it replaces the encoder of the parents of m or the
+// default one for the same content type.
+//   m.SetEncoder("text/csv", func(w http.ResponseWriter, r *http.Request, code int, v interface{}) error {
+//       ...
+//   })
+func (m *Mux) SetEncoder(contentType string, e Encoder) {
+	m.encoders = append(m.encoders, encoder{contentType, e})
+}
+
+// Render writes v with status code, encoded in the format negotiated with the
+// Accept header of r. JSON, XML, and HTML if v implements Templater, are
+// offered first followed by the content types of the encoders set with
+// SetEncoder. If none is acceptable it responds with 406 Not Acceptable.
+//   alien.Render(w, r, http.StatusOK, user)
+func Render(w http.ResponseWriter, r *http.Request, code int, v interface{}) error {
+	var chain []*Mux
+	for m := muxOf(r); m != nil; m = m.parent {
+		chain = append(chain, m)
+	}
+	set := make(map[string]Encoder)
+	var offers []string
+	_, templater := v.(Templater)
+	for _, e := range defaultEncoders {
+		if e.contentType != "text/html" || templater {
+			set[e.contentType] = e.encode
+			offers = append(offers, e.contentType)
+		}
+	}
+	// encoders of the Mux closest to the route are set last.
+	for i := len(chain) - 1; i >= 0; i-- {
+		for _, e := range chain[i].encoders {
+			if _, ok := set[e.contentType]; !ok {
+				offers = append(offers, e.contentType)
+			}
+			set[e.contentType] = e.encode
+		}
+	}
+	w.Header().Add("Vary", "Accept")
+	if best := Negotiate(r, offers...); best != "" {
+		return set[best](w, r, code, v)
+	}
+	http.Error(w, http.StatusText(http.StatusNotAcceptable), http.StatusNotAcceptable)
+	return errNotAcceptable
+}
@@ -0,0 +1,96 @@
+package alien
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	sample := []struct {
+		accept string
+		offers []string
+		result string
+	}{
+		{"", []string{"application/json", "text/html"}, "application/json"},
+		{"text/html", []string{"application/json", "text/html"}, "text/html"},
+		{"text/*;q=0.5, text/html, */*;q=0.1", []string{"image/png", "text/plain", "text/html"}, "text/html"},
+		{"text/*;q=0.5, text/html, */*;q=0.1", []string{"image/png", "text/plain"}, "text/plain"},
+		{"text/*;q=0.5, text/html, */*;q=0.1", []string{"image/png"}, "image/png"},
+		{"application/json;q=0, */*", []string{"application/json", "application/xml"}, "application/xml"},
+		{"application/xml;q=0.9, application/json;q=0.9", []string{"application/json", "application/xml"}, "application/json"},
+		{"Application/JSON", []string{"application/json"}, "application/json"},
+		{"image/*", []string{"application/json", "text/html"}, ""},
+		{"garbage", []string{"application/json"}, ""},
+	}
+	for _, v := range sample {
+		req, _ := http.NewRequest("GET", "/", nil)
+		if v.accept != "" {
+			req.Header.Set("Accept", v.accept)
+		}
+		if result := Negotiate(req, v.offers...); result != v.result {
+			t.Errorf("%q: expected %q got %q", v.accept, v.result, result)
+		}
+	}
+}
+
+type page struct {
+	Name string `json:"name" xml:"name"`
+}
+
+func (page) Template() string {
+	return "page"
+}
+
+type testRenderer struct{}
+
+func (testRenderer) Render(w io.Writer, name string, data interface{}) error {
+	_, err := io.WriteString(w, "<h1>"+name+" "+data.(page).Name+"</h1>")
+	return err
+}
+
+func TestRender_negotiate(t *testing.T) {
+	m := New()
+	m.SetRenderer(testRenderer{})
+	m.SetEncoder("text/csv", func(w http.ResponseWriter, r *http.Request, code int, v interface{}) error {
+		return Blob(w, code, "text/csv", []byte("name\n"+v.(page).Name+"\n"))
+	})
+	m.Get("/page", func(w http.ResponseWriter, r *http.Request) {
+		Render(w, r, http.StatusOK, page{Name: "alien"})
+	})
+	m.Get("/map", func(w http.ResponseWriter, r *http.Request) {
+		Render(w, r, http.StatusOK, map[string]string{"name": "alien"})
+	})
+	sample := []struct {
+		path, accept, contentType, body string
+		status                          int
+	}{
+		{"/page", "", "application/json; charset=utf-8", "{\"name\":\"alien\"}\n", http.StatusOK},
+		{"/page", "text/html", "text/html; charset=utf-8", "<h1>page alien</h1>", http.StatusOK},
+		{"/page", "application/xml", "application/xml; charset=utf-8", "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<page><name>alien</name></page>", http.StatusOK},
+		{"/page", "text/csv", "text/csv", "name\nalien\n", http.StatusOK},
+		{"/map", "text/html", "text/plain; charset=utf-8", "Not Acceptable\n", http.StatusNotAcceptable},
+	}
+	for _, v := range sample {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", v.path, nil)
+		if v.accept != "" {
+			req.Header.Set("Accept", v.accept)
+		}
+		m.ServeHTTP(w, req)
+		if w.Code != v.status {
+			t.Errorf("%s %s: expected %d got %d", v.path, v.accept, v.status, w.Code)
+		}
+		if ct := w.Header().Get("Content-Type"); ct != v.contentType {
+			t.Errorf("%s %s: expected %s got %s", v.path, v.accept, v.contentType, ct)
+		}
+		if !bytes.Equal(w.Body.Bytes(), []byte(v.body)) {
+			t.Errorf("%s %s: expected %q got %q", v.path, v.accept, v.body, w.Body.String())
+		}
+		if w.Header().Get("Vary") != "Accept" {
+			t.Errorf("expected Vary: Accept got %s", w.Header().Get("Vary"))
+		}
+	}
+}
@@ -0,0 +1,486 @@
+// Package openapi generates OpenAPI 3 documents from the routes of an alien
+// Mux.
+//
+//   s := openapi.New(openapi.Info{Title: "users", Version: "1.0.0"})
+//   s.Route("GET", "/users/:id").
+//   	Summary("Get a user").
+//   	Response(http.StatusOK, "the user", openapi.SchemaOf(User{}))
+//   s.Serve(m, "/openapi.json")
+//
+// Every route of the Mux is documented, named params like :id become path
+// templates like {id} and their regular expression constraints become the
+// pattern of the param schema. Catch all params are documented as a single
+// path param since OpenAPI has no way to describe params spanning segments.
+// Routes with host patterns are documented like any other route.
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gernest/alien"
+)
+
+// Version is the version of the OpenAPI specification documents conform to.
+const Version = "3.0.3"
+
+// methods maps the methods supported by OpenAPI to their path item keys,
+// CONNECT routes are left out of documents.
+var methods = map[string]string{
+	"GET": "get", "HEAD": "head", "POST": "post", "PUT": "put", "PATCH": "patch",
+	"DELETE": "delete", "OPTIONS": "options", "TRACE": "trace",
+}
+
+// Document is an OpenAPI document.
+type Document struct {
+	OpenAPI string              `json:"openapi"`
+	Info    Info                `json:"info"`
+	Paths   map[string]PathItem `json:"paths"`
+}
+
+// Info describes the API.
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// PathItem maps the lower case methods of a path to their operations.
+type PathItem map[string]*Operation
+
+// Operation describes a route.
+type Operation struct {
+	Summary     string               `json:"summary,omitempty"`
+	Description string               `json:"description,omitempty"`
+	OperationID string               `json:"operationId,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	Parameters  []Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+// Parameter describes a path or query param.
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema,omitempty"`
+}
+
+// RequestBody describes the body of requests.
+type RequestBody struct {
+	Description string               `json:"description,omitempty"`
+	Required    bool                 `json:"required,omitempty"`
+	Content     map[string]MediaType `json:"content"`
+}
+
+// Response describes a response.
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType describes the content of a request or response body.
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+// Schema describes a value.
+type Schema struct {
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+}
+
+// Spec builds documents from the routes of a Mux and the metadata registered
+// with Route.
+type Spec struct {
+	Info Info
+
+	mu      sync.Mutex
+	ops     map[string]*Operation
+	serving map[string]bool
+}
+
+// New returns a Spec describing an API with info.
+func New(info Info) *Spec {
+	return &Spec{
+		Info:    info,
+		ops:     make(map[string]*Operation),
+		serving: make(map[string]bool),
+	}
+}
+
+// Route returns a builder for the metadata of the route registered with method
+// and pattern. The pattern is the full pattern of the route, including the
+// prefix of the group it was registered with.
+func (s *Spec) Route(method, pattern string) *Route {
+	key := strings.ToUpper(method) + " " + pattern
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	op, ok := s.ops[key]
+	if !ok {
+		op = &Operation{}
+		s.ops[key] = op
+	}
+	return &Route{spec: s, op: op}
+}
+
+// Route adds metadata to the operation of a route, its methods return the
+// Route so calls can be chained.
+type Route struct {
+	spec *Spec
+	op   *Operation
+}
+
+func (r *Route) update(fn func(op *Operation)) *Route {
+	r.spec.mu.Lock()
+	fn(r.op)
+	r.spec.mu.Unlock()
+	return r
+}
+
+// Summary sets the summary of the operation.
+func (r *Route) Summary(s string) *Route {
+	return r.update(func(op *Operation) { op.Summary = s })
+}
+
+// Description sets the description of the operation.
+func (r *Route) Description(s string) *Route {
+	return r.update(func(op *Operation) { op.Description = s })
+}
+
+// OperationID sets the unique id of the operation.
+func (r *Route) OperationID(id string) *Route {
+	return r.update(func(op *Operation) { op.OperationID = id })
+}
+
+// Tags adds tags to the operation.
+func (r *Route) Tags(tags ...string) *Route {
+	return r.update(func(op *Operation) { op.Tags = append(op.Tags, tags...) })
+}
+
+// Param describes the path param name, by default path params are strings.
+func (r *Route) Param(name, description string, schema *Schema) *Route {
+	return r.update(func(op *Operation) {
+		op.Parameters = append(op.Parameters, Parameter{
+			Name: name, In: "path", Description: description, Required: true, Schema: schema,
+		})
+	})
+}
+
+// Query describes the query param name.
+func (r *Route) Query(name, description string, schema *Schema) *Route {
+	return r.update(func(op *Operation) {
+		op.Parameters = append(op.Parameters, Parameter{
+			Name: name, In: "query", Description: description, Schema: schema,
+		})
+	})
+}
+
+// Request describes the JSON body of requests.
+func (r *Route) Request(description string, schema *Schema) *Route {
+	return r.update(func(op *Operation) {
+		op.RequestBody = &RequestBody{
+			Description: description,
+			Required:    true,
+			Content:     map[string]MediaType{"application/json": {Schema: schema}},
+		}
+	})
+}
+
+// Response describes the response with status code, schema is the schema of
+// the JSON body and can be nil for responses without a body.
+func (r *Route) Response(code int, description string, schema *Schema) *Route {
+	return r.update(func(op *Operation) {
+		if op.Responses == nil {
+			op.Responses = make(map[string]*Response)
+		}
+		res := &Response{Description: description}
+		if schema != nil {
+			res.Content = map[string]MediaType{"application/json": {Schema: schema}}
+		}
+		op.Responses[strconv.Itoa(code)] = res
+	})
+}
+
+// Build returns the document describing the routes of m.
+func (s *Spec) Build(m *alien.Mux) *Document {
+	doc := &Document{OpenAPI: Version, Info: s.Info, Paths: make(map[string]PathItem)}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, info := range m.Routes() {
+		method, ok := methods[info.Method]
+		if !ok || s.serving[info.Pattern] {
+			continue
+		}
+		tpl, params := pathTemplate(info.Pattern)
+		op := &Operation{}
+		if v, ok := s.ops[info.Method+" "+info.Pattern]; ok {
+			*op = *v
+		}
+		op.Parameters = mergeParams(params, op.Parameters)
+		if len(op.Responses) == 0 {
+			op.Responses = map[string]*Response{"default": {Description: "Default response"}}
+		}
+		item, ok := doc.Paths[tpl]
+		if !ok {
+			item = make(PathItem)
+			doc.Paths[tpl] = item
+		}
+		item[method] = op
+	}
+	return doc
+}
+
+// Serve registers a GET route on pattern serving the document describing the
+// routes of m. The document is served as YAML when pattern ends with .yaml or
+// .yml and as JSON otherwise, it is built on every request so it includes
+// routes registered after Serve is called.
+func (s *Spec) Serve(m *alien.Mux, pattern string) error {
+	yaml := strings.HasSuffix(pattern, ".yaml") || strings.HasSuffix(pattern, ".yml")
+	err := m.Get(pattern, func(w http.ResponseWriter, r *http.Request) {
+		b, err := json.Marshal(s.Build(m))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !yaml {
+			alien.Blob(w, http.StatusOK, "application/json", b)
+			return
+		}
+		if b, err = toYAML(b); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		alien.Blob(w, http.StatusOK, "application/yaml", b)
+	})
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.serving[pattern] = true
+	s.mu.Unlock()
+	return nil
+}
+
+// pathTemplate converts the alien pattern to an OpenAPI path template and
+// returns the path params found in it.
+func pathTemplate(pattern string) (string, []Parameter) {
+	var params []Parameter
+	segments := strings.Split(pattern, "/")
+	for i, v := range segments {
+		if v == "" || v[0] != ':' && v[0] != '*' {
+			continue
+		}
+		name, schema := v[1:], &Schema{Type: "string"}
+		if j := strings.IndexByte(name, '('); j != -1 {
+			name, schema.Pattern = name[:j], "^(?:"+name[j+1:len(name)-1]+")$"
+		}
+		if name == "" {
+			name = "catch"
+		}
+		segments[i] = "{" + name + "}"
+		params = append(params, Parameter{Name: name, In: "path", Required: true, Schema: schema})
+	}
+	return strings.Join(segments, "/"), params
+}
+
+// mergeParams returns the path params with the described params replacing the
+// ones with the same name, followed by the remaining described params.
+func mergeParams(path, described []Parameter) []Parameter {
+	result := make([]Parameter, 0, len(path)+len(described))
+	used := make([]bool, len(described))
+	for _, p := range path {
+		for i, d := range described {
+			if d.In == p.In && d.Name == p.Name {
+				if d.Schema == nil {
+					d.Schema = p.Schema
+				}
+				p, used[i] = d, true
+				break
+			}
+		}
+		result = append(result, p)
+	}
+	for i, d := range described {
+		if !used[i] {
+			result = append(result, d)
+		}
+	}
+	if len(result) == 0 {
+		return nil
+	}
+	return result
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// SchemaOf returns the schema of the JSON encoding of v. Struct fields are
+// named after their json tag, and fields without omitempty are required.
+//   openapi.SchemaOf([]User{})
+func SchemaOf(v interface{}) *Schema {
+	return schemaOf(reflect.TypeOf(v), make(map[reflect.Type]bool))
+}
+
+func schemaOf(t reflect.Type, seen map[reflect.Type]bool) *Schema {
+	if t == nil {
+		return &Schema{}
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int32, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: schemaOf(t.Elem(), seen)}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: schemaOf(t.Elem(), seen)}
+	case reflect.Struct:
+		s := &Schema{Type: "object"}
+		if seen[t] {
+			// recursive types are described as plain objects.
+			return s
+		}
+		seen[t] = true
+		structFields(s, t, seen)
+		delete(seen, t)
+		return s
+	}
+	return &Schema{}
+}
+
+// structFields adds the fields of the struct t to the properties of s.
+func structFields(s *Schema, t reflect.Type, seen map[reflect.Type]bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" || f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" && f.Anonymous && f.Type.Kind() == reflect.Struct {
+			structFields(s, f.Type, seen)
+			continue
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if s.Properties == nil {
+			s.Properties = make(map[string]*Schema)
+		}
+		s.Properties[name] = schemaOf(f.Type, seen)
+		if !strings.Contains(","+opts+",", ",omitempty,") {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+// toYAML converts the JSON object b to YAML, keeping the order of keys.
+func toYAML(b []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := yamlObject(&buf, dec, 0); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// yamlObject writes the keys of the object being decoded by dec, up to and
+// including its closing delimiter.
+func yamlObject(buf *bytes.Buffer, dec *json.Decoder, indent int) error {
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		buf.WriteString(strings.Repeat(" ", indent))
+		buf.WriteString(strconv.Quote(key.(string)))
+		buf.WriteByte(':')
+		if err := yamlValue(buf, dec, indent+2); err != nil {
+			return err
+		}
+	}
+	_, err := dec.Token()
+	return err
+}
+
+// yamlValue writes the next value decoded by dec. Strings are written as
+// double quoted scalars, whose escapes are a superset of the ones of Go.
+func yamlValue(buf *bytes.Buffer, dec *json.Decoder, indent int) error {
+	t, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	switch v := t.(type) {
+	case json.Delim:
+		if !dec.More() {
+			if v == '{' {
+				buf.WriteString(" {}\n")
+			} else {
+				buf.WriteString(" []\n")
+			}
+			_, err := dec.Token()
+			return err
+		}
+		buf.WriteByte('\n')
+		if v == '{' {
+			return yamlObject(buf, dec, indent)
+		}
+		for dec.More() {
+			buf.WriteString(strings.Repeat(" ", indent))
+			buf.WriteByte('-')
+			if err := yamlValue(buf, dec, indent+2); err != nil {
+				return err
+			}
+		}
+		_, err := dec.Token()
+		return err
+	case string:
+		buf.WriteString(" " + strconv.Quote(v) + "\n")
+	case json.Number:
+		buf.WriteString(" " + v.String() + "\n")
+	case bool:
+		buf.WriteString(" " + strconv.FormatBool(v) + "\n")
+	default:
+		buf.WriteString(" null\n")
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gernest/alien"
+)
+
+type user struct {
+	ID      int       `json:"id"`
+	Name    string    `json:"name"`
+	Email   string    `json:"email,omitempty"`
+	Friends []*user   `json:"friends,omitempty"`
+	Created time.Time `json:"created"`
+	secret  string
+}
+
+func noop(_ http.ResponseWriter, _ *http.Request) {}
+
+func TestPathTemplate(t *testing.T) {
+	sample := []struct {
+		pattern, tpl string
+		params       []string
+	}{
+		{"/users", "/users", nil},
+		{"/users/:id", "/users/{id}", []string{"id"}},
+		{"/users/:id([0-9]+)/posts/:post", "/users/{id}/posts/{post}", []string{"id", "post"}},
+		{"/files/*path", "/files/{path}", []string{"path"}},
+		{"/static/*", "/static/{catch}", []string{"catch"}},
+	}
+	for _, v := range sample {
+		tpl, params := pathTemplate(v.pattern)
+		if tpl != v.tpl {
+			t.Errorf("expected %s got %s", v.tpl, tpl)
+		}
+		var names []string
+		for _, p := range params {
+			names = append(names, p.Name)
+		}
+		if !reflect.DeepEqual(names, v.params) {
+			t.Errorf("expected %v got %v", v.params, names)
+		}
+	}
+	_, params := pathTemplate("/users/:id([0-9]+)")
+	if params[0].Schema.Pattern != "^(?:[0-9]+)$" {
+		t.Errorf("expected the constraint as pattern got %s", params[0].Schema.Pattern)
+	}
+}
+
+func TestSchemaOf(t *testing.T) {
+	s := SchemaOf(&user{})
+	if s.Type != "object" {
+		t.Fatalf("expected object got %s", s.Type)
+	}
+	if len(s.Properties) != 5 {
+		t.Errorf("expected 5 properties got %d", len(s.Properties))
+	}
+	if !reflect.DeepEqual(s.Required, []string{"id", "name", "created"}) {
+		t.Errorf("expected id, name and created to be required got %v", s.Required)
+	}
+	if s.Properties["created"].Format != "date-time" {
+		t.Errorf("expected date-time got %s", s.Properties["created"].Format)
+	}
+	friends := s.Properties["friends"]
+	if friends.Type != "array" || friends.Items.Type != "object" || friends.Items.Properties != nil {
+		t.Errorf("expected an array of plain objects got %#v", friends)
+	}
+}
+
+func TestSpec_Serve(t *testing.T) {
+	m := alien.New()
+	m.Get("/users/:id", noop)
+	m.Post("/users", noop)
+	m.Connect("/tunnel", noop)
+	s := New(Info{Title: "users", Version: "1.0.0"})
+	s.Route("GET", "/users/:id").
+		Summary("Get a user").
+		Tags("users").
+		Param("id", "the user id", &Schema{Type: "integer"}).
+		Query("fields", "fields to include", &Schema{Type: "string"}).
+		Response(http.StatusOK, "the user", SchemaOf(user{})).
+		Response(http.StatusNotFound, "no such user", nil)
+	s.Route("POST", "/users").Request("the new user", SchemaOf(user{}))
+	if err := s.Serve(m, "/openapi.json"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Serve(m, "/openapi.yaml"); err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/openapi.json", nil)
+	m.ServeHTTP(w, req)
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected application/json got %s", ct)
+	}
+	var doc Document
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.OpenAPI != Version || doc.Info.Title != "users" {
+		t.Errorf("expected the openapi version and info got %s %v", doc.OpenAPI, doc.Info)
+	}
+	if len(doc.Paths) != 2 {
+		t.Errorf("expected 2 paths got %v", doc.Paths)
+	}
+	get := doc.Paths["/users/{id}"]["get"]
+	if get == nil {
+		t.Fatalf("expected get /users/{id} got %v", doc.Paths)
+	}
+	if get.Summary != "Get a user" || len(get.Responses) != 2 {
+		t.Errorf("expected the metadata got %#v", get)
+	}
+	if len(get.Parameters) != 2 || get.Parameters[0].Schema.Type != "integer" || get.Parameters[1].In != "query" {
+		t.Errorf("expected the id and fields params got %#v", get.Parameters)
+	}
+	post := doc.Paths["/users"]["post"]
+	if post == nil || post.RequestBody == nil || post.Responses["default"] == nil {
+		t.Errorf("expected the request body and a default response got %#v", post)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/openapi.yaml", nil)
+	m.ServeHTTP(w, req)
+	if ct := w.Header().Get("Content-Type"); ct != "application/yaml" {
+		t.Errorf("expected application/yaml got %s", ct)
+	}
+	body := w.Body.String()
+	for _, v := range []string{
+		"\"openapi\": \"3.0.3\"\n",
+		"\"paths\":\n  \"/users\":\n    \"post\":\n",
+		"\"tags\":\n        - \"users\"\n",
+		"\"required\": true\n",
+	} {
+		if !strings.Contains(body, v) {
+			t.Errorf("expected %q in\n%s", v, body)
+		}
+	}
+}
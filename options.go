@@ -0,0 +1,137 @@
+package alien
+
+import (
+	"log"
+	"net/http"
+)
+
+// Option configures a Mux created by New. Options are applied in order, they
+// have the same effect as the setter of the same name called on the Mux.
+type Option func(*Mux)
+
+// WithNotFound sets h to handle requests not matching any route, see
+// NotFoundHandler.
+func WithNotFound(h http.Handler) Option {
+	return func(m *Mux) {
+		m.NotFoundHandler(h)
+	}
+}
+
+// WithMethodNotAllowed sets h to handle requests matching routes of other
+// methods only, see MethodNotAllowed.
+func WithMethodNotAllowed(h http.Handler) Option {
+	return func(m *Mux) {
+		m.MethodNotAllowed(h)
+	}
+}
+
+// WithErrorHandler sets h to handle errors returned by handlers, see
+// ErrorHandler.
+func WithErrorHandler(h func(w http.ResponseWriter, r *http.Request, err error)) Option {
+	return func(m *Mux) {
+		m.ErrorHandler(h)
+	}
+}
+
+// WithAutoOptions answers OPTIONS requests automatically, see AutoOptions.
+func WithAutoOptions() Option {
+	return func(m *Mux) {
+		m.AutoOptions(true)
+	}
+}
+
+// WithRedirectTrailingSlash redirects requests to the registered form of paths
+// differing by a trailing slash, see RedirectTrailingSlash.
+func WithRedirectTrailingSlash() Option {
+	return func(m *Mux) {
+		m.RedirectTrailingSlash(true)
+	}
+}
+
+// WithRedirectFixedPath redirects requests to their cleaned path, see
+// RedirectFixedPath.
+func WithRedirectFixedPath() Option {
+	return func(m *Mux) {
+		m.RedirectFixedPath(true)
+	}
+}
+
+// WithCaseInsensitive matches paths ignoring case, see CaseInsensitive.
+func WithCaseInsensitive() Option {
+	return func(m *Mux) {
+		m.CaseInsensitive(true)
+	}
+}
+
+// WithProblemDetails responds to unmatched requests and errors with problem
+// details, see ProblemDetails.
+func WithProblemDetails() Option {
+	return func(m *Mux) {
+		m.ProblemDetails(true)
+	}
+}
+
+// WithWrapUnmatched runs middlewares for unmatched requests, see
+// WrapUnmatched.
+func WithWrapUnmatched() Option {
+	return func(m *Mux) {
+		m.WrapUnmatched(true)
+	}
+}
+
+// WithMaxParams limits the number of params of the patterns of routes to n,
+// registering a route with more fails. It guards against patterns built from
+// untrusted input, like the manifests of the config package.
+func WithMaxParams(n int) Option {
+	return func(m *Mux) {
+		m.maxParams = n
+	}
+}
+
+// WithMaxPathLength rejects requests whose path is longer than n bytes with
+// 414 URI Too Long, before they are routed.
+func WithMaxPathLength(n int) Option {
+	return func(m *Mux) {
+		m.maxPathLength = n
+	}
+}
+
+// WithMaxSegments rejects requests whose path has more than n segments with
+// 414 URI Too Long, before they are routed. Empty segments count, so //// has
+// four.
+func WithMaxSegments(n int) Option {
+	return func(m *Mux) {
+		m.maxSegments = n
+	}
+}
+
+// WithMatchCache caches the routes matched for the size most recently
+// requested paths, see MatchCache.
+func WithMatchCache(size int) Option {
+	return func(m *Mux) {
+		m.MatchCache(size)
+	}
+}
+
+// WithPrefix serves the Mux under prefix, see SetPrefix.
+func WithPrefix(prefix string) Option {
+	return func(m *Mux) {
+		m.SetPrefix(prefix)
+	}
+}
+
+// WithLogger sets l to log the errors which the Mux handles itself, those of
+// handlers responded to with 500 Internal Server Error by the default error
+// handler, and those of the servers started by Run and friends.
+func WithLogger(l *log.Logger) Option {
+	return func(m *Mux) {
+		m.logger = l
+	}
+}
+
+// logf logs with the logger of m, if there is one.
+func (m *Mux) logf(format string, v ...interface{}) {
+	if l := m.top().logger; l != nil {
+		l.Printf(format, v...)
+	}
+}
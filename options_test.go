@@ -0,0 +1,61 @@
+package alien
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNew_options(t *testing.T) {
+	var buf bytes.Buffer
+	m := New(
+		WithNotFound(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "custom not found", http.StatusNotFound)
+		})),
+		WithRedirectTrailingSlash(),
+		WithAutoOptions(),
+		WithMaxParams(2),
+		WithLogger(log.New(&buf, "", 0)),
+	)
+	h := func(_ http.ResponseWriter, _ *http.Request) {}
+	if err := m.Get("/users/:id/posts/:post", h); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Get("/a/:b/:c/*", h); err != errTooManyParams {
+		t.Errorf("expected %v got %v", errTooManyParams, err)
+	}
+	if err := m.Group("/orgs/:org").Get("/users/:id/*", h); err != errTooManyParams {
+		t.Errorf("expected %v got %v", errTooManyParams, err)
+	}
+	m.GetE("/fail", func(w http.ResponseWriter, r *http.Request) error {
+		return errors.New("database is down")
+	})
+
+	sample := []struct {
+		method, path string
+		code         int
+		body         string
+	}{
+		{"GET", "/nowhere", http.StatusNotFound, "custom not found\n"},
+		{"GET", "/users/1/posts/2/", http.StatusMovedPermanently, ""},
+		{"OPTIONS", "/users/1/posts/2", http.StatusNoContent, ""},
+		{"GET", "/fail", http.StatusInternalServerError, "Internal Server Error\n"},
+	}
+	for _, v := range sample {
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, httptest.NewRequest(v.method, v.path, nil))
+		if w.Code != v.code {
+			t.Errorf("%s %s: expected %d got %d", v.method, v.path, v.code, w.Code)
+		}
+		if v.body != "" && w.Body.String() != v.body {
+			t.Errorf("%s %s: expected %q got %q", v.method, v.path, v.body, w.Body)
+		}
+	}
+	if !strings.Contains(buf.String(), "GET /fail: database is down") {
+		t.Errorf("expected the error to be logged got %q", buf.String())
+	}
+}
@@ -0,0 +1,56 @@
+package alien
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// MethodOverride returns a middleware which changes the method of POST requests
+// to the one given by their X-HTTP-Method-Override header or their _method form
+// field, so HTML forms and clients which can only send GET and POST can reach
+// the routes of other methods. Only the methods in allowed can be requested,
+// defaults to PUT, PATCH and DELETE.
+//
+// Middlewares registered with Use run after the route is matched, so
+// MethodOverride must wrap the Mux itself
+//   log.Fatal(http.ListenAndServe(":8090", alien.MethodOverride()(m)))
+//
+// The header takes precedence over the form field. The form is parsed only for
+// urlencoded and multipart bodies, it remains available to handlers.
+func MethodOverride(allowed ...string) func(http.Handler) http.Handler {
+	if len(allowed) == 0 {
+		allowed = []string{http.MethodPut, http.MethodPatch, http.MethodDelete}
+	}
+	methods := make(map[string]bool, len(allowed))
+	for _, v := range allowed {
+		methods[strings.ToUpper(v)] = true
+	}
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodPost {
+				if method := overrideMethod(r); methods[method] {
+					// the caller keeps the request it passed, with the
+					// method it was received with.
+					r2 := new(http.Request)
+					*r2 = *r
+					r2.Method = method
+					r = r2
+				}
+			}
+			h.ServeHTTP(w, r)
+		})
+	}
+}
+
+// overrideMethod returns the method r asks for, in upper case.
+func overrideMethod(r *http.Request) string {
+	if v := r.Header.Get("X-Http-Method-Override"); v != "" {
+		return strings.ToUpper(strings.TrimSpace(v))
+	}
+	ct, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if ct != "application/x-www-form-urlencoded" && ct != "multipart/form-data" {
+		return ""
+	}
+	return strings.ToUpper(strings.TrimSpace(r.PostFormValue("_method")))
+}
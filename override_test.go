@@ -0,0 +1,54 @@
+package alien
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMethodOverride(t *testing.T) {
+	m := New()
+	h := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Method + " " + r.PostFormValue("title")))
+	}
+	m.Post("/posts/:id", h)
+	m.Put("/posts/:id", h)
+	m.Delete("/posts/:id", h)
+	m.Get("/posts/:id", h)
+	m.Patch("/posts/:id", h)
+	form := "application/x-www-form-urlencoded"
+	sample := []struct {
+		method, override, ct, body string
+		allowed                    []string
+		result                     string
+	}{
+		{"POST", "delete", "", "", nil, "DELETE "},
+		{"POST", "", form, "_method=put&title=hello", nil, "PUT hello"},
+		{"POST", "PATCH", form, "_method=put&title=hello", nil, "PATCH hello"},
+		{"POST", "", "application/json", "_method=put", nil, "POST "},
+		// only the allowed methods can be requested.
+		{"POST", "GET", "", "", nil, "POST "},
+		{"POST", "PATCH", "", "", []string{"delete"}, "POST "},
+		{"POST", "DELETE", "", "", []string{"delete"}, "DELETE "},
+		// only POST requests are overridden.
+		{"GET", "DELETE", "", "", nil, "GET "},
+	}
+	for _, v := range sample {
+		req := httptest.NewRequest(v.method, "/posts/1", strings.NewReader(v.body))
+		if v.override != "" {
+			req.Header.Set("X-HTTP-Method-Override", v.override)
+		}
+		if v.ct != "" {
+			req.Header.Set("Content-Type", v.ct)
+		}
+		w := httptest.NewRecorder()
+		MethodOverride(v.allowed...)(m).ServeHTTP(w, req)
+		if w.Body.String() != v.result {
+			t.Errorf("%s %s %s: expected %q got %q", v.method, v.override, v.body, v.result, w.Body)
+		}
+		if req.Method != v.method {
+			t.Errorf("%s %s: expected the request of the caller to keep its method got %s", v.method, v.override, req.Method)
+		}
+	}
+}
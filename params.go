@@ -0,0 +1,196 @@
+package alien
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+var errBadUUID = errors.New("invalid UUID")
+
+// ParamError is returned by the typed accessors of Params when a param is
+// missing or can't be converted to the requested type.
+type ParamError struct {
+	Name  string
+	Value string
+	Err   error
+}
+
+func (e *ParamError) Error() string {
+	if e.Err == errMissingParam {
+		return "alien: missing route param " + e.Name
+	}
+	return "alien: bad route param " + e.Name + "=" + strconv.Quote(e.Value) + ": " + e.Err.Error()
+}
+
+func (e *ParamError) Unwrap() error {
+	return e.Err
+}
+
+// Status returns 400 Bad Request.
+func (e *ParamError) Status() int {
+	return http.StatusBadRequest
+}
+
+// lookup returns the value of key, or a *ParamError if there is none.
+func (p Params) lookup(key string) (string, error) {
+	v, ok := p.lookupValue(key)
+	if !ok {
+		return "", &ParamError{Name: key, Err: errMissingParam}
+	}
+	return v, nil
+}
+
+// Int returns the value of key as an int.
+func (p Params) Int(key string) (int, error) {
+	v, err := p.Int64(key)
+	if err != nil {
+		return 0, err
+	}
+	if int64(int(v)) != v {
+		return 0, &ParamError{Name: key, Value: p.Get(key), Err: strconv.ErrRange}
+	}
+	return int(v), nil
+}
+
+// Int64 returns the value of key as an int64.
+func (p Params) Int64(key string) (int64, error) {
+	v, err := p.lookup(key)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return 0, &ParamError{Name: key, Value: v, Err: err.(*strconv.NumError).Err}
+	}
+	return n, nil
+}
+
+// Float returns the value of key as a float64.
+func (p Params) Float(key string) (float64, error) {
+	v, err := p.lookup(key)
+	if err != nil {
+		return 0, err
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, &ParamError{Name: key, Value: v, Err: err.(*strconv.NumError).Err}
+	}
+	return f, nil
+}
+
+// Bool returns the value of key as a bool. It accepts the values accepted by
+// strconv.ParseBool.
+func (p Params) Bool(key string) (bool, error) {
+	v, err := p.lookup(key)
+	if err != nil {
+		return false, err
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, &ParamError{Name: key, Value: v, Err: err.(*strconv.NumError).Err}
+	}
+	return b, nil
+}
+
+// UUID returns the value of key if it is a UUID in the canonical
+// 8-4-4-4-12 hexadecimal form.
+func (p Params) UUID(key string) (string, error) {
+	v, err := p.lookup(key)
+	if err != nil {
+		return "", err
+	}
+	if !isUUID(v) {
+		return "", &ParamError{Name: key, Value: v, Err: errBadUUID}
+	}
+	return v, nil
+}
+
+func isUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return false
+			}
+			continue
+		}
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
+
+// MustInt is like Int but panics with a *ParamError on failure. Use it with
+// the ParamErrors middleware to respond with 400 Bad Request.
+func (p Params) MustInt(key string) int {
+	v, err := p.Int(key)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// MustInt64 is like Int64 but panics with a *ParamError on failure.
+func (p Params) MustInt64(key string) int64 {
+	v, err := p.Int64(key)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// MustFloat is like Float but panics with a *ParamError on failure.
+func (p Params) MustFloat(key string) float64 {
+	v, err := p.Float(key)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// MustBool is like Bool but panics with a *ParamError on failure.
+func (p Params) MustBool(key string) bool {
+	v, err := p.Bool(key)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// MustUUID is like UUID but panics with a *ParamError on failure.
+func (p Params) MustUUID(key string) string {
+	v, err := p.UUID(key)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// ParamErrors is a middleware which recovers from panics with a *ParamError,
+// raised by the Must accessors of Params, and responds with 400 Bad Request.
+// Other panics are propagated.
+//   m.Use(alien.ParamErrors)
+//   m.Get("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+//       id := alien.GetParams(r).MustInt("id")
+//       ...
+//   })
+func ParamErrors(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if v := recover(); v != nil {
+				e, ok := v.(*ParamError)
+				if !ok {
+					panic(v)
+				}
+				http.Error(w, e.Error(), http.StatusBadRequest)
+			}
+		}()
+		h.ServeHTTP(w, r)
+	})
+}
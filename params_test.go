@@ -0,0 +1,96 @@
+package alien
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestParams_typed(t *testing.T) {
+	p := Params{
+		{"id", "42"},
+		{"big", "9223372036854775807"},
+		{"price", "9.99"},
+		{"ok", "true"},
+		{"uuid", "123e4567-e89b-12d3-a456-426614174000"},
+		{"bad", "x1"},
+	}
+	if v, err := p.Int("id"); err != nil || v != 42 {
+		t.Errorf("expected 42 got %d %v", v, err)
+	}
+	if v, err := p.Int64("big"); err != nil || v != 1<<63-1 {
+		t.Errorf("expected %d got %d %v", int64(1<<63-1), v, err)
+	}
+	if v, err := p.Float("price"); err != nil || v != 9.99 {
+		t.Errorf("expected 9.99 got %f %v", v, err)
+	}
+	if v, err := p.Bool("ok"); err != nil || !v {
+		t.Errorf("expected true got %v %v", v, err)
+	}
+	if v, err := p.UUID("uuid"); err != nil || v != p.Get("uuid") {
+		t.Errorf("expected %s got %s %v", p.Get("uuid"), v, err)
+	}
+
+	sample := []struct {
+		name string
+		get  func(string) error
+		want error
+	}{
+		{"bad", func(k string) error { _, err := p.Int(k); return err }, strconv.ErrSyntax},
+		{"bad", func(k string) error { _, err := p.Float(k); return err }, strconv.ErrSyntax},
+		{"bad", func(k string) error { _, err := p.Bool(k); return err }, strconv.ErrSyntax},
+		{"id", func(k string) error { _, err := p.UUID(k); return err }, errBadUUID},
+		{"missing", func(k string) error { _, err := p.Int64(k); return err }, errMissingParam},
+	}
+	for _, v := range sample {
+		err := v.get(v.name)
+		var pe *ParamError
+		if !errors.As(err, &pe) {
+			t.Fatalf("expected *ParamError got %v", err)
+		}
+		if pe.Name != v.name {
+			t.Errorf("expected %s got %s", v.name, pe.Name)
+		}
+		if !errors.Is(err, v.want) {
+			t.Errorf("expected %v got %v", v.want, err)
+		}
+	}
+}
+
+func TestParamErrors(t *testing.T) {
+	m := New()
+	m.Use(ParamErrors)
+	m.Get("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strconv.Itoa(GetParams(r).MustInt("id") * 2)))
+	})
+	sample := []struct {
+		path, body string
+		code       int
+	}{
+		{"/users/21", "42", http.StatusOK},
+		{"/users/abc", "", http.StatusBadRequest},
+	}
+	for _, v := range sample {
+		req, _ := http.NewRequest("GET", v.path, nil)
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, req)
+		if w.Code != v.code {
+			t.Errorf("%s: expected %d got %d", v.path, v.code, w.Code)
+		}
+		if v.code == http.StatusOK && w.Body.String() != v.body {
+			t.Errorf("%s: expected %s got %s", v.path, v.body, w.Body)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected other panics to propagate")
+		}
+	}()
+	h := ParamErrors(http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {
+		panic("boom")
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), &http.Request{})
+}
@@ -0,0 +1,88 @@
+package alien
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Problem is an error response in the problem details format of RFC 7807. It
+// implements error, so handlers registered with AddRouteE can return it and
+// have it written as is by the default error handler.
+//   return &alien.Problem{
+//       Type:   "https://example.com/probs/out-of-credit",
+//       Title:  "You do not have enough credit.",
+//       Status: http.StatusForbidden,
+//       Detail: "Your current balance is 30, but that costs 50.",
+//   }
+type Problem struct {
+	Type     string `json:"type,omitempty"`
+	Title    string `json:"title,omitempty"`
+	Status   int    `json:"status,omitempty"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+}
+
+// NewProblem returns a Problem with status, the status text as title and
+// detail.
+func NewProblem(status int, detail string) *Problem {
+	return &Problem{Title: http.StatusText(status), Status: status, Detail: detail}
+}
+
+func (p *Problem) Error() string {
+	if p.Detail != "" {
+		return p.Detail
+	}
+	return p.Title
+}
+
+// WriteProblem writes p encoded as application/problem+json with the status of
+// p, or 500 Internal Server Error if p has none.
+func WriteProblem(w http.ResponseWriter, p *Problem) error {
+	code := p.Status
+	if code == 0 {
+		code = http.StatusInternalServerError
+	}
+	b, err := json.Marshal(p)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return err
+	}
+	return Blob(w, code, "application/problem+json", append(b, '\n'))
+}
+
+// ProblemDetails when set to true makes the default not found, method not
+// allowed and error handlers respond with problem+json to requests accepting it
+// over plain text, that is with application/problem+json or application/json
+// but not only */* in their Accept header.
+func (m *Mux) ProblemDetails(ok bool) {
+	m.problemDetails = ok
+}
+
+// acceptsProblem returns true if problem details are enabled for m and r
+// prefers them to plain text.
+func (m *Mux) acceptsProblem(r *http.Request) bool {
+	for g := m; g != nil; g = g.parent {
+		if g.problemDetails {
+			switch Negotiate(r, "text/plain", "application/problem+json", "application/json") {
+			case "application/problem+json", "application/json":
+				return true
+			}
+			return false
+		}
+	}
+	return false
+}
+
+// httpError responds to r with status and msg, as problem details if r
+// accepts them.
+func (m *Mux) httpError(w http.ResponseWriter, r *http.Request, status int, msg string) {
+	if m.acceptsProblem(r) {
+		p := NewProblem(status, msg)
+		if msg == p.Title {
+			p.Detail = ""
+		}
+		WriteProblem(w, p)
+		return
+	}
+	http.Error(w, msg, status)
+}
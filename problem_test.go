@@ -0,0 +1,90 @@
+package alien
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWriteProblem(t *testing.T) {
+	w := httptest.NewRecorder()
+	WriteProblem(w, &Problem{Type: "https://example.com/probs/out-of-credit", Title: "Out of credit", Status: http.StatusForbidden, Instance: "/accounts/1"})
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected %d got %d", http.StatusForbidden, w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Errorf("expected application/problem+json got %s", ct)
+	}
+	e := `{"type":"https://example.com/probs/out-of-credit","title":"Out of credit","status":403,"instance":"/accounts/1"}` + "\n"
+	if w.Body.String() != e {
+		t.Errorf("expected %s got %s", e, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	WriteProblem(w, &Problem{Title: "oops"})
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected %d got %d", http.StatusInternalServerError, w.Code)
+	}
+}
+
+func TestMux_ProblemDetails(t *testing.T) {
+	m := New()
+	m.ProblemDetails(true)
+	m.Get("/users", func(w http.ResponseWriter, r *http.Request) {})
+	m.GetE("/fail", func(w http.ResponseWriter, r *http.Request) error {
+		return errors.New("database is down")
+	})
+	m.GetE("/problem", func(w http.ResponseWriter, r *http.Request) error {
+		return fmt.Errorf("wrapped: %w", NewProblem(http.StatusConflict, "user exists"))
+	})
+	m.GetE("/bind", func(w http.ResponseWriter, r *http.Request) error {
+		return &BindError{Field: "age", Err: errors.New("invalid")}
+	})
+	sample := []struct {
+		method, path, accept string
+		status               int
+		contentType, body    string
+	}{
+		{"GET", "/missing", "application/problem+json", http.StatusNotFound, "application/problem+json",
+			`{"title":"Not Found","status":404,"detail":"` + errRouteNotFound.Error() + `"}` + "\n"},
+		{"GET", "/missing", "application/json", http.StatusNotFound, "application/problem+json", ""},
+		{"GET", "/missing", "*/*", http.StatusNotFound, "text/plain; charset=utf-8", errRouteNotFound.Error() + "\n"},
+		{"GET", "/missing", "", http.StatusNotFound, "text/plain; charset=utf-8", ""},
+		{"POST", "/users", "application/problem+json", http.StatusMethodNotAllowed, "application/problem+json",
+			`{"title":"Method Not Allowed","status":405,"detail":"` + errNotAllowed.Error() + `"}` + "\n"},
+		{"GET", "/fail", "application/problem+json", http.StatusInternalServerError, "application/problem+json",
+			`{"title":"Internal Server Error","status":500}` + "\n"},
+		{"GET", "/fail", "text/html", http.StatusInternalServerError, "text/plain; charset=utf-8", ""},
+		{"GET", "/problem", "", http.StatusConflict, "application/problem+json",
+			`{"title":"Conflict","status":409,"detail":"user exists"}` + "\n"},
+		{"GET", "/bind", "application/problem+json", http.StatusBadRequest, "application/problem+json", ""},
+	}
+	for _, v := range sample {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(v.method, v.path, nil)
+		if v.accept != "" {
+			req.Header.Set("Accept", v.accept)
+		}
+		m.ServeHTTP(w, req)
+		if w.Code != v.status {
+			t.Errorf("%s %s %s: expected %d got %d", v.method, v.path, v.accept, v.status, w.Code)
+		}
+		if ct := w.Header().Get("Content-Type"); ct != v.contentType {
+			t.Errorf("%s %s %s: expected %s got %s", v.method, v.path, v.accept, v.contentType, ct)
+		}
+		if v.body != "" && w.Body.String() != v.body {
+			t.Errorf("%s %s %s: expected %s got %s", v.method, v.path, v.accept, v.body, w.Body.String())
+		}
+	}
+
+	// without ProblemDetails plain text is kept.
+	m = New()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/missing", nil)
+	req.Header.Set("Accept", "application/problem+json")
+	m.ServeHTTP(w, req)
+	if ct := w.Header().Get("Content-Type"); ct != "text/plain; charset=utf-8" {
+		t.Errorf("expected plain text got %s", ct)
+	}
+}
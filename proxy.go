@@ -0,0 +1,116 @@
+package alien
+
+import (
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+)
+
+// ProxyOption configures the reverse proxy registered with Proxy.
+type ProxyOption func(*proxyConfig)
+
+type proxyConfig struct {
+	preserveHost   bool
+	noForwarded    bool
+	strip          []string
+	transport      http.RoundTripper
+	modifyResponse func(*http.Response) error
+	errorHandler   func(http.ResponseWriter, *http.Request, error)
+}
+
+// PreserveHost forwards the Host header of requests, instead of setting it to
+// the host of the target.
+func PreserveHost() ProxyOption {
+	return func(c *proxyConfig) {
+		c.preserveHost = true
+	}
+}
+
+// NoForwardedHeaders stops the proxy from setting the X-Forwarded-For,
+// X-Forwarded-Host and X-Forwarded-Proto headers.
+func NoForwardedHeaders() ProxyOption {
+	return func(c *proxyConfig) {
+		c.noForwarded = true
+	}
+}
+
+// StripHeaders removes the request headers names before forwarding requests,
+// for instance Cookie or Authorization.
+func StripHeaders(names ...string) ProxyOption {
+	return func(c *proxyConfig) {
+		c.strip = append(c.strip, names...)
+	}
+}
+
+// ProxyTransport sets the transport used to send requests to the target,
+// defaults to http.DefaultTransport.
+func ProxyTransport(t http.RoundTripper) ProxyOption {
+	return func(c *proxyConfig) {
+		c.transport = t
+	}
+}
+
+// ModifyResponse sets fn to modify the responses of the target, if it returns
+// an error the error handler is called instead.
+func ModifyResponse(fn func(*http.Response) error) ProxyOption {
+	return func(c *proxyConfig) {
+		c.modifyResponse = fn
+	}
+}
+
+// ProxyErrorHandler sets h to respond when the target can't be reached or
+// ModifyResponse fails. Defaults to responding with 502 Bad Gateway.
+func ProxyErrorHandler(h func(w http.ResponseWriter, r *http.Request, err error)) ProxyOption {
+	return func(c *proxyConfig) {
+		c.errorHandler = h
+	}
+}
+
+// Proxy forwards requests of any method matching pattern to target. If
+// pattern ends with a catch all param, its value is appended to the path of
+// target, otherwise requests are sent to the path of target.
+//   m.Proxy("/api/*", "http://backend:8080/v1")
+// will forward /api/users?page=2 to http://backend:8080/v1/users?page=2
+//
+// The X-Forwarded headers are set, and the Host header is the host of target
+// unless PreserveHost is given.
+func (m *Mux) Proxy(pattern, target string, opts ...ProxyOption) error {
+	u, err := url.Parse(target)
+	if err != nil {
+		return err
+	}
+	c := &proxyConfig{}
+	for _, o := range opts {
+		o(c)
+	}
+	catch := ""
+	if i := strings.LastIndexByte(pattern, '/'); i != -1 && strings.HasPrefix(pattern[i+1:], "*") {
+		if catch = paramName(pattern[i+1:]); catch == "" {
+			catch = "catch"
+		}
+	}
+	p := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.Out.URL.Path = ""
+			pr.Out.URL.RawPath = ""
+			if catch != "" {
+				pr.Out.URL.Path = "/" + GetParams(pr.In).Get(catch)
+			}
+			pr.SetURL(u)
+			if c.preserveHost {
+				pr.Out.Host = pr.In.Host
+			}
+			if !c.noForwarded {
+				pr.SetXForwarded()
+			}
+			for _, name := range c.strip {
+				pr.Out.Header.Del(name)
+			}
+		},
+		Transport:      c.transport,
+		ModifyResponse: c.modifyResponse,
+		ErrorHandler:   c.errorHandler,
+	}
+	return m.Any(pattern, p.ServeHTTP)
+}
@@ -0,0 +1,60 @@
+package alien
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMux_Proxy(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Backend", "yes")
+		io.WriteString(w, strings.Join([]string{
+			r.Method, r.URL.RequestURI(), r.Host, r.Header.Get("X-Forwarded-For"), r.Header.Get("Cookie"),
+		}, " "))
+	}))
+	defer backend.Close()
+	host := strings.TrimPrefix(backend.URL, "http://")
+
+	m := New()
+	if err := m.Proxy("/api/*", backend.URL+"/v1", StripHeaders("Cookie")); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Proxy("/users/:id/*rest", backend.URL, PreserveHost(), NoForwardedHeaders()); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Proxy("/health", backend.URL+"/status", ModifyResponse(func(res *http.Response) error {
+		return errors.New("bad response")
+	}), ProxyErrorHandler(func(w http.ResponseWriter, r *http.Request, err error) {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	})); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Proxy("/bad", "http://[::1"); err == nil {
+		t.Error("expected an error for a bad target")
+	}
+	sample := []struct {
+		method, path, body string
+		status             int
+	}{
+		{"GET", "/api/users?page=2", "GET /v1/users?page=2 " + host + " 192.0.2.1 ", http.StatusOK},
+		{"DELETE", "/api/users/42", "DELETE /v1/users/42 " + host + " 192.0.2.1 ", http.StatusOK},
+		{"POST", "/users/42/posts", "POST /posts example.com  session=secret", http.StatusOK},
+		{"GET", "/health", "bad response\n", http.StatusServiceUnavailable},
+	}
+	for _, v := range sample {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(v.method, v.path, nil)
+		req.Header.Set("Cookie", "session=secret")
+		m.ServeHTTP(w, req)
+		if w.Code != v.status {
+			t.Errorf("%s %s: expected %d got %d", v.method, v.path, v.status, w.Code)
+		}
+		if w.Body.String() != v.body {
+			t.Errorf("%s %s: expected %q got %q", v.method, v.path, v.body, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package alien
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Push initiates an HTTP/2 server push of target, the path of an asset the
+// client will need to render the response, for instance a stylesheet.
+//   alien.Push(w, "/assets/app.css", nil)
+//
+// The writers wrapping w are unwrapped until one supports push. Connections
+// which can't push, like HTTP/1 connections or clients which disabled push, are
+// ignored and nil is returned.
+func Push(w http.ResponseWriter, target string, opts *http.PushOptions) error {
+	for {
+		switch v := w.(type) {
+		case http.Pusher:
+			if err := v.Push(target, opts); err != nil && !errors.Is(err, http.ErrNotSupported) {
+				return err
+			}
+			return nil
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = v.Unwrap()
+		default:
+			return nil
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package alien
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+type errPusher struct {
+	http.ResponseWriter
+	err error
+}
+
+func (w errPusher) Push(string, *http.PushOptions) error {
+	return w.err
+}
+
+func TestPush(t *testing.T) {
+	fw := &fullWriter{ResponseRecorder: httptest.NewRecorder()}
+	if err := Push(unwrapWriter{WrapWriter(fw)}, "/app.css", nil); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fw.pushed, []string{"/app.css"}) {
+		t.Errorf("expected /app.css to be pushed got %v", fw.pushed)
+	}
+
+	errPush := errors.New("push failed")
+	sample := []struct {
+		w   http.ResponseWriter
+		err error
+	}{
+		{httptest.NewRecorder(), nil},
+		{WrapWriter(httptest.NewRecorder()), nil},
+		{errPusher{httptest.NewRecorder(), http.ErrNotSupported}, nil},
+		{errPusher{httptest.NewRecorder(), errPush}, errPush},
+	}
+	for _, v := range sample {
+		if err := Push(v.w, "/app.css", nil); err != v.err {
+			t.Errorf("expected %v got %v", v.err, err)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package alien
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+)
+
+var realIPKey = &contextKey{"real ip"}
+
+// RealIPOption configures the middleware returned by RealIP.
+type RealIPOption func(*realIPConfig)
+
+type realIPConfig struct {
+	rewrite bool
+}
+
+// RewriteRemoteAddr sets r.RemoteAddr to the resolved client address, without
+// a port, so handlers and middlewares reading RemoteAddr see the client instead
+// of the proxy.
+func RewriteRemoteAddr() RealIPOption {
+	return func(c *realIPConfig) {
+		c.rewrite = true
+	}
+}
+
+// RealIP returns a middleware which resolves the address of the client when
+// requests come through proxies, the result is returned by ClientIP. trusted
+// lists the addresses of the proxies, as single addresses or CIDR ranges. It
+// panics if one of them is invalid.
+//   m.Use(alien.RealIP([]string{"10.0.0.0/8", "192.168.1.7"}))
+//
+// The Forwarded, X-Forwarded-For and X-Real-IP headers are only used when the
+// peer is trusted, since anyone else can set them. Forwarded is preferred, see
+// ForwardedHeaders. The addresses of the header are read from right to left and
+// the first one which isn't trusted is the client, so addresses the client put
+// in the header itself are ignored. X-Real-IP is used when neither header is
+// present, and the peer is the client when there is no header.
+func RealIP(trusted []string, opts ...RealIPOption) func(http.Handler) http.Handler {
+	var c realIPConfig
+	for _, o := range opts {
+		o(&c)
+	}
+	prefixes := parseTrusted(trusted)
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := resolveForwarded(r, prefixes).For
+			// WithContext copies r, the caller keeps its RemoteAddr.
+			r = r.WithContext(context.WithValue(r.Context(), realIPKey, ip))
+			if c.rewrite {
+				r.RemoteAddr = ip
+			}
+			h.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ClientIP returns the address of the client which sent r, as resolved by
+// RealIP, or the host of r.RemoteAddr when RealIP wasn't used.
+func ClientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(realIPKey).(string); ok {
+		return ip
+	}
+	return remoteHost(r)
+}
+
+// parseTrusted parses the addresses and ranges given to RealIP.
+func parseTrusted(trusted []string) []netip.Prefix {
+	prefixes := make([]netip.Prefix, 0, len(trusted))
+	for _, s := range trusted {
+		if p, err := netip.ParsePrefix(s); err == nil {
+			prefixes = append(prefixes, p.Masked())
+			continue
+		}
+		a, err := netip.ParseAddr(s)
+		if err != nil {
+			panic("alien: bad trusted proxy " + s)
+		}
+		a = a.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(a, a.BitLen()))
+	}
+	return prefixes
+}
+
+// isTrusted reports whether ip is in one of prefixes.
+func isTrusted(ip string, prefixes []netip.Prefix) bool {
+	a, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	a = a.Unmap()
+	for _, p := range prefixes {
+		if p.Contains(a) {
+			return true
+		}
+	}
+	return false
+}
+
+// remoteHost returns r.RemoteAddr without its port.
+func remoteHost(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
@@ -0,0 +1,81 @@
+package alien
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRealIP(t *testing.T) {
+	sample := []struct {
+		remote, forwarded, real, ip string
+	}{
+		{"203.0.113.9:1234", "", "", "203.0.113.9"},
+		// only trusted peers can set the headers.
+		{"203.0.113.9:1234", "198.51.100.1", "198.51.100.2", "203.0.113.9"},
+		{"10.0.0.1:1234", "198.51.100.1", "", "198.51.100.1"},
+		{"10.0.0.1:1234", "", "198.51.100.2", "198.51.100.2"},
+		{"10.0.0.1:1234", "", "nonsense", "10.0.0.1"},
+		// addresses added by the client before the trusted proxies are ignored.
+		{"10.0.0.1:1234", "6.6.6.6, 198.51.100.1, 192.168.1.7", "", "198.51.100.1"},
+		{"10.0.0.1:1234", "10.0.0.3, 192.168.1.7", "", "10.0.0.3"},
+		{"10.0.0.1:1234", "bogus, 198.51.100.1", "", "198.51.100.1"},
+		{"10.0.0.1:1234", "198.51.100.1, bogus", "", "10.0.0.1"},
+		{"[::ffff:10.0.0.1]:1234", "2001:db8::1", "", "2001:db8::1"},
+	}
+	var got, remote string
+	h := func(w http.ResponseWriter, r *http.Request) {
+		got = ClientIP(r)
+		remote = r.RemoteAddr
+	}
+	for _, rewrite := range []bool{false, true} {
+		var opts []RealIPOption
+		if rewrite {
+			opts = append(opts, RewriteRemoteAddr())
+		}
+		m := New()
+		m.Use(RealIP([]string{"10.0.0.0/8", "192.168.1.7"}, opts...))
+		m.Get("/", h)
+		for _, v := range sample {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = v.remote
+			if v.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", v.forwarded)
+			}
+			if v.real != "" {
+				req.Header.Set("X-Real-IP", v.real)
+			}
+			m.ServeHTTP(httptest.NewRecorder(), req)
+			if got != v.ip {
+				t.Errorf("%s %s: expected %s got %s", v.remote, v.forwarded, v.ip, got)
+			}
+			if rewrite && remote != v.ip {
+				t.Errorf("%s: expected RemoteAddr %s got %s", v.remote, v.ip, remote)
+			}
+			if !rewrite && remote != v.remote {
+				t.Errorf("expected RemoteAddr %s got %s", v.remote, remote)
+			}
+		}
+	}
+
+	// the request of the caller keeps its RemoteAddr.
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set("X-Forwarded-For", "198.51.100.1")
+	RealIP([]string{"10.0.0.0/8"}, RewriteRemoteAddr())(http.HandlerFunc(h)).ServeHTTP(httptest.NewRecorder(), req)
+	if remote != "198.51.100.1" || req.RemoteAddr != "10.0.0.1:1234" {
+		t.Errorf("expected RemoteAddr to be rewritten on a copy got %s and %s", remote, req.RemoteAddr)
+	}
+
+	req = httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "203.0.113.9:1234"
+	if ip := ClientIP(req); ip != "203.0.113.9" {
+		t.Errorf("expected the peer without RealIP got %s", ip)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic for a bad trusted proxy")
+		}
+	}()
+	RealIP([]string{"10.0.0.0/33"})
+}
@@ -0,0 +1,140 @@
+package alien
+
+import (
+	"net/http"
+	"path"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// redactedHeaders are the request headers whose values the recorder hides by
+// default.
+var redactedHeaders = []string{
+	"Authorization", "Proxy-Authorization", "Cookie", "X-Api-Key", "X-Auth-Token",
+}
+
+// RecordedRequest is a request kept by the recorder, see EnableRecorder.
+type RecordedRequest struct {
+	Time     time.Time   `json:"time"`
+	Method   string      `json:"method"`
+	Host     string      `json:"host"`
+	Path     string      `json:"path"`
+	Pattern  string      `json:"pattern,omitempty"`
+	Header   http.Header `json:"header"`
+	Status   int         `json:"status"`
+	Duration string      `json:"duration"`
+}
+
+// recorder keeps the last requests served by a Mux in a ring buffer.
+type recorder struct {
+	on      atomic.Bool
+	pattern string
+	redact  map[string]bool
+
+	mu   sync.Mutex
+	ring []RecordedRequest
+	next int
+	full bool
+}
+
+// record keeps r, which was served by rt with status in dur.
+func (rec *recorder) record(r *http.Request, rt *route, status int, dur time.Duration) {
+	if !rec.on.Load() || rt != nil && rt.path == rec.pattern {
+		return
+	}
+	v := RecordedRequest{
+		Time:     time.Now().Add(-dur),
+		Method:   r.Method,
+		Host:     r.Host,
+		Path:     r.URL.Path,
+		Header:   r.Header.Clone(),
+		Status:   status,
+		Duration: dur.String(),
+	}
+	if rt != nil {
+		v.Pattern = rt.path
+	}
+	for k := range v.Header {
+		if rec.redact[k] {
+			v.Header[k] = []string{"[redacted]"}
+		}
+	}
+	rec.mu.Lock()
+	rec.ring[rec.next] = v
+	rec.next = (rec.next + 1) % len(rec.ring)
+	rec.full = rec.full || rec.next == 0
+	rec.mu.Unlock()
+}
+
+// requests returns the recorded requests, oldest first.
+func (rec *recorder) requests() []RecordedRequest {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if !rec.full {
+		return append([]RecordedRequest(nil), rec.ring[:rec.next]...)
+	}
+	return append(append([]RecordedRequest(nil), rec.ring[rec.next:]...), rec.ring[:rec.next]...)
+}
+
+// EnableRecorder keeps the last n requests served by m, with their matched
+// route, headers, status and latency, and serves them as JSON with pattern to
+// diagnose routing issues. Recording starts right away and can be turned off
+// and on at runtime with Record, or by posting record=false or record=true to
+// pattern.
+//   m.EnableRecorder("/debug/requests", 100)
+//
+// The values of the Authorization, Proxy-Authorization, Cookie, X-Api-Key and
+// X-Auth-Token headers, and of the headers in redact, are replaced by
+// [redacted]. Requests to pattern aren't recorded. Protect pattern like any
+// other route, the requests can contain sensitive data.
+func (m *Mux) EnableRecorder(pattern string, n int, redact ...string) error {
+	if n <= 0 {
+		n = 1
+	}
+	rec := &recorder{
+		pattern: path.Join(m.prefix, pattern),
+		redact:  make(map[string]bool),
+		ring:    make([]RecordedRequest, n),
+	}
+	for _, h := range append(redactedHeaders, redact...) {
+		rec.redact[http.CanonicalHeaderKey(h)] = true
+	}
+	rec.on.Store(true)
+	t := m.top()
+	t.recorder = rec
+	h := func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			on, err := strconv.ParseBool(r.FormValue("record"))
+			if err != nil {
+				m.httpError(w, r, http.StatusBadRequest, "record must be true or false")
+				return
+			}
+			rec.on.Store(on)
+		}
+		JSON(w, http.StatusOK, map[string]interface{}{
+			"recording": rec.on.Load(),
+			"requests":  rec.requests(),
+		})
+	}
+	if err := m.Get(pattern, h); err != nil {
+		return err
+	}
+	return m.Post(pattern, h)
+}
+
+// Record turns the recorder set up with EnableRecorder on or off.
+func (m *Mux) Record(on bool) {
+	if rec := m.top().recorder; rec != nil {
+		rec.on.Store(on)
+	}
+}
+
+// Recorded returns the requests kept by the recorder, oldest first.
+func (m *Mux) Recorded() []RecordedRequest {
+	if rec := m.top().recorder; rec != nil {
+		return rec.requests()
+	}
+	return nil
+}
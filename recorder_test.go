@@ -0,0 +1,68 @@
+package alien
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMux_EnableRecorder(t *testing.T) {
+	m := New()
+	m.Get("/users/:id", func(w http.ResponseWriter, r *http.Request) {})
+	if err := m.EnableRecorder("/debug/requests", 2, "X-Secret"); err != nil {
+		t.Fatal(err)
+	}
+	send := func(method, path string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, req)
+		return w
+	}
+	send("GET", "/users/1", nil)
+	send("GET", "/users/2", http.Header{"Authorization": {"Bearer t"}, "X-Secret": {"s"}, "Accept": {"*/*"}})
+	send("GET", "/missing", nil)
+
+	recorded := m.Recorded()
+	if len(recorded) != 2 {
+		t.Fatalf("expected the last 2 requests got %d", len(recorded))
+	}
+	if v := recorded[0]; v.Path != "/users/2" || v.Pattern != "/users/:id" || v.Status != http.StatusOK || v.Duration == "" {
+		t.Errorf("unexpected record %+v", v)
+	}
+	h := recorded[0].Header
+	if h.Get("Authorization") != "[redacted]" || h.Get("X-Secret") != "[redacted]" || h.Get("Accept") != "*/*" {
+		t.Errorf("expected redacted headers got %v", h)
+	}
+	if v := recorded[1]; v.Path != "/missing" || v.Pattern != "" || v.Status != http.StatusNotFound {
+		t.Errorf("unexpected record %+v", v)
+	}
+
+	w := send("POST", "/debug/requests?record=false", nil)
+	var body struct {
+		Recording bool              `json:"recording"`
+		Requests  []RecordedRequest `json:"requests"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Recording || len(body.Requests) != 2 {
+		t.Errorf("expected recording off with 2 requests got %v %d", body.Recording, len(body.Requests))
+	}
+	send("GET", "/users/3", nil)
+	if v := m.Recorded(); v[1].Path != "/missing" {
+		t.Errorf("expected nothing recorded while off got %s", v[1].Path)
+	}
+	m.Record(true)
+	send("GET", "/users/4", nil)
+	if v := m.Recorded(); v[1].Path != "/users/4" {
+		t.Errorf("expected /users/4 recorded got %s", v[1].Path)
+	}
+	if w := send("POST", "/debug/requests?record=maybe", nil); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "record") {
+		t.Errorf("expected 400 got %d %s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,69 @@
+package alien
+
+import (
+	"net/http"
+	"path"
+	"strings"
+)
+
+// Redirect registers a route with method and pattern from which redirects
+// requests to to with status code. Params of from can be used in to, like
+// with Rewrite, and the query of the request is kept unless to has its own.
+//   m.Redirect("GET", "/old-path", "/new-path", http.StatusMovedPermanently)
+//   m.Redirect("GET", "/users/:id", "/people/:id", http.StatusMovedPermanently)
+//
+// to is not prefixed with the prefix of groups, and it can be an absolute url.
+// An error is returned if to uses a param which from doesn't have.
+func (m *Mux) Redirect(method, from, to string, code int) error {
+	to, query, hasQuery := strings.Cut(to, "?")
+	if err := checkTarget(from, to); err != nil {
+		return err
+	}
+	return m.AddRoute(method, from, func(w http.ResponseWriter, r *http.Request) {
+		target := expandTarget(to, GetParams(r))
+		if hasQuery {
+			target += "?" + query
+		}
+		m.redirectTo(w, r, target, code)
+	})
+}
+
+// RedirectPrefix redirects requests of any method for prefix and every path
+// below it to target, with the rest of the path appended to target.
+//   m.RedirectPrefix("/blog/", "https://blog.example.com/")
+// redirects /blog/2024/hello?ref=home to
+// https://blog.example.com/2024/hello?ref=home.
+//
+// GET and HEAD requests are redirected with 301 Moved Permanently, other
+// methods with 308 Permanent Redirect so clients repeat them with the same
+// method and body.
+func (m *Mux) RedirectPrefix(prefix, target string) error {
+	h := func(w http.ResponseWriter, r *http.Request) {
+		code := http.StatusPermanentRedirect
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			code = http.StatusMovedPermanently
+		}
+		u := target
+		if rest := GetParams(r).Get("catch"); rest != "" {
+			u = strings.TrimSuffix(u, "/") + "/" + strings.TrimPrefix(rest, "/")
+		}
+		m.redirectTo(w, r, u, code)
+	}
+	prefix = path.Join("/", prefix)
+	if err := m.Any(prefix, h); err != nil {
+		return err
+	}
+	return m.Any(path.Join(prefix, "*"), h)
+}
+
+// redirectTo redirects r to target with code, keeping the query of r unless
+// target has its own. Paths are prefixed with the prefix set with SetPrefix.
+func (m *Mux) redirectTo(w http.ResponseWriter, r *http.Request, target string, code int) {
+	if strings.HasPrefix(target, "/") && !strings.HasPrefix(target, "//") {
+		target = m.top().basePath + target
+	}
+	if r.URL.RawQuery != "" && !strings.Contains(target, "?") {
+		target += "?" + r.URL.RawQuery
+	}
+	http.Redirect(w, r, target, code)
+}
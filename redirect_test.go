@@ -0,0 +1,41 @@
+package alien
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMux_Redirect(t *testing.T) {
+	m := New()
+	m.Redirect("GET", "/old-path", "/new-path", http.StatusMovedPermanently)
+	m.Redirect("GET", "/users/:id", "/people/:id", http.StatusFound)
+	m.Redirect("POST", "/files/*path", "https://cdn.example.com/*path?v=2", http.StatusTemporaryRedirect)
+	m.RedirectPrefix("/blog/", "https://blog.example.com/")
+	if err := m.Redirect("GET", "/bad/:id", "/worse/:name", http.StatusFound); err != errMissingParam {
+		t.Errorf("expected %v got %v", errMissingParam, err)
+	}
+	sample := []struct {
+		method, path string
+		code         int
+		location     string
+	}{
+		{"GET", "/old-path", http.StatusMovedPermanently, "/new-path"},
+		{"GET", "/old-path?page=2", http.StatusMovedPermanently, "/new-path?page=2"},
+		{"GET", "/users/42", http.StatusFound, "/people/42"},
+		{"POST", "/files/img/logo.png?x=1", http.StatusTemporaryRedirect, "https://cdn.example.com/img/logo.png?v=2"},
+		{"GET", "/blog", http.StatusMovedPermanently, "https://blog.example.com/"},
+		{"GET", "/blog/2024/hello?ref=home", http.StatusMovedPermanently, "https://blog.example.com/2024/hello?ref=home"},
+		{"POST", "/blog/comments", http.StatusPermanentRedirect, "https://blog.example.com/comments"},
+	}
+	for _, v := range sample {
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, httptest.NewRequest(v.method, v.path, nil))
+		if w.Code != v.code {
+			t.Errorf("%s %s: expected %d got %d", v.method, v.path, v.code, w.Code)
+		}
+		if l := w.Header().Get("Location"); l != v.location {
+			t.Errorf("%s %s: expected %s got %s", v.method, v.path, v.location, l)
+		}
+	}
+}
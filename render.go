@@ -0,0 +1,108 @@
+package alien
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"sync/atomic"
+)
+
+var errNoRenderer = errors.New("alien: no renderer set")
+
+// debug is set by SetDebug.
+var debug atomic.Bool
+
+// Renderer renders templates by name, see the render package for an
+// implementation based on html/template.
+type Renderer interface {
+	Render(w io.Writer, name string, data interface{}) error
+}
+
+// SetDebug turns debug mode on or off. In debug mode JSON and XML responses
+// are indented to make them easy to read.
+func SetDebug(on bool) {
+	debug.Store(on)
+}
+
+// JSON writes v encoded as json with status code. v is encoded before anything
+// is written, so if encoding fails w gets a 500 Internal Server Error instead
+// and the error is returned.
+//   alien.JSON(w, http.StatusOK, map[string]string{"name": "alien"})
+func JSON(w http.ResponseWriter, code int, v interface{}) error {
+	var b []byte
+	var err error
+	if debug.Load() {
+		b, err = json.MarshalIndent(v, "", "  ")
+	} else {
+		b, err = json.Marshal(v)
+	}
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return err
+	}
+	return Blob(w, code, "application/json; charset=utf-8", append(b, '\n'))
+}
+
+// XML writes v encoded as xml, preceded by xml.Header, with status code.
+// Encoding errors are handled like JSON does.
+func XML(w http.ResponseWriter, code int, v interface{}) error {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	if debug.Load() {
+		enc.Indent("", "  ")
+	}
+	if err := enc.Encode(v); err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return err
+	}
+	return Blob(w, code, "application/xml; charset=utf-8", buf.Bytes())
+}
+
+// Text writes s as plain text with status code.
+func Text(w http.ResponseWriter, code int, s string) error {
+	return Blob(w, code, "text/plain; charset=utf-8", []byte(s))
+}
+
+// Blob writes b with status code and contentType as the Content-Type.
+func Blob(w http.ResponseWriter, code int, contentType string, b []byte) error {
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(code)
+	_, err := w.Write(b)
+	return err
+}
+
+// SetRenderer sets the renderer used by HTML for requests served by routes of
+// m. Groups and hosts use the renderer of their parent unless they have their
+// own.
+//   t, err := render.New(render.Options{FS: os.DirFS("views"), Layout: "layouts/base"})
+//   m.SetRenderer(t)
+func (m *Mux) SetRenderer(t Renderer) {
+	m.renderer = t
+}
+
+// HTML renders the template name with data using the renderer of the Mux
+// serving r, and writes it with status code.
+//   alien.HTML(w, r, http.StatusOK, "user/show", user)
+//
+// The template is rendered before anything is written, so if rendering fails,
+// or there is no renderer, w gets a 500 Internal Server Error instead and the
+// error is returned.
+func HTML(w http.ResponseWriter, r *http.Request, code int, name string, data interface{}) error {
+	err := errNoRenderer
+	var buf bytes.Buffer
+	for m := muxOf(r); m != nil; m = m.parent {
+		if m.renderer != nil {
+			err = m.renderer.Render(&buf, name, data)
+			break
+		}
+	}
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return err
+	}
+	return Blob(w, code, "text/html; charset=utf-8", buf.Bytes())
+}
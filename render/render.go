@@ -0,0 +1,151 @@
+// Package render provides an html/template based alien.Renderer supporting
+// layouts and partials.
+//
+//   t, err := render.New(render.Options{
+//       FS:       os.DirFS("views"),
+//       Layout:   "layouts/base",
+//       Partials: "partials",
+//   })
+//   m.SetRenderer(t)
+//   m.Get("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+//       alien.HTML(w, r, http.StatusOK, "user/show", user)
+//   })
+//
+// Templates are named after their path relative to the root of FS without the
+// extension, so user/show is views/user/show.html. Every template is parsed
+// together with the layout and the partials, the layout renders the page with
+//   {{block "content" .}}{{end}}
+// and pages define it with
+//   {{define "content"}}<h1>{{.Name}}</h1>{{end}}
+// while partials are included with {{template "partials/nav" .}}.
+package render
+
+import (
+	"errors"
+	"html/template"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+	"sync"
+)
+
+// Options configures Templates.
+type Options struct {
+	// FS contains the templates, use os.DirFS for templates on disk.
+	FS fs.FS
+
+	// Ext is the extension of template files, defaults to .html.
+	Ext string
+
+	// Layout is the name of the template which wraps pages, if it is empty
+	// pages are rendered on their own.
+	Layout string
+
+	// Partials is the directory of templates which are available to all
+	// pages.
+	Partials string
+
+	// Funcs are made available to all templates.
+	Funcs template.FuncMap
+
+	// Reload parses templates again on every render, so changes to the files
+	// are picked up without a restart. It is meant for development.
+	Reload bool
+}
+
+// Templates renders html templates, it implements alien.Renderer.
+type Templates struct {
+	opts  Options
+	mu    sync.RWMutex
+	pages map[string]*template.Template
+}
+
+// New parses the templates in o.FS.
+func New(o Options) (*Templates, error) {
+	if o.FS == nil {
+		return nil, errors.New("render: missing templates FS")
+	}
+	if o.Ext == "" {
+		o.Ext = ".html"
+	}
+	t := &Templates{opts: o}
+	pages, err := t.load()
+	if err != nil {
+		return nil, err
+	}
+	t.pages = pages
+	return t, nil
+}
+
+// load parses all pages found in the FS.
+func (t *Templates) load() (map[string]*template.Template, error) {
+	var shared, pages []string
+	err := fs.WalkDir(t.opts.FS, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || path.Ext(p) != t.opts.Ext {
+			return err
+		}
+		name := strings.TrimSuffix(p, t.opts.Ext)
+		if name == t.opts.Layout || t.opts.Partials != "" && strings.HasPrefix(p, t.opts.Partials+"/") {
+			shared = append(shared, name)
+		} else {
+			pages = append(pages, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	base := template.New("").Funcs(t.opts.Funcs)
+	for _, name := range shared {
+		if err := t.parse(base, name); err != nil {
+			return nil, err
+		}
+	}
+	result := make(map[string]*template.Template)
+	for _, name := range pages {
+		page, err := base.Clone()
+		if err != nil {
+			return nil, err
+		}
+		if err := t.parse(page, name); err != nil {
+			return nil, err
+		}
+		result[name] = page
+	}
+	return result, nil
+}
+
+// parse adds the template name to tpl.
+func (t *Templates) parse(tpl *template.Template, name string) error {
+	b, err := fs.ReadFile(t.opts.FS, name+t.opts.Ext)
+	if err != nil {
+		return err
+	}
+	_, err = tpl.New(name).Parse(string(b))
+	return err
+}
+
+// Render renders the page name with data to w. Pages are wrapped with the
+// layout if there is one.
+func (t *Templates) Render(w io.Writer, name string, data interface{}) error {
+	if t.opts.Reload {
+		pages, err := t.load()
+		if err != nil {
+			return err
+		}
+		t.mu.Lock()
+		t.pages = pages
+		t.mu.Unlock()
+	}
+	t.mu.RLock()
+	page, ok := t.pages[name]
+	t.mu.RUnlock()
+	if !ok {
+		return errors.New("render: unknown template " + name)
+	}
+	if t.opts.Layout != "" {
+		return page.ExecuteTemplate(w, t.opts.Layout, data)
+	}
+	return page.ExecuteTemplate(w, name, data)
+}
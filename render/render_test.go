@@ -0,0 +1,92 @@
+package render
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/gernest/alien"
+)
+
+func TestTemplates(t *testing.T) {
+	fsys := fstest.MapFS{
+		"layouts/base.html":   {Data: []byte(`<title>{{template "partials/title" .}}</title>{{block "content" .}}{{end}}`)},
+		"partials/title.html": {Data: []byte(`{{.Name | upper}}`)},
+		"user/show.html":      {Data: []byte(`{{define "content"}}<h1>{{.Name}}</h1>{{end}}`)},
+		"home.html":           {Data: []byte(`{{define "content"}}home{{end}}`)},
+	}
+	tpl, err := New(Options{
+		FS:       fsys,
+		Layout:   "layouts/base",
+		Partials: "partials",
+		Funcs:    template.FuncMap{"upper": strings.ToUpper},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := alien.New()
+	m.SetRenderer(tpl)
+	m.Get("/users/:name", func(w http.ResponseWriter, r *http.Request) {
+		alien.HTML(w, r, http.StatusOK, "user/show", map[string]string{"Name": alien.GetParams(r).Get("name")})
+	})
+	m.Group("/home").Get("/", func(w http.ResponseWriter, r *http.Request) {
+		alien.HTML(w, r, http.StatusOK, "home", map[string]string{"Name": "home"})
+	})
+	m.Get("/missing", func(w http.ResponseWriter, r *http.Request) {
+		alien.HTML(w, r, http.StatusOK, "missing", nil)
+	})
+
+	sample := []struct {
+		path, body string
+		code       int
+	}{
+		{"/users/<alien>", "<title>&lt;ALIEN&gt;</title><h1>&lt;alien&gt;</h1>", http.StatusOK},
+		{"/home", "<title>HOME</title>home", http.StatusOK},
+		{"/missing", "", http.StatusInternalServerError},
+	}
+	for _, v := range sample {
+		req, _ := http.NewRequest("GET", v.path, nil)
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, req)
+		if w.Code != v.code {
+			t.Errorf("%s: expected %d got %d", v.path, v.code, w.Code)
+		}
+		if v.code != http.StatusOK {
+			continue
+		}
+		if w.Body.String() != v.body {
+			t.Errorf("%s: expected %s got %s", v.path, v.body, w.Body)
+		}
+		if ct := w.Header().Get("Content-Type"); ct != "text/html; charset=utf-8" {
+			t.Errorf("expected text/html; charset=utf-8 got %s", ct)
+		}
+	}
+}
+
+func TestTemplates_reload(t *testing.T) {
+	fsys := fstest.MapFS{
+		"home.html": {Data: []byte(`v1`)},
+	}
+	for _, reload := range []bool{false, true} {
+		fsys["home.html"] = &fstest.MapFile{Data: []byte("v1")}
+		tpl, err := New(Options{FS: fsys, Reload: reload})
+		if err != nil {
+			t.Fatal(err)
+		}
+		fsys["home.html"] = &fstest.MapFile{Data: []byte("v2")}
+		var b strings.Builder
+		if err := tpl.Render(&b, "home", nil); err != nil {
+			t.Fatal(err)
+		}
+		expect := "v1"
+		if reload {
+			expect = "v2"
+		}
+		if b.String() != expect {
+			t.Errorf("expected %s got %s", expect, b.String())
+		}
+	}
+}
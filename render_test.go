@@ -0,0 +1,67 @@
+package alien
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	type user struct {
+		Name string `json:"name" xml:"name"`
+	}
+	sample := []struct {
+		render      func(w http.ResponseWriter) error
+		code        int
+		contentType string
+		body        string
+		debug       bool
+	}{
+		{
+			func(w http.ResponseWriter) error { return JSON(w, http.StatusCreated, user{"alien"}) },
+			http.StatusCreated, "application/json; charset=utf-8", "{\"name\":\"alien\"}\n", false,
+		},
+		{
+			func(w http.ResponseWriter) error { return JSON(w, http.StatusOK, user{"alien"}) },
+			http.StatusOK, "application/json; charset=utf-8", "{\n  \"name\": \"alien\"\n}\n", true,
+		},
+		{
+			func(w http.ResponseWriter) error { return XML(w, http.StatusOK, user{"alien"}) },
+			http.StatusOK, "application/xml; charset=utf-8",
+			"<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<user><name>alien</name></user>", false,
+		},
+		{
+			func(w http.ResponseWriter) error { return Text(w, http.StatusAccepted, "alien") },
+			http.StatusAccepted, "text/plain; charset=utf-8", "alien", false,
+		},
+		{
+			func(w http.ResponseWriter) error { return Blob(w, http.StatusOK, "image/png", []byte("png")) },
+			http.StatusOK, "image/png", "png", false,
+		},
+	}
+	for _, v := range sample {
+		SetDebug(v.debug)
+		w := httptest.NewRecorder()
+		if err := v.render(w); err != nil {
+			t.Fatal(err)
+		}
+		if w.Code != v.code {
+			t.Errorf("expected %d got %d", v.code, w.Code)
+		}
+		if ct := w.Header().Get("Content-Type"); ct != v.contentType {
+			t.Errorf("expected %s got %s", v.contentType, ct)
+		}
+		if w.Body.String() != v.body {
+			t.Errorf("expected %q got %q", v.body, w.Body)
+		}
+	}
+	SetDebug(false)
+
+	w := httptest.NewRecorder()
+	if err := JSON(w, http.StatusOK, make(chan int)); err == nil {
+		t.Error("expected an error")
+	}
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected %d got %d", http.StatusInternalServerError, w.Code)
+	}
+}
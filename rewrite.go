@@ -0,0 +1,124 @@
+package alien
+
+import (
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// Rewrite rewrites the path of requests matching the patterns which are the
+// keys of rules to the path they map to, before the request is routed. The
+// values of params and catch alls are substituted in the new path, in order as
+// $1 to $9, or by name as in route patterns.
+//   m.Rewrite(map[string]string{
+//       "/api/v1/*":          "/api/v2/$1",
+//       "/legacy/users/:id":  "/users/:id",
+//   })
+// routes /api/v1/users/42 as /api/v2/users/42, handlers see the new path in
+// r.URL.Path.
+//
+// Patterns are matched like routes, the most specific one wins, and are
+// relative to the prefix of m while the new paths are not. Rules of a host only
+// apply to requests for that host. An error is returned, and none of the rules
+// is added, if a pattern is invalid or conflicts with another one, or if a new
+// path uses a param its pattern doesn't have.
+func (m *Mux) Rewrite(rules map[string]string) error {
+	routes := make([]*route, 0, len(rules))
+	for from, to := range rules {
+		if m.prefix != "" {
+			from = path.Join(m.prefix, from)
+		}
+		rt, err := newRoute(from, m, nil)
+		if err != nil {
+			return err
+		}
+		if err := checkTarget(from, to); err != nil {
+			return err
+		}
+		rt.version = ""
+		rt.rewrite = to
+		routes = append(routes, rt)
+	}
+	return m.router.update(func(t *trees) error {
+		n := &node{typ: nodeRoot}
+		if t.rewrites != nil {
+			n = t.rewrites.clone()
+		}
+		for _, rt := range routes {
+			if err := n.insert(rt.path, rt); err != nil {
+				return err
+			}
+		}
+		t.rewrites = n
+		return nil
+	})
+}
+
+// rewrite returns the path p is rewritten to by the rules of r, if any.
+func (r *router) rewrite(p string) (string, bool) {
+	root := r.load().rewrites
+	if root == nil {
+		return "", false
+	}
+	rt, err := root.find(p, "")
+	if err != nil {
+		return "", false
+	}
+	params, _ := parseParams(p, rt.path)
+	return expandTarget(rt.rewrite, params), true
+}
+
+// checkTarget returns an error if target, the path requests matching pattern
+// are sent to, uses params which pattern doesn't have.
+func checkTarget(pattern, target string) error {
+	names := paramNames(pattern)
+	kv := make([]string, 0, 2*len(names))
+	for _, name := range names {
+		kv = append(kv, name, "")
+	}
+	if _, err := buildURL(target, kv...); err != nil {
+		return err
+	}
+	for i := 0; i+1 < len(target); i++ {
+		if target[i] == '$' && target[i+1] >= '1' && target[i+1] <= '9' && int(target[i+1]-'0') > len(names) {
+			return errMissingParam
+		}
+	}
+	return nil
+}
+
+// expandTarget substitutes the values of params in target, see Rewrite.
+func expandTarget(target string, params Params) string {
+	kv := make([]string, 0, 2*len(params))
+	for _, p := range params {
+		kv = append(kv, p.Key, p.Value)
+	}
+	target, _ = buildURL(target, kv...)
+	if strings.IndexByte(target, '$') == -1 {
+		return target
+	}
+	var b strings.Builder
+	for i := 0; i < len(target); i++ {
+		if target[i] == '$' && i+1 < len(target) && target[i+1] >= '1' && target[i+1] <= '9' {
+			if n := int(target[i+1] - '1'); n < len(params) {
+				b.WriteString(strings.TrimPrefix(params[n].Value, "/"))
+			}
+			i++
+			continue
+		}
+		b.WriteByte(target[i])
+	}
+	return b.String()
+}
+
+// withPath returns a shallow copy of r with the path p.
+func withPath(r *http.Request, p string) *http.Request {
+	r2 := new(http.Request)
+	*r2 = *r
+	r2.URL = new(url.URL)
+	*r2.URL = *r.URL
+	r2.URL.Path = p
+	r2.URL.RawPath = ""
+	return r2
+}
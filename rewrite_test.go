@@ -0,0 +1,65 @@
+package alien
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMux_Rewrite(t *testing.T) {
+	m := New()
+	h := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.Path + " " + GetParams(r).Get("id")))
+	}
+	m.Get("/api/v2/*", h)
+	m.Get("/users/:id", h)
+	m.Get("/orgs/:org/members/:id", h)
+	err := m.Rewrite(map[string]string{
+		"/api/v1/*":                 "/api/v2/$1",
+		"/legacy/users/:id([0-9]+)": "/users/:id",
+		"/teams/:team/people/:id":   "/orgs/$1/members/$2",
+		"/status":                   "/users/health",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := m.Group("/shop")
+	if err := g.Rewrite(map[string]string{"/items/:id": "/users/:id"}); err != nil {
+		t.Fatal(err)
+	}
+	sample := []struct {
+		path   string
+		code   int
+		result string
+	}{
+		{"/api/v1/users/42", http.StatusOK, "/api/v2/users/42 "},
+		{"/status", http.StatusOK, "/users/health health"},
+		{"/legacy/users/7", http.StatusOK, "/users/7 7"},
+		{"/legacy/users/gernest", http.StatusNotFound, ""},
+		{"/teams/alien/people/9", http.StatusOK, "/orgs/alien/members/9 9"},
+		{"/shop/items/3", http.StatusOK, "/users/3 3"},
+		{"/users/1", http.StatusOK, "/users/1 1"},
+	}
+	for _, v := range sample {
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, httptest.NewRequest("GET", v.path, nil))
+		if w.Code != v.code {
+			t.Errorf("%s: expected %d got %d", v.path, v.code, w.Code)
+		}
+		if v.result != "" && w.Body.String() != v.result {
+			t.Errorf("%s: expected %q got %q", v.path, v.result, w.Body)
+		}
+	}
+
+	errs := []map[string]string{
+		{"/old/:id": "/new/:name"},
+		{"/old/:id": "/new/$2"},
+		{"/a/:id": "/b", "/a/*": "/c"},
+		{"old": "/new"},
+	}
+	for _, rules := range errs {
+		if err := New().Rewrite(rules); err == nil {
+			t.Errorf("%v: expected an error", rules)
+		}
+	}
+}
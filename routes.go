@@ -0,0 +1,249 @@
+package alien
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// RouteInfo describes a registered route.
+type RouteInfo struct {
+	Method  string
+	Pattern string
+
+	// Name is the name the route was registered with by AddNamedRoute.
+	Name string
+
+	// Host is the host pattern of the route, it is empty for routes which
+	// match any host.
+	Host string
+
+	// Prefix is the prefix of the group the route was registered with.
+	Prefix string
+
+	// Handler is the name of the handler function, and Middleware the names
+	// of the middlewares wrapping it in the order they are executed.
+	Handler    string
+	Middleware []string
+
+	// Meta is the metadata attached to the route with Mux.Meta.
+	Meta map[string]interface{}
+
+	// Version is the API version the route was registered for with
+	// Mux.Version, it is empty for routes serving any version.
+	Version string
+}
+
+// Routes returns the routes registered with m, its groups and hosts sorted by
+// pattern and method. For a group only the routes registered through it are
+// returned. It can be used to print the route table of an application.
+//   for _, r := range m.Routes() {
+//       fmt.Println(r.Method, r.Pattern, r.Handler)
+//   }
+func (m *Mux) Routes() []RouteInfo {
+	var result []RouteInfo
+	m.walkRoutes(func(method string, rt *route) {
+		if rt.mux.descendsFrom(m) {
+			result = append(result, rt.info(method))
+		}
+	})
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Host != result[j].Host {
+			return result[i].Host < result[j].Host
+		}
+		if result[i].Pattern != result[j].Pattern {
+			return result[i].Pattern < result[j].Pattern
+		}
+		if result[i].Method != result[j].Method {
+			return methodIndex(result[i].Method) < methodIndex(result[j].Method)
+		}
+		return result[i].Version < result[j].Version
+	})
+	return result
+}
+
+// WriteRouteTable writes the routes of m to w as a table, one route per line
+// sorted like Routes, with their method, host and pattern, version, handler
+// and middlewares.
+//   METHOD  ROUTE                   VERSION  HANDLER        MIDDLEWARE
+//   GET     /users/:id              -        main.showUser  main.auth
+//   GET     api.example.com/status  -        main.status    -
+//
+// The output only changes when routes do, so it can be committed as a golden
+// file which tests compare with, to catch routes changed by accident
+//   var b bytes.Buffer
+//   m.WriteRouteTable(&b)
+//   golden, _ := os.ReadFile("testdata/routes.txt")
+//   if b.String() != string(golden) {
+//       t.Error("routes changed, update testdata/routes.txt")
+//   }
+//
+// Handlers and middlewares which are closures are named after the function
+// creating them, like main.auth.func1.
+func (m *Mux) WriteRouteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "METHOD\tROUTE\tVERSION\tHANDLER\tMIDDLEWARE")
+	for _, r := range m.Routes() {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", r.Method, r.Host+r.Pattern,
+			orDash(r.Version), orDash(r.Handler), orDash(strings.Join(r.Middleware, ", ")))
+	}
+	return tw.Flush()
+}
+
+// orDash returns s, or a dash if s is empty.
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// Match returns the route and params which a request with method and path
+// would be served with, without serving it. It runs the same lookup as
+// ServeHTTP, so path is cleaned and HEAD requests match GET routes. The error
+// is the one which the not found or method not allowed handler would respond
+// with if there is no matching route. Only routes registered without a version
+// are matched, see Version.
+//   info, params, err := m.Match("GET", "/users/42")
+//   // info.Pattern is /users/:id and params.Get("id") is 42
+func (m *Mux) Match(method, p string) (RouteInfo, Params, error) {
+	p = path.Clean(p)
+	h, p, err := m.lookup(method, p, "", m.anyFold)
+	if err != nil && method == httpMethods.head {
+		method = httpMethods.get
+		h, p, err = m.lookup(method, p, "", m.anyFold)
+	}
+	if err != nil {
+		if len(m.allowed(p, "")) > 0 {
+			return RouteInfo{}, nil, errNotAllowed
+		}
+		if f := m.fallback(method, p); f != nil {
+			return f.info(method), nil, nil
+		}
+		return RouteInfo{}, nil, errRouteNotFound
+	}
+	params, _ := parseParams(p, h.path)
+	return h.info(method), params, nil
+}
+
+// CurrentRoute returns the route which matched r, with its pattern, name and
+// metadata, and false if r was not matched by a Mux. The route is set before
+// the middlewares of the Mux run, so they can label metrics, logs or
+// authorization decisions by pattern like /users/:id instead of the raw path.
+//   func metrics(h http.Handler) http.Handler {
+//       return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+//           rt, _ := alien.CurrentRoute(r)
+//           requests.WithLabelValues(rt.Pattern).Inc()
+//           h.ServeHTTP(w, r)
+//       })
+//   }
+//
+// Method is the method of r, which is HEAD for HEAD requests served by a GET
+// route.
+func CurrentRoute(r *http.Request) (RouteInfo, bool) {
+	rt := routeOf(r.Context())
+	if rt == nil {
+		return RouteInfo{}, false
+	}
+	return rt.info(r.Method), true
+}
+
+// walkRoutes calls fn for every route of m and its hosts.
+func (m *Mux) walkRoutes(fn func(method string, rt *route)) {
+	t := m.load()
+	for _, method := range t.methods() {
+		if root := t.root(method); *root != nil {
+			(*root).walk(func(rt *route) {
+				fn(method, rt)
+			})
+		}
+	}
+	for _, hm := range m.hosts {
+		hm.walkRoutes(fn)
+	}
+}
+
+// walk calls fn for every route of the tree rooted at n.
+func (n *node) walk(fn func(rt *route)) {
+	for _, rt := range n.ends {
+		fn(rt)
+	}
+	for _, c := range n.children {
+		c.walk(fn)
+	}
+	if n.param != nil {
+		n.param.walk(fn)
+	}
+	if n.catchAll != nil {
+		n.catchAll.walk(fn)
+	}
+}
+
+// descendsFrom returns true if m is ancestor or one of its groups or hosts.
+func (m *Mux) descendsFrom(ancestor *Mux) bool {
+	for g := m; g != nil; g = g.parent {
+		if g == ancestor {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *route) info(method string) RouteInfo {
+	info := RouteInfo{
+		Method:  method,
+		Pattern: r.path,
+		Name:    r.name,
+		Prefix:  r.mux.prefix,
+		Handler: funcName(r.handler),
+		Version: r.version,
+		Host:    r.mux.hostOf(),
+	}
+	for g := r.mux; g != nil; g = g.parent {
+		for k, v := range g.meta {
+			if _, ok := info.Meta[k]; ok {
+				continue
+			}
+			if info.Meta == nil {
+				info.Meta = make(map[string]interface{})
+			}
+			info.Meta[k] = v
+		}
+	}
+	// the last middleware is the outermost one.
+	for i := len(r.middleware) - 1; i >= 0; i-- {
+		info.Middleware = append(info.Middleware, funcName(r.middleware[i]))
+	}
+	return info
+}
+
+// funcName returns the name of the function f, closures are named after the
+// function defining them with a .funcN suffix.
+func funcName(f interface{}) string {
+	v := reflect.ValueOf(f)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return ""
+	}
+	fn := runtime.FuncForPC(v.Pointer())
+	if fn == nil {
+		return ""
+	}
+	return strings.TrimSuffix(fn.Name(), "-fm")
+}
+
+// methodIndex returns the index of method in allMethods, methods registered
+// with RegisterMethod come last.
+func methodIndex(method string) int {
+	for i, v := range allMethods {
+		if v == method {
+			return i
+		}
+	}
+	return len(allMethods)
+}
@@ -0,0 +1,128 @@
+package alien
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func listUsers(_ http.ResponseWriter, _ *http.Request) {}
+
+func TestMux_Routes(t *testing.T) {
+	auth := BasicAuth("alien", func(_, _ string) bool { return true })
+	m := New()
+	m.Use(Timeout(0))
+	m.Get("/", listUsers)
+	api := m.Group("/api")
+	api.Post("/users", listUsers, auth)
+	api.Get("/users", listUsers)
+	m.Host("api.example.com").Get("/status", listUsers)
+
+	expect := []RouteInfo{
+		{Method: "GET", Pattern: "/", Handler: "github.com/gernest/alien.listUsers",
+			Middleware: []string{"github.com/gernest/alien.Timeout.func1"}},
+		{Method: "GET", Pattern: "/api/users", Prefix: "/api", Handler: "github.com/gernest/alien.listUsers",
+			Middleware: []string{"github.com/gernest/alien.Timeout.func1"}},
+		{Method: "POST", Pattern: "/api/users", Prefix: "/api", Handler: "github.com/gernest/alien.listUsers",
+			Middleware: []string{"github.com/gernest/alien.Timeout.func1", "github.com/gernest/alien.BasicAuth.func1"}},
+		{Method: "GET", Pattern: "/status", Host: "api.example.com", Handler: "github.com/gernest/alien.listUsers",
+			Middleware: []string{"github.com/gernest/alien.Timeout.func1"}},
+	}
+	if routes := m.Routes(); !reflect.DeepEqual(routes, expect) {
+		t.Errorf("expected %+v got %+v", expect, routes)
+	}
+	if routes := api.Routes(); len(routes) != 2 {
+		t.Errorf("expected 2 got %d", len(routes))
+	}
+}
+
+func TestMux_WriteRouteTable(t *testing.T) {
+	m := New()
+	m.Use(Timeout(0))
+	m.Host("api.example.com").Get("/status", listUsers)
+	m.Post("/users", listUsers, BasicAuth("alien", func(_, _ string) bool { return true }))
+	m.Version("v2").Get("/users", listUsers)
+	m.Get("/users", listUsers)
+	expect := `METHOD  ROUTE                   VERSION  HANDLER                             MIDDLEWARE
+GET     /users                  -        github.com/gernest/alien.listUsers  github.com/gernest/alien.Timeout.func1
+GET     /users                  v2       github.com/gernest/alien.listUsers  github.com/gernest/alien.Timeout.func1
+POST    /users                  -        github.com/gernest/alien.listUsers  github.com/gernest/alien.Timeout.func1, github.com/gernest/alien.BasicAuth.func1
+GET     api.example.com/status  -        github.com/gernest/alien.listUsers  github.com/gernest/alien.Timeout.func1
+`
+	var b strings.Builder
+	if err := m.WriteRouteTable(&b); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != expect {
+		t.Errorf("expected\n%s\ngot\n%s", expect, b.String())
+	}
+}
+
+func TestMux_Match(t *testing.T) {
+	m := New()
+	m.Get("/users/:id", listUsers)
+	m.Post("/login", listUsers)
+	m.Get("/files/:bucket/:key([0-9]+)/*path", listUsers)
+	sample := []struct {
+		method, path, pattern string
+		params                Params
+		err                   error
+	}{
+		{"GET", "/users/42", "/users/:id", Params{{"id", "42"}}, nil},
+		{"HEAD", "/users/42/", "/users/:id", Params{{"id", "42"}}, nil},
+		{"POST", "/login", "/login", nil, nil},
+		{"GET", "/login", "", nil, errNotAllowed},
+		{"GET", "/nowhere", "", nil, errRouteNotFound},
+		{"GET", "/files/media/7/a/b.png", "/files/:bucket/:key([0-9]+)/*path", Params{{"bucket", "media"}, {"key", "7"}, {"path", "a/b.png"}}, nil},
+		{"GET", "/files/media/seven/a/b.png", "", nil, errRouteNotFound},
+	}
+	for _, v := range sample {
+		info, params, err := m.Match(v.method, v.path)
+		if err != v.err {
+			t.Errorf("%s %s: expected %v got %v", v.method, v.path, v.err, err)
+		}
+		if info.Pattern != v.pattern {
+			t.Errorf("%s %s: expected %s got %s", v.method, v.path, v.pattern, info.Pattern)
+		}
+		if !reflect.DeepEqual(params, v.params) {
+			t.Errorf("%s %s: expected %v got %v", v.method, v.path, v.params, params)
+		}
+	}
+}
+
+func TestCurrentRoute(t *testing.T) {
+	m := New()
+	var got RouteInfo
+	var matched bool
+	m.Use(func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got, matched = CurrentRoute(r)
+			h.ServeHTTP(w, r)
+		})
+	})
+	doc := m.Meta("doc", "Show user")
+	doc.GetNamed("user_show", "/users/:id", listUsers)
+	doc.ReplaceRoute("GET", "/users/:id", listUsers)
+	sample := []struct {
+		method, path string
+		info         RouteInfo
+		matched      bool
+	}{
+		{"GET", "/users/42", RouteInfo{Method: "GET", Pattern: "/users/:id", Name: "user_show", Meta: map[string]interface{}{"doc": "Show user"}}, true},
+		{"HEAD", "/users/42", RouteInfo{Method: "HEAD", Pattern: "/users/:id", Name: "user_show", Meta: map[string]interface{}{"doc": "Show user"}}, true},
+		{"GET", "/nowhere", RouteInfo{}, false},
+	}
+	for _, v := range sample {
+		got, matched = RouteInfo{}, false
+		m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(v.method, v.path, nil))
+		if matched != v.matched || got.Method != v.info.Method || got.Pattern != v.info.Pattern ||
+			got.Name != v.info.Name || !reflect.DeepEqual(got.Meta, v.info.Meta) {
+			t.Errorf("%s %s: expected %+v got %+v", v.method, v.path, v.info, got)
+		}
+	}
+	if _, ok := CurrentRoute(httptest.NewRequest("GET", "/", nil)); ok {
+		t.Error("expected no route for a request not served by a Mux")
+	}
+}
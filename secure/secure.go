@@ -0,0 +1,114 @@
+// Package secure provides a middleware which sets security related response
+// headers.
+//
+//   m := alien.New()
+//   m.Use(secure.New(secure.DefaultOptions))
+//
+// Every header can be changed or disabled through Options
+//   o := secure.DefaultOptions
+//   o.FrameOptions = "SAMEORIGIN"
+//   o.ContentSecurityPolicy = ""
+//   m.Use(secure.New(o))
+package secure
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultOptions are sensible defaults for most applications.
+var DefaultOptions = Options{
+	ContentTypeOptions:    "nosniff",
+	FrameOptions:          "DENY",
+	ReferrerPolicy:        "strict-origin-when-cross-origin",
+	ContentSecurityPolicy: "default-src 'self'",
+	HSTSMaxAge:            365 * 24 * time.Hour,
+	HSTSIncludeSubdomains: true,
+}
+
+// Options configures the headers set by the middleware. Headers with empty
+// values are not set.
+type Options struct {
+	// ContentTypeOptions is the value of X-Content-Type-Options.
+	ContentTypeOptions string
+
+	// FrameOptions is the value of X-Frame-Options.
+	FrameOptions string
+
+	// ReferrerPolicy is the value of Referrer-Policy.
+	ReferrerPolicy string
+
+	// ContentSecurityPolicy is the value of Content-Security-Policy.
+	ContentSecurityPolicy string
+
+	// ContentSecurityPolicyReportOnly sends the policy in the
+	// Content-Security-Policy-Report-Only header instead.
+	ContentSecurityPolicyReportOnly bool
+
+	// HSTSMaxAge is the max-age of Strict-Transport-Security, zero disables
+	// the header. The header is only sent on requests made over https.
+	HSTSMaxAge time.Duration
+
+	// HSTSIncludeSubdomains adds includeSubDomains to
+	// Strict-Transport-Security.
+	HSTSIncludeSubdomains bool
+
+	// HSTSPreload adds preload to Strict-Transport-Security.
+	HSTSPreload bool
+
+	// TrustForwardedProto treats requests with X-Forwarded-Proto set to https
+	// as made over https, enable it only behind a trusted proxy.
+	TrustForwardedProto bool
+
+	// Skipper returns true for requests which bypass the middleware, see
+	// alien.SkipPaths.
+	Skipper func(r *http.Request) bool
+}
+
+// New returns a middleware which sets the headers configured by o.
+func New(o Options) func(http.Handler) http.Handler {
+	type header struct{ key, value string }
+	var headers []header
+	add := func(key, value string) {
+		if value != "" {
+			headers = append(headers, header{key, value})
+		}
+	}
+	add("X-Content-Type-Options", o.ContentTypeOptions)
+	add("X-Frame-Options", o.FrameOptions)
+	add("Referrer-Policy", o.ReferrerPolicy)
+	if o.ContentSecurityPolicyReportOnly {
+		add("Content-Security-Policy-Report-Only", o.ContentSecurityPolicy)
+	} else {
+		add("Content-Security-Policy", o.ContentSecurityPolicy)
+	}
+	var hsts string
+	if o.HSTSMaxAge > 0 {
+		v := []string{"max-age=" + strconv.FormatInt(int64(o.HSTSMaxAge/time.Second), 10)}
+		if o.HSTSIncludeSubdomains {
+			v = append(v, "includeSubDomains")
+		}
+		if o.HSTSPreload {
+			v = append(v, "preload")
+		}
+		hsts = strings.Join(v, "; ")
+	}
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if o.Skipper != nil && o.Skipper(r) {
+				h.ServeHTTP(w, r)
+				return
+			}
+			dst := w.Header()
+			for _, v := range headers {
+				dst.Set(v.key, v.value)
+			}
+			if hsts != "" && (r.TLS != nil || (o.TrustForwardedProto && strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https"))) {
+				dst.Set("Strict-Transport-Security", hsts)
+			}
+			h.ServeHTTP(w, r)
+		})
+	}
+}
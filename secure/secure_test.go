@@ -0,0 +1,80 @@
+package secure
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gernest/alien"
+)
+
+func serve(o Options, setup func(*http.Request)) http.Header {
+	m := alien.New()
+	m.Use(New(o))
+	m.Get("/", func(_ http.ResponseWriter, _ *http.Request) {})
+	req, _ := http.NewRequest("GET", "/", nil)
+	setup(req)
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, req)
+	return w.Header()
+}
+
+func TestDefaults(t *testing.T) {
+	h := serve(DefaultOptions, func(r *http.Request) {})
+	expect := map[string]string{
+		"X-Content-Type-Options":    "nosniff",
+		"X-Frame-Options":           "DENY",
+		"Referrer-Policy":           "strict-origin-when-cross-origin",
+		"Content-Security-Policy":   "default-src 'self'",
+		"Strict-Transport-Security": "",
+	}
+	for k, v := range expect {
+		if h.Get(k) != v {
+			t.Errorf("%s: expected %q got %q", k, v, h.Get(k))
+		}
+	}
+
+	h = serve(DefaultOptions, func(r *http.Request) { r.TLS = &tls.ConnectionState{} })
+	if s := h.Get("Strict-Transport-Security"); s != "max-age=31536000; includeSubDomains" {
+		t.Errorf("unexpected hsts %s", s)
+	}
+}
+
+func TestOptions(t *testing.T) {
+	o := Options{
+		FrameOptions:                    "SAMEORIGIN",
+		ContentSecurityPolicy:           "default-src 'none'",
+		ContentSecurityPolicyReportOnly: true,
+		HSTSMaxAge:                      time.Hour,
+		HSTSPreload:                     true,
+		TrustForwardedProto:             true,
+	}
+	h := serve(o, func(r *http.Request) { r.Header.Set("X-Forwarded-Proto", "https") })
+	expect := map[string]string{
+		"X-Content-Type-Options":              "",
+		"X-Frame-Options":                     "SAMEORIGIN",
+		"Content-Security-Policy":             "",
+		"Content-Security-Policy-Report-Only": "default-src 'none'",
+		"Strict-Transport-Security":           "max-age=3600; preload",
+	}
+	for k, v := range expect {
+		if h.Get(k) != v {
+			t.Errorf("%s: expected %q got %q", k, v, h.Get(k))
+		}
+	}
+}
+
+func TestOptions_skipper(t *testing.T) {
+	o := DefaultOptions
+	o.Skipper = func(r *http.Request) bool { return r.Header.Get("Upgrade") == "websocket" }
+	h := serve(o, func(r *http.Request) { r.Header.Set("Upgrade", "websocket") })
+	if v := h.Get("Content-Security-Policy"); v != "" {
+		t.Errorf("expected no headers got %s", v)
+	}
+	h = serve(o, func(r *http.Request) {})
+	if v := h.Get("Content-Security-Policy"); v == "" {
+		t.Error("expected headers")
+	}
+}
@@ -0,0 +1,274 @@
+package alien
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+var errNoDomains = errors.New("alien: no domains to obtain certificates for")
+
+// ServerOption configures the server started by Run.
+type ServerOption func(*serverConfig)
+
+type serverConfig struct {
+	server   *http.Server
+	listener net.Listener
+	ctx      context.Context
+	signals  []os.Signal
+	drain    time.Duration
+}
+
+// Timeouts sets the read, write and idle timeouts of the server. Defaults to
+// 30s, 60s and 120s, and the headers must be read within 10s.
+func Timeouts(read, write, idle time.Duration) ServerOption {
+	return func(c *serverConfig) {
+		c.server.ReadTimeout = read
+		c.server.WriteTimeout = write
+		c.server.IdleTimeout = idle
+	}
+}
+
+// DrainTimeout sets how long requests in flight are given to complete when the
+// server shuts down, after which their connections are closed. Defaults to 30s.
+func DrainTimeout(d time.Duration) ServerOption {
+	return func(c *serverConfig) {
+		c.drain = d
+	}
+}
+
+// Signals sets the signals which shut the server down. Defaults to SIGINT and
+// SIGTERM.
+func Signals(sig ...os.Signal) ServerOption {
+	return func(c *serverConfig) {
+		c.signals = sig
+	}
+}
+
+// ShutdownContext shuts the server down when ctx is done, in addition to the
+// signals.
+func ShutdownContext(ctx context.Context) ServerOption {
+	return func(c *serverConfig) {
+		c.ctx = ctx
+	}
+}
+
+// Listener serves on l instead of listening on the address given to Run.
+func Listener(l net.Listener) ServerOption {
+	return func(c *serverConfig) {
+		c.listener = l
+	}
+}
+
+// ConfigureServer calls fn with the server before it starts, to set the fields
+// which have no option like ErrorLog or ConnState.
+func ConfigureServer(fn func(s *http.Server)) ServerOption {
+	return func(c *serverConfig) {
+		fn(c.server)
+	}
+}
+
+// Run serves m on addr until SIGINT or SIGTERM is received, then shuts the
+// server down gracefully: it stops accepting connections and waits for the
+// requests in flight to complete.
+//   log.Fatal(m.Run(":8090", alien.DrainTimeout(10*time.Second)))
+//
+// A second signal received while draining terminates the process. The returned
+// error joins the errors of serving and shutting down, it is nil after a clean
+// shutdown. Hooks registered with OnStart and OnShutdown are called before
+// serving and after shutting down.
+func (m *Mux) Run(addr string, opts ...ServerOption) error {
+	return m.run(addr, opts, func(s *http.Server, ln net.Listener) error {
+		return s.Serve(ln)
+	})
+}
+
+// RunTLS is like Run but serves HTTPS with the certificate and key in the
+// files certFile and keyFile.
+func (m *Mux) RunTLS(addr, certFile, keyFile string, opts ...ServerOption) error {
+	return m.run(addr, opts, func(s *http.Server, ln net.Listener) error {
+		return s.ServeTLS(ln, certFile, keyFile)
+	})
+}
+
+// RunH2C is like Run but serves HTTP/2 over cleartext connections, known as
+// h2c, as well as HTTP/1.1. It is meant for servers behind a load balancer or
+// proxy which terminates TLS and speaks HTTP/2 to its backends, like the ones
+// in front of gRPC-web services. Clients must use prior knowledge, h2c upgrades
+// from HTTP/1.1 aren't supported.
+//   log.Fatal(m.RunH2C(":8090"))
+func (m *Mux) RunH2C(addr string, opts ...ServerOption) error {
+	opts = append([]ServerOption{ConfigureServer(func(s *http.Server) {
+		s.Protocols = new(http.Protocols)
+		s.Protocols.SetHTTP1(true)
+		s.Protocols.SetUnencryptedHTTP2(true)
+	})}, opts...)
+	return m.run(addr, opts, func(s *http.Server, ln net.Listener) error {
+		return s.Serve(ln)
+	})
+}
+
+// CertManager obtains certificates automatically, it is implemented by
+// *autocert.Manager of golang.org/x/crypto/acme/autocert.
+type CertManager interface {
+	GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error)
+
+	// HTTPHandler answers ACME challenges sent over http, and passes other
+	// requests to fallback.
+	HTTPHandler(fallback http.Handler) http.Handler
+}
+
+// AutoTLSCache sets the directory where RunAutoTLS caches certificates.
+// Defaults to alien-autocert in the user cache directory.
+func (m *Mux) AutoTLSCache(dir string) {
+	m.top().certCache = dir
+}
+
+// RunAutoTLS is like Run but serves HTTPS on :443 with certificates obtained
+// from Let's Encrypt for domains, accepting its terms of service. Certificates
+// are cached in the directory set with AutoTLSCache. Requests to :80 are
+// redirected to HTTPS, except for ACME challenges.
+//   m.AutoTLSCache("/var/cache/certs")
+//   log.Fatal(m.RunAutoTLS("example.com", "www.example.com"))
+//
+// Use RunCertManager to configure the manager or the server.
+func (m *Mux) RunAutoTLS(domains ...string) error {
+	if len(domains) == 0 {
+		return errNoDomains
+	}
+	dir := m.top().certCache
+	if dir == "" {
+		dir = "alien-autocert"
+		if cache, err := os.UserCacheDir(); err == nil {
+			dir = filepath.Join(cache, dir)
+		}
+	}
+	return m.RunCertManager(&autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(domains...),
+		Cache:      autocert.DirCache(dir),
+	})
+}
+
+// RunCertManager is like RunAutoTLS but obtains certificates with cm, for
+// other ACME providers or caches
+//   cm := &autocert.Manager{
+//       Prompt:     autocert.AcceptTOS,
+//       HostPolicy: autocert.HostWhitelist("example.com"),
+//       Cache:      autocert.DirCache("/var/cache/certs"),
+//       Email:      "ops@example.com",
+//   }
+//   log.Fatal(m.RunCertManager(cm, alien.DrainTimeout(10*time.Second)))
+func (m *Mux) RunCertManager(cm CertManager, opts ...ServerOption) error {
+	redirect := &http.Server{
+		Addr:              ":80",
+		Handler:           cm.HTTPHandler(http.HandlerFunc(redirectHTTPS)),
+		ReadHeaderTimeout: 10 * time.Second,
+		ErrorLog:          m.top().logger,
+	}
+	opts = append([]ServerOption{ConfigureServer(func(s *http.Server) {
+		s.TLSConfig = &tls.Config{
+			GetCertificate: cm.GetCertificate,
+			// acme-tls/1 is used by the TLS-ALPN-01 challenge.
+			NextProtos: []string{"h2", "http/1.1", "acme-tls/1"},
+		}
+	})}, opts...)
+	return m.run(":443", opts, func(s *http.Server, ln net.Listener) error {
+		return s.ServeTLS(ln, "", "")
+	}, redirect)
+}
+
+// redirectHTTPS redirects r to the same url with the https scheme.
+func redirectHTTPS(w http.ResponseWriter, r *http.Request) {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+}
+
+// run serves m with serve, along with the servers in companions, until a
+// signal is received or one of the servers fails.
+func (m *Mux) run(addr string, opts []ServerOption, serve func(s *http.Server, ln net.Listener) error, companions ...*http.Server) error {
+	c := &serverConfig{
+		server: &http.Server{
+			Addr:              addr,
+			Handler:           m,
+			ReadHeaderTimeout: 10 * time.Second,
+			ReadTimeout:       30 * time.Second,
+			WriteTimeout:      60 * time.Second,
+			IdleTimeout:       120 * time.Second,
+		},
+		ctx:     context.Background(),
+		signals: []os.Signal{os.Interrupt, syscall.SIGTERM},
+		drain:   30 * time.Second,
+	}
+	c.server.ErrorLog = m.top().logger
+	for _, o := range opts {
+		o(c)
+	}
+	ln := c.listener
+	if ln == nil {
+		var err error
+		if ln, err = net.Listen("tcp", addr); err != nil {
+			return err
+		}
+	}
+	if err := m.top().start(); err != nil {
+		ln.Close()
+		return err
+	}
+	ctx, stop := signal.NotifyContext(c.ctx, c.signals...)
+	defer stop()
+	servers := append([]*http.Server{c.server}, companions...)
+	served := make(chan error, len(servers))
+	go func() {
+		served <- serve(c.server, ln)
+	}()
+	for _, s := range companions {
+		go func(s *http.Server) {
+			served <- s.ListenAndServe()
+		}(s)
+	}
+	var errs []error
+	pending := len(servers)
+	select {
+	case err := <-served:
+		pending--
+		errs = append(errs, err)
+	case <-ctx.Done():
+	}
+	// restore the default behavior, so another signal kills the process.
+	stop()
+	drain, cancel := context.WithTimeout(context.Background(), c.drain)
+	defer cancel()
+	for _, s := range servers {
+		if err := s.Shutdown(drain); err != nil {
+			errs = append(errs, err)
+			s.Close()
+		}
+	}
+	for ; pending > 0; pending-- {
+		errs = append(errs, <-served)
+	}
+	if err := m.top().shutdown(drain); err != nil {
+		errs = append(errs, err)
+	}
+	// servers which were shut down return http.ErrServerClosed.
+	result := errs[:0]
+	for _, err := range errs {
+		if err != http.ErrServerClosed {
+			result = append(result, err)
+		}
+	}
+	return errors.Join(result...)
+}
@@ -0,0 +1,233 @@
+package alien
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMux_Run(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	started := make(chan struct{})
+	release := make(chan struct{})
+	m := New()
+	m.Get("/slow", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.Write([]byte("done"))
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	var srv *http.Server
+	ran := make(chan error, 1)
+	go func() {
+		ran <- m.Run("", Listener(ln), ShutdownContext(ctx), DrainTimeout(time.Second),
+			ConfigureServer(func(s *http.Server) { srv = s }))
+	}()
+
+	body := make(chan string, 1)
+	go func() {
+		res, err := http.Get("http://" + ln.Addr().String() + "/slow")
+		if err != nil {
+			body <- err.Error()
+			return
+		}
+		defer res.Body.Close()
+		b, _ := io.ReadAll(res.Body)
+		body <- string(b)
+	}()
+	<-started
+	cancel()
+	// the request in flight is drained before Run returns.
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case err := <-ran:
+		t.Fatalf("expected Run to wait for the request got %v", err)
+	default:
+	}
+	close(release)
+	if b := <-body; b != "done" {
+		t.Errorf("expected done got %s", b)
+	}
+	if err := <-ran; err != nil {
+		t.Errorf("expected a clean shutdown got %v", err)
+	}
+	if srv.ReadHeaderTimeout != 10*time.Second {
+		t.Errorf("expected the default timeouts got %v", srv.ReadHeaderTimeout)
+	}
+}
+
+func TestMux_Run_drainTimeout(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	started := make(chan struct{})
+	m := New()
+	m.Get("/stuck", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(time.Second)
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	ran := make(chan error, 1)
+	go func() {
+		ran <- m.Run("", Listener(ln), ShutdownContext(ctx), DrainTimeout(10*time.Millisecond))
+	}()
+	go http.Get("http://" + ln.Addr().String() + "/stuck")
+	<-started
+	cancel()
+	if err := <-ran; !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected %v got %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestMux_Run_listenError(t *testing.T) {
+	if err := New().Run("bad address"); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+// writeCert writes a self signed certificate for 127.0.0.1 to dir.
+func writeCert(t *testing.T, dir string) (certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, tpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: b}), 0600)
+	return certFile, keyFile
+}
+
+func TestMux_RunTLS(t *testing.T) {
+	certFile, keyFile := writeCert(t, t.TempDir())
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := New()
+	m.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	ran := make(chan error, 1)
+	go func() {
+		ran <- m.RunTLS("", certFile, keyFile, Listener(ln), ShutdownContext(ctx))
+	}()
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}}
+	res, err := client.Get("https://" + ln.Addr().String() + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if res.TLS == nil || string(b) != "HTTP/1.1" {
+		t.Errorf("expected a https response got %s", b)
+	}
+	cancel()
+	if err := <-ran; err != nil {
+		t.Errorf("expected a clean shutdown got %v", err)
+	}
+}
+
+func TestMux_RunH2C(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := New()
+	m.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	ran := make(chan error, 1)
+	go func() {
+		ran <- m.RunH2C("", Listener(ln), ShutdownContext(ctx))
+	}()
+	h2c := &http.Transport{Protocols: new(http.Protocols)}
+	h2c.Protocols.SetUnencryptedHTTP2(true)
+	sample := []struct {
+		client *http.Client
+		proto  string
+	}{
+		{&http.Client{Transport: h2c}, "HTTP/2.0"},
+		{&http.Client{Transport: &http.Transport{}}, "HTTP/1.1"},
+	}
+	for _, v := range sample {
+		res, err := v.client.Get("http://" + ln.Addr().String() + "/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := io.ReadAll(res.Body)
+		res.Body.Close()
+		if string(b) != v.proto {
+			t.Errorf("expected %s got %s", v.proto, b)
+		}
+		v.client.CloseIdleConnections()
+	}
+	cancel()
+	if err := <-ran; err != nil {
+		t.Errorf("expected a clean shutdown got %v", err)
+	}
+}
+
+func TestRedirectHTTPS(t *testing.T) {
+	sample := []struct {
+		host, path, location string
+	}{
+		{"example.com", "/users?page=2", "https://example.com/users?page=2"},
+		{"example.com:80", "/", "https://example.com/"},
+	}
+	for _, v := range sample {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", v.path, nil)
+		req.Host = v.host
+		redirectHTTPS(w, req)
+		if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != v.location {
+			t.Errorf("expected a redirect to %s got %d %s", v.location, w.Code, w.Header().Get("Location"))
+		}
+	}
+}
+
+func TestMux_RunAutoTLS(t *testing.T) {
+	m := New()
+	if err := m.RunAutoTLS(); err != errNoDomains {
+		t.Errorf("expected %v got %v", errNoDomains, err)
+	}
+	m.Group("/api").AutoTLSCache("/var/cache/certs")
+	if m.certCache != "/var/cache/certs" {
+		t.Errorf("expected the cache of the root Mux to be set got %q", m.certCache)
+	}
+}
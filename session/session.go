@@ -0,0 +1,349 @@
+// Package session provides cookie based sessions for alien.
+//
+//   m := alien.New()
+//   m.Use(session.New(session.Options{Store: session.NewMemoryStore()}))
+//   m.Get("/", func(w http.ResponseWriter, r *http.Request) {
+//   	s := session.Get(r)
+//   	n, _ := s.Get("visits").(int)
+//   	s.Set("visits", n+1)
+//   })
+//
+// The cookie only holds a random session id, values are kept in a Store.
+// Sessions are loaded from the store the first time they are accessed, and
+// saved before the response header is written if they were modified.
+//
+// Values are encoded with encoding/gob, custom types must be registered with
+// gob.Register before they can be stored.
+package session
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/gob"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gernest/alien"
+)
+
+const flashKey = "_flash"
+
+var (
+	errNoSession = errors.New("session: middleware is not installed")
+	sessionKey   = &contextKey{"session"}
+)
+
+func init() {
+	gob.Register([]interface{}{})
+	gob.Register(map[string]interface{}{})
+}
+
+type contextKey struct {
+	name string
+}
+
+// Store persists encoded session values. Get returns nil data and a nil error
+// if there is no session with id. Implementations must be safe for concurrent
+// use.
+//
+// A store backed by redis or any other key value database only needs to map
+// these methods to its get, set with expiry and delete commands.
+type Store interface {
+	Get(id string) ([]byte, error)
+	Set(id string, data []byte, ttl time.Duration) error
+	Delete(id string) error
+}
+
+// Options configures the session middleware.
+type Options struct {
+	// Store keeps session values. Defaults to a new MemoryStore.
+	Store Store
+
+	// CookieName is the name of the session cookie. Defaults to
+	// alien_session.
+	CookieName string
+
+	// MaxAge is how long sessions live. Defaults to 24 hours.
+	MaxAge time.Duration
+
+	// Path and Domain of the session cookie, Path defaults to /.
+	Path, Domain string
+
+	// Secure sets the Secure attribute of the session cookie.
+	Secure bool
+
+	// SameSite sets the SameSite attribute of the session cookie, defaults to
+	// http.SameSiteLaxMode.
+	SameSite http.SameSite
+
+	// ErrorHandler is called when the store fails to load or save a session.
+	// Defaults to ignoring the error.
+	ErrorHandler func(r *http.Request, err error)
+
+	// Skipper returns true for requests which bypass the middleware, see
+	// alien.SkipPaths.
+	Skipper func(r *http.Request) bool
+}
+
+// New returns a middleware which makes sessions available to handlers via Get.
+func New(o Options) func(http.Handler) http.Handler {
+	if o.Store == nil {
+		o.Store = NewMemoryStore()
+	}
+	if o.CookieName == "" {
+		o.CookieName = "alien_session"
+	}
+	if o.MaxAge == 0 {
+		o.MaxAge = 24 * time.Hour
+	}
+	if o.Path == "" {
+		o.Path = "/"
+	}
+	if o.SameSite == 0 {
+		o.SameSite = http.SameSiteLaxMode
+	}
+	if o.ErrorHandler == nil {
+		o.ErrorHandler = func(*http.Request, error) {}
+	}
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if o.Skipper != nil && o.Skipper(r) {
+				h.ServeHTTP(w, r)
+				return
+			}
+			s := &Session{opts: &o, r: r}
+			sw := &sessionWriter{ResponseWriter: alien.WrapWriter(w), s: s}
+			h.ServeHTTP(sw, r.WithContext(context.WithValue(r.Context(), sessionKey, s)))
+			sw.save()
+		})
+	}
+}
+
+// Get returns the session of r. It panics if the session middleware is not
+// installed.
+func Get(r *http.Request) *Session {
+	s, ok := r.Context().Value(sessionKey).(*Session)
+	if !ok {
+		panic(errNoSession)
+	}
+	return s
+}
+
+// Session is a set of values persisted across requests of the same client.
+type Session struct {
+	mu        sync.Mutex
+	opts      *Options
+	r         *http.Request
+	id        string
+	values    map[string]interface{}
+	loaded    bool
+	modified  bool
+	destroyed bool
+}
+
+// ID returns the id of the session, which is empty for new sessions until they
+// are saved.
+func (s *Session) ID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.load()
+	return s.id
+}
+
+// Get returns the value stored under key.
+func (s *Session) Get(key string) interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.load()
+	return s.values[key]
+}
+
+// Set stores value under key.
+func (s *Session) Set(key string, value interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.load()
+	s.values[key] = value
+	s.modified = true
+}
+
+// Delete removes the value stored under key.
+func (s *Session) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.load()
+	if _, ok := s.values[key]; ok {
+		delete(s.values, key)
+		s.modified = true
+	}
+}
+
+// AddFlash adds a value which will be available until it is read by Flashes,
+// usually on the next request.
+func (s *Session) AddFlash(value interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.load()
+	flashes, _ := s.values[flashKey].([]interface{})
+	s.values[flashKey] = append(flashes, value)
+	s.modified = true
+}
+
+// Flashes returns and removes the values added by AddFlash.
+func (s *Session) Flashes() []interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.load()
+	flashes, ok := s.values[flashKey].([]interface{})
+	if ok {
+		delete(s.values, flashKey)
+		s.modified = true
+	}
+	return flashes
+}
+
+// Destroy removes the session from the store and expires the session cookie.
+func (s *Session) Destroy() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.load()
+	s.values = make(map[string]interface{})
+	s.destroyed = true
+}
+
+// load reads the session from the store the first time it is called.
+func (s *Session) load() {
+	if s.loaded {
+		return
+	}
+	s.loaded = true
+	s.values = make(map[string]interface{})
+	c, err := s.r.Cookie(s.opts.CookieName)
+	if err != nil || c.Value == "" {
+		return
+	}
+	data, err := s.opts.Store.Get(c.Value)
+	if err != nil {
+		s.opts.ErrorHandler(s.r, err)
+		return
+	}
+	if data == nil {
+		return
+	}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&s.values); err != nil {
+		s.opts.ErrorHandler(s.r, err)
+		s.values = make(map[string]interface{})
+		return
+	}
+	s.id = c.Value
+}
+
+// save persists the session if it was modified and sets the session cookie on
+// w. It must be called before the header is written.
+func (s *Session) save(w http.ResponseWriter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	o := s.opts
+	cookie := &http.Cookie{
+		Name:     o.CookieName,
+		Path:     o.Path,
+		Domain:   o.Domain,
+		Secure:   o.Secure,
+		HttpOnly: true,
+		SameSite: o.SameSite,
+	}
+	if s.destroyed {
+		if s.id != "" {
+			if err := o.Store.Delete(s.id); err != nil {
+				o.ErrorHandler(s.r, err)
+			}
+			cookie.MaxAge = -1
+			http.SetCookie(w, cookie)
+		}
+		return
+	}
+	if !s.modified {
+		return
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(s.values); err != nil {
+		o.ErrorHandler(s.r, err)
+		return
+	}
+	if s.id == "" {
+		id, err := newID()
+		if err != nil {
+			o.ErrorHandler(s.r, err)
+			return
+		}
+		s.id = id
+	}
+	if err := o.Store.Set(s.id, buf.Bytes(), o.MaxAge); err != nil {
+		o.ErrorHandler(s.r, err)
+		return
+	}
+	cookie.Value = s.id
+	cookie.MaxAge = int(o.MaxAge / time.Second)
+	http.SetCookie(w, cookie)
+}
+
+func newID() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// sessionWriter saves the session right before the response header is written,
+// pushes are passed to the writer it wraps.
+type sessionWriter struct {
+	alien.ResponseWriter
+	s     *Session
+	saved bool
+}
+
+func (w *sessionWriter) save() {
+	if !w.saved {
+		w.saved = true
+		w.s.save(w.ResponseWriter)
+	}
+}
+
+func (w *sessionWriter) WriteHeader(code int) {
+	// the session is saved with the final response, not informational ones.
+	if code >= 200 || code == http.StatusSwitchingProtocols {
+		w.save()
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *sessionWriter) Write(b []byte) (int, error) {
+	w.save()
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *sessionWriter) Flush() {
+	w.save()
+	w.ResponseWriter.Flush()
+}
+
+// ReadFrom saves the session and copies the body from r with the ReadFrom of
+// the underlying writer.
+func (w *sessionWriter) ReadFrom(r io.Reader) (int64, error) {
+	w.save()
+	return w.ResponseWriter.ReadFrom(r)
+}
+
+// Hijack saves the session before passing the connection on.
+func (w *sessionWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.save()
+	return w.ResponseWriter.Hijack()
+}
@@ -0,0 +1,197 @@
+package session
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gernest/alien"
+	"github.com/gernest/alien/alientest"
+)
+
+func testMux(store Store) *alien.Mux {
+	m := alien.New()
+	m.Use(New(Options{Store: store}))
+	m.Get("/visit", func(w http.ResponseWriter, r *http.Request) {
+		s := Get(r)
+		n, _ := s.Get("visits").(int)
+		s.Set("visits", n+1)
+		fmt.Fprint(w, n+1)
+	})
+	m.Get("/flash", func(w http.ResponseWriter, r *http.Request) {
+		Get(r).AddFlash("hello")
+	})
+	m.Get("/flashes", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, Get(r).Flashes())
+	})
+	m.Get("/logout", func(w http.ResponseWriter, r *http.Request) {
+		Get(r).Destroy()
+	})
+	m.Get("/noop", func(w http.ResponseWriter, r *http.Request) {})
+	return m
+}
+
+type client struct {
+	t       *testing.T
+	h       http.Handler
+	cookies []*http.Cookie
+}
+
+func (c *client) get(path string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", path, nil)
+	for _, v := range c.cookies {
+		req.AddCookie(v)
+	}
+	w := httptest.NewRecorder()
+	c.h.ServeHTTP(w, req)
+	for _, v := range w.Result().Cookies() {
+		if v.MaxAge < 0 {
+			c.cookies = nil
+			continue
+		}
+		c.cookies = []*http.Cookie{v}
+	}
+	return w
+}
+
+func testSession(t *testing.T, store Store) {
+	c := &client{t: t, h: testMux(store)}
+	for i := 1; i <= 3; i++ {
+		w := c.get("/visit")
+		if w.Body.String() != fmt.Sprint(i) {
+			t.Errorf("expected %d got %s", i, w.Body)
+		}
+	}
+	cookie := c.cookies[0]
+	if !cookie.HttpOnly || cookie.Name != "alien_session" {
+		t.Errorf("unexpected cookie %v", cookie)
+	}
+	if w := c.get("/noop"); len(w.Result().Cookies()) != 0 {
+		t.Error("expected unmodified session not to be saved")
+	}
+
+	c.get("/flash")
+	if w := c.get("/flashes"); w.Body.String() != "[hello]" {
+		t.Errorf("expected [hello] got %s", w.Body)
+	}
+	if w := c.get("/flashes"); w.Body.String() != "[]" {
+		t.Errorf("expected [] got %s", w.Body)
+	}
+
+	c.get("/logout")
+	if len(c.cookies) != 0 {
+		t.Error("expected the cookie to be expired")
+	}
+	if data, _ := store.Get(cookie.Value); data != nil {
+		t.Error("expected the session to be deleted")
+	}
+	c.cookies = []*http.Cookie{cookie}
+	if w := c.get("/visit"); w.Body.String() != "1" {
+		t.Errorf("expected a new session got %s", w.Body)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	testSession(t, NewMemoryStore())
+
+	s := NewMemoryStore()
+	s.Set("id", []byte("hello"), -time.Second)
+	if data, _ := s.Get("id"); data != nil {
+		t.Error("expected expired session")
+	}
+
+	// expired sessions which are never read again are dropped by writes.
+	for i := 0; i < sweepBatch-1; i++ {
+		s.Set(fmt.Sprint("expired", i), []byte("hello"), -time.Second)
+	}
+	s.Set("live", []byte("hello"), time.Hour)
+	if len(s.items) != 1 {
+		t.Errorf("expected only the live session got %d", len(s.items))
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	s, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	testSession(t, s)
+
+	s.Set("id", []byte("hello"), -time.Second)
+	if data, _ := s.Get("id"); data != nil {
+		t.Error("expected expired session")
+	}
+	if err := s.Set("../id", []byte("hello"), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := s.Get("../id"); data != nil {
+		t.Error("expected invalid id to be ignored")
+	}
+	// the files of expired sessions which are never read again are removed.
+	s, err = NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Set("expired", []byte("hello"), -2*sweepInterval)
+	s.lastSweep.Store(0)
+	s.Set("live", []byte("hello"), time.Hour)
+	deadline := time.Now().Add(time.Second)
+	for {
+		entries, _ := os.ReadDir(s.dir)
+		if len(entries) == 1 && entries[0].Name() == "live" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected only the live session got %v", entries)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSession_writer(t *testing.T) {
+	m := alien.New()
+	m.Use(New(Options{Store: NewMemoryStore()}))
+	m.Get("/flush", func(w http.ResponseWriter, r *http.Request) {
+		Get(r).Set("visits", 1)
+		if err := w.(http.Pusher).Push("/app.js", nil); err != nil {
+			t.Error(err)
+		}
+		w.Write([]byte("hello"))
+		w.(http.Flusher).Flush()
+	})
+	m.Get("/hijack", func(w http.ResponseWriter, r *http.Request) {
+		Get(r).Set("visits", 1)
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		conn.Close()
+	})
+
+	req, _ := http.NewRequest("GET", "/flush", nil)
+	w := alientest.NewRecorder()
+	m.ServeHTTP(w, req)
+	if !w.Flushed {
+		t.Error("expected the response to be flushed")
+	}
+	if len(w.Pushed) != 1 || w.Pushed[0] != "/app.js" {
+		t.Errorf("expected /app.js to be pushed got %v", w.Pushed)
+	}
+	if w.Header().Get("Set-Cookie") == "" {
+		t.Error("expected the session to be saved before the flush")
+	}
+
+	req, _ = http.NewRequest("GET", "/hijack", nil)
+	w = alientest.NewRecorder()
+	m.ServeHTTP(w, req)
+	if !w.Hijacked {
+		t.Error("expected the connection to be hijacked")
+	}
+	if w.Header().Get("Set-Cookie") == "" {
+		t.Error("expected the session to be saved before the hijack")
+	}
+}
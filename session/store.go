@@ -0,0 +1,195 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// sweepBatch is the number of sessions a MemoryStore checks for expiry on
+	// each Set.
+	sweepBatch = 16
+
+	// sweepInterval is how often a FileStore removes the files of expired
+	// sessions, and how long they are kept after they expire.
+	sweepInterval = time.Minute
+)
+
+// MemoryStore keeps sessions in memory. Sessions are lost when the process
+// exits, and are not shared between processes.
+type MemoryStore struct {
+	mu    sync.RWMutex
+	items map[string]memoryItem
+}
+
+type memoryItem struct {
+	data    []byte
+	expires time.Time
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{items: make(map[string]memoryItem)}
+}
+
+// Get implements Store.
+func (m *MemoryStore) Get(id string) ([]byte, error) {
+	m.mu.RLock()
+	item, ok := m.items[id]
+	m.mu.RUnlock()
+	if !ok {
+		return nil, nil
+	}
+	if time.Now().After(item.expires) {
+		m.Delete(id)
+		return nil, nil
+	}
+	return item.data, nil
+}
+
+// Set implements Store.
+func (m *MemoryStore) Set(id string, data []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	// check a few sessions for expiry on writes, so sessions which are never
+	// read again don't stay forever. Maps are iterated from a random entry.
+	n := 0
+	for k, v := range m.items {
+		if now.After(v.expires) {
+			delete(m.items, k)
+		}
+		if n++; n == sweepBatch {
+			break
+		}
+	}
+	m.items[id] = memoryItem{data: append([]byte(nil), data...), expires: now.Add(ttl)}
+	return nil
+}
+
+// Delete implements Store.
+func (m *MemoryStore) Delete(id string) error {
+	m.mu.Lock()
+	delete(m.items, id)
+	m.mu.Unlock()
+	return nil
+}
+
+// FileStore keeps sessions in files inside a directory, one file per session.
+// The files of expired sessions are removed in the background while sessions
+// are written.
+type FileStore struct {
+	dir       string
+	lastSweep atomic.Int64
+}
+
+// NewFileStore returns a FileStore keeping sessions in dir, which is created if
+// it doesn't exist.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// file returns the path of the file for session id. Ids are generated by the
+// middleware, but they come back from cookies so anything which could escape
+// the directory is rejected.
+func (f *FileStore) file(id string) (string, bool) {
+	if id == "" || strings.ContainsAny(id, `/\.`) {
+		return "", false
+	}
+	return filepath.Join(f.dir, id), true
+}
+
+// Get implements Store.
+func (f *FileStore) Get(id string) ([]byte, error) {
+	name, ok := f.file(id)
+	if !ok {
+		return nil, nil
+	}
+	info, err := os.Stat(name)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	// the modification time of the file is set to the expiry time.
+	if time.Now().After(info.ModTime()) {
+		return nil, f.Delete(id)
+	}
+	data, err := os.ReadFile(name)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return data, err
+}
+
+// Set implements Store.
+func (f *FileStore) Set(id string, data []byte, ttl time.Duration) error {
+	name, ok := f.file(id)
+	if !ok {
+		return nil
+	}
+	now := time.Now()
+	if last := f.lastSweep.Load(); now.UnixNano()-last > int64(sweepInterval) && f.lastSweep.CompareAndSwap(last, now.UnixNano()) {
+		go f.sweep(now)
+	}
+	// the file is written aside and renamed, so that it is never seen with the
+	// time of the write as its expiry time.
+	tmp, err := os.CreateTemp(f.dir, id+".*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	expires := now.Add(ttl)
+	if err == nil {
+		err = os.Chtimes(tmp.Name(), expires, expires)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), name)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// sweep removes the files of the sessions which expired for longer than
+// sweepInterval, and the temporary files left by failed writes.
+func (f *FileStore) sweep(now time.Time) {
+	entries, err := os.ReadDir(f.dir)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		if info.ModTime().Before(now.Add(-sweepInterval)) {
+			os.Remove(filepath.Join(f.dir, e.Name()))
+		}
+	}
+}
+
+// Delete implements Store.
+func (f *FileStore) Delete(id string) error {
+	name, ok := f.file(id)
+	if !ok {
+		return nil
+	}
+	err := os.Remove(name)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
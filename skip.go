@@ -0,0 +1,60 @@
+package alien
+
+import (
+	"net/http"
+	"path"
+	"strings"
+)
+
+// Skipper returns true for requests which a middleware must pass to the next
+// handler untouched. The middlewares of alien's packages accept one in the
+// Skipper field of their options.
+type Skipper func(r *http.Request) bool
+
+// Skip returns a middleware which applies mw to requests, except those for
+// which skip returns true.
+//   m.Use(alien.Skip(logger, func(r *http.Request) bool {
+//       return r.Header.Get("User-Agent") == "kube-probe"
+//   }))
+func Skip(mw func(http.Handler) http.Handler, skip Skipper) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		wrapped := mw(h)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if skip(r) {
+				h.ServeHTTP(w, r)
+				return
+			}
+			wrapped.ServeHTTP(w, r)
+		})
+	}
+}
+
+// SkipPaths returns a Skipper for requests to one of paths. A path matches
+// requests with the same cleaned path or served by a route with the same
+// pattern, and a path ending with /* matches every path below it.
+//   alien.SkipPaths("/healthz", "/users/:id", "/static/*")
+func SkipPaths(paths ...string) Skipper {
+	return func(r *http.Request) bool {
+		p := path.Clean(r.URL.Path)
+		pattern := RoutePattern(r)
+		for _, v := range paths {
+			if prefix, ok := strings.CutSuffix(v, "/*"); ok {
+				if p == prefix || strings.HasPrefix(p, prefix+"/") {
+					return true
+				}
+				continue
+			}
+			if p == v || pattern == v {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// UseExcept is like Use, but mw is skipped for requests to paths as matched by
+// SkipPaths.
+//   m.UseExcept(requireAuth, "/healthz", "/metrics")
+func (m *Mux) UseExcept(mw func(http.Handler) http.Handler, paths ...string) {
+	m.Use(Skip(mw, SkipPaths(paths...)))
+}
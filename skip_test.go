@@ -0,0 +1,64 @@
+package alien
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMux_UseExcept(t *testing.T) {
+	mark := func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Mark", "auth")
+			h.ServeHTTP(w, r)
+		})
+	}
+	h := func(_ http.ResponseWriter, _ *http.Request) {}
+	m := New()
+	m.UseExcept(mark, "/healthz", "/users/:id", "/static/*")
+	for _, p := range []string{"/healthz", "/metrics", "/users/:id", "/users", "/static/*", "/statics"} {
+		m.Get(p, h)
+	}
+	sample := []struct {
+		path string
+		mark bool
+	}{
+		{"/healthz", false},
+		{"/healthz/", false},
+		{"/metrics", true},
+		{"/users/42", false},
+		{"/users", true},
+		{"/static/css/site.css", false},
+		{"/statics", true},
+	}
+	for _, v := range sample {
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, httptest.NewRequest("GET", v.path, nil))
+		if marked := w.Header().Get("X-Mark") != ""; marked != v.mark {
+			t.Errorf("%s: expected marked to be %v", v.path, v.mark)
+		}
+	}
+}
+
+func TestSkip(t *testing.T) {
+	mark := func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Mark", "log")
+			h.ServeHTTP(w, r)
+		})
+	}
+	probe := func(r *http.Request) bool {
+		return strings.HasPrefix(r.UserAgent(), "kube-probe")
+	}
+	h := Skip(mark, probe)(http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {}))
+	for _, ua := range []string{"kube-probe/1.29", "curl/8.0"} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("User-Agent", ua)
+		h.ServeHTTP(w, req)
+		if marked := w.Header().Get("X-Mark") != ""; marked == probe(req) {
+			t.Errorf("%s: expected marked to be %v", ua, !probe(req))
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package alien
+
+import (
+	"log"
+	"net/http"
+	"runtime"
+	"time"
+)
+
+// SlowRequest describes a request which took longer than the threshold given
+// to SlowRequests.
+type SlowRequest struct {
+	Method   string
+	Path     string
+	Pattern  string
+	Params   Params
+	Duration time.Duration
+
+	// Stack holds the stacks of all goroutines when the threshold was
+	// reached, if SlowStack was given.
+	Stack []byte
+}
+
+// SlowOption configures the middleware returned by SlowRequests.
+type SlowOption func(*slowConfig)
+
+type slowConfig struct {
+	stack  bool
+	report func(*SlowRequest)
+}
+
+// SlowStack captures the stacks of all goroutines when a request reaches the
+// threshold, which shows where the handler is stuck. It stops the world while
+// doing so, keep the threshold high enough for it to be rare.
+func SlowStack() SlowOption {
+	return func(c *slowConfig) {
+		c.stack = true
+	}
+}
+
+// SlowReporter sets fn to report slow requests instead of logging them.
+func SlowReporter(fn func(s *SlowRequest)) SlowOption {
+	return func(c *slowConfig) {
+		c.report = fn
+	}
+}
+
+// SlowRequests returns a middleware which reports requests whose handler takes
+// longer than threshold, with their route pattern and params. They are logged
+// with the logger of the Mux, see WithLogger, or the standard logger if it has
+// none.
+//   m.Use(alien.SlowRequests(2*time.Second, alien.SlowStack()))
+// logs
+//   alien: slow request GET /users/42 matched /users/:id [id=42] took 3.2s
+func SlowRequests(threshold time.Duration, opts ...SlowOption) func(http.Handler) http.Handler {
+	var c slowConfig
+	for _, o := range opts {
+		o(&c)
+	}
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var stack []byte
+			captured := make(chan struct{})
+			var timer *time.Timer
+			if c.stack {
+				timer = time.AfterFunc(threshold, func() {
+					defer close(captured)
+					buf := make([]byte, 64<<10)
+					for {
+						n := runtime.Stack(buf, true)
+						if n < len(buf) {
+							stack = buf[:n]
+							return
+						}
+						buf = make([]byte, 2*len(buf))
+					}
+				})
+			}
+			start := time.Now()
+			defer func() {
+				d := time.Since(start)
+				if timer != nil && !timer.Stop() {
+					<-captured
+				}
+				if d < threshold {
+					return
+				}
+				s := &SlowRequest{
+					Method:   r.Method,
+					Path:     r.URL.Path,
+					Pattern:  RoutePattern(r),
+					Params:   append(Params(nil), GetParams(r)...),
+					Duration: d,
+					Stack:    stack,
+				}
+				if c.report != nil {
+					c.report(s)
+					return
+				}
+				logSlow(r, s)
+			}()
+			h.ServeHTTP(w, r)
+		})
+	}
+}
+
+// logSlow logs s with the logger of the Mux serving r.
+func logSlow(r *http.Request, s *SlowRequest) {
+	l := log.Default()
+	if m := muxOf(r); m != nil && m.top().logger != nil {
+		l = m.top().logger
+	}
+	var params []byte
+	for i, p := range s.Params {
+		if i > 0 {
+			params = append(params, ' ')
+		}
+		params = append(params, p.Key+"="+p.Value...)
+	}
+	l.Printf("alien: slow request %s %s matched %s [%s] took %v", s.Method, s.Path, s.Pattern, params, s.Duration)
+	if s.Stack != nil {
+		l.Printf("alien: goroutines of slow request %s %s:\n%s", s.Method, s.Path, s.Stack)
+	}
+}
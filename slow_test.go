@@ -0,0 +1,54 @@
+package alien
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSlowRequests(t *testing.T) {
+	var reported []*SlowRequest
+	m := New()
+	m.Use(SlowRequests(20*time.Millisecond, SlowStack(), SlowReporter(func(s *SlowRequest) {
+		reported = append(reported, s)
+	})))
+	m.Get("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		if GetParams(r).Get("id") == "slow" {
+			time.Sleep(50 * time.Millisecond)
+		}
+	})
+	for _, path := range []string{"/users/fast", "/users/slow"} {
+		m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+	if len(reported) != 1 {
+		t.Fatalf("expected 1 slow request got %d", len(reported))
+	}
+	s := reported[0]
+	if s.Method != "GET" || s.Path != "/users/slow" || s.Pattern != "/users/:id" || s.Params.Get("id") != "slow" {
+		t.Errorf("unexpected report %+v", s)
+	}
+	if s.Duration < 50*time.Millisecond {
+		t.Errorf("expected at least 50ms got %v", s.Duration)
+	}
+	if !bytes.Contains(s.Stack, []byte("time.Sleep")) {
+		t.Errorf("expected the stack of the sleeping handler got %s", s.Stack)
+	}
+
+	var buf bytes.Buffer
+	m = New(WithLogger(log.New(&buf, "", 0)))
+	m.Get("/reports/:year", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(5 * time.Millisecond)
+	}, SlowRequests(time.Millisecond))
+	m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/reports/2024", nil))
+	expect := "alien: slow request GET /reports/2024 matched /reports/:year [year=2024] took "
+	if !strings.HasPrefix(buf.String(), expect) {
+		t.Errorf("expected %q got %q", expect, buf.String())
+	}
+	if strings.Contains(buf.String(), "goroutines") {
+		t.Error("expected no stack without SlowStack")
+	}
+}
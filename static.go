@@ -0,0 +1,263 @@
+package alien
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StaticOption configures how static files are served.
+type StaticOption func(*staticConfig)
+
+type staticConfig struct {
+	index        string
+	notFound     http.Handler
+	sub          string
+	cacheControl string
+	etags        bool
+	push         map[string][]string
+	hashes       sync.Map
+}
+
+// fileHash is a cached content hash of a file, it is valid as long as the
+// modification time and size of the file don't change.
+type fileHash struct {
+	modTime time.Time
+	size    int64
+	etag    string
+}
+
+// Index sets the file served for requests to directories, for instance
+// index.html. Without it requests to directories are not found.
+func Index(name string) StaticOption {
+	return func(c *staticConfig) {
+		c.index = name
+	}
+}
+
+// StaticNotFound sets the handler executed when the requested file doesn't
+// exist. Defaults to the not found handler of the Mux.
+func StaticNotFound(h http.Handler) StaticOption {
+	return func(c *staticConfig) {
+		c.notFound = h
+	}
+}
+
+// Sub serves files from the directory dir inside the root instead of the root
+// itself. It is useful with embed.FS whose root contains the embedded
+// directory.
+//   //go:embed public
+//   var public embed.FS
+//   m.StaticFS("/assets", public, alien.Sub("public"))
+func Sub(dir string) StaticOption {
+	return func(c *staticConfig) {
+		c.sub = dir
+	}
+}
+
+// CacheControl sets the Cache-Control header of served files to value, for
+// instance "public, max-age=31536000, immutable".
+func CacheControl(value string) StaticOption {
+	return func(c *staticConfig) {
+		c.cacheControl = value
+	}
+}
+
+// PushManifest declares the assets pushed with the files of manifest, which
+// maps the names of files, relative to the root, to the paths of the assets
+// the client needs to render them. Assets are pushed with Push when a file is
+// requested with GET, so HTML pages get their stylesheets and scripts without
+// another round trip.
+//   m.Static("/", "./public", alien.Index("index.html"), alien.PushManifest(map[string][]string{
+//       "/index.html": {"/css/app.css", "/js/app.js"},
+//   }))
+func PushManifest(manifest map[string][]string) StaticOption {
+	return func(c *staticConfig) {
+		c.push = make(map[string][]string, len(manifest))
+		for k, v := range manifest {
+			c.push[path.Join("/", k)] = v
+		}
+	}
+}
+
+// Static serves files from the directory root for GET and HEAD requests whose
+// path starts with prefix. The files are served through the route tree, so
+// middlewares of m apply to them.
+//   m.Static("/assets", "./public", alien.Index("index.html"))
+// will serve ./public/css/app.css for requests to /assets/css/app.css
+//
+// Files served at the root of m, with the prefix "/", are only served to
+// requests which don't match any route, so they don't conflict with the routes
+// registered on m.
+//
+// Requests can not escape root, the Content-Type is set from the file
+// extension or content, and conditional and range requests are supported.
+func (m *Mux) Static(prefix, root string, opts ...StaticOption) error {
+	c := newStaticConfig(opts)
+	return m.serveFiles(prefix, http.Dir(path.Join(root, c.sub)), c)
+}
+
+// StaticFS is like Static but serves files from fsys, for instance an
+// embed.FS. Files are served with a strong ETag derived from the hash of their
+// content, which is computed once per file.
+func (m *Mux) StaticFS(prefix string, fsys fs.FS, opts ...StaticOption) error {
+	c := newStaticConfig(opts)
+	c.etags = true
+	if c.sub != "" {
+		sub, err := fs.Sub(fsys, c.sub)
+		if err != nil {
+			return err
+		}
+		fsys = sub
+	}
+	return m.serveFiles(prefix, http.FS(fsys), c)
+}
+
+// SPA serves a single page application from fsys for GET and HEAD requests
+// whose path starts with prefix. Requests for files which exist in fsys are
+// served like StaticFS does, while any other path is served the index file so
+// the application can handle its own routing.
+//   m.Get("/api/users", users)
+//   m.SPA("/", dist, "index.html", alien.Sub("dist"))
+// will serve /api/users with the users handler, /app.js from dist/app.js and
+// /users/42 with dist/index.html
+//
+// The application is only used for requests which don't match any route, so
+// routes registered on m always win regardless of the order of registration.
+func (m *Mux) SPA(prefix string, fsys fs.FS, index string, opts ...StaticOption) error {
+	c := newStaticConfig(opts)
+	c.etags = true
+	if c.sub != "" {
+		sub, err := fs.Sub(fsys, c.sub)
+		if err != nil {
+			return err
+		}
+		fsys = sub
+	}
+	files := http.FS(fsys)
+	prefix = path.Join("/", m.prefix, prefix)
+	index = path.Join("/", index)
+	h := func(w http.ResponseWriter, r *http.Request) {
+		name := path.Clean("/" + strings.TrimPrefix(path.Clean(r.URL.Path), prefix))
+		if c.serveFile(w, r, files, name, "") || c.serveFile(w, r, files, index, "") {
+			return
+		}
+		m.closestNotFound().ServeHTTP(w, r)
+	}
+	m.addFallback(&route{mux: m, path: prefix, handler: h, middleware: m.chain()})
+	return nil
+}
+
+func newStaticConfig(opts []StaticOption) *staticConfig {
+	c := &staticConfig{}
+	for _, o := range opts {
+		o(c)
+	}
+	return c
+}
+
+func (m *Mux) serveFiles(prefix string, fs http.FileSystem, c *staticConfig) error {
+	serve := func(w http.ResponseWriter, r *http.Request, name string) {
+		if c.serveFile(w, r, fs, name, c.index) {
+			return
+		}
+		if c.notFound != nil {
+			c.notFound.ServeHTTP(w, r)
+			return
+		}
+		m.closestNotFound().ServeHTTP(w, r)
+	}
+	if path.Join("/", prefix) == "/" {
+		// a catch all route at the root of m would conflict with every GET
+		// route of m, files are served to requests which don't match one
+		// instead, like SPA does.
+		root := path.Join("/", m.prefix)
+		h := func(w http.ResponseWriter, r *http.Request) {
+			serve(w, r, path.Clean("/"+strings.TrimPrefix(path.Clean(r.URL.Path), root)))
+		}
+		m.addFallback(&route{mux: m, path: root, handler: h, middleware: m.chain()})
+		return nil
+	}
+	h := func(w http.ResponseWriter, r *http.Request) {
+		serve(w, r, path.Clean("/"+GetParams(r).Get("catch")))
+	}
+	// request paths are cleaned, so /assets/ is matched as /assets which the
+	// catch all route doesn't match.
+	if err := m.Get(path.Join("/", prefix), h); err != nil {
+		return err
+	}
+	return m.Get(path.Join("/", prefix, "*"), h)
+}
+
+// serveFile serves the file name from fs, if name is a directory index is
+// served instead. It returns false if there is no such file.
+func (c *staticConfig) serveFile(w http.ResponseWriter, r *http.Request, fs http.FileSystem, name, index string) bool {
+	f, err := fs.Open(name)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	if info.IsDir() {
+		if index == "" {
+			return false
+		}
+		return c.serveFile(w, r, fs, path.Join(name, index), "")
+	}
+	if c.cacheControl != "" {
+		w.Header().Set("Cache-Control", c.cacheControl)
+	}
+	if r.Method == http.MethodGet {
+		for _, target := range c.push[name] {
+			Push(w, target, nil)
+		}
+	}
+	if c.etags {
+		if etag := c.etag(name, info, f); etag != "" {
+			w.Header().Set("ETag", etag)
+		}
+	}
+	http.ServeContent(w, r, info.Name(), info.ModTime(), f)
+	return true
+}
+
+// etag returns the ETag of the file name, computing it from the content of f
+// if it is not cached.
+func (c *staticConfig) etag(name string, info fs.FileInfo, f http.File) string {
+	if v, ok := c.hashes.Load(name); ok {
+		h := v.(*fileHash)
+		if h.modTime.Equal(info.ModTime()) && h.size == info.Size() {
+			return h.etag
+		}
+	}
+	sum := sha256.New()
+	if _, err := io.Copy(sum, f); err != nil {
+		return ""
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return ""
+	}
+	etag := `"` + hex.EncodeToString(sum.Sum(nil)[:16]) + `"`
+	c.hashes.Store(name, &fileHash{modTime: info.ModTime(), size: info.Size(), etag: etag})
+	return etag
+}
+
+// closestNotFound returns the not found handler of the closest group or Mux
+// which has one, starting with m itself.
+func (m *Mux) closestNotFound() http.Handler {
+	for g := m; g != nil; g = g.parent {
+		if g.notFound != nil {
+			return g.notFound
+		}
+	}
+	return http.NotFoundHandler()
+}
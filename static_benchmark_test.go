@@ -174,3 +174,36 @@ func init() {
 func BenchmarkAlien_StaticAll(b *testing.B) {
 	benchRoutes(b, staticAlien, staticRoutes)
 }
+
+// BenchmarkAlien_StaticRouting serves the static routes with a handler which
+// doesn't allocate, so that only routing is measured.
+func BenchmarkAlien_StaticRouting(b *testing.B) {
+	m := New()
+	for _, v := range staticRoutes {
+		m.AddRoute(v.method, v.path, alienHandle)
+	}
+	benchRoutes(b, m, staticRoutes)
+}
+
+// BenchmarkAlien_StaticLookup finds the static routes with the index of routes
+// without params, BenchmarkAlien_StaticTreeLookup by walking the tree.
+func BenchmarkAlien_StaticLookup(b *testing.B) {
+	m := loadAlien(staticRoutes)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, v := range staticRoutes {
+			m.find(v.method, v.path, "")
+		}
+	}
+}
+
+func BenchmarkAlien_StaticTreeLookup(b *testing.B) {
+	m := loadAlien(staticRoutes)
+	root := *m.load().root("GET")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, v := range staticRoutes {
+			root.find(v.path, "")
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package alien
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestMux_Static(t *testing.T) {
+	m := New()
+	m.Static("/assets", "testdata/public", Index("index.html"))
+	m.Group("/files").Static("/", "testdata/public", StaticNotFound(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})))
+
+	sample := []struct {
+		path, body, contentType string
+		code                    int
+	}{
+		{"/assets/css/app.css", "body{}\n", "text/css; charset=utf-8", http.StatusOK},
+		{"/assets/index.html", "<h1>alien</h1>\n", "text/html; charset=utf-8", http.StatusOK},
+		{"/assets/", "<h1>alien</h1>\n", "text/html; charset=utf-8", http.StatusOK},
+		{"/assets/css", "", "", http.StatusNotFound},
+		{"/assets/missing.js", "", "", http.StatusNotFound},
+		{"/assets/../static.go", "", "", http.StatusNotFound},
+		{"/files/css/app.css", "body{}\n", "text/css; charset=utf-8", http.StatusOK},
+		{"/files/", "", "", http.StatusTeapot},
+	}
+	for _, v := range sample {
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.URL.Path = v.path
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, req)
+		if w.Code != v.code {
+			t.Errorf("%s: expected %d got %d", v.path, v.code, w.Code)
+		}
+		if v.code != http.StatusOK {
+			continue
+		}
+		if w.Body.String() != v.body {
+			t.Errorf("%s: expected %s got %s", v.path, v.body, w.Body)
+		}
+		if ct := w.Header().Get("Content-Type"); ct != v.contentType {
+			t.Errorf("%s: expected %s got %s", v.path, v.contentType, ct)
+		}
+	}
+
+	req, _ := http.NewRequest("HEAD", "/assets/css/app.css", nil)
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.Len() != 0 {
+		t.Errorf("unexpected HEAD response %d %s", w.Code, w.Body)
+	}
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/css") {
+		t.Errorf("unexpected content type %s", w.Header().Get("Content-Type"))
+	}
+}
+
+func TestMux_Static_root(t *testing.T) {
+	users := func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte("users"))
+	}
+	before := New()
+	if err := before.Static("/", "testdata/public", Index("index.html")); err != nil {
+		t.Fatal(err)
+	}
+	if err := before.Get("/api/users", users); err != nil {
+		t.Fatal(err)
+	}
+	after := New()
+	if err := after.Get("/api/users", users); err != nil {
+		t.Fatal(err)
+	}
+	if err := after.Static("/", "testdata/public", Index("index.html")); err != nil {
+		t.Fatal(err)
+	}
+
+	sample := []struct {
+		path, body string
+		code       int
+	}{
+		{"/api/users", "users", http.StatusOK},
+		{"/css/app.css", "body{}\n", http.StatusOK},
+		{"/", "<h1>alien</h1>\n", http.StatusOK},
+		{"/api/missing", "", http.StatusNotFound},
+	}
+	for _, m := range []*Mux{before, after} {
+		for _, v := range sample {
+			req, _ := http.NewRequest("GET", v.path, nil)
+			w := httptest.NewRecorder()
+			m.ServeHTTP(w, req)
+			if w.Code != v.code {
+				t.Errorf("%s: expected %d got %d", v.path, v.code, w.Code)
+			}
+			if v.code == http.StatusOK && w.Body.String() != v.body {
+				t.Errorf("%s: expected %s got %s", v.path, v.body, w.Body)
+			}
+		}
+	}
+}
+
+func TestMux_StaticFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"dist/app.js":     {Data: []byte("alert('alien')")},
+		"dist/index.html": {Data: []byte("<h1>alien</h1>")},
+	}
+	m := New()
+	err := m.StaticFS("/assets", fsys, Sub("dist"), Index("index.html"), CacheControl("public, max-age=3600"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest("GET", "/assets/app.js", nil)
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != "alert('alien')" {
+		t.Fatalf("unexpected response %d %s", w.Code, w.Body)
+	}
+	if cc := w.Header().Get("Cache-Control"); cc != "public, max-age=3600" {
+		t.Errorf("expected public, max-age=3600 got %s", cc)
+	}
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("expected an etag")
+	}
+
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	m.ServeHTTP(w, req)
+	if w.Code != http.StatusNotModified {
+		t.Errorf("expected %d got %d", http.StatusNotModified, w.Code)
+	}
+
+	req, _ = http.NewRequest("GET", "/assets/", nil)
+	w = httptest.NewRecorder()
+	m.ServeHTTP(w, req)
+	if w.Body.String() != "<h1>alien</h1>" {
+		t.Errorf("expected index got %s", w.Body)
+	}
+	if e := w.Header().Get("ETag"); e == "" || e == etag {
+		t.Errorf("unexpected etag %s", e)
+	}
+}
+
+func TestMux_SPA(t *testing.T) {
+	fsys := fstest.MapFS{
+		"dist/app.js":     {Data: []byte("alert('alien')")},
+		"dist/index.html": {Data: []byte("<h1>alien</h1>")},
+	}
+	m := New()
+	m.Get("/api/users", func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte("users"))
+	})
+	m.Post("/api/login", func(_ http.ResponseWriter, _ *http.Request) {})
+	m.SPA("/", fsys, "index.html", Sub("dist"))
+	m.Group("/admin").SPA("/", fsys, "/dist/index.html")
+
+	sample := []struct {
+		method, path, body string
+		code               int
+	}{
+		{"GET", "/api/users", "users", http.StatusOK},
+		{"GET", "/app.js", "alert('alien')", http.StatusOK},
+		{"GET", "/", "<h1>alien</h1>", http.StatusOK},
+		{"GET", "/users/42", "<h1>alien</h1>", http.StatusOK},
+		{"GET", "/admin/dist/app.js", "alert('alien')", http.StatusOK},
+		{"GET", "/admin/settings", "<h1>alien</h1>", http.StatusOK},
+		{"HEAD", "/users/42", "", http.StatusOK},
+		{"GET", "/api/login", "", http.StatusMethodNotAllowed},
+		{"POST", "/users/42", "", http.StatusNotFound},
+	}
+	for _, v := range sample {
+		req, _ := http.NewRequest(v.method, v.path, nil)
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, req)
+		if w.Code != v.code {
+			t.Errorf("%s %s: expected %d got %d", v.method, v.path, v.code, w.Code)
+		}
+		if v.code == http.StatusOK && w.Body.String() != v.body {
+			t.Errorf("%s %s: expected %s got %s", v.method, v.path, v.body, w.Body)
+		}
+	}
+}
+
+func TestPushManifest(t *testing.T) {
+	m := New()
+	m.Static("/", "testdata/public", Index("index.html"), PushManifest(map[string][]string{
+		"index.html": {"/css/app.css"},
+	}))
+	sample := []struct {
+		method, path string
+		pushed       []string
+	}{
+		{"GET", "/", []string{"/css/app.css"}},
+		{"GET", "/index.html", []string{"/css/app.css"}},
+		{"HEAD", "/", nil},
+		{"GET", "/css/app.css", nil},
+	}
+	for _, v := range sample {
+		fw := &fullWriter{ResponseRecorder: httptest.NewRecorder()}
+		m.ServeHTTP(fw, httptest.NewRequest(v.method, v.path, nil))
+		if !reflect.DeepEqual(fw.pushed, v.pushed) {
+			t.Errorf("%s %s: expected %v got %v", v.method, v.path, v.pushed, fw.pushed)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package alien
+
+import (
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// Stats are counters of the requests served by a Mux, see EnableStats.
+type Stats struct {
+	Started  time.Time `json:"started"`
+	Uptime   string    `json:"uptime"`
+	Requests int64     `json:"requests"`
+	InFlight int64     `json:"in_flight"`
+
+	// Status counts the responses by class of status, 2xx, 4xx and so on.
+	Status map[string]int64 `json:"status"`
+
+	// Routes counts the requests served by each route, keyed by method and
+	// pattern like "GET /users/:id". Patterns of hosts are prefixed with their
+	// host pattern.
+	Routes map[string]int64 `json:"routes"`
+}
+
+// stats collects the counters of Stats.
+type stats struct {
+	started  time.Time
+	requests atomic.Int64
+	inFlight atomic.Int64
+	classes  [6]atomic.Int64
+}
+
+// record counts a response with status.
+func (s *stats) record(status int) {
+	if c := status / 100; c > 0 && c < len(s.classes) {
+		s.classes[c].Add(1)
+	}
+}
+
+// EnableStats starts counting the requests served by m, and serves the
+// counters as JSON with pattern. It is a lightweight alternative to a metrics
+// library, for instance
+//   m.EnableStats("/debug/alien")
+// responds with
+//   {
+//     "started": "2024-05-01T10:00:00Z",
+//     "uptime": "2h13m5s",
+//     "requests": 1042,
+//     "in_flight": 3,
+//     "status": {"2xx": 1001, "4xx": 38},
+//     "routes": {"GET /users/:id": 980, "POST /users": 21}
+//   }
+//
+// The counters are returned by Stats too, to publish them with expvar
+//   expvar.Publish("alien", expvar.Func(func() interface{} { return m.Stats() }))
+//
+// Counting applies to the whole Mux, protect pattern like any other route.
+func (m *Mux) EnableStats(pattern string) error {
+	t := m.top()
+	if t.stats == nil {
+		t.stats = &stats{started: time.Now()}
+	}
+	return m.Get(pattern, func(w http.ResponseWriter, r *http.Request) {
+		JSON(w, http.StatusOK, t.Stats())
+	})
+}
+
+// Stats returns the counters of the requests served by m, they are all zero
+// unless EnableStats was called.
+func (m *Mux) Stats() Stats {
+	t := m.top()
+	st := Stats{Status: make(map[string]int64), Routes: make(map[string]int64)}
+	s := t.stats
+	if s == nil {
+		return st
+	}
+	st.Started = s.started
+	st.Uptime = time.Since(s.started).Round(time.Second).String()
+	st.Requests = s.requests.Load()
+	st.InFlight = s.inFlight.Load()
+	for c := 1; c < len(s.classes); c++ {
+		if n := s.classes[c].Load(); n > 0 {
+			st.Status[strconv.Itoa(c)+"xx"] = n
+		}
+	}
+	t.walkRoutes(func(method string, rt *route) {
+		if n := rt.hits.Load(); n > 0 {
+			st.Routes[method+" "+rt.mux.hostOf()+rt.path] += n
+		}
+	})
+	return st
+}
+
+// hostOf returns the host pattern of the host m belongs to, or "" if it
+// matches any host.
+func (m *Mux) hostOf() string {
+	for g := m; g != nil; g = g.parent {
+		if g.host != "" {
+			return g.host
+		}
+	}
+	return ""
+}
@@ -0,0 +1,67 @@
+package alien
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestMux_EnableStats(t *testing.T) {
+	m := New()
+	var inFlight int64
+	m.Get("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		inFlight = m.Stats().InFlight
+	})
+	m.Post("/users", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})
+	m.Host("api.example.com").Get("/", func(w http.ResponseWriter, r *http.Request) {})
+	if s := m.Stats(); s.Requests != 0 || len(s.Routes) != 0 {
+		t.Errorf("expected no stats before EnableStats got %+v", s)
+	}
+	if err := m.Group("/debug").EnableStats("/alien"); err != nil {
+		t.Fatal(err)
+	}
+	sample := []struct {
+		method, host, path string
+	}{
+		{"GET", "example.com", "/users/1"},
+		{"GET", "example.com", "/users/2"},
+		{"POST", "example.com", "/users"},
+		{"GET", "example.com", "/nowhere"},
+		{"GET", "api.example.com", "/"},
+	}
+	for _, v := range sample {
+		req := httptest.NewRequest(v.method, v.path, nil)
+		req.Host = v.host
+		m.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	if inFlight != 1 {
+		t.Errorf("expected 1 request in flight got %d", inFlight)
+	}
+
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest("GET", "/debug/alien", nil))
+	var s Stats
+	if err := json.Unmarshal(w.Body.Bytes(), &s); err != nil {
+		t.Fatal(err)
+	}
+	if s.Requests != 6 || s.InFlight != 1 || s.Started.IsZero() || s.Uptime == "" {
+		t.Errorf("unexpected counters %+v", s)
+	}
+	status := map[string]int64{"2xx": 4, "4xx": 1}
+	if !reflect.DeepEqual(s.Status, status) {
+		t.Errorf("expected %v got %v", status, s.Status)
+	}
+	// requests are counted once served, the request for the stats is not.
+	routes := map[string]int64{
+		"GET /users/:id":       2,
+		"POST /users":          1,
+		"GET api.example.com/": 1,
+	}
+	if !reflect.DeepEqual(s.Routes, routes) {
+		t.Errorf("expected %v got %v", routes, s.Routes)
+	}
+}
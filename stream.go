@@ -0,0 +1,106 @@
+package alien
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// StreamWriter writes a streamed response, see Stream.
+type StreamWriter struct {
+	w   http.ResponseWriter
+	rc  *http.ResponseController
+	ctx context.Context
+}
+
+// Stream calls fn to write a long running response to w, like an NDJSON export
+// or a feed, with a StreamWriter which flushes when asked to and stops writing
+// once the client of r goes away.
+//   alien.Stream(w, r, func(sw *alien.StreamWriter) error {
+//       for rows.Next() {
+//           if err := sw.WriteJSON(row); err != nil {
+//               return err // the client is gone
+//           }
+//           if err := sw.Flush(); err != nil {
+//               return err
+//           }
+//       }
+//       return rows.Err()
+//   })
+//
+// Streams outlive the WriteTimeout of servers, so the write deadline of the
+// connection is lifted, set one with SetWriteDeadline to bound slow clients.
+// The error of fn is returned, the client keeps what was written before it.
+func Stream(w http.ResponseWriter, r *http.Request, fn func(sw *StreamWriter) error) error {
+	sw := &StreamWriter{w: w, rc: http.NewResponseController(w), ctx: r.Context()}
+	sw.rc.SetWriteDeadline(time.Time{})
+	return fn(sw)
+}
+
+// Context returns the context of the request, which is done when the client
+// goes away.
+func (sw *StreamWriter) Context() context.Context {
+	return sw.ctx
+}
+
+// Header returns the header of the response, it must be set before the first
+// write.
+func (sw *StreamWriter) Header() http.Header {
+	return sw.w.Header()
+}
+
+// Write writes b to the response, or returns the error of the context if the
+// client went away.
+func (sw *StreamWriter) Write(b []byte) (int, error) {
+	if err := sw.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return sw.w.Write(b)
+}
+
+// WriteJSON writes v encoded as JSON followed by a newline, a line of NDJSON.
+// The Content-Type is set to application/x-ndjson if it wasn't set before the
+// response was committed.
+func (sw *StreamWriter) WriteJSON(v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if h := sw.w.Header(); h.Get("Content-Type") == "" && !Committed(sw.w) {
+		h.Set("Content-Type", "application/x-ndjson")
+	}
+	_, err = sw.Write(append(b, '\n'))
+	return err
+}
+
+// Flush sends the buffered response to the client. Writers which can't flush
+// are ignored.
+func (sw *StreamWriter) Flush() error {
+	if err := sw.ctx.Err(); err != nil {
+		return err
+	}
+	if err := sw.rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
+
+// DeclareTrailer declares the trailers names, it must be called before the
+// first write. See DeclareTrailer.
+func (sw *StreamWriter) DeclareTrailer(names ...string) {
+	DeclareTrailer(sw.w, names...)
+}
+
+// SetTrailer sets the trailer key to value, to report how the stream ended
+// after the last write for instance. See SetTrailer.
+func (sw *StreamWriter) SetTrailer(key, value string) {
+	SetTrailer(sw.w, key, value)
+}
+
+// SetWriteDeadline sets the deadline for writes to the connection, writes
+// after it fail. The zero time means no deadline.
+func (sw *StreamWriter) SetWriteDeadline(t time.Time) error {
+	return sw.rc.SetWriteDeadline(t)
+}
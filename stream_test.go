@@ -0,0 +1,89 @@
+package alien
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestStream(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/export", nil)
+	err := Stream(w, r, func(sw *StreamWriter) error {
+		for i := 0; i < 3; i++ {
+			if err := sw.WriteJSON(map[string]int{"n": i}); err != nil {
+				return err
+			}
+		}
+		return sw.Flush()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("expected ndjson got %s", ct)
+	}
+	if b := w.Body.String(); b != "{\"n\":0}\n{\"n\":1}\n{\"n\":2}\n" || !w.Flushed {
+		t.Errorf("expected 3 flushed lines got %q", b)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = Stream(httptest.NewRecorder(), r.WithContext(ctx), func(sw *StreamWriter) error {
+		if _, err := sw.Write([]byte("x")); !errors.Is(err, context.Canceled) {
+			t.Errorf("expected the write to fail once the client is gone got %v", err)
+		}
+		return sw.Flush()
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected %v got %v", context.Canceled, err)
+	}
+}
+
+func TestStream_disconnect(t *testing.T) {
+	done := make(chan error, 1)
+	m := New()
+	m.Get("/feed", func(w http.ResponseWriter, r *http.Request) {
+		done <- Stream(w, r, func(sw *StreamWriter) error {
+			for i := 0; ; i++ {
+				if _, err := sw.Write([]byte(strconv.Itoa(i) + "\n")); err != nil {
+					return err
+				}
+				if err := sw.Flush(); err != nil {
+					return err
+				}
+				time.Sleep(5 * time.Millisecond)
+			}
+		})
+	})
+	s := httptest.NewUnstartedServer(m)
+	// the stream outlives the write timeout.
+	s.Config.WriteTimeout = 20 * time.Millisecond
+	s.Start()
+	defer s.Close()
+
+	res, err := http.Get(s.URL + "/feed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc := bufio.NewScanner(res.Body)
+	for i := 0; i < 20; i++ {
+		if !sc.Scan() || sc.Text() != strconv.Itoa(i) {
+			t.Fatalf("expected line %d got %q %v", i, sc.Text(), sc.Err())
+		}
+	}
+	res.Body.Close()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("expected the stream to stop with an error")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the stream to stop once the client went away")
+	}
+}
@@ -0,0 +1,149 @@
+package alien
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Timeout returns a middleware which cancels the request context after d and
+// responds with 503 Service Unavailable if the handler has not returned by then.
+//
+// The response of the handler is buffered and written only when the handler
+// returns in time, writes made after the deadline fail with
+// http.ErrHandlerTimeout, flushes are ignored and connections can't be
+// hijacked. Handlers doing long running work should watch
+// r.Context().Done() and return early. If the client goes away before the
+// deadline nothing is written.
+//
+// Timeout can be used globally with Use, or on a single route
+//   m.Get("/report", report, alien.Timeout(5*time.Second))
+func Timeout(d time.Duration) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// the handler may outlive this call, so the route context must not
+			// be recycled when the Mux is done with the request.
+			retain(r.Context())
+			ctx, cancel := context.WithCancel(r.Context())
+			defer cancel()
+			timer := time.NewTimer(d)
+			defer timer.Stop()
+			r = r.WithContext(ctx)
+			tw := &timeoutWriter{w: w, header: make(http.Header)}
+			done := make(chan struct{})
+			panicked := make(chan interface{}, 1)
+			go func() {
+				defer func() {
+					if p := recover(); p != nil {
+						panicked <- p
+					}
+				}()
+				h.ServeHTTP(tw, r)
+				close(done)
+			}()
+			select {
+			case p := <-panicked:
+				panic(p)
+			case <-done:
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+				dst := w.Header()
+				for k, v := range tw.header {
+					dst[k] = append([]string(nil), v...)
+				}
+				if tw.code == 0 {
+					tw.code = http.StatusOK
+				}
+				restore := HoldTrailers(dst)
+				w.WriteHeader(tw.code)
+				restore()
+				w.Write(tw.buf.Bytes())
+			case <-timer.C:
+				// the writer is marked before cancelling the context, so the
+				// handler can't observe the cancellation and still write.
+				tw.expire()
+				cancel()
+				http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+			case <-ctx.Done():
+				tw.expire()
+			}
+		})
+	}
+}
+
+// timeoutWriter buffers the response of a handler running under Timeout.
+type timeoutWriter struct {
+	w        http.ResponseWriter
+	mu       sync.Mutex
+	header   http.Header
+	buf      bytes.Buffer
+	code     int
+	timedOut bool
+}
+
+func (w *timeoutWriter) expire() {
+	w.mu.Lock()
+	w.timedOut = true
+	w.mu.Unlock()
+}
+
+// Header returns the buffered header. Once the deadline passed the handler
+// gets a header of its own, so that it can't race with the 503 response.
+func (w *timeoutWriter) Header() http.Header {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return make(http.Header)
+	}
+	return w.header
+}
+
+func (w *timeoutWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	return w.buf.Write(b)
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	// informational responses can't be sent before the buffered response, so
+	// they are dropped.
+	if w.timedOut || w.code != 0 || informational(code) {
+		return
+	}
+	w.code = code
+}
+
+// Status returns the status code of the buffered response, so that Committed
+// reports responses written behind Timeout.
+func (w *timeoutWriter) Status() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.code
+}
+
+// Flush does nothing, the response is buffered until the handler returns.
+func (w *timeoutWriter) Flush() {}
+
+// Hijack returns an error, the connection belongs to the response written
+// once the handler returns.
+func (w *timeoutWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, errHijackNotSupported
+}
+
+// Unwrap returns the underlying http.ResponseWriter, it is used by
+// http.ResponseController.
+func (w *timeoutWriter) Unwrap() http.ResponseWriter {
+	return w.w
+}
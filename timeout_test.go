@@ -0,0 +1,109 @@
+package alien
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTimeout(t *testing.T) {
+	written := make(chan error, 1)
+	m := New()
+	m.Get("/fast", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("X-Alien", "fast")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("fast"))
+	}, Timeout(time.Second))
+	m.Get("/slow", func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		_, err := w.Write([]byte("slow"))
+		written <- err
+	}, Timeout(10*time.Millisecond))
+	m.Get("/panic", func(_ http.ResponseWriter, _ *http.Request) {
+		panic("alien")
+	}, Timeout(time.Second))
+
+	req, _ := http.NewRequest("GET", "/fast", nil)
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Errorf("expected %d got %d", http.StatusCreated, w.Code)
+	}
+	if w.Body.String() != "fast" || w.Header().Get("X-Alien") != "fast" {
+		t.Errorf("unexpected response %s", w.Body)
+	}
+
+	req, _ = http.NewRequest("GET", "/slow", nil)
+	w = httptest.NewRecorder()
+	m.ServeHTTP(w, req)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected %d got %d", http.StatusServiceUnavailable, w.Code)
+	}
+	if err := <-written; err != http.ErrHandlerTimeout {
+		t.Errorf("expected %v got %v", http.ErrHandlerTimeout, err)
+	}
+
+	defer func() {
+		if p := recover(); p != "alien" {
+			t.Errorf("expected alien got %v", p)
+		}
+	}()
+	req, _ = http.NewRequest("GET", "/panic", nil)
+	m.ServeHTTP(httptest.NewRecorder(), req)
+}
+
+func TestTimeout_abort(t *testing.T) {
+	reached := false
+	auth := func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") == "" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+			}
+			h.ServeHTTP(w, r)
+		})
+	}
+	m := New()
+	m.Get("/", func(w http.ResponseWriter, _ *http.Request) {
+		reached = true
+		w.Write([]byte("secret"))
+	}, Timeout(time.Second), auth)
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if reached || w.Code != http.StatusUnauthorized || w.Body.String() != "unauthorized\n" {
+		t.Errorf("expected the chain to be aborted got %d %q", w.Code, w.Body)
+	}
+}
+
+func TestTimeout_writer(t *testing.T) {
+	done := make(chan struct{})
+	m := New()
+	m.Get("/buffered", func(w http.ResponseWriter, _ *http.Request) {
+		rc := http.NewResponseController(w)
+		if err := rc.Flush(); err != nil {
+			t.Errorf("expected flushes to be ignored got %v", err)
+		}
+		if _, _, err := rc.Hijack(); err == nil {
+			t.Error("expected hijacking to fail")
+		}
+		w.Write([]byte("buffered"))
+	}, Timeout(time.Second))
+	m.Get("/late", func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		w.Header().Set("X-Late", "late")
+		close(done)
+	}, Timeout(10*time.Millisecond))
+
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest("GET", "/buffered", nil))
+	if w.Flushed || w.Body.String() != "buffered" {
+		t.Errorf("expected the buffered response got %v %q", w.Flushed, w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest("GET", "/late", nil))
+	<-done
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("X-Late") != "" {
+		t.Errorf("expected a 503 without the late header got %d %v", w.Code, w.Header())
+	}
+}
@@ -0,0 +1,179 @@
+package alien
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var timingKey = &contextKey{"timing"}
+
+// timingUsed is set once a ServerTiming middleware is created, so that routes
+// only look for timings when they can be collected.
+var timingUsed atomic.Bool
+
+// Timings collects the timing segments of a request, see ServerTiming. The
+// methods of a nil Timings do nothing, so handlers can time segments whether
+// or not the middleware is installed.
+type Timings struct {
+	start time.Time
+
+	mu      sync.Mutex
+	metrics []*Metric
+}
+
+// Metric is a timing segment of a request.
+type Metric struct {
+	t     *Timings
+	name  string
+	desc  string
+	start time.Time
+	dur   time.Duration
+	done  bool
+}
+
+// Timing returns the timings of r, or nil if r isn't served by the
+// ServerTiming middleware.
+//   defer alien.Timing(r).Start("db").Stop()
+func Timing(r *http.Request) *Timings {
+	return timingsFrom(r.Context())
+}
+
+func timingsFrom(ctx context.Context) *Timings {
+	if !timingUsed.Load() {
+		return nil
+	}
+	t, _ := ctx.Value(timingKey).(*Timings)
+	return t
+}
+
+// Start starts the segment name, which ends when its Stop method is called.
+// Names are tokens, like db or cache-miss.
+func (t *Timings) Start(name string) *Metric {
+	return t.StartDesc(name, "")
+}
+
+// StartDesc is like Start but gives the segment a description, shown instead
+// of the name by browsers.
+func (t *Timings) StartDesc(name, desc string) *Metric {
+	if t == nil {
+		return nil
+	}
+	m := &Metric{t: t, name: name, desc: desc, start: time.Now()}
+	t.mu.Lock()
+	t.metrics = append(t.metrics, m)
+	t.mu.Unlock()
+	return m
+}
+
+// Add adds the segment name which took d.
+func (t *Timings) Add(name string, d time.Duration) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	t.metrics = append(t.metrics, &Metric{t: t, name: name, dur: d, done: true})
+	t.mu.Unlock()
+}
+
+// Stop ends the segment, calling it more than once does nothing.
+func (m *Metric) Stop() {
+	if m == nil {
+		return
+	}
+	m.t.mu.Lock()
+	defer m.t.mu.Unlock()
+	if !m.done {
+		m.done = true
+		m.dur = time.Since(m.start)
+	}
+}
+
+// header returns the value of the Server-Timing header, segments which didn't
+// end are reported with their duration so far.
+func (t *Timings) header() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var b strings.Builder
+	for i, m := range t.metrics {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		dur := m.dur
+		if !m.done {
+			dur = time.Since(m.start)
+		}
+		b.WriteString(m.name)
+		if m.desc != "" {
+			b.WriteString(";desc=")
+			b.WriteString(strconv.Quote(m.desc))
+		}
+		b.WriteString(";dur=")
+		b.WriteString(strconv.FormatFloat(float64(dur)/float64(time.Millisecond), 'f', 3, 64))
+	}
+	return b.String()
+}
+
+// routed records the route segment, from the start of the request until it was
+// matched, and starts the middleware segment.
+func (t *Timings) routed() {
+	t.Add("route", time.Since(t.start))
+	t.Start("middleware")
+}
+
+// handle ends the middleware segment and starts the handler segment.
+func (t *Timings) handle() *Metric {
+	t.mu.Lock()
+	for _, m := range t.metrics {
+		if m.name == "middleware" && !m.done {
+			m.done = true
+			m.dur = time.Since(m.start)
+		}
+	}
+	t.mu.Unlock()
+	return t.Start("handler")
+}
+
+// ServerTiming is a middleware which writes the timing segments of requests to
+// the Server-Timing header of their responses, for browsers to show them with
+// the timings of the requests. Handlers add their own segments with Timing.
+//   http.ListenAndServe(":8090", alien.ServerTiming(m))
+//   m.Get("/users", func(w http.ResponseWriter, r *http.Request) {
+//       db := alien.Timing(r).Start("db")
+//       users := loadUsers(r.Context())
+//       db.Stop()
+//       alien.JSON(w, http.StatusOK, users)
+//   })
+// responds with
+//   Server-Timing: route;dur=0.012, middleware;dur=0.104, handler;dur=12.480, db;dur=12.301
+//
+// The route, middleware and handler segments are recorded for requests matched
+// by a Mux wrapped by the middleware, route being the time it took to route the
+// request and handler the time until the response was committed. The header is
+// written when the response is committed, segments which didn't end by then
+// are reported with their duration so far.
+//
+// Timings tell how a server works, skip the middleware for untrusted clients
+// with Skip.
+func ServerTiming(h http.Handler) http.Handler {
+	timingUsed.Store(true)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t := &Timings{start: time.Now()}
+		committed := false
+		tw := &responseWriter{ResponseWriter: w}
+		tw.commit = func() {
+			committed = true
+			if v := t.header(); v != "" {
+				w.Header().Set("Server-Timing", v)
+			}
+		}
+		h.ServeHTTP(tw, r.WithContext(context.WithValue(r.Context(), timingKey, t)))
+		if !committed {
+			tw.WriteHeader(http.StatusOK)
+		}
+	})
+}
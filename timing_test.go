@@ -0,0 +1,56 @@
+package alien
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestServerTiming(t *testing.T) {
+	m := New()
+	m.Use(func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Timing(r).Add("auth", 2*time.Millisecond)
+			h.ServeHTTP(w, r)
+		})
+	})
+	m.Get("/users", func(w http.ResponseWriter, r *http.Request) {
+		db := Timing(r).StartDesc("db", "load users")
+		db.Stop()
+		db.Stop()
+		Timing(r).Start("render")
+		w.Write([]byte("users"))
+	})
+	m.Get("/empty", func(w http.ResponseWriter, r *http.Request) {})
+	sample := []struct {
+		h      http.Handler
+		path   string
+		timing string
+	}{
+		{ServerTiming(m), "/users", `^route;dur=\d+\.\d{3}, middleware;dur=\d+\.\d{3}, auth;dur=2\.000, handler;dur=\d+\.\d{3}, db;desc="load users";dur=\d+\.\d{3}, render;dur=\d+\.\d{3}$`},
+		{ServerTiming(m), "/empty", `^route;dur=[\d.]+, middleware;dur=[\d.]+, auth;dur=2\.000, handler;dur=[\d.]+$`},
+		{ServerTiming(m), "/missing", `^$`},
+		{m, "/users", `^$`},
+	}
+	for _, v := range sample {
+		w := httptest.NewRecorder()
+		v.h.ServeHTTP(w, httptest.NewRequest("GET", v.path, nil))
+		if got := w.Header().Get("Server-Timing"); !regexp.MustCompile(v.timing).MatchString(got) {
+			t.Errorf("%s: expected %s got %s", v.path, v.timing, got)
+		}
+	}
+
+	// as a route middleware the request is already routed.
+	m = New()
+	m.Use(ServerTiming)
+	m.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	})
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if got := w.Header().Get("Server-Timing"); !regexp.MustCompile(`^handler;dur=[\d.]+$`).MatchString(got) {
+		t.Errorf("expected the handler segment got %s", got)
+	}
+}
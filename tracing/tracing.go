@@ -0,0 +1,177 @@
+// Package tracing provides a middleware starting a server span for every
+// request served by alien, with W3C trace context propagation.
+//
+//   m := alien.New()
+//   m.Use(tracing.New(tracer))
+//
+// Spans are named after the method and the pattern of the matched route, like
+// GET /users/:id, and record the status of the response. Spans are created by
+// a Tracer, which keeps alien free of tracing dependencies. An adapter for the
+// OpenTelemetry SDK fits in a few lines
+//   type otelTracer struct{ t trace.Tracer }
+//
+//   func (o otelTracer) Start(ctx context.Context, name string, parent tracing.SpanContext) (context.Context, tracing.Span) {
+//   	if parent.IsValid() {
+//   		ctx = trace.ContextWithRemoteSpanContext(ctx, trace.NewSpanContext(trace.SpanContextConfig{
+//   			TraceID: parent.TraceID, SpanID: parent.SpanID,
+//   			TraceFlags: trace.TraceFlags(parent.Flags), Remote: true,
+//   		}))
+//   	}
+//   	ctx, span := o.t.Start(ctx, name, trace.WithSpanKind(trace.SpanKindServer))
+//   	return ctx, otelSpan{span}
+//   }
+// where otelSpan implements Span by calling the methods of trace.Span.
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+
+	"github.com/gernest/alien"
+)
+
+// SpanContext identifies a span across process boundaries.
+type SpanContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	Flags   byte
+}
+
+// IsValid returns true if the trace and span ids are not zero.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != [16]byte{} && sc.SpanID != [8]byte{}
+}
+
+// String returns sc formatted as a traceparent header.
+func (sc SpanContext) String() string {
+	return "00-" + hex.EncodeToString(sc.TraceID[:]) + "-" + hex.EncodeToString(sc.SpanID[:]) + "-" + hex.EncodeToString([]byte{sc.Flags})
+}
+
+// Parse parses the traceparent header s, as defined by the W3C trace context
+// specification. It returns false if s is malformed.
+func Parse(s string) (SpanContext, bool) {
+	var sc SpanContext
+	// future versions may append fields, but they start like version 00.
+	if len(s) < 55 || s[2] != '-' || s[35] != '-' || s[52] != '-' || len(s) > 55 && (s[:2] == "00" || s[55] != '-') {
+		return sc, false
+	}
+	var version [1]byte
+	if !decodeLower(version[:], s[:2]) || version[0] == 0xff {
+		return sc, false
+	}
+	if !decodeLower(sc.TraceID[:], s[3:35]) || !decodeLower(sc.SpanID[:], s[36:52]) {
+		return sc, false
+	}
+	var flags [1]byte
+	if !decodeLower(flags[:], s[53:55]) {
+		return sc, false
+	}
+	sc.Flags = flags[0]
+	return sc, sc.IsValid()
+}
+
+// decodeLower decodes the lower case hex string s into dst.
+func decodeLower(dst []byte, s string) bool {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c >= 'A' && c <= 'F' {
+			return false
+		}
+	}
+	_, err := hex.Decode(dst, []byte(s))
+	return err == nil
+}
+
+// Span is a span started by a Tracer.
+type Span interface {
+	// SpanContext returns the context of the span, it is propagated to
+	// outgoing requests by Inject.
+	SpanContext() SpanContext
+
+	SetAttribute(key string, value interface{})
+
+	// SetStatus records the status code of the response, codes of 500 and
+	// above mark the span as failed.
+	SetStatus(code int)
+
+	RecordError(err error)
+	End()
+}
+
+// Tracer starts server spans.
+type Tracer interface {
+	// Start starts a span named name, parent is the context of the remote
+	// span found in the traceparent header of the request, it is not valid if
+	// the request has none. The returned context carries the span.
+	Start(ctx context.Context, name string, parent SpanContext) (context.Context, Span)
+}
+
+type spanKey struct{}
+
+// New returns a middleware which starts a server span with t for every
+// request. It must be registered with Use or on a route, so that the route is
+// known when the span is started.
+func New(t Tracer) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			parent, _ := Parse(r.Header.Get("traceparent"))
+			pattern := alien.RoutePattern(r)
+			name := r.Method
+			if pattern != "" {
+				name += " " + pattern
+			}
+			ctx, span := t.Start(r.Context(), name, parent)
+			defer span.End()
+			span.SetAttribute("http.request.method", r.Method)
+			span.SetAttribute("url.path", r.URL.Path)
+			if pattern != "" {
+				span.SetAttribute("http.route", pattern)
+			}
+			rw := alien.WrapWriter(w)
+			defer func() {
+				if v := recover(); v != nil {
+					span.RecordError(fmt.Errorf("panic: %v", v))
+					span.SetStatus(http.StatusInternalServerError)
+					panic(v)
+				}
+			}()
+			h.ServeHTTP(rw, r.WithContext(context.WithValue(ctx, spanKey{}, span)))
+			status := rw.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			span.SetAttribute("http.response.status_code", status)
+			span.SetStatus(status)
+		})
+	}
+}
+
+// SpanFromContext returns the span started by the middleware for the request
+// with context ctx, or nil if there is none.
+func SpanFromContext(ctx context.Context) Span {
+	s, _ := ctx.Value(spanKey{}).(Span)
+	return s
+}
+
+// RecordError records err on the span of the request with context ctx, for
+// instance from an alien error handler
+//   m.ErrorHandler(func(w http.ResponseWriter, r *http.Request, err error) {
+//   	tracing.RecordError(r.Context(), err)
+//   	http.Error(w, err.Error(), http.StatusInternalServerError)
+//   })
+func RecordError(ctx context.Context, err error) {
+	if s := SpanFromContext(ctx); s != nil {
+		s.RecordError(err)
+	}
+}
+
+// Inject sets the traceparent header of h to the context of the span of ctx,
+// so that outgoing requests continue the trace.
+func Inject(ctx context.Context, h http.Header) {
+	if s := SpanFromContext(ctx); s != nil {
+		if sc := s.SpanContext(); sc.IsValid() {
+			h.Set("traceparent", sc.String())
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gernest/alien"
+)
+
+type testSpan struct {
+	name   string
+	parent SpanContext
+	sc     SpanContext
+	attrs  map[string]interface{}
+	status int
+	errs   []error
+	ended  bool
+}
+
+func (s *testSpan) SpanContext() SpanContext                   { return s.sc }
+func (s *testSpan) SetAttribute(key string, value interface{}) { s.attrs[key] = value }
+func (s *testSpan) SetStatus(code int)                         { s.status = code }
+func (s *testSpan) RecordError(err error)                      { s.errs = append(s.errs, err) }
+func (s *testSpan) End()                                       { s.ended = true }
+
+type testTracer struct {
+	spans []*testSpan
+}
+
+func (t *testTracer) Start(ctx context.Context, name string, parent SpanContext) (context.Context, Span) {
+	s := &testSpan{name: name, parent: parent, attrs: make(map[string]interface{})}
+	s.sc = SpanContext{TraceID: parent.TraceID, SpanID: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}, Flags: 1}
+	t.spans = append(t.spans, s)
+	return ctx, s
+}
+
+func TestParse(t *testing.T) {
+	sample := []struct {
+		header string
+		valid  bool
+	}{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true},
+		{"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-future", true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", false},
+		{"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false},
+		{"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", false},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7", false},
+		{"", false},
+	}
+	for _, v := range sample {
+		sc, ok := Parse(v.header)
+		if ok != v.valid {
+			t.Errorf("%q: expected %v got %v", v.header, v.valid, ok)
+		}
+		if ok && v.header[:2] == "00" && sc.String() != v.header {
+			t.Errorf("expected %s got %s", v.header, sc.String())
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	tr := &testTracer{}
+	m := alien.New()
+	m.Use(New(tr))
+	m.ErrorHandler(func(w http.ResponseWriter, r *http.Request, err error) {
+		RecordError(r.Context(), err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	})
+	m.Get("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		Inject(r.Context(), w.Header())
+		w.WriteHeader(http.StatusNoContent)
+	})
+	m.GetE("/fail", func(w http.ResponseWriter, r *http.Request) error {
+		return errors.New("boom")
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/users/42", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	m.ServeHTTP(w, req)
+	s := tr.spans[0]
+	if s.name != "GET /users/:id" {
+		t.Errorf("expected GET /users/:id got %s", s.name)
+	}
+	if s.parent.String() != req.Header.Get("traceparent") {
+		t.Errorf("expected the remote parent got %s", s.parent)
+	}
+	if s.status != http.StatusNoContent || s.attrs["http.route"] != "/users/:id" || !s.ended {
+		t.Errorf("unexpected span %#v", s)
+	}
+	if h := w.Header().Get("traceparent"); h != "00-4bf92f3577b34da6a3ce929d0e0e4736-0102030405060708-01" {
+		t.Errorf("expected the span context to be injected got %s", h)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/fail", nil)
+	m.ServeHTTP(w, req)
+	s = tr.spans[1]
+	if s.parent.IsValid() {
+		t.Errorf("expected no parent got %s", s.parent)
+	}
+	if s.status != http.StatusInternalServerError || len(s.errs) != 1 {
+		t.Errorf("expected the error to be recorded got %#v", s)
+	}
+}
@@ -0,0 +1,93 @@
+package alien
+
+import (
+	"net/http"
+	"strings"
+)
+
+// DeclareTrailer declares the trailers names in the header of the response
+// written with w, it must be called before the response is committed. Trailers
+// are headers sent after the body, like a checksum of the body or the status
+// of a stream, and declaring them makes the response chunked so that they can
+// be sent.
+//   alien.DeclareTrailer(w, "X-Checksum")
+func DeclareTrailer(w http.ResponseWriter, names ...string) {
+	h := w.Header()
+	for _, v := range names {
+		v = http.CanonicalHeaderKey(v)
+		if !declared(h, v) {
+			h.Add("Trailer", v)
+		}
+	}
+}
+
+// SetTrailer sets the trailer key to value. Trailers which were not declared
+// are declared while the response isn't committed, trailers set after that
+// are only sent if the response is chunked, which it is once it was flushed.
+//   sum := sha256.New()
+//   io.Copy(io.MultiWriter(w, sum), f)
+//   alien.SetTrailer(w, "X-Checksum", hex.EncodeToString(sum.Sum(nil)))
+//
+// The middlewares of alien and its sub packages keep trailers out of the
+// header of the response, even when they buffer it, and responses with
+// trailers are not cached.
+func SetTrailer(w http.ResponseWriter, key, value string) {
+	key = http.CanonicalHeaderKey(key)
+	h := w.Header()
+	if !Committed(w) && !declared(h, key) {
+		h.Add("Trailer", key)
+	}
+	h.Set(http.TrailerPrefix+key, value)
+}
+
+// HoldTrailers removes the trailers declared or set in h, and returns a
+// function which puts them back. Writers which buffer the response hold the
+// trailers while they write its header, the trailers set before the body was
+// written would be sent in the header otherwise.
+//   restore := alien.HoldTrailers(w.Header())
+//   w.WriteHeader(code)
+//   restore()
+//   w.Write(buf)
+func HoldTrailers(h http.Header) (restore func()) {
+	held := make(http.Header)
+	for k, v := range h {
+		if strings.HasPrefix(k, http.TrailerPrefix) || declared(h, k) {
+			held[k] = v
+		}
+	}
+	for k := range held {
+		delete(h, k)
+	}
+	return func() {
+		for k, v := range held {
+			h[k] = v
+		}
+	}
+}
+
+// HasTrailers returns true if trailers are declared or set in h. Responses
+// with trailers depend on how their body was sent, so they shouldn't be
+// replayed from a cache.
+func HasTrailers(h http.Header) bool {
+	if len(h["Trailer"]) > 0 {
+		return true
+	}
+	for k := range h {
+		if strings.HasPrefix(k, http.TrailerPrefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// declared returns true if the trailer key is declared in h.
+func declared(h http.Header, key string) bool {
+	for _, v := range h["Trailer"] {
+		for _, name := range strings.Split(v, ",") {
+			if http.CanonicalHeaderKey(strings.TrimSpace(name)) == key {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+package alien
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSetTrailer(t *testing.T) {
+	m := New()
+	m.Get("/json", func(w http.ResponseWriter, r *http.Request) {
+		DeclareTrailer(w, "X-Checksum")
+		JSON(w, http.StatusOK, map[string]string{"name": "alien"})
+		SetTrailer(w, "x-checksum", "abc")
+	})
+	m.Get("/text", func(w http.ResponseWriter, r *http.Request) {
+		SetTrailer(w, "X-Checksum", "early")
+		Text(w, http.StatusOK, "hello")
+		SetTrailer(w, "X-Checksum", "abc")
+	}, Timeout(time.Second))
+	m.Get("/stream", func(w http.ResponseWriter, r *http.Request) {
+		Stream(w, r, func(sw *StreamWriter) error {
+			sw.DeclareTrailer("X-Status")
+			sw.WriteJSON(1)
+			sw.Flush()
+			sw.SetTrailer("X-Status", "done")
+			sw.SetTrailer("X-Count", "1")
+			return nil
+		})
+	})
+	srv := httptest.NewServer(m)
+	defer srv.Close()
+	sample := []struct {
+		path    string
+		trailer http.Header
+	}{
+		{"/json", http.Header{"X-Checksum": {"abc"}}},
+		{"/text", http.Header{"X-Checksum": {"abc"}}},
+		{"/stream", http.Header{"X-Status": {"done"}, "X-Count": {"1"}}},
+	}
+	for _, v := range sample {
+		res, err := http.Get(srv.URL + v.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.ReadAll(res.Body)
+		res.Body.Close()
+		if !reflect.DeepEqual(res.Trailer, v.trailer) {
+			t.Errorf("%s: expected trailer %v got %v", v.path, v.trailer, res.Trailer)
+		}
+		for k := range v.trailer {
+			if res.Header.Get(k) != "" {
+				t.Errorf("%s: expected %s not to be sent in the header", v.path, k)
+			}
+		}
+	}
+}
+
+func TestDeclareTrailer(t *testing.T) {
+	w := httptest.NewRecorder()
+	w.Header().Set("Trailer", "X-Checksum, X-Status")
+	DeclareTrailer(w, "x-status", "X-Count")
+	if got := w.Header().Values("Trailer"); !reflect.DeepEqual(got, []string{"X-Checksum, X-Status", "X-Count"}) {
+		t.Errorf("unexpected trailer declaration %v", got)
+	}
+}
+
+func TestHoldTrailers(t *testing.T) {
+	h := http.Header{}
+	if HasTrailers(h) {
+		t.Error("expected no trailers")
+	}
+	h.Set("Content-Type", "text/plain")
+	h.Set("Trailer", "X-Checksum")
+	h.Set("X-Checksum", "early")
+	h.Set(http.TrailerPrefix+"X-Status", "ok")
+	if !HasTrailers(h) {
+		t.Error("expected trailers")
+	}
+	want := h.Clone()
+	restore := HoldTrailers(h)
+	if !reflect.DeepEqual(h, http.Header{"Content-Type": {"text/plain"}, "Trailer": {"X-Checksum"}}) {
+		t.Errorf("expected the trailers to be held got %v", h)
+	}
+	restore()
+	if !reflect.DeepEqual(h, want) {
+		t.Errorf("expected the trailers to be restored got %v", h)
+	}
+}
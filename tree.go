@@ -0,0 +1,201 @@
+package alien
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// TreeStats describes the shape of the route trees of a Mux, see
+// Mux.TreeStats.
+type TreeStats struct {
+	Routes int `json:"routes"`
+
+	// Nodes counts the nodes of the trees, StaticNodes, ParamNodes and
+	// CatchAllNodes those of each kind.
+	Nodes         int `json:"nodes"`
+	StaticNodes   int `json:"static_nodes"`
+	ParamNodes    int `json:"param_nodes"`
+	CatchAllNodes int `json:"catch_all_nodes"`
+
+	// MaxDepth is the number of nodes on the longest path from the root of a
+	// tree, the most a lookup can visit.
+	MaxDepth int `json:"max_depth"`
+
+	// Bytes estimates the memory used by the nodes and routes.
+	Bytes int64 `json:"bytes"`
+
+	// ParamsPerRoute counts the routes by number of params, for instance
+	// {0: 12, 1: 30, 2: 4}.
+	ParamsPerRoute map[int]int `json:"params_per_route"`
+
+	// Chains counts the chains of two or more nodes without routes and with a
+	// single child, which lookups walk without choosing a branch, LongestChain
+	// is the length of the longest one. Long chains come from patterns with
+	// many params and no routes for their prefixes.
+	Chains       int `json:"chains"`
+	LongestChain int `json:"longest_chain"`
+}
+
+var (
+	nodeSize  = int64(reflect.TypeOf(node{}).Size())
+	routeSize = int64(reflect.TypeOf(route{}).Size())
+	ptrSize   = int64(reflect.TypeOf(&node{}).Size())
+)
+
+// TreeStats returns the shape of the route trees of m and its hosts, so that
+// applications with many routes can reason about the memory used by routing
+// and the cost of lookups.
+//   s := m.TreeStats()
+//   fmt.Println(s.Routes, s.Nodes, s.MaxDepth, s.Bytes)
+func (m *Mux) TreeStats() TreeStats {
+	s := TreeStats{ParamsPerRoute: make(map[int]int)}
+	seen := make(map[*route]bool)
+	for _, r := range m.top().routers() {
+		t := r.load()
+		for _, method := range t.methods() {
+			if root := *t.root(method); root != nil {
+				s.add(root, 0, 0, seen)
+			}
+		}
+	}
+	return s
+}
+
+// add adds the node n at depth, ending a chain of chain nodes, and its
+// children to s.
+func (s *TreeStats) add(n *node, depth, chain int, seen map[*route]bool) {
+	if n.typ != nodeRoot {
+		depth++
+		s.Nodes++
+		switch n.typ {
+		case nodeParam:
+			s.ParamNodes++
+		case nodeCatchAll:
+			s.CatchAllNodes++
+		default:
+			s.StaticNodes++
+		}
+		if depth > s.MaxDepth {
+			s.MaxDepth = depth
+		}
+	}
+	s.Bytes += nodeSize + int64(len(n.path)) + ptrSize*int64(cap(n.ends)+cap(n.children))
+	if n.index != nil {
+		s.Bytes += int64(len(n.index)) * (1 + ptrSize)
+	}
+	for _, rt := range n.ends {
+		s.Routes++
+		s.ParamsPerRoute[strings.Count(rt.path, "/:")+strings.Count(rt.path, "/*")]++
+		if !seen[rt] {
+			seen[rt] = true
+			s.Bytes += routeSize + int64(len(rt.path)+len(rt.name)+len(rt.version))
+		}
+	}
+	children := n.childNodes()
+	if n.typ != nodeRoot && len(n.ends) == 0 && len(children) == 1 {
+		chain++
+	} else {
+		s.endChain(chain)
+		chain = 0
+	}
+	for _, c := range children {
+		s.add(c, depth, chain, seen)
+	}
+}
+
+// endChain counts a chain of n nodes.
+func (s *TreeStats) endChain(n int) {
+	if n < 2 {
+		return
+	}
+	s.Chains++
+	if n > s.LongestChain {
+		s.LongestChain = n
+	}
+}
+
+// childNodes returns the static children of n followed by its param and catch
+// all children.
+func (n *node) childNodes() []*node {
+	children := n.children
+	if n.param != nil || n.catchAll != nil {
+		children = append([]*node(nil), children...)
+		if n.param != nil {
+			children = append(children, n.param)
+		}
+		if n.catchAll != nil {
+			children = append(children, n.catchAll)
+		}
+	}
+	return children
+}
+
+// DumpTree writes the route trees of m and its hosts to w, one tree per method
+// and host, to see how the routes are laid out. Nodes are indented below their
+// parent, params are shown as : and catch alls as *, and the patterns of the
+// routes ending at a node follow an arrow.
+//   GET
+//   /users => /users
+//     /
+//       : => /users/:id
+func (m *Mux) DumpTree(w io.Writer) error {
+	return m.top().dumpTree(w)
+}
+
+// dumpTree writes the trees of m, followed by those of its hosts, to w.
+func (m *Mux) dumpTree(w io.Writer) error {
+	t := m.load()
+	for _, method := range t.methods() {
+		root := *t.root(method)
+		if root == nil {
+			continue
+		}
+		if _, err := fmt.Fprintln(w, strings.TrimSpace(method+" "+m.host)); err != nil {
+			return err
+		}
+		if err := root.dump(w, -1); err != nil {
+			return err
+		}
+	}
+	for _, h := range m.hosts {
+		if err := h.dumpTree(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dump writes n at depth and its children to w.
+func (n *node) dump(w io.Writer, depth int) error {
+	if n.typ != nodeRoot {
+		label := n.path
+		switch n.typ {
+		case nodeParam:
+			label = ":"
+		case nodeCatchAll:
+			label = "*"
+		}
+		var patterns []string
+		for _, rt := range n.ends {
+			p := rt.path
+			if rt.version != "" {
+				p += " (" + rt.version + ")"
+			}
+			patterns = append(patterns, p)
+		}
+		if len(patterns) > 0 {
+			label += " => " + strings.Join(patterns, ", ")
+		}
+		if _, err := fmt.Fprintln(w, strings.Repeat("  ", depth)+label); err != nil {
+			return err
+		}
+	}
+	for _, c := range n.childNodes() {
+		if err := c.dump(w, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package alien
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMux_TreeStats(t *testing.T) {
+	m := New()
+	m.Get("/users", listUsers)
+	m.Get("/users/:id", listUsers)
+	m.Post("/users", listUsers)
+	m.Get("/repos/:owner/:repo/issues/:number", listUsers)
+	m.Get("/files/*path", listUsers)
+	m.Host("api.example.com").Get("/status", listUsers)
+
+	s := m.TreeStats()
+	if s.Routes != 6 {
+		t.Errorf("expected 6 routes got %d", s.Routes)
+	}
+	params := map[int]int{0: 3, 1: 2, 3: 1}
+	if !reflect.DeepEqual(s.ParamsPerRoute, params) {
+		t.Errorf("expected %v got %v", params, s.ParamsPerRoute)
+	}
+	if s.ParamNodes != 4 || s.CatchAllNodes != 1 || s.Nodes != s.StaticNodes+s.ParamNodes+s.CatchAllNodes {
+		t.Errorf("unexpected node counts %+v", s)
+	}
+	// GET / -> repos/ -> : -> / -> : -> /issues/ -> :
+	if s.MaxDepth != 7 {
+		t.Errorf("expected a depth of 7 got %d", s.MaxDepth)
+	}
+	// repos/ : / : /issues/ have no routes and a single child.
+	if s.Chains != 1 || s.LongestChain != 5 {
+		t.Errorf("expected a chain of 5 nodes got %d chains, longest %d", s.Chains, s.LongestChain)
+	}
+	if s.Bytes <= 0 {
+		t.Errorf("expected the bytes used got %d", s.Bytes)
+	}
+}
+
+func TestMux_DumpTree(t *testing.T) {
+	m := New()
+	m.Get("/users", listUsers)
+	m.Get("/users/:id", listUsers)
+	m.Version("2").Get("/users/:id", listUsers)
+	m.Get("/files/*path", listUsers)
+	m.Host("api.example.com").Post("/status", listUsers)
+
+	var b strings.Builder
+	if err := m.DumpTree(&b); err != nil {
+		t.Fatal(err)
+	}
+	expect := `GET
+/
+  users => /users
+    /
+      : => /users/:id, /users/:id (2)
+  files/
+    * => /files/*path
+POST api.example.com
+/status => /status
+`
+	if b.String() != expect {
+		t.Errorf("expected\n%s\ngot\n%s", expect, b.String())
+	}
+}
@@ -0,0 +1,182 @@
+package alien
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"reflect"
+)
+
+// Handle registers fn with pattern and method on m as a typed handler. The
+// request is decoded into a Req, fn is called with it and the context of the
+// request, and the Resp it returns is written as JSON.
+//   type createUser struct {
+//       Org  string `param:"org"`
+//       Name string `json:"name"`
+//       Dry  bool   `query:"dry"`
+//   }
+//   alien.Handle(m, "POST", "/orgs/:org/users", func(ctx context.Context, req createUser) (*User, error) {
+//       return users.Create(ctx, req.Org, req.Name)
+//   })
+//
+// When Req is a struct, JSON request bodies are decoded into it with
+// encoding/json and form bodies are bound to its form tags like BindForm
+// does. Then its query tags are bound from the query, and its param tags from
+// the route params, so params take precedence. Default tags are only used for
+// fields which none of them provide. Other Req types are only decoded from JSON
+// bodies, of up to 1MB unless MaxBodySize raises the limit. The decoded value
+// is validated, see SetValidator.
+//
+// Resp is written with 201 Created for POST requests and 200 OK otherwise,
+// unless it has a StatusCode() int method, and a nil Resp responds with 204 No
+// Content. Decoding errors and errors returned by fn are passed to the error
+// handler of m, see ErrorHandler.
+func Handle[Req, Resp any](m *Mux, method, pattern string, fn func(ctx context.Context, req Req) (Resp, error), wares ...func(http.Handler) http.Handler) error {
+	return m.AddRouteE(method, pattern, func(w http.ResponseWriter, r *http.Request) error {
+		var req Req
+		if err := decodeRequest(w, r, &req, m.maxBodySize()); err != nil {
+			return err
+		}
+		resp, err := fn(r.Context(), req)
+		if err != nil {
+			return err
+		}
+		return writeResponse(w, r, resp)
+	}, wares...)
+}
+
+// decodeError is returned when the body of a request can't be decoded.
+type decodeError struct {
+	err error
+}
+
+func (e *decodeError) Error() string {
+	return "alien: can't decode body: " + e.err.Error()
+}
+
+func (e *decodeError) Unwrap() error {
+	return e.err
+}
+
+func (e *decodeError) Status() int {
+	var tooLarge *http.MaxBytesError
+	if errors.As(e.err, &tooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+// defaultMaxBodySize is the size of the JSON bodies decoded by typed handlers
+// unless MaxBodySize is used.
+const defaultMaxBodySize = 1 << 20
+
+// MaxBodySize sets the largest JSON body decoded by the typed handlers
+// registered with Handle through m. The limit is a number of bytes with an
+// optional unit like the one of BodyLimit, it panics if limit is invalid.
+// Larger bodies are rejected with 413 Request Entity Too Large.
+//   m.MaxBodySize("10MB")
+//
+// Groups and hosts use the limit of their parent unless they have their own.
+func (m *Mux) MaxBodySize(limit string) {
+	n, err := parseSize(limit)
+	if err != nil {
+		panic("alien: bad max body size " + limit)
+	}
+	m.maxBody = n
+}
+
+// maxBodySize returns the limit of the JSON bodies decoded by typed handlers.
+func (m *Mux) maxBodySize() int64 {
+	for g := m; g != nil; g = g.parent {
+		if g.maxBody != 0 {
+			return g.maxBody
+		}
+	}
+	return defaultMaxBodySize
+}
+
+// decodeRequest decodes the body, query and params of r into dst, which
+// points to the Req of a typed handler. JSON bodies are limited to maxBody
+// bytes.
+func decodeRequest(w http.ResponseWriter, r *http.Request, dst interface{}, maxBody int64) error {
+	isStruct := reflect.TypeOf(dst).Elem().Kind() == reflect.Struct
+	if isStruct {
+		// defaults are set first, so that they are only kept for the fields
+		// which neither the body, the query nor the params provide.
+		if err := bindDefaults(reflect.ValueOf(dst).Elem(), "form", "query", "param"); err != nil {
+			return err
+		}
+	}
+	ct, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch {
+	case ct == "application/x-www-form-urlencoded" || ct == "multipart/form-data":
+		if isStruct {
+			if err := r.ParseMultipartForm(defaultMaxMemory); err != nil && err != http.ErrNotMultipart {
+				return &decodeError{err: err}
+			}
+			var files map[string][]*multipart.FileHeader
+			if r.MultipartForm != nil {
+				files = r.MultipartForm.File
+			}
+			if err := bindValues(dst, r.PostForm, files, "form", false); err != nil {
+				return err
+			}
+		}
+	case r.Body != nil && r.Body != http.NoBody && r.ContentLength != 0:
+		if r.ContentLength > maxBody {
+			return &decodeError{err: &http.MaxBytesError{Limit: maxBody}}
+		}
+		r.Body = http.MaxBytesReader(innermostWriter(w), r.Body, maxBody)
+		err := json.NewDecoder(r.Body).Decode(dst)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return &decodeError{err: err}
+		}
+	}
+	if isStruct {
+		if err := bindValues(dst, r.URL.Query(), nil, "query", false); err != nil {
+			return err
+		}
+		params := GetParams(r)
+		values := make(url.Values, len(params))
+		for _, p := range params {
+			values[p.Key] = append(values[p.Key], p.Value)
+		}
+		if err := bindValues(dst, values, nil, "param", false); err != nil {
+			return err
+		}
+	}
+	return validate(r, dst)
+}
+
+// writeResponse writes resp, returned by a typed handler for r, to w.
+func writeResponse(w http.ResponseWriter, r *http.Request, resp interface{}) error {
+	if isNil(resp) {
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	code := http.StatusOK
+	if r.Method == http.MethodPost {
+		code = http.StatusCreated
+	}
+	if s, ok := resp.(interface{ StatusCode() int }); ok {
+		code = s.StatusCode()
+	}
+	return JSON(w, code, resp)
+}
+
+// isNil reports whether v is nil or a nil pointer, map, slice or interface.
+func isNil(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface:
+		return rv.IsNil()
+	}
+	return false
+}
@@ -0,0 +1,164 @@
+package alien
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type typedUser struct {
+	Org  string `param:"org" json:"org"`
+	Name string `json:"name"`
+	Dry  bool   `query:"dry" json:"dry"`
+}
+
+type accepted struct {
+	ID string `json:"id"`
+}
+
+func (accepted) StatusCode() int {
+	return http.StatusAccepted
+}
+
+func TestHandle(t *testing.T) {
+	m := New()
+	err := Handle(m, "POST", "/orgs/:org/users", func(ctx context.Context, req typedUser) (*typedUser, error) {
+		if req.Name == "" {
+			return nil, &Problem{Status: http.StatusUnprocessableEntity, Title: "name is required"}
+		}
+		return &req, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = Handle(m, "GET", "/orgs/:org", func(ctx context.Context, req typedUser) (typedUser, error) {
+		if req.Org == "fail" {
+			return req, errors.New("boom")
+		}
+		return req, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = Handle(m, "DELETE", "/orgs/:org", func(ctx context.Context, req typedUser) (*typedUser, error) {
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = Handle(m, "PUT", "/jobs", func(ctx context.Context, req []string) (accepted, error) {
+		return accepted{ID: strings.Join(req, ",")}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sample := []struct {
+		method, path, ct, body string
+		code                   int
+		result                 string
+	}{
+		{"POST", "/orgs/acme/users?dry=true", "application/json", `{"name":"gernest","org":"other"}`, http.StatusCreated, `{"org":"acme","name":"gernest","dry":true}`},
+		{"POST", "/orgs/acme/users", "application/x-www-form-urlencoded", "name=gernest", http.StatusUnprocessableEntity, ""},
+		{"POST", "/orgs/acme/users", "application/json", `{"name":`, http.StatusBadRequest, ""},
+		{"POST", "/orgs/acme/users", "application/json", "", http.StatusUnprocessableEntity, ""},
+		{"GET", "/orgs/acme?dry=1", "", "", http.StatusOK, `{"org":"acme","name":"","dry":true}`},
+		{"GET", "/orgs/acme?dry=maybe", "", "", http.StatusBadRequest, ""},
+		{"GET", "/orgs/fail", "", "", http.StatusInternalServerError, ""},
+		{"DELETE", "/orgs/acme", "", "", http.StatusNoContent, ""},
+		{"PUT", "/jobs", "application/json", `["a","b"]`, http.StatusAccepted, `{"id":"a,b"}`},
+	}
+	for _, v := range sample {
+		req := httptest.NewRequest(v.method, v.path, strings.NewReader(v.body))
+		if v.ct != "" {
+			req.Header.Set("Content-Type", v.ct)
+		}
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, req)
+		if w.Code != v.code {
+			t.Errorf("%s %s: expected %d got %d %s", v.method, v.path, v.code, w.Code, w.Body)
+		}
+		if v.result != "" && strings.TrimSpace(w.Body.String()) != v.result {
+			t.Errorf("%s %s: expected %s got %s", v.method, v.path, v.result, w.Body)
+		}
+	}
+}
+
+func TestHandle_defaults(t *testing.T) {
+	type search struct {
+		Query string `json:"q" query:"q" default:"all"`
+		Limit int    `json:"limit" query:"limit" param:"limit" default:"10"`
+	}
+	m := New()
+	err := Handle(m, "POST", "/search", func(ctx context.Context, req search) (search, error) {
+		return req, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sample := []struct {
+		path, body, result string
+	}{
+		{"/search", `{"q":"alien","limit":5}`, `{"q":"alien","limit":5}`},
+		{"/search?limit=20", `{"q":"alien"}`, `{"q":"alien","limit":20}`},
+		{"/search?q=go", "", `{"q":"go","limit":10}`},
+		{"/search", "", `{"q":"all","limit":10}`},
+	}
+	for _, v := range sample {
+		req := httptest.NewRequest("POST", v.path, strings.NewReader(v.body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, req)
+		if strings.TrimSpace(w.Body.String()) != v.result {
+			t.Errorf("%s %s: expected %s got %s", v.path, v.body, v.result, w.Body)
+		}
+	}
+}
+
+func TestHandle_maxBodySize(t *testing.T) {
+	m := New()
+	echo := func(ctx context.Context, req typedUser) (typedUser, error) {
+		return req, nil
+	}
+	if err := Handle(m, "POST", "/users", echo); err != nil {
+		t.Fatal(err)
+	}
+	small := m.Group("/small")
+	small.MaxBodySize("16B")
+	if err := Handle(small, "POST", "/users", echo); err != nil {
+		t.Fatal(err)
+	}
+	large := `{"name":"` + strings.Repeat("a", defaultMaxBodySize) + `"}`
+	sample := []struct {
+		path, body string
+		chunked    bool
+		code       int
+	}{
+		{"/users", `{"name":"gernest"}`, false, http.StatusCreated},
+		{"/users", large, false, http.StatusRequestEntityTooLarge},
+		{"/users", large, true, http.StatusRequestEntityTooLarge},
+		{"/small/users", `{"name":"a"}`, false, http.StatusCreated},
+		{"/small/users", `{"name":"gernest"}`, false, http.StatusRequestEntityTooLarge},
+		{"/small/users", `{"name":"gernest"}`, true, http.StatusRequestEntityTooLarge},
+	}
+	for _, v := range sample {
+		req := httptest.NewRequest("POST", v.path, strings.NewReader(v.body))
+		req.Header.Set("Content-Type", "application/json")
+		if v.chunked {
+			req.ContentLength = -1
+		}
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, req)
+		if w.Code != v.code {
+			t.Errorf("%s %d bytes chunked=%v: expected %d got %d", v.path, len(v.body), v.chunked, v.code, w.Code)
+		}
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a bad max body size to panic")
+		}
+	}()
+	m.MaxBodySize("lots")
+}
@@ -0,0 +1,67 @@
+package alien
+
+import "net/http"
+
+// Validator validates values decoded by the Bind functions.
+type Validator interface {
+	Validate(v interface{}) error
+}
+
+// ValidatorFunc is an adapter to use ordinary functions as a Validator.
+type ValidatorFunc func(v interface{}) error
+
+// Validate calls f(v).
+func (f ValidatorFunc) Validate(v interface{}) error {
+	return f(v)
+}
+
+// ValidationError is returned by the Bind functions when the decoded value is
+// not valid.
+type ValidationError struct {
+	Err error
+}
+
+func (e *ValidationError) Error() string {
+	return "alien: validation failed: " + e.Err.Error()
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// Status returns 422 Unprocessable Entity, the status for responses to
+// requests which failed validation.
+func (e *ValidationError) Status() int {
+	return http.StatusUnprocessableEntity
+}
+
+// SetValidator sets the validator called by the Bind functions for requests
+// served by routes of m, after the request is decoded. For instance with
+// github.com/go-playground/validator
+//   v := validator.New()
+//   m.SetValidator(alien.ValidatorFunc(v.Struct))
+//
+// Groups and hosts use the validator of their parent unless they have their
+// own. Values with a Validate() error method are validated by it as well.
+func (m *Mux) SetValidator(v Validator) {
+	m.validator = v
+}
+
+// validate validates dst, which was decoded from r, with its Validate method
+// and the validator of the Mux serving r.
+func validate(r *http.Request, dst interface{}) error {
+	if v, ok := dst.(interface{ Validate() error }); ok {
+		if err := v.Validate(); err != nil {
+			return &ValidationError{Err: err}
+		}
+	}
+	for m := muxOf(r); m != nil; m = m.parent {
+		if m.validator != nil {
+			if err := m.validator.Validate(dst); err != nil {
+				return &ValidationError{Err: err}
+			}
+			return nil
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package alien
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type signup struct {
+	Name string `form:"name"`
+	Age  int    `form:"age"`
+}
+
+func (s *signup) Validate() error {
+	if s.Age < 18 {
+		return errors.New("too young")
+	}
+	return nil
+}
+
+func TestMux_SetValidator(t *testing.T) {
+	h := func(w http.ResponseWriter, r *http.Request) {
+		var s signup
+		if err := BindForm(r, &s); err != nil {
+			var status interface{ Status() int }
+			if !errors.As(err, &status) {
+				t.Fatalf("expected an error with a status got %v", err)
+			}
+			http.Error(w, err.Error(), status.Status())
+			return
+		}
+		w.Write([]byte(s.Name))
+	}
+	m := New()
+	m.SetValidator(ValidatorFunc(func(v interface{}) error {
+		if v.(*signup).Name == "" {
+			return errors.New("name is required")
+		}
+		return nil
+	}))
+	m.Get("/signup", h)
+	m.Group("/api").Get("/signup", h)
+
+	sample := []struct {
+		path string
+		code int
+	}{
+		{"/signup?name=alien&age=20", http.StatusOK},
+		{"/signup?name=alien&age=old", http.StatusBadRequest},
+		{"/signup?name=alien&age=10", http.StatusUnprocessableEntity},
+		{"/signup?age=20", http.StatusUnprocessableEntity},
+		{"/api/signup?age=20", http.StatusUnprocessableEntity},
+	}
+	for _, v := range sample {
+		req, _ := http.NewRequest("GET", v.path, nil)
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, req)
+		if w.Code != v.code {
+			t.Errorf("%s: expected %d got %d", v.path, v.code, w.Code)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package alien
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Version returns a Mux which registers routes serving version v of an API,
+// so that the same method and pattern can be routed to different handlers
+// without putting the version in the url.
+//   m.Get("/users", listUsers)
+//   m.Version("2").Get("/users", listUsersV2)
+//
+// The version of a request is the value of its X-API-Version header, or else
+// the version of the vendor media type in its Accept header
+//   Accept: application/vnd.myapp.v2+json
+// Requests are served by the route registered for their version, requests
+// without a version or with a version having no route are served by the route
+// registered without a version if there is any.
+//
+// The returned Mux shares the prefix, middlewares and settings of m.
+// RemoveRoute and ReplaceRoute called on it apply to the routes of version v.
+func (m *Mux) Version(v string) *Mux {
+	return &Mux{
+		prefix:  m.prefix,
+		parent:  m,
+		router:  m.router,
+		version: v,
+	}
+}
+
+// routeVersion returns the version of the routes registered by m.
+func (m *Mux) routeVersion() string {
+	for g := m; g != nil; g = g.parent {
+		if g.version != "" {
+			return g.version
+		}
+	}
+	return ""
+}
+
+// RouteVersion returns the version of the route which matched r, or an empty
+// string if the route has no version or r was not matched by a Mux.
+func RouteVersion(r *http.Request) string {
+	if h := routeOf(r.Context()); h != nil {
+		return h.version
+	}
+	return ""
+}
+
+// requestVersion returns the API version requested by r.
+func requestVersion(r *http.Request) string {
+	if v := r.Header["X-Api-Version"]; len(v) > 0 && v[0] != "" {
+		return strings.TrimSpace(v[0])
+	}
+	return acceptVersion(r.Header["Accept"])
+}
+
+// acceptVersion returns the version of the first vendor media type with a
+// version found in the Accept header values accept, like 2 for
+// application/vnd.myapp.v2+json.
+func acceptVersion(accept []string) string {
+	for _, h := range accept {
+		for h != "" {
+			var mt string
+			mt, h, _ = strings.Cut(h, ",")
+			mt, _, _ = strings.Cut(mt, ";")
+			_, sub, ok := strings.Cut(strings.TrimSpace(mt), "/vnd.")
+			if !ok {
+				continue
+			}
+			sub, _, _ = strings.Cut(sub, "+")
+			i := strings.LastIndex(sub, ".v")
+			if i == -1 || i+2 == len(sub) || sub[i+2] < '0' || sub[i+2] > '9' {
+				continue
+			}
+			return sub[i+2:]
+		}
+	}
+	return ""
+}
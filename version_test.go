@@ -0,0 +1,90 @@
+package alien
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMux_Version(t *testing.T) {
+	m := New()
+	reply := func(s string) func(http.ResponseWriter, *http.Request) {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(s + RouteVersion(r)))
+		}
+	}
+	m.Get("/users", reply("users"))
+	v2 := m.Version("2")
+	if err := v2.Get("/users", reply("users v")); err != nil {
+		t.Fatal(err)
+	}
+	if err := v2.Get("/users", reply("users v")); err == nil {
+		t.Error("expected conflict for the same version")
+	}
+	v2.Group("/admin").Get("/stats", reply("stats v"))
+	v2.Post("/users", reply("create v"))
+	m.Version("3").Get("/users/:id([0-9]+)", reply("user v"))
+
+	sample := []struct {
+		method, path, header, value string
+		status                      int
+		body                        string
+	}{
+		{"GET", "/users", "", "", http.StatusOK, "users"},
+		{"GET", "/users", "X-API-Version", "2", http.StatusOK, "users v2"},
+		{"GET", "/users", "X-API-Version", "9", http.StatusOK, "users"},
+		{"GET", "/users", "Accept", "application/vnd.myapp.v2+json", http.StatusOK, "users v2"},
+		{"GET", "/users", "Accept", "text/html, application/vnd.myapp.v2+json;q=0.9", http.StatusOK, "users v2"},
+		{"GET", "/users", "Accept", "application/vnd.myapp.vendor+json", http.StatusOK, "users"},
+		{"GET", "/admin/stats", "X-API-Version", "2", http.StatusOK, "stats v2"},
+		{"GET", "/admin/stats", "", "", http.StatusNotFound, ""},
+		{"POST", "/users", "X-API-Version", "2", http.StatusOK, "create v2"},
+		{"POST", "/users", "", "", http.StatusMethodNotAllowed, ""},
+		{"GET", "/users/42", "X-API-Version", "3", http.StatusOK, "user v3"},
+		{"GET", "/users/gernest", "X-API-Version", "3", http.StatusNotFound, ""},
+	}
+	for _, v := range sample {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(v.method, v.path, nil)
+		if v.header != "" {
+			req.Header.Set(v.header, v.value)
+		}
+		m.ServeHTTP(w, req)
+		if w.Code != v.status {
+			t.Errorf("%s %s %s: expected %d got %d", v.method, v.path, v.value, v.status, w.Code)
+			continue
+		}
+		if v.body != "" && w.Body.String() != v.body {
+			t.Errorf("%s %s %s: expected %q got %q", v.method, v.path, v.value, v.body, w.Body.String())
+		}
+	}
+
+	if err := v2.RemoveRoute("GET", "/users"); err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/users", nil)
+	req.Header.Set("X-API-Version", "2")
+	m.ServeHTTP(w, req)
+	if w.Body.String() != "users" {
+		t.Errorf("expected the unversioned route got %q", w.Body.String())
+	}
+}
+
+func TestAcceptVersion(t *testing.T) {
+	sample := []struct {
+		accept, version string
+	}{
+		{"application/vnd.myapp.v2+json", "2"},
+		{"application/vnd.myapp.v2.1+json; charset=utf-8", "2.1"},
+		{"application/json, application/vnd.github.v3+json", "3"},
+		{"application/vnd.myapp+json", ""},
+		{"application/vnd.myapp.v+json", ""},
+		{"application/json", ""},
+	}
+	for _, v := range sample {
+		if version := acceptVersion([]string{v.accept}); version != v.version {
+			t.Errorf("%s: expected %q got %q", v.accept, v.version, version)
+		}
+	}
+}
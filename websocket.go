@@ -0,0 +1,356 @@
+package alien
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Message types of websocket messages, they are the opcodes of RFC 6455.
+const (
+	TextMessage   = 1
+	BinaryMessage = 2
+	closeMessage  = 8
+	pingMessage   = 9
+	pongMessage   = 10
+)
+
+// websocketGUID is used to compute Sec-WebSocket-Accept.
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// defaultReadLimit is the maximum size of messages read by a WebSocketConn.
+const defaultReadLimit = 32 << 20
+
+var (
+	errBadHandshake  = errors.New("alien: bad websocket handshake")
+	errBadOrigin     = errors.New("alien: websocket origin not allowed")
+	errBadFrame      = errors.New("alien: bad websocket frame")
+	errBadText       = errors.New("alien: websocket text message is not valid UTF-8")
+	errMessageTooBig = errors.New("alien: websocket message too big")
+)
+
+// CloseError is returned by WebSocketConn.ReadMessage when the peer closes the
+// connection.
+type CloseError struct {
+	Code int
+	Text string
+}
+
+func (e *CloseError) Error() string {
+	return "alien: websocket closed " + strconv.Itoa(e.Code) + " " + e.Text
+}
+
+// WebSocketHandler serves a websocket connection, r is the request which was
+// upgraded. The connection is closed when it returns.
+type WebSocketHandler func(c *WebSocketConn, r *http.Request)
+
+// WebSocket registers h to serve websocket connections on pattern. Requests are
+// upgraded following the RFC 6455 handshake after going through the
+// middlewares of the route, so they can be authenticated like any other
+// request.
+//   m.WebSocket("/ws/:room", func(c *alien.WebSocketConn, r *http.Request) {
+//       for {
+//           typ, msg, err := c.ReadMessage()
+//           if err != nil {
+//               return
+//           }
+//           c.WriteMessage(typ, msg)
+//       }
+//   })
+//
+// Requests which are not valid websocket handshakes are rejected with 400 Bad
+// Request, and handshakes from other origins with 403 Forbidden, see
+// CheckOrigin.
+func (m *Mux) WebSocket(pattern string, h WebSocketHandler, wares ...func(http.Handler) http.Handler) error {
+	return m.Get(pattern, func(w http.ResponseWriter, r *http.Request) {
+		if !m.originAllowed(r) {
+			http.Error(w, errBadOrigin.Error(), http.StatusForbidden)
+			return
+		}
+		c, err := upgrade(w, r)
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		h(c, r)
+	}, wares...)
+}
+
+// CheckOrigin sets the function which decides whether websocket handshakes are
+// accepted from the Origin of r. By default handshakes whose Origin host isn't
+// the Host of the request are rejected, so other sites can't open connections
+// authenticated with the cookies of their visitors. Handshakes without an
+// Origin header are accepted, browsers always send one.
+//   m.CheckOrigin(func(r *http.Request) bool {
+//       return r.Header.Get("Origin") == "https://app.example.com"
+//   })
+//
+// Groups and hosts use the function of their parent unless they have their
+// own.
+func (m *Mux) CheckOrigin(fn func(r *http.Request) bool) {
+	m.checkOrigin = fn
+}
+
+// originAllowed returns true if the websocket handshake r is accepted from its
+// origin.
+func (m *Mux) originAllowed(r *http.Request) bool {
+	for g := m; g != nil; g = g.parent {
+		if g.checkOrigin != nil {
+			return g.checkOrigin(r)
+		}
+	}
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+// upgrade performs the websocket handshake, on failure the error response is
+// written to w.
+func upgrade(w http.ResponseWriter, r *http.Request) (*WebSocketConn, error) {
+	if !headerContains(r.Header, "Connection", "upgrade") || !headerContains(r.Header, "Upgrade", "websocket") {
+		http.Error(w, errBadHandshake.Error(), http.StatusBadRequest)
+		return nil, errBadHandshake
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, errBadHandshake.Error(), http.StatusUpgradeRequired)
+		return nil, errBadHandshake
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if b, err := base64.StdEncoding.DecodeString(key); err != nil || len(b) != 16 {
+		http.Error(w, errBadHandshake.Error(), http.StatusBadRequest)
+		return nil, errBadHandshake
+	}
+	conn, brw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, err
+	}
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	brw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: ")
+	brw.WriteString(base64.StdEncoding.EncodeToString(sum[:]))
+	brw.WriteString("\r\n\r\n")
+	if err := brw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &WebSocketConn{conn: conn, r: brw.Reader, w: brw.Writer, limit: defaultReadLimit}, nil
+}
+
+// headerContains returns true if the comma separated values of the header key
+// contain token, ignoring case.
+func headerContains(h http.Header, key, token string) bool {
+	for _, v := range h[key] {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// WebSocketConn is a server side websocket connection. ReadMessage must not be
+// called concurrently, while WriteMessage is safe to call from many goroutines.
+type WebSocketConn struct {
+	conn  net.Conn
+	r     *bufio.Reader
+	limit int64
+
+	mu     sync.Mutex
+	w      *bufio.Writer
+	closed bool
+}
+
+// SetReadLimit sets the maximum size of messages read from c, larger messages
+// close the connection. Defaults to 32MB.
+func (c *WebSocketConn) SetReadLimit(n int64) {
+	c.limit = n
+}
+
+// RemoteAddr returns the address of the peer.
+func (c *WebSocketConn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// ReadMessage reads the next text or binary message from c, fragmented
+// messages are reassembled. Pings are answered while reading, and a
+// *CloseError is returned when the peer closes the connection. Text messages
+// which are not valid UTF-8 close the connection with 1007.
+func (c *WebSocketConn) ReadMessage() (typ int, msg []byte, err error) {
+	for {
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+		switch opcode {
+		case pingMessage:
+			if err := c.writeFrame(pongMessage, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case pongMessage:
+			continue
+		case closeMessage:
+			e := &CloseError{Code: 1005}
+			if len(payload) >= 2 {
+				e.Code = int(binary.BigEndian.Uint16(payload))
+				e.Text = string(payload[2:])
+			}
+			if len(payload) > 2 {
+				payload = payload[:2]
+			}
+			c.writeFrame(closeMessage, payload)
+			return 0, nil, e
+		case 0:
+			if typ == 0 {
+				return 0, nil, c.fail(1002, errBadFrame)
+			}
+		case TextMessage, BinaryMessage:
+			if typ != 0 {
+				return 0, nil, c.fail(1002, errBadFrame)
+			}
+			typ = opcode
+		default:
+			return 0, nil, c.fail(1002, errBadFrame)
+		}
+		if int64(len(msg)+len(payload)) > c.limit {
+			return 0, nil, c.fail(1009, errMessageTooBig)
+		}
+		msg = append(msg, payload...)
+		if fin {
+			if typ == TextMessage && !utf8.Valid(msg) {
+				return 0, nil, c.fail(1007, errBadText)
+			}
+			return typ, msg, nil
+		}
+	}
+}
+
+// readFrame reads a single frame from c and unmasks its payload.
+func (c *WebSocketConn) readFrame() (fin bool, opcode int, payload []byte, err error) {
+	var h [8]byte
+	if _, err = io.ReadFull(c.r, h[:2]); err != nil {
+		return
+	}
+	fin = h[0]&0x80 != 0
+	opcode = int(h[0] & 0x0f)
+	masked := h[1]&0x80 != 0
+	n := int64(h[1] & 0x7f)
+	if h[0]&0x70 != 0 || !masked || opcode >= closeMessage && (!fin || n > 125) {
+		err = c.fail(1002, errBadFrame)
+		return
+	}
+	switch n {
+	case 126:
+		if _, err = io.ReadFull(c.r, h[:2]); err != nil {
+			return
+		}
+		n = int64(binary.BigEndian.Uint16(h[:2]))
+	case 127:
+		if _, err = io.ReadFull(c.r, h[:8]); err != nil {
+			return
+		}
+		n = int64(binary.BigEndian.Uint64(h[:8]))
+	}
+	if n < 0 || n > c.limit {
+		err = c.fail(1009, errMessageTooBig)
+		return
+	}
+	var mask [4]byte
+	if _, err = io.ReadFull(c.r, mask[:]); err != nil {
+		return
+	}
+	payload = make([]byte, n)
+	if _, err = io.ReadFull(c.r, payload); err != nil {
+		return
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return
+}
+
+// WriteMessage writes msg to c as a single frame of type typ, which must be
+// TextMessage or BinaryMessage.
+func (c *WebSocketConn) WriteMessage(typ int, msg []byte) error {
+	if typ != TextMessage && typ != BinaryMessage {
+		return errBadFrame
+	}
+	return c.writeFrame(typ, msg)
+}
+
+// Ping sends a ping to the peer, which answers with a pong that is discarded
+// by ReadMessage.
+func (c *WebSocketConn) Ping(data []byte) error {
+	if len(data) > 125 {
+		return errBadFrame
+	}
+	return c.writeFrame(pingMessage, data)
+}
+
+func (c *WebSocketConn) writeFrame(opcode int, payload []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return net.ErrClosed
+	}
+	var h [10]byte
+	h[0] = 0x80 | byte(opcode)
+	n := 2
+	switch l := len(payload); {
+	case l <= 125:
+		h[1] = byte(l)
+	case l <= 0xffff:
+		h[1] = 126
+		binary.BigEndian.PutUint16(h[2:], uint16(l))
+		n = 4
+	default:
+		h[1] = 127
+		binary.BigEndian.PutUint64(h[2:], uint64(l))
+		n = 10
+	}
+	c.w.Write(h[:n])
+	c.w.Write(payload)
+	err := c.w.Flush()
+	if opcode == closeMessage {
+		c.closed = true
+	}
+	return err
+}
+
+// fail closes c with code because of err, and returns err.
+func (c *WebSocketConn) fail(code int, err error) error {
+	c.CloseWithCode(code, err.Error())
+	return err
+}
+
+// CloseWithCode sends a close frame with code and text to the peer, and closes
+// the connection.
+func (c *WebSocketConn) CloseWithCode(code int, text string) error {
+	if len(text) > 123 {
+		text = text[:123]
+	}
+	payload := make([]byte, 2, 2+len(text))
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	c.writeFrame(closeMessage, append(payload, text...))
+	return c.conn.Close()
+}
+
+// Close closes the connection with the normal closure code 1000.
+func (c *WebSocketConn) Close() error {
+	return c.CloseWithCode(1000, "")
+}
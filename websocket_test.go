@@ -0,0 +1,191 @@
+package alien
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// writeClientFrame writes a masked frame like a websocket client does.
+func writeClientFrame(w io.Writer, fin bool, opcode int, payload []byte) {
+	b := []byte{byte(opcode), 0x80}
+	if fin {
+		b[0] |= 0x80
+	}
+	if len(payload) > 125 {
+		b[1] |= 126
+		b = binary.BigEndian.AppendUint16(b, uint16(len(payload)))
+	} else {
+		b[1] |= byte(len(payload))
+	}
+	mask := []byte{1, 2, 3, 4}
+	b = append(b, mask...)
+	for i, v := range payload {
+		b = append(b, v^mask[i%4])
+	}
+	w.Write(b)
+}
+
+// readServerFrame reads an unmasked frame sent by the server.
+func readServerFrame(t *testing.T, r *bufio.Reader) (int, []byte) {
+	var h [2]byte
+	if _, err := io.ReadFull(r, h[:]); err != nil {
+		t.Fatal(err)
+	}
+	n := int(h[1] & 0x7f)
+	if n == 126 {
+		var l [2]byte
+		io.ReadFull(r, l[:])
+		n = int(binary.BigEndian.Uint16(l[:]))
+	}
+	payload := make([]byte, n)
+	io.ReadFull(r, payload)
+	return int(h[0] & 0x0f), payload
+}
+
+func TestMux_WebSocket(t *testing.T) {
+	m := New()
+	m.WebSocket("/ws/:room", func(c *WebSocketConn, r *http.Request) {
+		room := GetParams(r).Get("room")
+		for {
+			typ, msg, err := c.ReadMessage()
+			if err != nil {
+				return
+			}
+			c.WriteMessage(typ, append([]byte(room+":"), msg...))
+		}
+	}, BasicAuth("ws", func(user, pass string) bool {
+		return user == "alien" && pass == "secret"
+	}))
+	ts := httptest.NewServer(m)
+	defer ts.Close()
+
+	handshake := "GET /ws/lobby HTTP/1.1\r\nHost: alien\r\nUpgrade: websocket\r\nConnection: keep-alive, Upgrade\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n"
+	sample := []struct {
+		auth string
+		code int
+	}{
+		{"", http.StatusUnauthorized},
+		{"Authorization: Basic YWxpZW46c2VjcmV0\r\n", http.StatusSwitchingProtocols},
+	}
+	for _, v := range sample {
+		conn, err := net.Dial("tcp", ts.Listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		conn.Write([]byte(handshake + v.auth + "\r\n"))
+		br := bufio.NewReader(conn)
+		resp, err := http.ReadResponse(br, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != v.code {
+			t.Fatalf("expected %d got %d", v.code, resp.StatusCode)
+		}
+		if v.code != http.StatusSwitchingProtocols {
+			continue
+		}
+		// example from section 1.3 of RFC 6455
+		if a := resp.Header.Get("Sec-WebSocket-Accept"); a != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+			t.Errorf("expected s3pPLMBiTxaQ9kYGzzhZRbK+xOo= got %s", a)
+		}
+
+		writeClientFrame(conn, true, TextMessage, []byte("hello"))
+		if typ, msg := readServerFrame(t, br); typ != TextMessage || string(msg) != "lobby:hello" {
+			t.Errorf("expected lobby:hello got %d %s", typ, msg)
+		}
+
+		writeClientFrame(conn, false, BinaryMessage, []byte(strings.Repeat("a", 200)))
+		writeClientFrame(conn, true, pingMessage, []byte("ping"))
+		writeClientFrame(conn, true, 0, []byte("b"))
+		if typ, msg := readServerFrame(t, br); typ != pongMessage || string(msg) != "ping" {
+			t.Errorf("expected pong got %d %s", typ, msg)
+		}
+		if typ, msg := readServerFrame(t, br); typ != BinaryMessage || len(msg) != len("lobby:")+201 {
+			t.Errorf("expected a fragmented binary message got %d %d", typ, len(msg))
+		}
+
+		writeClientFrame(conn, true, closeMessage, []byte{0x03, 0xe8})
+		if typ, msg := readServerFrame(t, br); typ != closeMessage || binary.BigEndian.Uint16(msg) != 1000 {
+			t.Errorf("expected close 1000 got %d %v", typ, msg)
+		}
+	}
+
+	req, _ := http.NewRequest("GET", "/ws/lobby", nil)
+	req.SetBasicAuth("alien", "secret")
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected %d got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestMux_WebSocket_origin(t *testing.T) {
+	echo := func(c *WebSocketConn, r *http.Request) {
+		for {
+			typ, msg, err := c.ReadMessage()
+			if err != nil {
+				return
+			}
+			c.WriteMessage(typ, msg)
+		}
+	}
+	m := New()
+	m.WebSocket("/ws", echo)
+	partners := m.Group("/partners")
+	partners.CheckOrigin(func(r *http.Request) bool {
+		return r.Header.Get("Origin") == "https://partner.example.com"
+	})
+	partners.WebSocket("/ws", echo)
+	ts := httptest.NewServer(m)
+	defer ts.Close()
+
+	sample := []struct {
+		path, origin string
+		code         int
+	}{
+		{"/ws", "", http.StatusSwitchingProtocols},
+		{"/ws", "http://alien", http.StatusSwitchingProtocols},
+		{"/ws", "https://evil.example.com", http.StatusForbidden},
+		{"/partners/ws", "https://partner.example.com", http.StatusSwitchingProtocols},
+		{"/partners/ws", "http://alien", http.StatusForbidden},
+	}
+	for _, v := range sample {
+		conn, err := net.Dial("tcp", ts.Listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		handshake := "GET " + v.path + " HTTP/1.1\r\nHost: alien\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+			"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n"
+		if v.origin != "" {
+			handshake += "Origin: " + v.origin + "\r\n"
+		}
+		conn.Write([]byte(handshake + "\r\n"))
+		br := bufio.NewReader(conn)
+		resp, err := http.ReadResponse(br, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != v.code {
+			t.Errorf("%s %s: expected %d got %d", v.path, v.origin, v.code, resp.StatusCode)
+			continue
+		}
+		if v.code != http.StatusSwitchingProtocols {
+			continue
+		}
+
+		// text messages must be valid UTF-8.
+		writeClientFrame(conn, true, TextMessage, []byte{0xff, 0xfe})
+		if typ, msg := readServerFrame(t, br); typ != closeMessage || binary.BigEndian.Uint16(msg) != 1007 {
+			t.Errorf("%s %s: expected close 1007 got %d %v", v.path, v.origin, typ, msg)
+		}
+	}
+}
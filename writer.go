@@ -0,0 +1,169 @@
+package alien
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+)
+
+var errHijackNotSupported = errors.New("hijacking not supported")
+
+// ResponseWriter is a http.ResponseWriter which keeps track of the status code
+// and the number of bytes written to the response body.
+//
+// It implements the optional interfaces of the writers of net/http, so that
+// wrapping a writer doesn't break websockets, sendfile or server push.
+type ResponseWriter interface {
+	http.ResponseWriter
+	http.Flusher
+	http.Hijacker
+	http.Pusher
+	io.ReaderFrom
+
+	// Status returns the status code of the response, or 0 if nothing was
+	// written yet.
+	Status() int
+
+	// Written returns the number of bytes written to the response body.
+	Written() int64
+}
+
+// WrapWriter returns a ResponseWriter wrapping w. Flush, Hijack, Push and
+// ReadFrom are passed to w when it supports them. Otherwise Flush is a no-op,
+// Hijack returns an error, Push returns http.ErrNotSupported and ReadFrom
+// copies with Write. Bytes written with ReadFrom are counted.
+//
+// If w is already a ResponseWriter it is returned as is.
+func WrapWriter(w http.ResponseWriter) ResponseWriter {
+	if rw, ok := w.(ResponseWriter); ok {
+		return rw
+	}
+	return &responseWriter{ResponseWriter: w}
+}
+
+type responseWriter struct {
+	http.ResponseWriter
+	status  int
+	written int64
+
+	// commit is called once before the response is committed, it lets
+	// middlewares set headers as late as possible.
+	commit func()
+}
+
+// committing calls the commit hook if it wasn't called yet.
+func (w *responseWriter) committing() {
+	if f := w.commit; f != nil {
+		w.commit = nil
+		f()
+	}
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	if !informational(code) {
+		w.committing()
+		if w.status == 0 {
+			w.status = code
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	w.committing()
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.written += int64(n)
+	return n, err
+}
+
+func (w *responseWriter) Flush() {
+	w.committing()
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		if w.status == 0 {
+			w.status = http.StatusOK
+		}
+		f.Flush()
+	}
+}
+
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.committing()
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, errHijackNotSupported
+}
+
+func (w *responseWriter) Push(target string, opts *http.PushOptions) error {
+	if p, ok := w.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+// ReadFrom copies the response body from r, with the ReadFrom of the
+// underlying writer when it has one, so that files are sent with sendfile.
+func (w *responseWriter) ReadFrom(r io.Reader) (int64, error) {
+	w.committing()
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := io.Copy(w.ResponseWriter, r)
+	w.written += n
+	return n, err
+}
+
+func (w *responseWriter) Status() int {
+	return w.status
+}
+
+func (w *responseWriter) Written() int64 {
+	return w.written
+}
+
+// Unwrap returns the underlying http.ResponseWriter, it is used by
+// http.ResponseController.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// informational returns true for the status codes of informational responses,
+// like 103 Early Hints, which are sent before the final response. 101
+// Switching Protocols is final.
+func informational(code int) bool {
+	return code >= 100 && code < 200 && code != http.StatusSwitchingProtocols
+}
+
+// Committed returns true if a response was written with w, or with the
+// ResponseWriter it wraps. The responses of requests served by a Mux are
+// tracked, so it can be used by middlewares wrapping the writer with their own.
+func Committed(w http.ResponseWriter) bool {
+	for {
+		switch v := w.(type) {
+		case interface{ Status() int }:
+			return v.Status() != 0
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = v.Unwrap()
+		default:
+			return false
+		}
+	}
+}
+
+// guard returns a handler which calls h unless the response written with w was
+// already committed. Routes guard the handler passed to each middleware, so a
+// middleware which writes a response aborts the chain even if it calls the
+// next handler afterwards.
+func guard(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if Committed(w) {
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
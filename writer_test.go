@@ -0,0 +1,234 @@
+package alien
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWrapWriter(t *testing.T) {
+	rec := httptest.NewRecorder()
+	w := WrapWriter(rec)
+	if w.Status() != 0 {
+		t.Errorf("expected 0 got %d", w.Status())
+	}
+	w.WriteHeader(http.StatusCreated)
+	w.WriteHeader(http.StatusAccepted)
+	w.Write([]byte("hello"))
+	w.Write([]byte(" world"))
+	w.Flush()
+	if w.Status() != http.StatusCreated {
+		t.Errorf("expected %d got %d", http.StatusCreated, w.Status())
+	}
+	if w.Written() != 11 {
+		t.Errorf("expected 11 got %d", w.Written())
+	}
+	if !rec.Flushed {
+		t.Error("expected the response to be flushed")
+	}
+	if _, _, err := w.Hijack(); err != errHijackNotSupported {
+		t.Errorf("expected %v got %v", errHijackNotSupported, err)
+	}
+	if WrapWriter(w) != w {
+		t.Error("expected the same writer")
+	}
+
+	w = WrapWriter(httptest.NewRecorder())
+	w.Write([]byte("hello"))
+	if w.Status() != http.StatusOK {
+		t.Errorf("expected %d got %d", http.StatusOK, w.Status())
+	}
+}
+
+// fullWriter implements the optional interfaces of the writers of net/http.
+type fullWriter struct {
+	*httptest.ResponseRecorder
+	readFrom bool
+	pushed   []string
+	hijacked bool
+}
+
+func (w *fullWriter) ReadFrom(r io.Reader) (int64, error) {
+	w.readFrom = true
+	return io.Copy(w.ResponseRecorder, r)
+}
+
+func (w *fullWriter) Push(target string, _ *http.PushOptions) error {
+	w.pushed = append(w.pushed, target)
+	return nil
+}
+
+func (w *fullWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.hijacked = true
+	return nil, nil, nil
+}
+
+// onlyReader hides the WriteTo of a reader, so that io.Copy uses ReadFrom.
+type onlyReader struct {
+	io.Reader
+}
+
+func TestWrapWriter_interfaces(t *testing.T) {
+	fw := &fullWriter{ResponseRecorder: httptest.NewRecorder()}
+	w := WrapWriter(fw)
+	if err := w.Push("/app.css", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := w.Hijack(); err != nil {
+		t.Fatal(err)
+	}
+	n, err := io.Copy(w, onlyReader{strings.NewReader("hello")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 5 || w.Written() != 5 || w.Status() != http.StatusOK {
+		t.Errorf("expected 5 bytes with status 200 got %d, %d, %d", n, w.Written(), w.Status())
+	}
+	if !fw.readFrom || !fw.hijacked || !reflect.DeepEqual(fw.pushed, []string{"/app.css"}) {
+		t.Errorf("expected the calls to be passed on got %+v", fw)
+	}
+	if fw.Body.String() != "hello" {
+		t.Errorf("expected hello got %s", fw.Body)
+	}
+
+	rec := httptest.NewRecorder()
+	w = WrapWriter(rec)
+	if err := w.Push("/app.css", nil); !errors.Is(err, http.ErrNotSupported) {
+		t.Errorf("expected %v got %v", http.ErrNotSupported, err)
+	}
+	if n, err := w.ReadFrom(strings.NewReader("hello")); n != 5 || err != nil || rec.Body.String() != "hello" {
+		t.Errorf("expected hello to be copied got %d, %v, %s", n, err, rec.Body)
+	}
+}
+
+func TestResponseWriter_commit(t *testing.T) {
+	calls := 0
+	rec := httptest.NewRecorder()
+	w := &responseWriter{ResponseWriter: rec}
+	w.commit = func() {
+		calls++
+		w.Header().Set("X-Committed", "true")
+	}
+	w.Write([]byte("hello"))
+	w.WriteHeader(http.StatusAccepted)
+	w.Flush()
+	if calls != 1 {
+		t.Errorf("expected the hook to be called once got %d", calls)
+	}
+	if v := rec.Result().Header.Get("X-Committed"); v != "true" {
+		t.Errorf("expected the header to be set before the response got %q", v)
+	}
+
+	calls = 0
+	fw := &fullWriter{ResponseRecorder: httptest.NewRecorder()}
+	w = &responseWriter{ResponseWriter: fw, commit: func() { calls++ }}
+	if _, _, err := w.Hijack(); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 || !fw.hijacked {
+		t.Errorf("expected the hook to be called before the hijack got %d", calls)
+	}
+}
+
+func TestMux_writerInterfaces(t *testing.T) {
+	m := New()
+	m.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			if err := w.(http.Pusher).Push("/app.css", nil); err != nil {
+				t.Error(err)
+			}
+		}
+		io.Copy(w, onlyReader{strings.NewReader("hello")})
+	})
+	fw := &fullWriter{ResponseRecorder: httptest.NewRecorder()}
+	m.ServeHTTP(fw, httptest.NewRequest("GET", "/", nil))
+	if !fw.readFrom {
+		t.Error("expected the body to be copied with ReadFrom")
+	}
+	if len(fw.pushed) != 1 || fw.Body.String() != "hello" {
+		t.Errorf("expected a push and hello got %v, %s", fw.pushed, fw.Body)
+	}
+
+	fw = &fullWriter{ResponseRecorder: httptest.NewRecorder()}
+	m.ServeHTTP(fw, httptest.NewRequest("HEAD", "/", nil))
+	if fw.Body.Len() != 0 {
+		t.Errorf("expected no body got %s", fw.Body)
+	}
+}
+
+type unwrapWriter struct {
+	http.ResponseWriter
+}
+
+func (w unwrapWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func TestCommitted(t *testing.T) {
+	rec := httptest.NewRecorder()
+	if Committed(rec) {
+		t.Error("expected a plain writer not to be tracked")
+	}
+	w := WrapWriter(rec)
+	u := unwrapWriter{w}
+	if Committed(u) {
+		t.Error("expected the response not to be committed")
+	}
+	w.WriteHeader(http.StatusUnauthorized)
+	if !Committed(u) {
+		t.Error("expected the response to be committed")
+	}
+}
+
+func TestMux_abortChain(t *testing.T) {
+	auth := func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") == "" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+			}
+			// calling the next handler after writing a response is a no-op.
+			h.ServeHTTP(unwrapWriter{w}, r)
+		})
+	}
+	var reached []string
+	mark := func(s string) func(http.Handler) http.Handler {
+		return func(h http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				reached = append(reached, s)
+				h.ServeHTTP(w, r)
+			})
+		}
+	}
+	m := New()
+	m.Use(mark("use"))
+	m.Get("/admin", func(w http.ResponseWriter, r *http.Request) {
+		reached = append(reached, "h")
+		w.Write([]byte("admin"))
+	}, auth, mark("route"))
+	sample := []struct {
+		auth, body string
+		reached    []string
+	}{
+		{"", "unauthorized\n", []string{"use"}},
+		{"token", "admin", []string{"use", "route", "h"}},
+	}
+	for _, v := range sample {
+		reached = nil
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/admin", nil)
+		req.Header.Set("Authorization", v.auth)
+		m.ServeHTTP(w, req)
+		if w.Body.String() != v.body {
+			t.Errorf("expected %q got %q", v.body, w.Body.String())
+		}
+		if !reflect.DeepEqual(reached, v.reached) {
+			t.Errorf("expected %v got %v", v.reached, reached)
+		}
+	}
+}